@@ -20,11 +20,17 @@ const version = "0.1.0"
 var (
 	showVersion = kingpin.Flag("version", "Shows version info").Short('v').Bool()
 	apiAddress  = kingpin.Flag("api", "API endpoint of the provisionize service").Default("[::1]:1337").String()
-	id          = kingpin.Flag("id", "Internal identifier of the VM").String()
+	id          = kingpin.Flag("id", "oVirt ID of the VM (default: lookup by name and cluster)").String()
 	vmName      = kingpin.Arg("name", "Name of the VM to delete").Required().String()
 	clusterName = kingpin.Flag("cluster", "Name of the cluster the VM should be removed from").String()
 	fqdn        = kingpin.Flag("fqdn", "Full qualified domain name of the VM").Default("").String()
+	template    = kingpin.Flag("template", "Name of the template the VM is based on").String()
 	debug       = kingpin.Flag("debug", "Print debug information recevied from server").Bool()
+	shutdown    = kingpin.Flag("shutdown", "Shut down the VM (ACPI) if it is still running").Bool()
+	timeout     = kingpin.Flag("shutdown-timeout", "Time to wait for the VM to shut down (default defined by server)").Duration()
+	force       = kingpin.Flag("force", "Stop the VM if it is still running (after the shutdown timed out if --shutdown is set)").Bool()
+	dnsAliases  = kingpin.Flag("dns-alias", "Alias name (CNAME) created for the VM to remove. Can be used multiple times").Strings()
+	dnsRecords  = kingpin.Flag("dns-record", "Additional DNS record created for the VM to remove (name type value, @ for the FQDN). Can be used multiple times").Strings()
 )
 
 func main() {
@@ -62,7 +68,11 @@ func startDeprovisioning() (bool, error) {
 
 	client := proto.NewProvisionizeServiceClient(conn)
 
-	req := requestFromParameters()
+	req, err := requestFromParameters()
+	if err != nil {
+		return false, err
+	}
+
 	stream, err := client.Deprovisionize(context.Background(), req)
 	if err != nil {
 		return false, errors.Wrap(err, "error on provisionize call")
@@ -86,7 +96,12 @@ func startDeprovisioning() (bool, error) {
 	}
 }
 
-func requestFromParameters() *proto.ProvisionizeRequest {
+func requestFromParameters() (*proto.ProvisionizeRequest, error) {
+	recs, err := clientutils.ParseDNSRecords(*dnsRecords)
+	if err != nil {
+		return nil, err
+	}
+
 	return &proto.ProvisionizeRequest{
 		RequestId: uuid.New().String(),
 		VirtualMachine: &proto.VirtualMachine{
@@ -94,6 +109,14 @@ func requestFromParameters() *proto.ProvisionizeRequest {
 			Id:          *id,
 			Fqdn:        *fqdn,
 			Name:        *vmName,
+			Template:    *template,
+			DnsAliases:  *dnsAliases,
+			DnsRecords:  recs,
 		},
-	}
+		DeprovisionOptions: &proto.DeprovisionOptions{
+			Shutdown:               *shutdown,
+			ShutdownTimeoutSeconds: uint32(timeout.Seconds()),
+			Force:                  *force,
+		},
+	}, nil
 }
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/MauveSoftware/provisionize/cmd/provisionize/config"
+	"github.com/MauveSoftware/provisionize/pkg/api/proto"
+	"github.com/MauveSoftware/provisionize/pkg/cloudinit"
+	"github.com/MauveSoftware/provisionize/pkg/utils"
+)
+
+// applyCloudInit merges the cloud-init defaults of the template into the settings of the request.
+// The password policy is rendered into the user data.
+func applyCloudInit(vm *proto.VirtualMachine, defaults *config.CloudInitDefaults) error {
+	if defaults == nil {
+		defaults = &config.CloudInitDefaults{}
+	}
+
+	c := vm.CloudInit
+	if c == nil {
+		c = &proto.CloudInit{}
+	}
+
+	c.AuthorizedSshKeys = utils.AppendMissing(utils.AppendMissing(nil, defaults.AuthorizedSSHKeys...), c.AuthorizedSshKeys...)
+	c.DisablePasswordAuth = c.DisablePasswordAuth || defaults.DisablePasswordAuth
+	c.ExpirePassword = c.ExpirePassword || defaults.ExpirePassword
+
+	if len(c.UserName) == 0 {
+		c.UserName = defaults.UserName
+	}
+
+	if len(c.Timezone) == 0 {
+		c.Timezone = defaults.Timezone
+	}
+
+	if len(c.DnsServers) == 0 {
+		c.DnsServers = defaults.DNSServers
+	}
+
+	if len(c.DnsSearch) == 0 {
+		c.DnsSearch = defaults.DNSSearch
+	}
+
+	policy := ""
+	if c.DisablePasswordAuth {
+		policy += "ssh_pwauth: false\n"
+	}
+
+	if c.ExpirePassword {
+		policy += "chpasswd:\n  expire: true\n"
+	}
+
+	userData, err := cloudinit.Merge(defaults.UserData, c.UserData, policy)
+	if err != nil {
+		return errors.Wrap(err, "cloud_init")
+	}
+	c.UserData = userData
+
+	if vm.CloudInit != nil || !isEmptyCloudInit(c) {
+		vm.CloudInit = c
+	}
+
+	return nil
+}
+
+func isEmptyCloudInit(c *proto.CloudInit) bool {
+	return len(c.AuthorizedSshKeys) == 0 && len(c.UserName) == 0 && len(c.Password) == 0 && len(c.Timezone) == 0 &&
+		len(c.DnsServers) == 0 && len(c.DnsSearch) == 0 && len(c.UserData) == 0
+}
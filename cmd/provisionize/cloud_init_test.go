@@ -0,0 +1,68 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/MauveSoftware/provisionize/cmd/provisionize/config"
+	"github.com/MauveSoftware/provisionize/pkg/api/proto"
+)
+
+func TestApplyCloudInit(t *testing.T) {
+	defaults := &config.CloudInitDefaults{
+		AuthorizedSSHKeys:   []string{"ssh-ed25519 AAAA ops"},
+		UserName:            "ops",
+		DisablePasswordAuth: true,
+		Timezone:            "Europe/Berlin",
+		DNSServers:          []string{"192.168.1.53"},
+		DNSSearch:           []string{"example.com"},
+		UserData:            "packages:\n  - htop\n",
+	}
+
+	t.Run("defaults only", func(t *testing.T) {
+		vm := &proto.VirtualMachine{}
+		require.NoError(t, applyCloudInit(vm, defaults))
+
+		assert.Equal(t, &proto.CloudInit{
+			AuthorizedSshKeys:   []string{"ssh-ed25519 AAAA ops"},
+			UserName:            "ops",
+			DisablePasswordAuth: true,
+			Timezone:            "Europe/Berlin",
+			DnsServers:          []string{"192.168.1.53"},
+			DnsSearch:           []string{"example.com"},
+			UserData:            "#cloud-config\npackages:\n- htop\nssh_pwauth: false\n",
+		}, vm.CloudInit)
+	})
+
+	t.Run("request overrides defaults", func(t *testing.T) {
+		vm := &proto.VirtualMachine{
+			CloudInit: &proto.CloudInit{
+				AuthorizedSshKeys: []string{"ssh-ed25519 BBBB dba", "ssh-ed25519 AAAA ops"},
+				UserName:          "dba",
+				Password:          "secret",
+				ExpirePassword:    true,
+				DnsServers:        []string{"10.0.0.53"},
+				UserData:          "#cloud-config\npackages:\n  - postgresql\n",
+			},
+		}
+		require.NoError(t, applyCloudInit(vm, defaults))
+
+		assert.Equal(t, []string{"ssh-ed25519 AAAA ops", "ssh-ed25519 BBBB dba"}, vm.CloudInit.AuthorizedSshKeys)
+		assert.Equal(t, "dba", vm.CloudInit.UserName)
+		assert.Equal(t, []string{"10.0.0.53"}, vm.CloudInit.DnsServers)
+		assert.Equal(t, "#cloud-config\nchpasswd:\n  expire: true\npackages:\n- htop\n- postgresql\nssh_pwauth: false\n", vm.CloudInit.UserData)
+	})
+
+	t.Run("nothing configured", func(t *testing.T) {
+		vm := &proto.VirtualMachine{}
+		require.NoError(t, applyCloudInit(vm, nil))
+		assert.Nil(t, vm.CloudInit)
+	})
+
+	t.Run("invalid user data", func(t *testing.T) {
+		vm := &proto.VirtualMachine{CloudInit: &proto.CloudInit{UserData: "packages: [htop"}}
+		assert.Error(t, applyCloudInit(vm, defaults))
+	})
+}
@@ -1,7 +1,11 @@
 package config
 
 import (
+	"fmt"
 	"io"
+	"io/ioutil"
+	"reflect"
+	"time"
 
 	yaml "gopkg.in/yaml.v2"
 
@@ -14,45 +18,293 @@ type Config struct {
 	Ovirt           *OvirtConfig          `yaml:"ovirt"`
 	GooglecCloudDNS *GoogleCloudDNSConfig `yaml:"gcloud"`
 	AnsibleTower    *AnsibleTowerConfig   `yaml:"ansible_tower"`
+	Pipeline        []*Step               `yaml:"pipeline"`
 	Templates       []*ProvisionTemplate  `yaml:"templates"`
+	Secrets         *SecretsConfig        `yaml:"secrets"`
+	RecycleBin      *RecycleBinConfig     `yaml:"recycle_bin"`
 }
 
 // ProvisionTemplate represents a set of templates to apply for a certain template defined in VM
 type ProvisionTemplate struct {
-	Name             string `yaml:"name"`
-	OvirtTemplate    string `yaml:"ovirt"`
-	AnsibleTemplates []uint `yaml:"ansible_tower"`
-	BootDiskName     string `yaml:"boot_disk_name"`
+	Name                        string             `yaml:"name"`
+	OvirtTemplate               string             `yaml:"ovirt"`
+	AnsibleTemplates            []uint             `yaml:"ansible_tower"`
+	AnsibleWorkflows            []uint             `yaml:"ansible_tower_workflows"`
+	AnsibleDeprovisionTemplates []uint             `yaml:"ansible_tower_deprovision"`
+	AnsiblePlaybooks            []string           `yaml:"ansible_playbooks"`
+	AnsibleDeprovisionPlaybooks []string           `yaml:"ansible_deprovision_playbooks"`
+	BootDiskName                string             `yaml:"boot_disk_name"`
+	SkipSteps                   []string           `yaml:"skip_steps"`
+	CPUCores                    *ResourceLimits    `yaml:"cpu_cores"`
+	MemoryMB                    *ResourceLimits    `yaml:"memory_mb"`
+	Cluster                     string             `yaml:"cluster"`
+	Network                     *NetworkDefaults   `yaml:"network"`
+	CloudInit                   *CloudInitDefaults `yaml:"cloud_init"`
+	ExtraVars                   ExtraVars          `yaml:"extra_vars"`
+}
+
+// ExtraVars are static variables passed to the Ansible Tower job templates and playbooks of a template
+type ExtraVars map[string]interface{}
+
+// UnmarshalYAML converts nested mappings to map[string]interface{} so the vars can be serialized to JSON
+func (e *ExtraVars) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	m := make(map[string]interface{})
+	err := unmarshal(&m)
+	if err != nil {
+		return err
+	}
+
+	for k, v := range m {
+		m[k] = jsonValue(v)
+	}
+
+	*e = m
+	return nil
+}
+
+func jsonValue(v interface{}) interface{} {
+	switch x := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(x))
+		for k, val := range x {
+			m[fmt.Sprint(k)] = jsonValue(val)
+		}
+		return m
+	case []interface{}:
+		for i, val := range x {
+			x[i] = jsonValue(val)
+		}
+		return x
+	default:
+		return v
+	}
+}
+
+// ResourceLimits represents the default value and the bounds of a resource of a VM (0 means not set)
+type ResourceLimits struct {
+	Default uint32 `yaml:"default"`
+	Min     uint32 `yaml:"min"`
+	Max     uint32 `yaml:"max"`
+}
+
+// NetworkDefaults represents the default network settings for VMs based on a template
+type NetworkDefaults struct {
+	IPv4 *IPDefaults `yaml:"ipv4"`
+	IPv6 *IPDefaults `yaml:"ipv6"`
+}
+
+// IPDefaults represents the default settings for an address family
+type IPDefaults struct {
+	PrefixLength uint32 `yaml:"prefix_length"`
+	Gateway      string `yaml:"gateway"`
+}
+
+// CloudInitDefaults represents the cloud-init settings for VMs based on a template.
+// UserData is merged with the user data of the request, SSH keys are added to the keys of the request.
+type CloudInitDefaults struct {
+	AuthorizedSSHKeys   []string `yaml:"authorized_ssh_keys"`
+	UserName            string   `yaml:"user_name"`
+	DisablePasswordAuth bool     `yaml:"disable_password_auth"`
+	ExpirePassword      bool     `yaml:"expire_password"`
+	Timezone            string   `yaml:"timezone"`
+	DNSServers          []string `yaml:"dns_servers"`
+	DNSSearch           []string `yaml:"dns_search"`
+	UserData            string   `yaml:"user_data"`
 }
 
 // OvirtConfig represents to oVirt configuration part
 type OvirtConfig struct {
-	URL          string `yaml:"url"`
-	Username     string `yaml:"username"`
-	Password     string `yaml:"password"`
-	TemplatePath string `yaml:"template_path"`
+	URL           string `yaml:"url"`
+	Username      string `yaml:"username"`
+	Password      string `yaml:"password"`
+	PasswordFile  string `yaml:"password_file"`
+	TemplatePath  string `yaml:"template_path"`
+	KeepDataDisks bool   `yaml:"keep_data_disks"`
 }
 
 // GoogleCloudDNSConfig represents to DNS configuration part
 type GoogleCloudDNSConfig struct {
-	CredentialsFile string `yaml:"credentials_file"`
-	ProjectID       string `yaml:"project_id"`
+	CredentialsFile string             `yaml:"credentials_file"`
+	ProjectID       string             `yaml:"project_id"`
+	TTL             uint32             `yaml:"ttl"`
+	Zones           []*DNSZoneSettings `yaml:"zones"`
+	UpdateExisting  bool               `yaml:"update_existing"`
+	AliasDomains    []string           `yaml:"alias_domains"`
+}
+
+// DNSZoneSettings represents settings applied to records in a certain zone
+type DNSZoneSettings struct {
+	Name string `yaml:"name"`
+	TTL  uint32 `yaml:"ttl"`
 }
 
 // AnsibleTowerConfig represents the Ansible Tower configuration part
 type AnsibleTowerConfig struct {
-	URL      string `yaml:"url"`
-	Username string `yaml:"username"`
-	Password string `yaml:"password"`
+	URL          string                `yaml:"url"`
+	Username     string                `yaml:"username"`
+	Password     string                `yaml:"password"`
+	PasswordFile string                `yaml:"password_file"`
+	Token        string                `yaml:"token"`
+	TokenFile    string                `yaml:"token_file"`
+	CAFile       string                `yaml:"ca_file"`
+	Insecure     bool                  `yaml:"insecure"`
+	Timeout      time.Duration         `yaml:"timeout"`
+	Inventory    *TowerInventoryConfig `yaml:"inventory"`
+}
+
+// TowerInventoryConfig represents the inventory (and group) provisioned VMs are added to
+type TowerInventoryConfig struct {
+	ID      uint `yaml:"id"`
+	GroupID uint `yaml:"group_id"`
+}
+
+// AnsibleConfig represents the configuration of the local ansible-playbook runner
+type AnsibleConfig struct {
+	PlaybookDir string        `yaml:"playbook_dir"`
+	Binary      string        `yaml:"binary"`
+	Timeout     time.Duration `yaml:"timeout"`
+}
+
+// ReadinessConfig represents the configuration of the step waiting for a new VM to become reachable
+type ReadinessConfig struct {
+	Ports      []uint16      `yaml:"ports"`
+	GuestAgent bool          `yaml:"guest_agent"`
+	Timeout    time.Duration `yaml:"timeout"`
+	Interval   time.Duration `yaml:"interval"`
+}
+
+// WebhookConfig represents the configuration of the webhook step
+type WebhookConfig struct {
+	Endpoints []*WebhookEndpointConfig `yaml:"endpoints"`
+}
+
+// WebhookEndpointConfig represents an URL notified about provisioning events
+type WebhookEndpointConfig struct {
+	Name       string            `yaml:"name"`
+	URL        string            `yaml:"url"`
+	Secret     string            `yaml:"secret"`
+	SecretFile string            `yaml:"secret_file"`
+	Mode       string            `yaml:"mode"`
+	Phases     []string          `yaml:"phases"`
+	Headers    map[string]string `yaml:"headers"`
+	Timeout    time.Duration     `yaml:"timeout"`
+	Retries    int               `yaml:"retries"`
+}
+
+const (
+	// WebhookModeSync waits for the endpoint to respond, failures fail the provisioning
+	WebhookModeSync = "sync"
+
+	// WebhookModeAsync sends the payload in background
+	WebhookModeAsync = "async"
+)
+
+// IPAMConfig represents the configuration of the IP address management
+type IPAMConfig struct {
+	StateFile    string          `yaml:"state_file"`
+	SkipDNSCheck bool            `yaml:"skip_dns_check"`
+	Pools        []*IPPoolConfig `yaml:"pools"`
 }
 
-// Load reads a reader and parses the content
+// IPPoolConfig represents a prefix addresses are allocated from
+type IPPoolConfig struct {
+	Name    string   `yaml:"name"`
+	Cluster string   `yaml:"cluster"`
+	Network string   `yaml:"network"`
+	Prefix  string   `yaml:"prefix"`
+	Gateway string   `yaml:"gateway"`
+	Exclude []string `yaml:"exclude"`
+}
+
+// NetBoxConfig represents the configuration of the NetBox integration
+type NetBoxConfig struct {
+	URL          string                `yaml:"url"`
+	Token        string                `yaml:"token"`
+	TokenFile    string                `yaml:"token_file"`
+	Decommission bool                  `yaml:"decommission"`
+	Timeout      time.Duration         `yaml:"timeout"`
+	Prefixes     []*NetBoxPrefixConfig `yaml:"prefixes"`
+}
+
+// NetBoxPrefixConfig represents a NetBox prefix addresses are allocated from
+type NetBoxPrefixConfig struct {
+	Cluster string `yaml:"cluster"`
+	Network string `yaml:"network"`
+	Prefix  string `yaml:"prefix"`
+	Gateway string `yaml:"gateway"`
+}
+
+// RecycleBinConfig represents the configuration of the recycle bin keeping deprovisioned VMs for a retention period
+type RecycleBinConfig struct {
+	StateFile string        `yaml:"state_file"`
+	Retention time.Duration `yaml:"retention"`
+}
+
+// SecretsConfig represents the configuration of the secret provider used to resolve secret references
+type SecretsConfig struct {
+	Vault *VaultConfig `yaml:"vault"`
+}
+
+// VaultConfig represents the configuration of the HashiCorp Vault KV secret provider
+type VaultConfig struct {
+	Address   string `yaml:"address"`
+	Token     string `yaml:"token"`
+	TokenFile string `yaml:"token_file"`
+	Mount     string `yaml:"mount"`
+}
+
+// Load reads a reader and parses the content.
+// Environment variables referenced as ${NAME} in string values are expanded, secrets are read from files or the configured
+// secret provider and the result is validated.
 func Load(r io.Reader) (*Config, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not read config")
+	}
+
 	config := &Config{}
-	err := yaml.NewDecoder(r).Decode(config)
+	err = yaml.Unmarshal(b, config)
 	if err != nil {
 		return nil, errors.Wrap(err, "could parse config")
 	}
 
+	expandEnvValues(reflect.ValueOf(config))
+
+	err = config.normalizePipeline()
+	if err != nil {
+		return nil, err
+	}
+
+	err = config.resolveSecretFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := config.secretProvider()
+	if err != nil {
+		return nil, err
+	}
+
+	err = config.ResolveSecrets(p)
+	if err != nil {
+		return nil, err
+	}
+
+	err = config.Validate()
+	if err != nil {
+		return nil, err
+	}
+
 	return config, nil
 }
+
+// SkipsStep returns true if the step is listed in skip_steps
+func (t *ProvisionTemplate) SkipsStep(step string) bool {
+	for _, s := range t.SkipSteps {
+		if s == step {
+			return true
+		}
+	}
+
+	return false
+}
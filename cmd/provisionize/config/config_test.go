@@ -1,10 +1,16 @@
 package config
 
 import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestLoad(t *testing.T) {
@@ -28,6 +34,10 @@ templates:
       - 1
       - 2
     boot_disk_name: new-disk
+    extra_vars:
+      environment: production
+      ntp:
+        servers: [ntp1, ntp2]
 `
 	expected := &Config{
 		ListenAddress: "[::]:1337",
@@ -52,10 +62,20 @@ templates:
 				OvirtTemplate:    "ubuntu-18.04",
 				AnsibleTemplates: []uint{1, 2},
 				BootDiskName:     "new-disk",
+				ExtraVars: ExtraVars{
+					"environment": "production",
+					"ntp":         map[string]interface{}{"servers": []interface{}{"ntp1", "ntp2"}},
+				},
 			},
 		},
 	}
 
+	expected.Pipeline = []*Step{
+		{Name: "ovirt", Type: StepTypeOvirt, Ovirt: expected.Ovirt, fromSection: true},
+		{Name: "gcloud", Type: StepTypeGoogleCloudDNS, GooglecCloudDNS: expected.GooglecCloudDNS, fromSection: true},
+		{Name: "ansible_tower", Type: StepTypeAnsibleTower, AnsibleTower: expected.AnsibleTower, fromSection: true},
+	}
+
 	r := strings.NewReader(config)
 	cfg, err := Load(r)
 	if err != nil {
@@ -64,3 +84,400 @@ templates:
 
 	assert.Equal(t, expected, cfg)
 }
+
+func TestLoadWithEnvAndSecretFile(t *testing.T) {
+	dir := t.TempDir()
+	passwordFile := filepath.Join(dir, "tower-password")
+	err := ioutil.WriteFile(passwordFile, []byte("magic\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	os.Setenv("PROVISIONIZE_TEST_OVIRT_PASSWORD", "allTheThings")
+	defer os.Unsetenv("PROVISIONIZE_TEST_OVIRT_PASSWORD")
+
+	config := `listen_address: "[::]:1337"
+ovirt:
+  url: https://my-ovirt.instance
+  username: provisionize
+  password: ${PROVISIONIZE_TEST_OVIRT_PASSWORD}
+  template_path: /etc/provisionize/template
+gcloud:
+  credentials_file: "/config/cred.json"
+  project_id: "123"
+ansible_tower:
+  url: https://tower
+  username: ansible
+  password_file: ` + passwordFile + `
+`
+
+	cfg, err := Load(strings.NewReader(config))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "allTheThings", cfg.Ovirt.Password)
+	assert.Equal(t, "magic", cfg.AnsibleTower.Password)
+}
+
+func TestLoadExpandsEnvInValues(t *testing.T) {
+	os.Setenv("PROVISIONIZE_TEST_OVIRT_PASSWORD", "secret\n  url: https://evil # comment")
+	os.Setenv("PROVISIONIZE_TEST_PLAYBOOK", "base.yml")
+	os.Setenv("PROVISIONIZE_TEST_NTP", "ntp1.example.com")
+	defer os.Unsetenv("PROVISIONIZE_TEST_OVIRT_PASSWORD")
+	defer os.Unsetenv("PROVISIONIZE_TEST_PLAYBOOK")
+	defer os.Unsetenv("PROVISIONIZE_TEST_NTP")
+
+	config := `listen_address: "[::]:1337"
+ovirt:
+  url: https://my-ovirt.instance
+  username: provisionize
+  password: ${PROVISIONIZE_TEST_OVIRT_PASSWORD}
+  template_path: /etc/provisionize/template
+templates:
+  - name: linux
+    ansible_playbooks: [ "${PROVISIONIZE_TEST_PLAYBOOK}" ]
+    extra_vars:
+      ntp:
+        servers: [ "${PROVISIONIZE_TEST_NTP}" ]
+`
+
+	cfg, err := Load(strings.NewReader(config))
+	require.NoError(t, err)
+
+	assert.Equal(t, "secret\n  url: https://evil # comment", cfg.Ovirt.Password)
+	assert.Equal(t, "https://my-ovirt.instance", cfg.Ovirt.URL)
+	assert.Equal(t, []string{"base.yml"}, cfg.Templates[0].AnsiblePlaybooks)
+	assert.Equal(t, map[string]interface{}{"servers": []interface{}{"ntp1.example.com"}}, cfg.Templates[0].ExtraVars["ntp"])
+}
+
+func TestLoadTowerToken(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "tower-token")
+	err := ioutil.WriteFile(tokenFile, []byte("abc123\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := `listen_address: "[::]:1337"
+ansible_tower:
+  url: https://awx
+  token_file: ` + tokenFile + `
+  ca_file: /etc/ssl/awx-ca.pem
+  timeout: 10s
+`
+
+	cfg, err := Load(strings.NewReader(config))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "abc123", cfg.AnsibleTower.Token)
+	assert.Equal(t, "/etc/ssl/awx-ca.pem", cfg.AnsibleTower.CAFile)
+	assert.Equal(t, 10*time.Second, cfg.AnsibleTower.Timeout)
+}
+
+type mockSecretProvider struct {
+	secrets map[string]string
+}
+
+func (m *mockSecretProvider) Secret(path, key string) (string, error) {
+	v, found := m.secrets[path+"#"+key]
+	if !found {
+		return "", fmt.Errorf("secret %s#%s not found", path, key)
+	}
+
+	return v, nil
+}
+
+func TestResolveSecrets(t *testing.T) {
+	tests := []struct {
+		name             string
+		password         string
+		provider         SecretProvider
+		expectedPassword string
+		expectError      bool
+	}{
+		{
+			name:             "plain password",
+			password:         "allTheThings",
+			expectedPassword: "allTheThings",
+		},
+		{
+			name:             "secret reference",
+			password:         "secret:provisionize#ovirt",
+			provider:         &mockSecretProvider{secrets: map[string]string{"provisionize#ovirt": "fromVault"}},
+			expectedPassword: "fromVault",
+		},
+		{
+			name:        "secret reference without provider",
+			password:    "secret:provisionize#ovirt",
+			expectError: true,
+		},
+		{
+			name:        "invalid secret reference",
+			password:    "secret:provisionize",
+			provider:    &mockSecretProvider{},
+			expectError: true,
+		},
+		{
+			name:        "unknown secret",
+			password:    "secret:provisionize#tower",
+			provider:    &mockSecretProvider{},
+			expectError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			o := &OvirtConfig{Password: test.password}
+			cfg := &Config{Pipeline: []*Step{{Name: "vm", Type: StepTypeOvirt, Ovirt: o}}}
+
+			err := cfg.ResolveSecrets(test.provider)
+			if test.expectError {
+				assert.Error(t, err)
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assert.Equal(t, test.expectedPassword, o.Password)
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name        string
+		config      string
+		expectError string
+	}{
+		{
+			name:        "no steps",
+			config:      `listen_address: "[::]:1337"`,
+			expectError: "no steps defined",
+		},
+		{
+			name: "pipeline combined with sections",
+			config: `listen_address: "[::]:1337"
+gcloud:
+  credentials_file: "/config/cred.json"
+  project_id: "123"
+pipeline:
+  - name: dns
+    type: gcloud
+    gcloud:
+      credentials_file: "/config/cred.json"
+      project_id: "123"
+`,
+			expectError: "can not be combined with pipeline",
+		},
+		{
+			name: "unknown step type",
+			config: `listen_address: "[::]:1337"
+pipeline:
+  - name: dns
+    type: route53
+`,
+			expectError: "pipeline[dns]: unknown step type 'route53'",
+		},
+		{
+			name: "missing step settings",
+			config: `listen_address: "[::]:1337"
+pipeline:
+  - name: dns
+    type: gcloud
+`,
+			expectError: "pipeline[dns].gcloud settings are missing",
+		},
+		{
+			name: "tower inventory without id",
+			config: `listen_address: "[::]:1337"
+ansible_tower:
+  url: https://tower
+  username: ansible
+  password: magic
+  inventory:
+    group_id: 3
+`,
+			expectError: "ansible_tower.inventory.id is required",
+		},
+		{
+			name: "ansible without playbook dir",
+			config: `listen_address: "[::]:1337"
+pipeline:
+  - name: config
+    type: ansible
+    ansible:
+      timeout: 10m
+`,
+			expectError: "pipeline[config].ansible.playbook_dir is required",
+		},
+		{
+			name: "invalid webhook endpoint",
+			config: `listen_address: "[::]:1337"
+pipeline:
+  - name: cmdb
+    type: webhook
+    webhook:
+      endpoints:
+        - name: cmdb
+          mode: later
+          phases: [provision, recycle]
+`,
+			expectError: "pipeline[cmdb].webhook.endpoints[0].url is required; pipeline[cmdb].webhook.endpoints[0].mode: unknown mode 'later'; " +
+				"pipeline[cmdb].webhook.endpoints[0].phases: unknown phase 'recycle'",
+		},
+		{
+			name: "readiness without checks",
+			config: `listen_address: "[::]:1337"
+pipeline:
+  - name: wait
+    type: readiness
+    readiness:
+      timeout: 5m
+`,
+			expectError: "pipeline[wait].readiness: ports or guest_agent is required",
+		},
+		{
+			name: "dns zone without name",
+			config: `listen_address: "[::]:1337"
+gcloud:
+  credentials_file: "/config/cred.json"
+  project_id: "123"
+  ttl: 3600
+  zones:
+    - ttl: 60
+`,
+			expectError: "gcloud.zones[0].name is required",
+		},
+		{
+			name: "unknown step skipped by template",
+			config: `listen_address: "[::]:1337"
+gcloud:
+  credentials_file: "/config/cred.json"
+  project_id: "123"
+templates:
+  - name: linux
+    skip_steps:
+      - config
+`,
+			expectError: "templates[linux].skip_steps: unknown step config",
+		},
+		{
+			name: "invalid ipam pool",
+			config: `listen_address: "[::]:1337"
+pipeline:
+  - name: ipam
+    type: ipam
+    ipam:
+      pools:
+        - name: servers
+          prefix: 192.168.0.0
+`,
+			expectError: "pipeline[ipam].ipam.state_file is required; pipeline[ipam].ipam.pools[0].prefix: invalid prefix 192.168.0.0",
+		},
+		{
+			name: "missing netbox token",
+			config: `listen_address: "[::]:1337"
+pipeline:
+  - name: netbox
+    type: netbox
+    netbox:
+      url: https://netbox
+      prefixes:
+        - prefix: 192.168.0.0/24
+`,
+			expectError: "invalid config: pipeline[netbox].netbox.token is required",
+		},
+		{
+			name: "invalid cloud-init user data",
+			config: `listen_address: "[::]:1337"
+gcloud:
+  credentials_file: "/config/cred.json"
+  project_id: "123"
+templates:
+  - name: linux
+    cloud_init:
+      user_data: |
+        - htop
+`,
+			expectError: "templates[linux].cloud_init.user_data: invalid user data: expected a YAML mapping",
+		},
+		{
+			name: "recycle bin without retention",
+			config: `listen_address: "[::]:1337"
+gcloud:
+  credentials_file: "/config/cred.json"
+  project_id: "123"
+recycle_bin:
+  state_file: /var/lib/provisionize/recycle_bin.json
+`,
+			expectError: "recycle_bin.retention has to be greater than 0",
+		},
+		{
+			name: "missing required fields",
+			config: `ovirt:
+  url: https://my-ovirt.instance
+gcloud:
+  project_id: "123"
+ansible_tower:
+  url: https://tower
+`,
+			expectError: "invalid config: listen_address is required; ovirt.username is required; ovirt.password is required; " +
+				"ovirt.template_path is required; gcloud.credentials_file is required; ansible_tower.username is required; " +
+				"ansible_tower.password is required",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := Load(strings.NewReader(test.config))
+			if assert.Error(t, err) {
+				assert.Contains(t, err.Error(), test.expectError)
+			}
+		})
+	}
+}
+
+func TestLoadPipeline(t *testing.T) {
+	config := `listen_address: "[::]:1337"
+pipeline:
+  - name: vm
+    type: ovirt
+    ovirt:
+      url: https://my-ovirt.instance
+      username: provisionize
+      password: allTheThings
+      template_path: /etc/provisionize/template
+  - name: dns
+    type: gcloud
+    disabled: true
+    gcloud:
+      credentials_file: "/config/cred.json"
+      project_id: "123"
+templates:
+  - name: linux
+    ovirt: ubuntu-18.04
+    skip_steps:
+      - dns
+recycle_bin:
+  state_file: /var/lib/provisionize/recycle_bin.json
+  retention: 72h
+`
+
+	cfg, err := Load(strings.NewReader(config))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, []string{"dns"}, cfg.Templates[0].SkipSteps)
+	assert.Equal(t, &RecycleBinConfig{StateFile: "/var/lib/provisionize/recycle_bin.json", Retention: 72 * time.Hour}, cfg.RecycleBin)
+
+	steps := cfg.EnabledSteps()
+	if assert.Len(t, steps, 1) {
+		assert.Equal(t, "vm", steps[0].Name)
+		assert.Equal(t, "allTheThings", steps[0].Ovirt.Password)
+	}
+}
@@ -0,0 +1,99 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// StepTypeOvirt is the type of steps creating the VM in oVirt
+	StepTypeOvirt = "ovirt"
+
+	// StepTypeGoogleCloudDNS is the type of steps managing DNS records in Google Cloud DNS
+	StepTypeGoogleCloudDNS = "gcloud"
+
+	// StepTypeAnsibleTower is the type of steps configuring the VM by Ansible Tower jobs
+	StepTypeAnsibleTower = "ansible_tower"
+
+	// StepTypeAnsible is the type of steps configuring the VM by running ansible-playbook locally
+	StepTypeAnsible = "ansible"
+
+	// StepTypeWebhook is the type of steps notifying external systems by webhooks
+	StepTypeWebhook = "webhook"
+
+	// StepTypeReadiness is the type of steps waiting for a new VM to become reachable
+	StepTypeReadiness = "readiness"
+
+	// StepTypeIPAM is the type of steps allocating IP addresses from pools
+	StepTypeIPAM = "ipam"
+
+	// StepTypeNetBox is the type of steps allocating IP addresses and registering the VM in NetBox
+	StepTypeNetBox = "netbox"
+)
+
+// Step represents a named step of the provisioning pipeline
+type Step struct {
+	Name            string                `yaml:"name"`
+	Type            string                `yaml:"type"`
+	Disabled        bool                  `yaml:"disabled"`
+	Ovirt           *OvirtConfig          `yaml:"ovirt"`
+	GooglecCloudDNS *GoogleCloudDNSConfig `yaml:"gcloud"`
+	AnsibleTower    *AnsibleTowerConfig   `yaml:"ansible_tower"`
+	Ansible         *AnsibleConfig        `yaml:"ansible"`
+	Webhook         *WebhookConfig        `yaml:"webhook"`
+	Readiness       *ReadinessConfig      `yaml:"readiness"`
+	IPAM            *IPAMConfig           `yaml:"ipam"`
+	NetBox          *NetBoxConfig         `yaml:"netbox"`
+
+	// fromSection is set when the step was derived from a top level section of the config
+	fromSection bool
+}
+
+// normalizePipeline derives the pipeline from the top level sections (ovirt, gcloud, ansible_tower) if no pipeline is defined
+func (c *Config) normalizePipeline() error {
+	hasSections := c.Ovirt != nil || c.GooglecCloudDNS != nil || c.AnsibleTower != nil
+
+	if len(c.Pipeline) > 0 {
+		if hasSections {
+			return errors.New("the sections ovirt, gcloud and ansible_tower can not be combined with pipeline")
+		}
+
+		return nil
+	}
+
+	if c.Ovirt != nil {
+		c.Pipeline = append(c.Pipeline, &Step{Name: StepTypeOvirt, Type: StepTypeOvirt, Ovirt: c.Ovirt, fromSection: true})
+	}
+
+	if c.GooglecCloudDNS != nil {
+		c.Pipeline = append(c.Pipeline, &Step{Name: StepTypeGoogleCloudDNS, Type: StepTypeGoogleCloudDNS, GooglecCloudDNS: c.GooglecCloudDNS, fromSection: true})
+	}
+
+	if c.AnsibleTower != nil {
+		c.Pipeline = append(c.Pipeline, &Step{Name: StepTypeAnsibleTower, Type: StepTypeAnsibleTower, AnsibleTower: c.AnsibleTower, fromSection: true})
+	}
+
+	return nil
+}
+
+// EnabledSteps returns the steps of the pipeline which are not disabled
+func (c *Config) EnabledSteps() []*Step {
+	steps := make([]*Step, 0, len(c.Pipeline))
+	for _, s := range c.Pipeline {
+		if !s.Disabled {
+			steps = append(steps, s)
+		}
+	}
+
+	return steps
+}
+
+// path returns the path of the settings of the step used in error messages
+func (s *Step) path() string {
+	if s.fromSection {
+		return s.Type
+	}
+
+	return fmt.Sprintf("pipeline[%s].%s", s.Name, s.Type)
+}
@@ -0,0 +1,218 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/MauveSoftware/provisionize/pkg/secrets/vault"
+)
+
+// secretPrefix marks a value as reference to a secret in the configured secret provider (secret:<path>#<key>)
+const secretPrefix = "secret:"
+
+var envPattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// SecretProvider provides secrets stored outside of the config file
+type SecretProvider interface {
+	// Secret returns the value for key of the secret stored at path
+	Secret(path, key string) (string, error)
+}
+
+func expandEnv(s string) string {
+	return envPattern.ReplaceAllStringFunc(s, func(m string) string {
+		return os.Getenv(envPattern.FindStringSubmatch(m)[1])
+	})
+}
+
+// expandEnvValues expands the environment variables in all string values of the parsed config.
+// Expanding after parsing ensures a value can not change the structure of the document.
+func expandEnvValues(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if !v.IsNil() {
+			expandEnvValues(v.Elem())
+		}
+	case reflect.Interface:
+		if !v.IsNil() && v.CanSet() {
+			v.Set(expandedValue(v.Elem()))
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if len(v.Type().Field(i).PkgPath) == 0 {
+				expandEnvValues(v.Field(i))
+			}
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			expandEnvValues(v.Index(i))
+		}
+	case reflect.Map:
+		for _, k := range v.MapKeys() {
+			v.SetMapIndex(k, expandedValue(v.MapIndex(k)))
+		}
+	case reflect.String:
+		if v.CanSet() {
+			v.SetString(expandEnv(v.String()))
+		}
+	}
+}
+
+// expandedValue returns a copy of v with expanded environment variables (map entries are not addressable)
+func expandedValue(v reflect.Value) reflect.Value {
+	c := reflect.New(v.Type()).Elem()
+	c.Set(v)
+	expandEnvValues(c)
+
+	return c
+}
+
+func (c *Config) resolveSecretFiles() error {
+	for _, step := range c.Pipeline {
+		if step.Ovirt != nil {
+			err := readSecretFile(&step.Ovirt.Password, step.Ovirt.PasswordFile)
+			if err != nil {
+				return errors.Wrap(err, step.path())
+			}
+		}
+
+		if step.AnsibleTower != nil {
+			err := readSecretFile(&step.AnsibleTower.Password, step.AnsibleTower.PasswordFile)
+			if err != nil {
+				return errors.Wrap(err, step.path())
+			}
+
+			err = readSecretFile(&step.AnsibleTower.Token, step.AnsibleTower.TokenFile)
+			if err != nil {
+				return errors.Wrap(err, step.path())
+			}
+		}
+
+		if step.Webhook != nil {
+			for _, e := range step.Webhook.Endpoints {
+				err := readSecretFile(&e.Secret, e.SecretFile)
+				if err != nil {
+					return errors.Wrap(err, step.path())
+				}
+			}
+		}
+
+		if step.NetBox != nil {
+			err := readSecretFile(&step.NetBox.Token, step.NetBox.TokenFile)
+			if err != nil {
+				return errors.Wrap(err, step.path())
+			}
+		}
+	}
+
+	if c.Secrets != nil && c.Secrets.Vault != nil {
+		err := readSecretFile(&c.Secrets.Vault.Token, c.Secrets.Vault.TokenFile)
+		if err != nil {
+			return errors.Wrap(err, "secrets.vault")
+		}
+	}
+
+	return nil
+}
+
+func readSecretFile(target *string, path string) error {
+	if len(path) == 0 {
+		return nil
+	}
+
+	if len(*target) > 0 {
+		return fmt.Errorf("secret and secret file (%s) must not be set both", path)
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return errors.Wrap(err, "could not read secret file")
+	}
+
+	*target = strings.TrimRight(string(b), "\r\n")
+	return nil
+}
+
+func (c *Config) secretProvider() (SecretProvider, error) {
+	if c.Secrets == nil || c.Secrets.Vault == nil {
+		return nil, nil
+	}
+
+	v := c.Secrets.Vault
+	if len(v.Address) == 0 {
+		return nil, errors.New("secrets.vault: address is required")
+	}
+
+	return vault.NewClient(v.Address, v.Token, v.Mount), nil
+}
+
+// ResolveSecrets replaces all secret references (secret:<path>#<key>) by the value returned from the provider
+func (c *Config) ResolveSecrets(p SecretProvider) error {
+	for _, step := range c.Pipeline {
+		if step.Ovirt != nil {
+			err := resolveSecret(&step.Ovirt.Password, p)
+			if err != nil {
+				return errors.Wrap(err, step.path()+".password")
+			}
+		}
+
+		if step.AnsibleTower != nil {
+			err := resolveSecret(&step.AnsibleTower.Password, p)
+			if err != nil {
+				return errors.Wrap(err, step.path()+".password")
+			}
+
+			err = resolveSecret(&step.AnsibleTower.Token, p)
+			if err != nil {
+				return errors.Wrap(err, step.path()+".token")
+			}
+		}
+
+		if step.Webhook != nil {
+			for i, e := range step.Webhook.Endpoints {
+				err := resolveSecret(&e.Secret, p)
+				if err != nil {
+					return errors.Wrapf(err, "%s.endpoints[%d].secret", step.path(), i)
+				}
+			}
+		}
+
+		if step.NetBox != nil {
+			err := resolveSecret(&step.NetBox.Token, p)
+			if err != nil {
+				return errors.Wrap(err, step.path()+".token")
+			}
+		}
+	}
+
+	return nil
+}
+
+func resolveSecret(value *string, p SecretProvider) error {
+	if !strings.HasPrefix(*value, secretPrefix) {
+		return nil
+	}
+
+	if p == nil {
+		return errors.New("secret reference found, but no secret provider is configured")
+	}
+
+	ref := strings.TrimPrefix(*value, secretPrefix)
+	t := strings.SplitN(ref, "#", 2)
+	if len(t) != 2 || len(t[0]) == 0 || len(t[1]) == 0 {
+		return fmt.Errorf("invalid secret reference %s (expected secret:<path>#<key>)", *value)
+	}
+
+	v, err := p.Secret(t[0], t[1])
+	if err != nil {
+		return err
+	}
+
+	*value = v
+	return nil
+}
@@ -0,0 +1,241 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/MauveSoftware/provisionize/pkg/cloudinit"
+	"github.com/MauveSoftware/provisionize/pkg/hooks/webhook"
+)
+
+var webhookPhases = map[string]bool{
+	webhook.PhaseProvision:   true,
+	webhook.PhaseDeprovision: true,
+	webhook.PhaseRestore:     true,
+}
+
+type validationErrors []string
+
+func (v *validationErrors) require(value, field string) {
+	if len(value) == 0 {
+		*v = append(*v, fmt.Sprintf("%s is required", field))
+	}
+}
+
+func (v *validationErrors) requirePrefix(value, field string) {
+	v.require(value, field)
+
+	if _, _, err := net.ParseCIDR(value); len(value) > 0 && err != nil {
+		*v = append(*v, fmt.Sprintf("%s: invalid prefix %s", field, value))
+	}
+}
+
+// Validate checks that all required fields are set
+func (c *Config) Validate() error {
+	errs := validationErrors{}
+
+	errs.require(c.ListenAddress, "listen_address")
+
+	if len(c.Pipeline) == 0 {
+		errs = append(errs, "no steps defined (define pipeline or at least one of the sections ovirt, gcloud or ansible_tower)")
+	}
+
+	steps := make(map[string]bool)
+	for i, s := range c.Pipeline {
+		if len(s.Name) == 0 {
+			errs = append(errs, fmt.Sprintf("pipeline[%d].name is required", i))
+			continue
+		}
+
+		if steps[s.Name] {
+			errs = append(errs, fmt.Sprintf("step %s is defined more than once", s.Name))
+		}
+		steps[s.Name] = true
+
+		s.validate(&errs)
+	}
+
+	if c.RecycleBin != nil {
+		errs.require(c.RecycleBin.StateFile, "recycle_bin.state_file")
+
+		if c.RecycleBin.Retention <= 0 {
+			errs = append(errs, "recycle_bin.retention has to be greater than 0")
+		}
+	}
+
+	templates := make(map[string]bool)
+	for i, t := range c.Templates {
+		if len(t.Name) == 0 {
+			errs = append(errs, fmt.Sprintf("templates[%d].name is required", i))
+			continue
+		}
+
+		if templates[t.Name] {
+			errs = append(errs, fmt.Sprintf("template %s is defined more than once", t.Name))
+		}
+		templates[t.Name] = true
+
+		t.CPUCores.validate(fmt.Sprintf("templates[%s].cpu_cores", t.Name), &errs)
+		t.MemoryMB.validate(fmt.Sprintf("templates[%s].memory_mb", t.Name), &errs)
+
+		if t.CloudInit != nil {
+			if err := cloudinit.Validate(t.CloudInit.UserData); err != nil {
+				errs = append(errs, fmt.Sprintf("templates[%s].cloud_init.user_data: %v", t.Name, err))
+			}
+		}
+
+		for _, s := range t.SkipSteps {
+			if !steps[s] {
+				errs = append(errs, fmt.Sprintf("templates[%s].skip_steps: unknown step %s", t.Name, s))
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid config: %s", strings.Join(errs, "; "))
+	}
+
+	return nil
+}
+
+func (s *Step) validate(errs *validationErrors) {
+	p := s.path()
+
+	switch s.Type {
+	case StepTypeOvirt:
+		if s.Ovirt == nil {
+			*errs = append(*errs, fmt.Sprintf("%s settings are missing", p))
+			return
+		}
+
+		errs.require(s.Ovirt.URL, p+".url")
+		errs.require(s.Ovirt.Username, p+".username")
+		errs.require(s.Ovirt.Password, p+".password")
+		errs.require(s.Ovirt.TemplatePath, p+".template_path")
+
+	case StepTypeGoogleCloudDNS:
+		if s.GooglecCloudDNS == nil {
+			*errs = append(*errs, fmt.Sprintf("%s settings are missing", p))
+			return
+		}
+
+		errs.require(s.GooglecCloudDNS.ProjectID, p+".project_id")
+		errs.require(s.GooglecCloudDNS.CredentialsFile, p+".credentials_file")
+
+		for i, z := range s.GooglecCloudDNS.Zones {
+			errs.require(z.Name, fmt.Sprintf("%s.zones[%d].name", p, i))
+		}
+
+	case StepTypeAnsibleTower:
+		if s.AnsibleTower == nil {
+			*errs = append(*errs, fmt.Sprintf("%s settings are missing", p))
+			return
+		}
+
+		errs.require(s.AnsibleTower.URL, p+".url")
+
+		if len(s.AnsibleTower.Token) == 0 {
+			errs.require(s.AnsibleTower.Username, p+".username")
+			errs.require(s.AnsibleTower.Password, p+".password")
+		}
+
+		if s.AnsibleTower.Inventory != nil && s.AnsibleTower.Inventory.ID == 0 {
+			*errs = append(*errs, fmt.Sprintf("%s.inventory.id is required", p))
+		}
+
+	case StepTypeAnsible:
+		if s.Ansible == nil {
+			*errs = append(*errs, fmt.Sprintf("%s settings are missing", p))
+			return
+		}
+
+		errs.require(s.Ansible.PlaybookDir, p+".playbook_dir")
+
+	case StepTypeWebhook:
+		if s.Webhook == nil {
+			*errs = append(*errs, fmt.Sprintf("%s settings are missing", p))
+			return
+		}
+
+		if len(s.Webhook.Endpoints) == 0 {
+			*errs = append(*errs, fmt.Sprintf("%s.endpoints: at least one endpoint is required", p))
+		}
+
+		for i, e := range s.Webhook.Endpoints {
+			e.validate(fmt.Sprintf("%s.endpoints[%d]", p, i), errs)
+		}
+
+	case StepTypeReadiness:
+		if s.Readiness == nil {
+			*errs = append(*errs, fmt.Sprintf("%s settings are missing", p))
+			return
+		}
+
+		if len(s.Readiness.Ports) == 0 && !s.Readiness.GuestAgent {
+			*errs = append(*errs, fmt.Sprintf("%s: ports or guest_agent is required", p))
+		}
+
+	case StepTypeIPAM:
+		if s.IPAM == nil {
+			*errs = append(*errs, fmt.Sprintf("%s settings are missing", p))
+			return
+		}
+
+		errs.require(s.IPAM.StateFile, p+".state_file")
+
+		if len(s.IPAM.Pools) == 0 {
+			*errs = append(*errs, fmt.Sprintf("%s.pools: at least one pool is required", p))
+		}
+
+		for i, pool := range s.IPAM.Pools {
+			pp := fmt.Sprintf("%s.pools[%d]", p, i)
+			errs.require(pool.Name, pp+".name")
+			errs.requirePrefix(pool.Prefix, pp+".prefix")
+		}
+
+	case StepTypeNetBox:
+		if s.NetBox == nil {
+			*errs = append(*errs, fmt.Sprintf("%s settings are missing", p))
+			return
+		}
+
+		errs.require(s.NetBox.URL, p+".url")
+		errs.require(s.NetBox.Token, p+".token")
+
+		for i, pfx := range s.NetBox.Prefixes {
+			errs.requirePrefix(pfx.Prefix, fmt.Sprintf("%s.prefixes[%d].prefix", p, i))
+		}
+
+	default:
+		*errs = append(*errs, fmt.Sprintf("pipeline[%s]: unknown step type '%s'", s.Name, s.Type))
+	}
+}
+
+func (e *WebhookEndpointConfig) validate(path string, errs *validationErrors) {
+	errs.require(e.URL, path+".url")
+
+	if len(e.Mode) > 0 && e.Mode != WebhookModeSync && e.Mode != WebhookModeAsync {
+		*errs = append(*errs, fmt.Sprintf("%s.mode: unknown mode '%s'", path, e.Mode))
+	}
+
+	for _, phase := range e.Phases {
+		if !webhookPhases[phase] {
+			*errs = append(*errs, fmt.Sprintf("%s.phases: unknown phase '%s'", path, phase))
+		}
+	}
+}
+
+func (l *ResourceLimits) validate(path string, errs *validationErrors) {
+	if l == nil {
+		return
+	}
+
+	if l.Max > 0 && l.Min > l.Max {
+		*errs = append(*errs, fmt.Sprintf("%s: min (%d) is greater than max (%d)", path, l.Min, l.Max))
+	}
+
+	if l.Default > 0 && (l.Default < l.Min || (l.Max > 0 && l.Default > l.Max)) {
+		*errs = append(*errs, fmt.Sprintf("%s: default (%d) is out of bounds", path, l.Default))
+	}
+}
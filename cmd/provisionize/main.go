@@ -1,23 +1,28 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"io/ioutil"
 	"net"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/MauveSoftware/provisionize/cmd/provisionize/config"
+	"github.com/MauveSoftware/provisionize/pkg/configuration/ansible"
 	"github.com/MauveSoftware/provisionize/pkg/configuration/tower"
 	"github.com/MauveSoftware/provisionize/pkg/dns/gclouddns"
+	"github.com/MauveSoftware/provisionize/pkg/hooks/webhook"
+	"github.com/MauveSoftware/provisionize/pkg/ipam"
+	"github.com/MauveSoftware/provisionize/pkg/ipam/netbox"
+	"github.com/MauveSoftware/provisionize/pkg/readiness"
 	"github.com/MauveSoftware/provisionize/pkg/server"
 	"github.com/MauveSoftware/provisionize/pkg/vm/ovirt"
 
-	"contrib.go.opencensus.io/exporter/zipkin"
-	openzipkin "github.com/openzipkin/zipkin-go"
-	zipkinHTTP "github.com/openzipkin/zipkin-go/reporter/http"
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
-	"go.opencensus.io/trace"
 	kingpin "gopkg.in/alecthomas/kingpin.v2"
 )
 
@@ -27,6 +32,11 @@ func main() {
 	showVersion := kingpin.Flag("version", "Shows version info").Short('v').Bool()
 	configFile := kingpin.Flag("config", "Path to config file").Short('c').Default("config.yml").String()
 	zipkinEndpoint := kingpin.Flag("zipkin-endpoint", "URL to sent tracing information to").String()
+	otlpEndpoint := kingpin.Flag("otlp-endpoint", "Host and port of an OTLP/gRPC receiver to send tracing information to").String()
+	otlpInsecure := kingpin.Flag("otlp-insecure", "Disables TLS for the connection to the OTLP receiver").Bool()
+	watchInterval := kingpin.Flag("watch-interval", "Interval to check config and template file for changes (0 disables watching, SIGHUP always triggers a reload)").Default("10s").Duration()
+	purgeInterval := kingpin.Flag("purge-interval", "Interval to purge VMs whose retention period in the recycle bin expired").Default("10m").Duration()
+	shutdownTimeout := kingpin.Flag("shutdown-timeout", "Time to wait for requests in progress and background work (e.g. async webhooks) to complete on SIGINT/SIGTERM").Default("30s").Duration()
 	kingpin.Parse()
 
 	if *showVersion {
@@ -34,26 +44,30 @@ func main() {
 		os.Exit(0)
 	}
 
-	initializeZipkin(*zipkinEndpoint)
+	initializeTracing(*zipkinEndpoint, *otlpEndpoint, *otlpInsecure)
 
-	cfg, err := loadConfig(*configFile)
+	r := newReloader(*configFile)
+	cfg, pipeline, err := r.load()
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	templateManager := newTemplateManager(cfg.Templates)
-	services := []server.ProvisionService{
-		ovirtService(cfg, templateManager),
-		googleCloudService(cfg),
-		ansibleTowerService(cfg, templateManager),
-	}
+	srv := server.NewServer(pipeline)
+	r.start(srv, cfg, *watchInterval)
+	startPurging(srv, *purgeInterval)
+	closed := closeOnSignal(srv, *shutdownTimeout)
 
 	list, err := net.Listen("tcp", cfg.ListenAddress)
 	if err != nil {
 		log.Fatal(errors.Wrapf(err, "could not listen on %s", cfg.ListenAddress))
 	}
 
-	server.StartServer(list, services)
+	err = srv.Serve(list)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	<-closed
 }
 
 func loadConfig(configFile string) (*config.Config, error) {
@@ -66,57 +80,266 @@ func loadConfig(configFile string) (*config.Config, error) {
 	return config.Load(f)
 }
 
-func ovirtService(cfg *config.Config, t *templateManager) server.ProvisionService {
-	c := cfg.Ovirt
+func buildPipeline(cfg *config.Config, state *stateFiles) (*server.Pipeline, error) {
+	templateManager := newTemplateManager(cfg.Templates)
+	pipeline := &server.Pipeline{Templates: templateManager}
+
+	for _, step := range cfg.EnabledSteps() {
+		svc, err := serviceForStep(step, templateManager, pipeline.Steps, state)
+		if err != nil {
+			return nil, errors.Wrapf(err, "step %s", step.Name)
+		}
+
+		pipeline.Steps = append(pipeline.Steps, &server.Step{Name: step.Name, Service: svc})
+	}
+
+	err := verifyTemplates(cfg.Templates, pipeline)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.RecycleBin != nil {
+		pipeline.RecycleBin, err = state.recycleBin(cfg.RecycleBin.StateFile, cfg.RecycleBin.Retention)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not initialize recycle bin")
+		}
+	}
+
+	return pipeline, nil
+}
+
+func startPurging(srv *server.Server, interval time.Duration) {
+	go func() {
+		for range time.Tick(interval) {
+			srv.PurgeRecycleBin(context.Background())
+		}
+	}()
+}
+
+// closeOnSignal stops the server on SIGINT or SIGTERM, waiting for requests in progress and background work of the pipeline
+// to complete. The returned channel is closed when the server is closed.
+func closeOnSignal(srv *server.Server, timeout time.Duration) <-chan struct{} {
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
 
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+
+		s := <-sig
+		log.Infof("Received %s: shutting down", s)
+
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+
+		err := srv.Close(ctx)
+		if err != nil {
+			log.Error(err)
+		}
+	}()
+
+	return closed
+}
+
+// serviceForStep creates the service for the step. previous are the steps defined before in the pipeline.
+func serviceForStep(step *config.Step, t *templateManager, previous []*server.Step, state *stateFiles) (server.ProvisionService, error) {
+	switch step.Type {
+	case config.StepTypeOvirt:
+		return ovirtService(step.Ovirt, t)
+	case config.StepTypeGoogleCloudDNS:
+		return googleCloudService(step.GooglecCloudDNS)
+	case config.StepTypeAnsibleTower:
+		return ansibleTowerService(step.AnsibleTower, t)
+	case config.StepTypeAnsible:
+		return ansibleService(step.Ansible, t), nil
+	case config.StepTypeWebhook:
+		return webhookService(step.Webhook), nil
+	case config.StepTypeReadiness:
+		return readinessService(step.Readiness, previous)
+	case config.StepTypeIPAM:
+		return ipamService(step.IPAM, state)
+	case config.StepTypeNetBox:
+		return netBoxService(step.NetBox)
+	default:
+		return nil, fmt.Errorf("unknown step type %s", step.Type)
+	}
+}
+
+func ovirtService(c *config.OvirtConfig, t *templateManager) (server.ProvisionService, error) {
 	template, err := ioutil.ReadFile(c.TemplatePath)
 	if err != nil {
-		log.Fatal(errors.Wrap(err, "could not load template file"))
+		return nil, errors.Wrap(err, "could not load template file")
+	}
+
+	opts := []ovirt.Option{}
+	if c.KeepDataDisks {
+		opts = append(opts, ovirt.WithKeepDataDisks())
 	}
 
-	svc, err := ovirt.NewService(c.URL, c.Username, c.Password, string(template), t)
+	svc, err := ovirt.NewService(c.URL, c.Username, c.Password, string(template), t, opts...)
 	if err != nil {
-		log.Fatal(errors.Wrap(err, "could initialize oVirt service"))
+		return nil, errors.Wrap(err, "could initialize oVirt service")
 	}
 
-	return svc
+	return svc, nil
 }
 
-func googleCloudService(cfg *config.Config) server.ProvisionService {
-	f, err := os.Open(cfg.GooglecCloudDNS.CredentialsFile)
+func googleCloudService(c *config.GoogleCloudDNSConfig) (server.ProvisionService, error) {
+	f, err := os.Open(c.CredentialsFile)
 	if err != nil {
-		log.Fatal(errors.Wrap(err, "could not load Google Cloud credentials file"))
+		return nil, errors.Wrap(err, "could not load Google Cloud credentials file")
 	}
 	defer f.Close()
 
-	svc, err := gclouddns.NewDNSService(cfg.GooglecCloudDNS.ProjectID, f)
+	opts := []gclouddns.Option{}
+	if c.TTL > 0 {
+		opts = append(opts, gclouddns.WithTTL(c.TTL))
+	}
+
+	for _, z := range c.Zones {
+		if z.TTL > 0 {
+			opts = append(opts, gclouddns.WithZoneTTL(z.Name, z.TTL))
+		}
+	}
+
+	if c.UpdateExisting {
+		opts = append(opts, gclouddns.WithUpdateExisting())
+	}
+
+	if len(c.AliasDomains) > 0 {
+		opts = append(opts, gclouddns.WithAliasDomains(c.AliasDomains...))
+	}
+
+	svc, err := gclouddns.NewDNSService(c.ProjectID, f, opts...)
 	if err != nil {
-		log.Fatal(errors.Wrap(err, "could not initialize Google Cloud DNS service"))
+		return nil, errors.Wrap(err, "could not initialize Google Cloud DNS service")
 	}
 
-	return svc
+	return svc, nil
 }
 
-func ansibleTowerService(cfg *config.Config, t *templateManager) server.ProvisionService {
-	return tower.NewService(cfg.AnsibleTower.URL, cfg.AnsibleTower.Username, cfg.AnsibleTower.Password, t)
+func ansibleTowerService(c *config.AnsibleTowerConfig, t *templateManager) (server.ProvisionService, error) {
+	client, err := tower.NewHTTPClient(c.CAFile, c.Insecure, c.Timeout)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not initialize Ansible Tower client")
+	}
+
+	opts := []tower.Option{tower.WithHTTPClient(client)}
+	if len(c.Token) > 0 {
+		opts = append(opts, tower.WithToken(c.Token))
+	}
+
+	if c.Inventory != nil {
+		opts = append(opts, tower.WithInventory(c.Inventory.ID, c.Inventory.GroupID))
+	}
+
+	return tower.NewService(c.URL, c.Username, c.Password, t, opts...), nil
+}
+
+func ansibleService(c *config.AnsibleConfig, t *templateManager) server.ProvisionService {
+	opts := []ansible.Option{}
+	if len(c.Binary) > 0 {
+		opts = append(opts, ansible.WithBinary(c.Binary))
+	}
+
+	if c.Timeout > 0 {
+		opts = append(opts, ansible.WithTimeout(c.Timeout))
+	}
+
+	return ansible.NewService(c.PlaybookDir, t, opts...)
 }
 
-func initializeZipkin(zipkinEndpoint string) {
-	if len(zipkinEndpoint) == 0 {
-		return
+func readinessService(c *config.ReadinessConfig, previous []*server.Step) (server.ProvisionService, error) {
+	opts := []readiness.Option{}
+	if c.Timeout > 0 {
+		opts = append(opts, readiness.WithTimeout(c.Timeout))
+	}
+
+	if c.Interval > 0 {
+		opts = append(opts, readiness.WithInterval(c.Interval))
+	}
+
+	if c.GuestAgent {
+		g := guestAgent(previous)
+		if g == nil {
+			return nil, errors.New("guest_agent requires a step reporting the guest agent state (ovirt) before")
+		}
+
+		opts = append(opts, readiness.WithGuestAgent(g))
 	}
 
-	localEndpoint, err := openzipkin.NewEndpoint("provisionize", ":0")
+	return readiness.NewService(c.Ports, opts...), nil
+}
+
+func guestAgent(steps []*server.Step) readiness.GuestAgent {
+	for _, s := range steps {
+		if g, ok := s.Service.(readiness.GuestAgent); ok {
+			return g
+		}
+	}
+
+	return nil
+}
+
+func webhookService(c *config.WebhookConfig) server.ProvisionService {
+	endpoints := make([]*webhook.Endpoint, len(c.Endpoints))
+	for i, e := range c.Endpoints {
+		name := e.Name
+		if len(name) == 0 {
+			name = e.URL
+		}
+
+		endpoints[i] = &webhook.Endpoint{
+			Name:    name,
+			URL:     e.URL,
+			Secret:  e.Secret,
+			Async:   e.Mode == config.WebhookModeAsync,
+			Phases:  e.Phases,
+			Headers: e.Headers,
+			Timeout: e.Timeout,
+			Retries: e.Retries,
+		}
+	}
+
+	return webhook.NewService(endpoints)
+}
+
+func ipamService(c *config.IPAMConfig, state *stateFiles) (server.ProvisionService, error) {
+	pools := make([]*ipam.Pool, len(c.Pools))
+	for i, p := range c.Pools {
+		pool, err := ipam.NewPool(p.Name, p.Cluster, p.Network, p.Prefix, p.Gateway, p.Exclude)
+		if err != nil {
+			return nil, err
+		}
+
+		pools[i] = pool
+	}
+
+	store, err := state.leaseStore(c.StateFile)
 	if err != nil {
-		log.Error(err)
-		return
+		return nil, errors.Wrap(err, "could not initialize IPAM store")
+	}
+
+	var resolver ipam.Resolver = net.DefaultResolver
+	if c.SkipDNSCheck {
+		resolver = nil
 	}
 
-	reporter := zipkinHTTP.NewReporter(zipkinEndpoint)
-	exporter := zipkin.NewExporter(reporter, localEndpoint)
-	trace.RegisterExporter(exporter)
+	return ipam.NewService(pools, store, resolver), nil
+}
+
+func netBoxService(c *config.NetBoxConfig) (server.ProvisionService, error) {
+	prefixes := make([]*netbox.Prefix, len(c.Prefixes))
+	for i, p := range c.Prefixes {
+		prefixes[i] = &netbox.Prefix{Cluster: p.Cluster, Network: p.Network, Prefix: p.Prefix, Gateway: p.Gateway}
+	}
+
+	svc, err := netbox.NewService(c.URL, c.Token, prefixes, netbox.Options{Decommission: c.Decommission, Timeout: c.Timeout})
+	if err != nil {
+		return nil, errors.Wrap(err, "could not initialize NetBox service")
+	}
 
-	trace.ApplyConfig(trace.Config{DefaultSampler: trace.AlwaysSample()})
+	return svc, nil
 }
 
 func printVersion() {
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/MauveSoftware/provisionize/cmd/provisionize/config"
+	"github.com/MauveSoftware/provisionize/pkg/server"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// reloader rebuilds the pipeline when the config or the oVirt template file changes or SIGHUP is received
+type reloader struct {
+	configFile string
+	mu         sync.Mutex
+	cfg        *config.Config
+	watcher    *fileWatcher
+	state      *stateFiles
+}
+
+func newReloader(configFile string) *reloader {
+	return &reloader{
+		configFile: configFile,
+		watcher:    &fileWatcher{},
+		state:      newStateFiles(),
+	}
+}
+
+// load loads the config and builds a new pipeline from it. Nothing is applied if any of these steps fail.
+func (r *reloader) load() (*config.Config, *server.Pipeline, error) {
+	cfg, err := loadConfig(r.configFile)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pipeline, err := buildPipeline(cfg, r.state)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return cfg, pipeline, nil
+}
+
+func (r *reloader) start(srv *server.Server, cfg *config.Config, interval time.Duration) {
+	r.cfg = cfg
+	r.watcher.update(r.watchedFiles(cfg))
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGHUP)
+
+	var tick <-chan time.Time
+	if interval > 0 {
+		tick = time.NewTicker(interval).C
+	}
+
+	go func() {
+		for {
+			select {
+			case <-sig:
+				log.Info("Received SIGHUP: reloading config")
+				r.reload(srv)
+			case <-tick:
+				if r.watcher.changed() {
+					log.Info("Config or template file changed: reloading config")
+					r.reload(srv)
+				}
+			}
+		}
+	}()
+}
+
+func (r *reloader) reload(srv *server.Server) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	cfg, pipeline, err := r.load()
+	if err != nil {
+		log.Errorf("Reload failed, keeping current config: %v", err)
+		r.watcher.update(r.watchedFiles(r.cfg))
+		return
+	}
+
+	if cfg.ListenAddress != r.cfg.ListenAddress {
+		log.Warnf("Changing listen_address (%s => %s) requires a restart", r.cfg.ListenAddress, cfg.ListenAddress)
+	}
+
+	srv.UpdatePipeline(pipeline)
+	r.cfg = cfg
+	r.watcher.update(r.watchedFiles(cfg))
+	log.Info("Config reloaded successfully")
+}
+
+func (r *reloader) watchedFiles(cfg *config.Config) []string {
+	files := []string{r.configFile}
+
+	for _, step := range cfg.EnabledSteps() {
+		if step.Ovirt != nil {
+			files = append(files, step.Ovirt.TemplatePath)
+		}
+	}
+
+	return files
+}
+
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+// fileWatcher detects changes of files by comparing modification time and size
+type fileWatcher struct {
+	mu    sync.Mutex
+	files map[string]fileState
+}
+
+func (w *fileWatcher) update(paths []string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.files = make(map[string]fileState)
+	for _, p := range paths {
+		w.files[p] = stateOfFile(p)
+	}
+}
+
+func (w *fileWatcher) changed() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for p, s := range w.files {
+		if stateOfFile(p) != s {
+			return true
+		}
+	}
+
+	return false
+}
+
+func stateOfFile(path string) fileState {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return fileState{}
+	}
+
+	return fileState{modTime: fi.ModTime(), size: fi.Size()}
+}
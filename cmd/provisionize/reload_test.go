@@ -0,0 +1,39 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileWatcher(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yml")
+
+	err := ioutil.WriteFile(path, []byte("listen_address: \"[::]:1337\"\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := &fileWatcher{}
+	w.update([]string{path})
+	assert.False(t, w.changed(), "unchanged file")
+
+	err = ioutil.WriteFile(path, []byte("listen_address: \"[::]:1338\"\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Second)
+	os.Chtimes(path, later, later)
+	assert.True(t, w.changed(), "modified file")
+
+	w.update([]string{path})
+	assert.False(t, w.changed(), "after update")
+
+	os.Remove(path)
+	assert.True(t, w.changed(), "removed file")
+}
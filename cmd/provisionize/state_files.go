@@ -0,0 +1,73 @@
+package main
+
+import (
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/MauveSoftware/provisionize/pkg/ipam"
+	"github.com/MauveSoftware/provisionize/pkg/recyclebin"
+)
+
+// stateFiles keeps one instance per state file, so pipelines built on reload share the state of the pipelines before
+type stateFiles struct {
+	mu          sync.Mutex
+	recycleBins map[string]*recyclebin.RecycleBin
+	leaseStores map[string]*ipam.FileStore
+}
+
+func newStateFiles() *stateFiles {
+	return &stateFiles{
+		recycleBins: make(map[string]*recyclebin.RecycleBin),
+		leaseStores: make(map[string]*ipam.FileStore),
+	}
+}
+
+// recycleBin returns the recycle bin backed by path. The retention of an existing instance is updated.
+func (s *stateFiles) recycleBin(path string, retention time.Duration) (*recyclebin.RecycleBin, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key, err := filepath.Abs(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not resolve recycle bin path")
+	}
+
+	if b, found := s.recycleBins[key]; found {
+		b.SetRetention(retention)
+		return b, nil
+	}
+
+	b, err := recyclebin.New(path, retention)
+	if err != nil {
+		return nil, err
+	}
+
+	s.recycleBins[key] = b
+	return b, nil
+}
+
+// leaseStore returns the IPAM lease store backed by path
+func (s *stateFiles) leaseStore(path string) (*ipam.FileStore, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key, err := filepath.Abs(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not resolve lease file path")
+	}
+
+	if st, found := s.leaseStores[key]; found {
+		return st, nil
+	}
+
+	st, err := ipam.NewFileStore(path)
+	if err != nil {
+		return nil, err
+	}
+
+	s.leaseStores[key] = st
+	return st, nil
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStateFiles(t *testing.T) {
+	dir := t.TempDir()
+	s := newStateFiles()
+
+	b1, err := s.recycleBin(filepath.Join(dir, "recycle_bin.json"), time.Hour)
+	require.NoError(t, err)
+
+	b2, err := s.recycleBin(filepath.Join(dir, ".", "recycle_bin.json"), 2*time.Hour)
+	require.NoError(t, err)
+	assert.Same(t, b1, b2, "recycle bin is reused")
+	assert.Equal(t, 2*time.Hour, b1.Retention(), "retention is updated")
+
+	l1, err := s.leaseStore(filepath.Join(dir, "leases.json"))
+	require.NoError(t, err)
+
+	l2, err := s.leaseStore(filepath.Join(dir, "leases.json"))
+	require.NoError(t, err)
+	assert.Same(t, l1, l2, "lease store is reused")
+
+	l3, err := s.leaseStore(filepath.Join(dir, "other.json"))
+	require.NoError(t, err)
+	assert.NotSame(t, l1, l3)
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/MauveSoftware/provisionize/cmd/provisionize/config"
+	"github.com/MauveSoftware/provisionize/pkg/server"
+)
+
+const templateCheckTimeout = 30 * time.Second
+
+type ovirtTemplateChecker interface {
+	TemplateExists(ctx context.Context, name string) (bool, error)
+}
+
+type towerTemplateChecker interface {
+	JobTemplateExists(ctx context.Context, id uint) (bool, error)
+	WorkflowTemplateExists(ctx context.Context, id uint) (bool, error)
+}
+
+type playbookChecker interface {
+	PlaybookExists(ctx context.Context, playbook string) (bool, error)
+}
+
+// templateCheck collects the results of the lookups for the templates
+type templateCheck struct {
+	problems []string
+	warnings []string
+}
+
+// add records a missing object as problem. Failed lookups are recorded as warnings only since the backend may be unavailable temporarily.
+func (c *templateCheck) add(prefix string, found bool, err error, missing string) {
+	if err != nil {
+		c.warnings = append(c.warnings, fmt.Sprintf("%s: %v", prefix, err))
+	} else if !found {
+		c.problems = append(c.problems, fmt.Sprintf("%s: %s does not exist", prefix, missing))
+	}
+}
+
+// verifyTemplates checks that all oVirt templates, Tower (workflow) job templates and playbooks referenced by the templates exist.
+// It fails only if a backend confirms that a referenced object does not exist.
+func verifyTemplates(templates []*config.ProvisionTemplate, pipeline *server.Pipeline) error {
+	ctx, cancel := context.WithTimeout(context.Background(), templateCheckTimeout)
+	defer cancel()
+
+	c := &templateCheck{}
+	for _, t := range templates {
+		for _, step := range pipeline.Steps {
+			if t.SkipsStep(step.Name) {
+				continue
+			}
+
+			verifyTemplateForStep(ctx, t, step, c)
+		}
+	}
+
+	for _, w := range c.warnings {
+		log.Warnf("Could not verify template %s", w)
+	}
+
+	if len(c.problems) > 0 {
+		return fmt.Errorf("template check failed: %s", strings.Join(c.problems, "; "))
+	}
+
+	return nil
+}
+
+func verifyTemplateForStep(ctx context.Context, t *config.ProvisionTemplate, step *server.Step, c *templateCheck) {
+	prefix := fmt.Sprintf("template %s (step %s)", t.Name, step.Name)
+
+	if checker, ok := step.Service.(ovirtTemplateChecker); ok && len(t.OvirtTemplate) > 0 {
+		found, err := checker.TemplateExists(ctx, t.OvirtTemplate)
+		c.add(prefix, found, err, fmt.Sprintf("oVirt template %s", t.OvirtTemplate))
+	}
+
+	if checker, ok := step.Service.(towerTemplateChecker); ok {
+		jobTemplates := append([]uint{}, t.AnsibleTemplates...)
+		jobTemplates = append(jobTemplates, t.AnsibleDeprovisionTemplates...)
+
+		for _, id := range jobTemplates {
+			found, err := checker.JobTemplateExists(ctx, id)
+			c.add(prefix, found, err, fmt.Sprintf("Tower job template %d", id))
+		}
+
+		for _, id := range t.AnsibleWorkflows {
+			found, err := checker.WorkflowTemplateExists(ctx, id)
+			c.add(prefix, found, err, fmt.Sprintf("Tower workflow job template %d", id))
+		}
+	}
+
+	if checker, ok := step.Service.(playbookChecker); ok {
+		playbooks := append([]string{}, t.AnsiblePlaybooks...)
+		playbooks = append(playbooks, t.AnsibleDeprovisionPlaybooks...)
+
+		for _, p := range playbooks {
+			found, err := checker.PlaybookExists(ctx, p)
+			c.add(prefix, found, err, fmt.Sprintf("playbook %s", p))
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/MauveSoftware/provisionize/cmd/provisionize/config"
+	"github.com/MauveSoftware/provisionize/pkg/api/proto"
+	"github.com/MauveSoftware/provisionize/pkg/server"
+)
+
+type mockChecker struct {
+	ovirtTemplates map[string]bool
+	jobTemplates   map[uint]bool
+	workflows      map[uint]bool
+	playbooks      map[string]bool
+	err            error
+}
+
+func (m *mockChecker) Provision(ctx context.Context, vm *proto.VirtualMachine, ch chan<- *proto.StatusUpdate) bool {
+	return true
+}
+
+func (m *mockChecker) Deprovision(ctx context.Context, vm *proto.VirtualMachine, ch chan<- *proto.StatusUpdate) bool {
+	return true
+}
+
+func (m *mockChecker) TemplateExists(ctx context.Context, name string) (bool, error) {
+	return m.ovirtTemplates[name], m.err
+}
+
+func (m *mockChecker) JobTemplateExists(ctx context.Context, id uint) (bool, error) {
+	return m.jobTemplates[id], m.err
+}
+
+func (m *mockChecker) WorkflowTemplateExists(ctx context.Context, id uint) (bool, error) {
+	return m.workflows[id], m.err
+}
+
+func (m *mockChecker) PlaybookExists(ctx context.Context, playbook string) (bool, error) {
+	return m.playbooks[playbook], m.err
+}
+
+func TestVerifyTemplates(t *testing.T) {
+	checker := &mockChecker{
+		ovirtTemplates: map[string]bool{"ubuntu-18.04": true},
+		jobTemplates:   map[uint]bool{1: true},
+		workflows:      map[uint]bool{10: true},
+		playbooks:      map[string]bool{"base.yml": true},
+	}
+	pipeline := &server.Pipeline{
+		Steps: []*server.Step{
+			{Name: "vm", Service: checker},
+		},
+	}
+
+	tests := []struct {
+		name        string
+		template    *config.ProvisionTemplate
+		expectError string
+	}{
+		{
+			name: "all templates exist",
+			template: &config.ProvisionTemplate{
+				Name:             "linux",
+				OvirtTemplate:    "ubuntu-18.04",
+				AnsibleTemplates: []uint{1},
+			},
+		},
+		{
+			name: "unknown oVirt template",
+			template: &config.ProvisionTemplate{
+				Name:          "linux",
+				OvirtTemplate: "ubuntu-20.04",
+			},
+			expectError: "template linux (step vm): oVirt template ubuntu-20.04 does not exist",
+		},
+		{
+			name: "unknown job template",
+			template: &config.ProvisionTemplate{
+				Name:             "linux",
+				OvirtTemplate:    "ubuntu-18.04",
+				AnsibleTemplates: []uint{1, 2},
+			},
+			expectError: "template linux (step vm): Tower job template 2 does not exist",
+		},
+		{
+			name: "unknown deprovisioning job template",
+			template: &config.ProvisionTemplate{
+				Name:                        "linux",
+				OvirtTemplate:               "ubuntu-18.04",
+				AnsibleTemplates:            []uint{1},
+				AnsibleDeprovisionTemplates: []uint{3},
+			},
+			expectError: "template linux (step vm): Tower job template 3 does not exist",
+		},
+		{
+			name: "unknown workflow job template",
+			template: &config.ProvisionTemplate{
+				Name:             "linux",
+				OvirtTemplate:    "ubuntu-18.04",
+				AnsibleWorkflows: []uint{10, 11},
+			},
+			expectError: "template linux (step vm): Tower workflow job template 11 does not exist",
+		},
+		{
+			name: "unknown playbook",
+			template: &config.ProvisionTemplate{
+				Name:                        "linux",
+				OvirtTemplate:               "ubuntu-18.04",
+				AnsiblePlaybooks:            []string{"base.yml"},
+				AnsibleDeprovisionPlaybooks: []string{"decommission.yml"},
+			},
+			expectError: "template linux (step vm): playbook decommission.yml does not exist",
+		},
+		{
+			name: "step skipped",
+			template: &config.ProvisionTemplate{
+				Name:          "linux",
+				OvirtTemplate: "ubuntu-20.04",
+				SkipSteps:     []string{"vm"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := verifyTemplates([]*config.ProvisionTemplate{test.template}, pipeline)
+			if len(test.expectError) == 0 {
+				assert.NoError(t, err)
+				return
+			}
+
+			if assert.Error(t, err) {
+				assert.Contains(t, err.Error(), test.expectError)
+			}
+		})
+	}
+}
+
+func TestVerifyTemplatesLookupError(t *testing.T) {
+	pipeline := &server.Pipeline{
+		Steps: []*server.Step{
+			{Name: "vm", Service: &mockChecker{err: errors.New("connection refused")}},
+		},
+	}
+	template := &config.ProvisionTemplate{
+		Name:             "linux",
+		OvirtTemplate:    "ubuntu-18.04",
+		AnsibleTemplates: []uint{1},
+	}
+
+	assert.NoError(t, verifyTemplates([]*config.ProvisionTemplate{template}, pipeline))
+}
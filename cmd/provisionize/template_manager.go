@@ -1,12 +1,20 @@
 package main
 
 import (
+	"fmt"
+
 	"github.com/MauveSoftware/provisionize/cmd/provisionize/config"
 	"github.com/MauveSoftware/provisionize/pkg/api/proto"
 )
 
+const (
+	defaultCPUCores = 4
+	defaultMemoryMB = 1024
+)
+
 type templateManager struct {
 	templates map[string]*config.ProvisionTemplate
+	ordered   []*config.ProvisionTemplate
 }
 
 func newTemplateManager(templates []*config.ProvisionTemplate) *templateManager {
@@ -15,7 +23,7 @@ func newTemplateManager(templates []*config.ProvisionTemplate) *templateManager
 		m[t.Name] = t
 	}
 
-	return &templateManager{templates: m}
+	return &templateManager{templates: m, ordered: templates}
 }
 
 func (t *templateManager) OvirtTemplateNameForVM(vm *proto.VirtualMachine) string {
@@ -41,3 +49,234 @@ func (t *templateManager) TowerTemplateIDsForVM(vm *proto.VirtualMachine) []uint
 
 	return []uint{}
 }
+
+func (t *templateManager) TowerWorkflowIDsForVM(vm *proto.VirtualMachine) []uint {
+	if template, found := t.templates[vm.Template]; found {
+		return template.AnsibleWorkflows
+	}
+
+	return []uint{}
+}
+
+func (t *templateManager) TowerDeprovisionTemplateIDsForVM(vm *proto.VirtualMachine) []uint {
+	if template, found := t.templates[vm.Template]; found {
+		return template.AnsibleDeprovisionTemplates
+	}
+
+	return []uint{}
+}
+
+func (t *templateManager) TowerExtraVarsForVM(vm *proto.VirtualMachine) map[string]interface{} {
+	return t.ExtraVarsForVM(vm)
+}
+
+func (t *templateManager) PlaybooksForVM(vm *proto.VirtualMachine) []string {
+	if template, found := t.templates[vm.Template]; found {
+		return template.AnsiblePlaybooks
+	}
+
+	return []string{}
+}
+
+func (t *templateManager) DeprovisionPlaybooksForVM(vm *proto.VirtualMachine) []string {
+	if template, found := t.templates[vm.Template]; found {
+		return template.AnsibleDeprovisionPlaybooks
+	}
+
+	return []string{}
+}
+
+func (t *templateManager) ExtraVarsForVM(vm *proto.VirtualMachine) map[string]interface{} {
+	if template, found := t.templates[vm.Template]; found {
+		return template.ExtraVars
+	}
+
+	return nil
+}
+
+func (t *templateManager) SkipStep(vm *proto.VirtualMachine, step string) bool {
+	if template, found := t.templates[vm.Template]; found {
+		return template.SkipsStep(step)
+	}
+
+	return false
+}
+
+func (t *templateManager) Templates() []*proto.Template {
+	res := make([]*proto.Template, len(t.ordered))
+	for i, template := range t.ordered {
+		res[i] = protoTemplate(template)
+	}
+
+	return res
+}
+
+func protoTemplate(t *config.ProvisionTemplate) *proto.Template {
+	ids := protoIDs(t.AnsibleTemplates)
+
+	return &proto.Template{
+		Name:                                t.Name,
+		OvirtTemplate:                       t.OvirtTemplate,
+		BootDiskName:                        t.BootDiskName,
+		AnsibleTowerJobTemplates:            ids,
+		AnsibleTowerWorkflows:               protoIDs(t.AnsibleWorkflows),
+		AnsibleTowerDeprovisionJobTemplates: protoIDs(t.AnsibleDeprovisionTemplates),
+		SkipSteps:                           t.SkipSteps,
+		CpuCores:                            protoLimits(t.CPUCores),
+		MemoryMb:                            protoLimits(t.MemoryMB),
+		DefaultClusterName:                  t.Cluster,
+	}
+}
+
+func protoIDs(ids []uint) []uint32 {
+	res := make([]uint32, len(ids))
+	for i, id := range ids {
+		res[i] = uint32(id)
+	}
+
+	return res
+}
+
+func protoLimits(l *config.ResourceLimits) *proto.ResourceLimits {
+	if l == nil {
+		return nil
+	}
+
+	return &proto.ResourceLimits{
+		Default: l.Default,
+		Min:     l.Min,
+		Max:     l.Max,
+	}
+}
+
+func (t *templateManager) ApplyTemplate(vm *proto.VirtualMachine) error {
+	template, found := t.templates[vm.Template]
+	if !found {
+		if len(vm.Template) > 0 {
+			return fmt.Errorf("template %s does not exist", vm.Template)
+		}
+
+		template = &config.ProvisionTemplate{}
+	}
+
+	if len(vm.ClusterName) == 0 {
+		vm.ClusterName = template.Cluster
+	}
+
+	var err error
+	vm.CpuCores, err = applyLimits("cpu_cores", vm.CpuCores, template.CPUCores, defaultCPUCores)
+	if err != nil {
+		return err
+	}
+
+	vm.MemoryMb, err = applyLimits("memory_mb", vm.MemoryMb, template.MemoryMB, defaultMemoryMB)
+	if err != nil {
+		return err
+	}
+
+	var ipv4Defaults, ipv6Defaults *config.IPDefaults
+	if template.Network != nil {
+		ipv4Defaults = template.Network.IPv4
+		ipv6Defaults = template.Network.IPv6
+	}
+
+	// network defaults of the template only apply to the primary interface
+	for _, n := range vm.Interfaces {
+		if n.Primary {
+			applyIPDefaults(n.Ipv4, ipv4Defaults, 32)
+			applyIPDefaults(n.Ipv6, ipv6Defaults, 128)
+		} else {
+			applyIPDefaults(n.Ipv4, nil, 32)
+			applyIPDefaults(n.Ipv6, nil, 128)
+		}
+	}
+
+	return applyCloudInit(vm, template.CloudInit)
+}
+
+func applyLimits(name string, value uint32, limits *config.ResourceLimits, fallback uint32) (uint32, error) {
+	if limits == nil {
+		limits = &config.ResourceLimits{}
+	}
+
+	if value == 0 {
+		value = limits.Default
+	}
+
+	if value == 0 {
+		value = fallback
+	}
+
+	return value, checkLimits(name, value, limits)
+}
+
+func checkLimits(name string, value uint32, limits *config.ResourceLimits) error {
+	if limits == nil {
+		return nil
+	}
+
+	if value < limits.Min {
+		return fmt.Errorf("%s (%d) is below the minimum of %d", name, value, limits.Min)
+	}
+
+	if limits.Max > 0 && value > limits.Max {
+		return fmt.Errorf("%s (%d) exceeds the maximum of %d", name, value, limits.Max)
+	}
+
+	return nil
+}
+
+// CheckLimits checks CPU cores and memory of an existing VM against the limits of its template.
+// Without template name all templates based on the oVirt template the VM was created from are checked.
+func (t *templateManager) CheckLimits(vm *proto.VirtualMachine, ovirtTemplate string) error {
+	templates := []*config.ProvisionTemplate{}
+	if len(vm.Template) > 0 {
+		template, found := t.templates[vm.Template]
+		if !found {
+			return fmt.Errorf("template %s does not exist", vm.Template)
+		}
+
+		templates = append(templates, template)
+	} else if len(ovirtTemplate) > 0 {
+		for _, template := range t.ordered {
+			if template.OvirtTemplate == ovirtTemplate {
+				templates = append(templates, template)
+			}
+		}
+	}
+
+	for _, template := range templates {
+		err := checkLimits("cpu_cores", vm.CpuCores, template.CPUCores)
+		if err == nil {
+			err = checkLimits("memory_mb", vm.MemoryMb, template.MemoryMB)
+		}
+
+		if err != nil {
+			return fmt.Errorf("template %s: %v", template.Name, err)
+		}
+	}
+
+	return nil
+}
+
+func applyIPDefaults(ip *proto.IPConfig, defaults *config.IPDefaults, hostPrefixLength uint32) {
+	if ip == nil || len(ip.Address) == 0 {
+		return
+	}
+
+	if defaults == nil {
+		defaults = &config.IPDefaults{}
+	}
+
+	if ip.PrefixLength == 0 {
+		ip.PrefixLength = defaults.PrefixLength
+	}
+
+	if ip.PrefixLength == 0 {
+		ip.PrefixLength = hostPrefixLength
+	}
+
+	if len(ip.Gateway) == 0 {
+		ip.Gateway = defaults.Gateway
+	}
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/MauveSoftware/provisionize/cmd/provisionize/config"
+	"github.com/MauveSoftware/provisionize/pkg/api/proto"
+)
+
+func TestApplyTemplate(t *testing.T) {
+	m := newTemplateManager([]*config.ProvisionTemplate{
+		{
+			Name:     "db",
+			Cluster:  "cluster1",
+			CPUCores: &config.ResourceLimits{Default: 8, Min: 4, Max: 16},
+			MemoryMB: &config.ResourceLimits{Default: 16384, Max: 65536},
+			Network: &config.NetworkDefaults{
+				IPv4: &config.IPDefaults{PrefixLength: 24, Gateway: "192.168.1.1"},
+				IPv6: &config.IPDefaults{PrefixLength: 64, Gateway: "2001:678:1e0::1"},
+			},
+		},
+		{
+			Name: "web",
+		},
+	})
+
+	tests := []struct {
+		name        string
+		vm          *proto.VirtualMachine
+		expected    *proto.VirtualMachine
+		expectError string
+	}{
+		{
+			name: "defaults from template",
+			vm: &proto.VirtualMachine{
+				Template: "db",
+				Ipv4:     &proto.IPConfig{Address: "192.168.1.100"},
+				Ipv6:     &proto.IPConfig{Address: "2001:678:1e0::f00"},
+			},
+			expected: &proto.VirtualMachine{
+				Template:    "db",
+				ClusterName: "cluster1",
+				CpuCores:    8,
+				MemoryMb:    16384,
+				Ipv4:        &proto.IPConfig{Address: "192.168.1.100", PrefixLength: 24, Gateway: "192.168.1.1"},
+				Ipv6:        &proto.IPConfig{Address: "2001:678:1e0::f00", PrefixLength: 64, Gateway: "2001:678:1e0::1"},
+			},
+		},
+		{
+			name: "values from request",
+			vm: &proto.VirtualMachine{
+				Template:    "db",
+				ClusterName: "cluster2",
+				CpuCores:    4,
+				MemoryMb:    8192,
+				Ipv4:        &proto.IPConfig{Address: "10.0.0.2", PrefixLength: 32, Gateway: "10.0.0.1"},
+			},
+			expected: &proto.VirtualMachine{
+				Template:    "db",
+				ClusterName: "cluster2",
+				CpuCores:    4,
+				MemoryMb:    8192,
+				Ipv4:        &proto.IPConfig{Address: "10.0.0.2", PrefixLength: 32, Gateway: "10.0.0.1"},
+				Ipv6:        &proto.IPConfig{},
+			},
+		},
+		{
+			name: "template without defaults",
+			vm: &proto.VirtualMachine{
+				Template: "web",
+				Ipv4:     &proto.IPConfig{Address: "10.0.0.2"},
+				Ipv6:     &proto.IPConfig{},
+			},
+			expected: &proto.VirtualMachine{
+				Template: "web",
+				CpuCores: 4,
+				MemoryMb: 1024,
+				Ipv4:     &proto.IPConfig{Address: "10.0.0.2", PrefixLength: 32},
+				Ipv6:     &proto.IPConfig{},
+			},
+		},
+		{
+			name:        "below minimum",
+			vm:          &proto.VirtualMachine{Template: "db", CpuCores: 2},
+			expectError: "cpu_cores (2) is below the minimum of 4",
+		},
+		{
+			name:        "above maximum",
+			vm:          &proto.VirtualMachine{Template: "db", MemoryMb: 131072},
+			expectError: "memory_mb (131072) exceeds the maximum of 65536",
+		},
+		{
+			name:        "unknown template",
+			vm:          &proto.VirtualMachine{Template: "bsd"},
+			expectError: "template bsd does not exist",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require.NoError(t, test.vm.NormalizeInterfaces())
+
+			err := m.ApplyTemplate(test.vm)
+			if len(test.expectError) > 0 {
+				assert.EqualError(t, err, test.expectError)
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			test.vm.Interfaces = nil
+			assert.Equal(t, test.expected, test.vm)
+		})
+	}
+}
+
+func TestApplyTemplateMultipleInterfaces(t *testing.T) {
+	m := newTemplateManager([]*config.ProvisionTemplate{
+		{
+			Name: "db",
+			Network: &config.NetworkDefaults{
+				IPv4: &config.IPDefaults{PrefixLength: 24, Gateway: "192.168.1.1"},
+			},
+		},
+	})
+
+	vm := &proto.VirtualMachine{
+		Template: "db",
+		Interfaces: []*proto.NetworkInterface{
+			{Name: "ens3", Ipv4: &proto.IPConfig{Address: "192.168.1.100"}},
+			{Name: "ens4", Network: "storage", Ipv4: &proto.IPConfig{Address: "10.0.0.100"}},
+		},
+	}
+	require.NoError(t, vm.NormalizeInterfaces())
+	require.NoError(t, m.ApplyTemplate(vm))
+
+	assert.Equal(t, &proto.IPConfig{Address: "192.168.1.100", PrefixLength: 24, Gateway: "192.168.1.1"}, vm.Interfaces[0].Ipv4)
+	assert.Equal(t, &proto.IPConfig{Address: "10.0.0.100", PrefixLength: 32}, vm.Interfaces[1].Ipv4)
+}
+
+func TestCheckLimits(t *testing.T) {
+	m := newTemplateManager([]*config.ProvisionTemplate{
+		{
+			Name:          "db",
+			OvirtTemplate: "debian-12",
+			CPUCores:      &config.ResourceLimits{Min: 4, Max: 16},
+			MemoryMB:      &config.ResourceLimits{Max: 65536},
+		},
+		{
+			Name:          "web",
+			OvirtTemplate: "debian-12",
+			CPUCores:      &config.ResourceLimits{Max: 8},
+		},
+	})
+
+	tests := []struct {
+		name          string
+		vm            *proto.VirtualMachine
+		ovirtTemplate string
+		expectError   string
+	}{
+		{
+			name: "within limits",
+			vm:   &proto.VirtualMachine{Template: "db", CpuCores: 16, MemoryMb: 8192},
+		},
+		{
+			name:        "too many cores",
+			vm:          &proto.VirtualMachine{Template: "db", CpuCores: 32, MemoryMb: 8192},
+			expectError: "template db: cpu_cores (32) exceeds the maximum of 16",
+		},
+		{
+			name:        "too much memory",
+			vm:          &proto.VirtualMachine{Template: "db", CpuCores: 4, MemoryMb: 131072},
+			expectError: "template db: memory_mb (131072) exceeds the maximum of 65536",
+		},
+		{
+			name:        "unknown template",
+			vm:          &proto.VirtualMachine{Template: "mail", CpuCores: 4},
+			expectError: "template mail does not exist",
+		},
+		{
+			name:          "templates based on oVirt template",
+			vm:            &proto.VirtualMachine{CpuCores: 12, MemoryMb: 8192},
+			ovirtTemplate: "debian-12",
+			expectError:   "template web: cpu_cores (12) exceeds the maximum of 8",
+		},
+		{
+			name:          "no matching template",
+			vm:            &proto.VirtualMachine{CpuCores: 64, MemoryMb: 8192},
+			ovirtTemplate: "ubuntu-22.04",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := m.CheckLimits(test.vm, test.ovirtTemplate)
+			if len(test.expectError) == 0 {
+				assert.NoError(t, err)
+				return
+			}
+
+			assert.EqualError(t, err, test.expectError)
+		})
+	}
+}
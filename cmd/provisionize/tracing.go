@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+
+	"contrib.go.opencensus.io/exporter/zipkin"
+	openzipkin "github.com/openzipkin/zipkin-go"
+	zipkinHTTP "github.com/openzipkin/zipkin-go/reporter/http"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"go.opencensus.io/trace"
+	ocbridge "go.opentelemetry.io/otel/bridge/opencensus"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+)
+
+const tracingServiceName = "provisionize"
+
+func initializeTracing(zipkinEndpoint, otlpEndpoint string, otlpInsecure bool) {
+	if len(otlpEndpoint) > 0 {
+		if len(zipkinEndpoint) > 0 {
+			log.Warn("OTLP endpoint is set, Zipkin endpoint will be ignored")
+		}
+
+		err := initializeOTLP(otlpEndpoint, otlpInsecure)
+		if err != nil {
+			log.Error(err)
+		}
+
+		return
+	}
+
+	initializeZipkin(zipkinEndpoint)
+}
+
+func initializeZipkin(zipkinEndpoint string) {
+	if len(zipkinEndpoint) == 0 {
+		return
+	}
+
+	localEndpoint, err := openzipkin.NewEndpoint(tracingServiceName, ":0")
+	if err != nil {
+		log.Error(err)
+		return
+	}
+
+	reporter := zipkinHTTP.NewReporter(zipkinEndpoint)
+	exporter := zipkin.NewExporter(reporter, localEndpoint)
+	trace.RegisterExporter(exporter)
+
+	trace.ApplyConfig(trace.Config{DefaultSampler: trace.AlwaysSample()})
+}
+
+// initializeOTLP redirects all OpenCensus spans to an OpenTelemetry tracer provider exporting via OTLP/gRPC
+func initializeOTLP(endpoint string, insecure bool) error {
+	opts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(endpoint)}
+	if insecure {
+		opts = append(opts, otlptracegrpc.WithInsecure())
+	}
+
+	exporter, err := otlptracegrpc.New(context.Background(), opts...)
+	if err != nil {
+		return errors.Wrap(err, "could not initialize OTLP exporter")
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(tracingServiceName))),
+	)
+	ocbridge.InstallTraceBridge(ocbridge.WithTracerProvider(tp))
+
+	return nil
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/MauveSoftware/provisionize/pkg/api/proto"
+)
+
+func cloudInitFromParameters() (*proto.CloudInit, error) {
+	c := &proto.CloudInit{
+		AuthorizedSshKeys:   *sshKeys,
+		UserName:            *userName,
+		DisablePasswordAuth: *noPwAuth,
+		ExpirePassword:      *expirePw,
+		Timezone:            *timezone,
+		DnsServers:          *dnsServers,
+		DnsSearch:           *dnsSearch,
+	}
+
+	for _, f := range *sshKeyFiles {
+		keys, err := readSSHKeys(f)
+		if err != nil {
+			return nil, err
+		}
+
+		c.AuthorizedSshKeys = append(c.AuthorizedSshKeys, keys...)
+	}
+
+	if len(*passwordFile) > 0 {
+		b, err := ioutil.ReadFile(*passwordFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not read password file")
+		}
+
+		c.Password = strings.TrimSpace(string(b))
+	}
+
+	if len(*userDataFile) > 0 {
+		b, err := ioutil.ReadFile(*userDataFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not read user data")
+		}
+
+		c.UserData = string(b)
+	}
+
+	if isEmptyCloudInit(c) {
+		return nil, nil
+	}
+
+	return c, nil
+}
+
+// readSSHKeys reads the public keys from a file in authorized_keys format
+func readSSHKeys(path string) ([]string, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not read SSH keys from %s", path)
+	}
+
+	return parseSSHKeys(b), nil
+}
+
+func parseSSHKeys(b []byte) []string {
+	keys := []string{}
+
+	s := bufio.NewScanner(bytes.NewReader(b))
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		keys = append(keys, line)
+	}
+
+	return keys
+}
+
+func isEmptyCloudInit(c *proto.CloudInit) bool {
+	return len(c.AuthorizedSshKeys) == 0 && len(c.UserName) == 0 && len(c.Password) == 0 &&
+		!c.DisablePasswordAuth && !c.ExpirePassword && len(c.Timezone) == 0 &&
+		len(c.DnsServers) == 0 && len(c.DnsSearch) == 0 && len(c.UserData) == 0
+}
@@ -0,0 +1,18 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSSHKeys(t *testing.T) {
+	b := []byte(`# ops team
+ssh-ed25519 AAAA ops@example.com
+
+  ssh-rsa BBBB dba@example.com  
+`)
+
+	assert.Equal(t, []string{"ssh-ed25519 AAAA ops@example.com", "ssh-rsa BBBB dba@example.com"}, parseSSHKeys(b))
+	assert.Empty(t, parseSSHKeys([]byte("\n# nothing\n")))
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/MauveSoftware/provisionize/pkg/api/proto"
+)
+
+// parseDisk parses a disk definition in the form
+// size=100,storage-domain=ssd,name=db1_data,interface=virtio_scsi,format=cow,bootable
+func parseDisk(s string) (*proto.Disk, error) {
+	d := &proto.Disk{}
+
+	for _, opt := range strings.Split(s, ",") {
+		t := strings.SplitN(opt, "=", 2)
+		key := strings.TrimSpace(t[0])
+
+		if key == "bootable" && len(t) == 1 {
+			d.Bootable = true
+			continue
+		}
+
+		if len(t) != 2 {
+			return nil, fmt.Errorf("invalid disk option %s (expected key=value)", opt)
+		}
+
+		value := strings.TrimSpace(t[1])
+
+		switch key {
+		case "name":
+			d.Name = value
+		case "size":
+			size, err := strconv.ParseUint(value, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid disk size %s (expected size in GB)", value)
+			}
+			d.SizeGb = size
+		case "storage-domain":
+			d.StorageDomain = value
+		case "interface":
+			d.Interface = value
+		case "format":
+			d.Format = value
+		default:
+			return nil, fmt.Errorf("unknown disk option %s", key)
+		}
+	}
+
+	if d.SizeGb == 0 {
+		return nil, fmt.Errorf("disk %s: size is required", s)
+	}
+
+	return d, nil
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/MauveSoftware/provisionize/pkg/api/proto"
+)
+
+func TestParseDisk(t *testing.T) {
+	tests := []struct {
+		name        string
+		value       string
+		expected    *proto.Disk
+		expectError string
+	}{
+		{
+			name:  "all options",
+			value: "name=db1_data,size=100,storage-domain=ssd,interface=virtio,format=raw,bootable",
+			expected: &proto.Disk{
+				Name:          "db1_data",
+				SizeGb:        100,
+				StorageDomain: "ssd",
+				Interface:     "virtio",
+				Format:        "raw",
+				Bootable:      true,
+			},
+		},
+		{
+			name:        "missing size",
+			value:       "storage-domain=ssd",
+			expectError: "disk storage-domain=ssd: size is required",
+		},
+		{
+			name:        "invalid size",
+			value:       "size=100G",
+			expectError: "invalid disk size 100G (expected size in GB)",
+		},
+		{
+			name:        "unknown option",
+			value:       "size=10,thin",
+			expectError: "invalid disk option thin (expected key=value)",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			d, err := parseDisk(test.value)
+			if len(test.expectError) > 0 {
+				assert.EqualError(t, err, test.expectError)
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assert.Equal(t, test.expected, d)
+		})
+	}
+}
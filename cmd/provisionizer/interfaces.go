@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/MauveSoftware/provisionize/pkg/api/proto"
+)
+
+// parseInterface parses an interface definition in the form
+// name=ens4,network=storage,vnic-profile=storage,mac=00:1a:4a:16:01:51,ipv4=10.0.0.5/24,ipv4-gateway=10.0.0.1,ipv6=2001:db8::5/64,ipv6-gateway=2001:db8::1,primary
+func parseInterface(s string) (*proto.NetworkInterface, error) {
+	n := &proto.NetworkInterface{
+		Ipv4: &proto.IPConfig{},
+		Ipv6: &proto.IPConfig{},
+	}
+
+	for _, opt := range strings.Split(s, ",") {
+		t := strings.SplitN(opt, "=", 2)
+		key := strings.TrimSpace(t[0])
+
+		if key == "primary" && len(t) == 1 {
+			n.Primary = true
+			continue
+		}
+
+		if len(t) != 2 {
+			return nil, fmt.Errorf("invalid interface option %s (expected key=value)", opt)
+		}
+
+		value := strings.TrimSpace(t[1])
+		var err error
+
+		switch key {
+		case "name":
+			n.Name = value
+		case "network":
+			n.Network = value
+		case "vnic-profile":
+			n.VnicProfile = value
+		case "mac":
+			n.Mac = value
+		case "ipv4":
+			err = parseAddress(value, n.Ipv4)
+		case "ipv6":
+			err = parseAddress(value, n.Ipv6)
+		case "ipv4-gateway":
+			n.Ipv4.Gateway, err = parseIP(value)
+		case "ipv6-gateway":
+			n.Ipv6.Gateway, err = parseIP(value)
+		default:
+			err = fmt.Errorf("unknown interface option %s", key)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(n.Name) == 0 {
+		return nil, fmt.Errorf("interface %s: name is required", s)
+	}
+
+	return n, nil
+}
+
+// parseAddress parses an address with optional prefix length (e.g. 10.0.0.5/24)
+func parseAddress(s string, cfg *proto.IPConfig) error {
+	t := strings.SplitN(s, "/", 2)
+
+	addr, err := parseIP(t[0])
+	if err != nil {
+		return err
+	}
+	cfg.Address = addr
+
+	if len(t) == 2 {
+		l, err := strconv.ParseUint(t[1], 10, 8)
+		if err != nil {
+			return fmt.Errorf("invalid prefix length in %s", s)
+		}
+
+		cfg.PrefixLength = uint32(l)
+	}
+
+	return nil
+}
+
+func parseIP(s string) (string, error) {
+	ip := net.ParseIP(s)
+	if ip == nil {
+		return "", fmt.Errorf("invalid IP address %s", s)
+	}
+
+	return ip.String(), nil
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/MauveSoftware/provisionize/pkg/api/proto"
+)
+
+func TestParseInterface(t *testing.T) {
+	tests := []struct {
+		name        string
+		value       string
+		expected    *proto.NetworkInterface
+		expectError string
+	}{
+		{
+			name:  "all options",
+			value: "name=ens4,network=storage,vnic-profile=storage-jumbo,mac=00:1a:4a:16:01:51,ipv4=10.0.0.5/24,ipv4-gateway=10.0.0.1,ipv6=2001:db8::5/64,ipv6-gateway=2001:db8::1,primary",
+			expected: &proto.NetworkInterface{
+				Name:        "ens4",
+				Network:     "storage",
+				VnicProfile: "storage-jumbo",
+				Mac:         "00:1a:4a:16:01:51",
+				Primary:     true,
+				Ipv4:        &proto.IPConfig{Address: "10.0.0.5", PrefixLength: 24, Gateway: "10.0.0.1"},
+				Ipv6:        &proto.IPConfig{Address: "2001:db8::5", PrefixLength: 64, Gateway: "2001:db8::1"},
+			},
+		},
+		{
+			name:  "name only",
+			value: "name=ens3",
+			expected: &proto.NetworkInterface{
+				Name: "ens3",
+				Ipv4: &proto.IPConfig{},
+				Ipv6: &proto.IPConfig{},
+			},
+		},
+		{
+			name:        "missing name",
+			value:       "network=storage",
+			expectError: "interface network=storage: name is required",
+		},
+		{
+			name:        "invalid address",
+			value:       "name=ens3,ipv4=10.0.0",
+			expectError: "invalid IP address 10.0.0",
+		},
+		{
+			name:        "unknown option",
+			value:       "name=ens3,vlan=12",
+			expectError: "unknown interface option vlan",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			n, err := parseInterface(test.value)
+			if len(test.expectError) > 0 {
+				assert.EqualError(t, err, test.expectError)
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assert.Equal(t, test.expected, n)
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/pkg/errors"
+
+	"github.com/MauveSoftware/provisionize/pkg/api/proto"
+)
+
+func describeVM() (bool, error) {
+	conn, client, err := connect()
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+
+	vm := describeTarget.virtualMachine()
+	vm.Fqdn = *describeFqdn
+
+	state, err := client.DescribeVM(context.Background(), &proto.DescribeVMRequest{VirtualMachine: vm})
+	if err != nil {
+		return false, errors.Wrap(err, "error on describe VM call")
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	printVMState(w, state)
+
+	return len(state.Errors) == 0, w.Flush()
+}
+
+func printVMState(w io.Writer, state *proto.VMState) {
+	vm := state.VirtualMachine
+	fmt.Fprintf(w, "Name:\t%s\n", vm.Name)
+	fmt.Fprintf(w, "ID:\t%s\n", vm.Id)
+	fmt.Fprintf(w, "Status:\t%s\n", state.Status)
+	fmt.Fprintf(w, "Cluster:\t%s\n", vm.ClusterName)
+	fmt.Fprintf(w, "FQDN:\t%s\n", vm.Fqdn)
+	fmt.Fprintf(w, "CPU cores:\t%d\n", vm.CpuCores)
+	fmt.Fprintf(w, "Memory (MB):\t%d\n", vm.MemoryMb)
+
+	for _, d := range vm.Disks {
+		fmt.Fprintf(w, "Disk:\t%s (%d GB, %s, %s, %s)\n", d.Name, d.SizeGb, d.StorageDomain, d.Interface, d.Format)
+	}
+
+	for _, r := range state.DnsRecords {
+		fmt.Fprintf(w, "DNS record:\t%s %d %s %s\n", r.Name, r.Ttl, r.Type, strings.Join(r.Values, ", "))
+	}
+
+	for _, j := range state.TowerJobs {
+		fmt.Fprintf(w, "Tower job:\t%d %s (%s, finished %s)\n", j.Id, j.Name, j.Status, j.Finished)
+	}
+
+	for _, e := range state.Errors {
+		fmt.Fprintf(w, "Error:\t%s: %s\n", e.ServiceName, e.Message)
+	}
+}
+
+func listVMs() (bool, error) {
+	conn, client, err := connect()
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+
+	res, err := client.ListVMs(context.Background(), &proto.ListVMsRequest{
+		ClusterName: *listCluster,
+		Template:    *listTemplate,
+		Details:     *listDetails,
+	})
+	if err != nil {
+		return false, errors.Wrap(err, "error on list VMs call")
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tSTATUS\tCLUSTER\tFQDN\tCORES\tMEMORY (MB)\tERRORS")
+	for _, s := range res.Vms {
+		vm := s.VirtualMachine
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%d\t%d\n", vm.Name, s.Status, vm.ClusterName, vm.Fqdn, vm.CpuCores, vm.MemoryMb, len(s.Errors))
+	}
+
+	return true, w.Flush()
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	kingpin "gopkg.in/alecthomas/kingpin.v2"
+
+	"github.com/MauveSoftware/provisionize/pkg/api/proto"
+)
+
+type vmSelector struct {
+	name    *string
+	id      *string
+	cluster *string
+}
+
+func newVMSelector(cmd *kingpin.CmdClause) *vmSelector {
+	return &vmSelector{
+		name:    cmd.Arg("name", "Name of the VM").Required().String(),
+		id:      cmd.Flag("id", "oVirt ID of the VM (default: lookup by name and cluster)").String(),
+		cluster: cmd.Flag("cluster", "Name of the cluster the VM is deployed on").String(),
+	}
+}
+
+func (s *vmSelector) virtualMachine() *proto.VirtualMachine {
+	return &proto.VirtualMachine{
+		Name:        *s.name,
+		Id:          *s.id,
+		ClusterName: *s.cluster,
+	}
+}
+
+type lifecycleCall func(ctx context.Context, client proto.ProvisionizeServiceClient, req *proto.VMActionRequest, opts ...grpc.CallOption) (updateStream, error)
+
+func startVM() (bool, error) {
+	req := &proto.VMActionRequest{VirtualMachine: startTarget.virtualMachine()}
+	return runLifecycleAction(req, func(ctx context.Context, client proto.ProvisionizeServiceClient, req *proto.VMActionRequest, opts ...grpc.CallOption) (updateStream, error) {
+		return client.StartVM(ctx, req, opts...)
+	})
+}
+
+func stopVM() (bool, error) {
+	req := &proto.VMActionRequest{
+		VirtualMachine:         stopTarget.virtualMachine(),
+		Force:                  *stopForce,
+		ShutdownTimeoutSeconds: uint32(stopTimeout.Seconds()),
+	}
+	return runLifecycleAction(req, func(ctx context.Context, client proto.ProvisionizeServiceClient, req *proto.VMActionRequest, opts ...grpc.CallOption) (updateStream, error) {
+		return client.StopVM(ctx, req, opts...)
+	})
+}
+
+func rebootVM() (bool, error) {
+	req := &proto.VMActionRequest{VirtualMachine: rebootTarget.virtualMachine()}
+	return runLifecycleAction(req, func(ctx context.Context, client proto.ProvisionizeServiceClient, req *proto.VMActionRequest, opts ...grpc.CallOption) (updateStream, error) {
+		return client.RebootVM(ctx, req, opts...)
+	})
+}
+
+func resizeVM() (bool, error) {
+	vm := resizeTarget.virtualMachine()
+	vm.CpuCores = uint32(*resizeCores)
+	vm.MemoryMb = uint32(*resizeMemory)
+
+	req := &proto.VMActionRequest{VirtualMachine: vm}
+	return runLifecycleAction(req, func(ctx context.Context, client proto.ProvisionizeServiceClient, req *proto.VMActionRequest, opts ...grpc.CallOption) (updateStream, error) {
+		return client.ResizeVM(ctx, req, opts...)
+	})
+}
+
+func runLifecycleAction(req *proto.VMActionRequest, call lifecycleCall) (bool, error) {
+	conn, client, err := connect()
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+
+	req.RequestId = uuid.New().String()
+
+	stream, err := call(context.Background(), client, req)
+	if err != nil {
+		return false, errors.Wrap(err, "error on lifecycle call")
+	}
+
+	return receiveUpdates(stream)
+}
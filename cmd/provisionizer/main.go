@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"net"
 	"os"
 
 	"github.com/google/uuid"
@@ -20,33 +21,110 @@ import (
 const version = "0.5.0"
 
 var (
-	showVersion  = kingpin.Flag("version", "Shows version info").Short('v').Bool()
-	apiAddress   = kingpin.Flag("api", "API endpoint of the provisionize service").Default("[::1]:1337").String()
-	id           = kingpin.Flag("id", "Internal identifier of the VM").String()
-	vmName       = kingpin.Arg("name", "Name of the VM to create").Required().String()
-	clusterName  = kingpin.Flag("cluster", "Name of the cluster the VM should be deployed on").String()
-	templateName = kingpin.Flag("template", "Name of the template to use").String()
-	fqdn         = kingpin.Flag("fqdn", "Full qualified domain name of the VM").Default("").String()
-	ipv4         = kingpin.Flag("ipv4", "IPv4 address").IP()
-	ipv6         = kingpin.Flag("ipv6", "IPv6 address").IP()
-	cores        = kingpin.Flag("cores", "Number of CPU cores").Default("4").Uint()
-	memory       = kingpin.Flag("memory", "Memory in MB").Default("1024").Uint()
-	ipv4PfxLen   = kingpin.Flag("ipv4-pfx-len", "Prefix length for IPv4").Default("32").Uint()
-	ipv6PfxLen   = kingpin.Flag("ipv6-pfx-len", "Prefix length for IPv4").Default("128").Uint()
-	ipv4Gateway  = kingpin.Flag("ipv4-gateway", "Gateway IP for IPv4").IP()
-	ipv6Gateway  = kingpin.Flag("ipv6-gateway", "Gateway IP for IPv6").IP()
-	debug        = kingpin.Flag("debug", "Print debug information recevied from server").Bool()
+	showVersion = kingpin.Flag("version", "Shows version info").Short('v').Bool()
+	apiAddress  = kingpin.Flag("api", "API endpoint of the provisionize service").Default("[::1]:1337").String()
+	debug       = kingpin.Flag("debug", "Print debug information recevied from server").Bool()
+
+	createCmd    = kingpin.Command("create", "Creates a new VM").Default()
+	id           = createCmd.Flag("id", "Internal identifier of the VM").String()
+	vmName       = createCmd.Arg("name", "Name of the VM to create").Required().String()
+	clusterName  = createCmd.Flag("cluster", "Name of the cluster the VM should be deployed on").String()
+	templateName = createCmd.Flag("template", "Name of the template to use").String()
+	fqdn         = createCmd.Flag("fqdn", "Full qualified domain name of the VM").Default("").String()
+	ipv4         = createCmd.Flag("ipv4", "IPv4 address").IP()
+	ipv6         = createCmd.Flag("ipv6", "IPv6 address").IP()
+	cores        = createCmd.Flag("cores", "Number of CPU cores (default defined by template)").Uint()
+	memory       = createCmd.Flag("memory", "Memory in MB (default defined by template)").Uint()
+	ipv4PfxLen   = createCmd.Flag("ipv4-pfx-len", "Prefix length for IPv4 (default defined by template)").Uint()
+	ipv6PfxLen   = createCmd.Flag("ipv6-pfx-len", "Prefix length for IPv6 (default defined by template)").Uint()
+	ipv4Gateway  = createCmd.Flag("ipv4-gateway", "Gateway IP for IPv4").IP()
+	ipv6Gateway  = createCmd.Flag("ipv6-gateway", "Gateway IP for IPv6").IP()
+	interfaces   = createCmd.Flag("interface", "Network interface (name=ens4,network=storage,vnic-profile=...,mac=...,ipv4=10.0.0.5/24,ipv4-gateway=...,ipv6=...,ipv6-gateway=...,primary). Can be used multiple times instead of --ipv4/--ipv6").Strings()
+	disks        = createCmd.Flag("disk", "Additional disk (size=100,storage-domain=ssd,name=...,interface=virtio_scsi,format=cow,bootable). Size in GB, can be used multiple times").Strings()
+	sshKeys      = createCmd.Flag("ssh-key", "Authorized SSH public key (cloud-init). Can be used multiple times").Strings()
+	sshKeyFiles  = createCmd.Flag("ssh-key-file", "File containing authorized SSH public keys (cloud-init). Can be used multiple times").ExistingFiles()
+	userName     = createCmd.Flag("user", "Name of the user to configure (cloud-init)").String()
+	passwordFile = createCmd.Flag("password-file", "File containing the password of the user (cloud-init)").ExistingFile()
+	noPwAuth     = createCmd.Flag("disable-password-auth", "Disable SSH password authentication (cloud-init)").Bool()
+	expirePw     = createCmd.Flag("expire-password", "Force a password change on first login (cloud-init)").Bool()
+	timezone     = createCmd.Flag("timezone", "Timezone of the VM, e.g. Europe/Berlin (cloud-init)").String()
+	dnsServers   = createCmd.Flag("dns-server", "DNS server (cloud-init). Can be used multiple times").Strings()
+	dnsSearch    = createCmd.Flag("dns-search", "DNS search domain (cloud-init). Can be used multiple times").Strings()
+	userDataFile = createCmd.Flag("user-data", "File containing cloud-config user data merged with the template defaults").ExistingFile()
+	dnsTTL       = createCmd.Flag("dns-ttl", "TTL of the DNS records (default defined by server)").Uint32()
+	dnsAliases   = createCmd.Flag("dns-alias", "Alias name (CNAME) pointing to the FQDN. Can be used multiple times").Strings()
+	dnsRecords   = createCmd.Flag("dns-record", "Additional DNS record (name type value, @ for the FQDN), e.g. '@ SSHFP 1 2 abc'. Can be used multiple times").Strings()
+	extraVars    = createCmd.Flag("extra-var", "Extra var passed to the Ansible Tower job templates (key=value). Can be used multiple times").StringMap()
+
+	templatesCmd = kingpin.Command("templates", "Lists the templates available on the server")
+
+	templateCmd          = kingpin.Command("template", "Shows the details of a template")
+	describeTemplateName = templateCmd.Arg("name", "Name of the template").Required().String()
+
+	restoreCmd     = kingpin.Command("restore", "Restores a deprovisioned VM from the recycle bin")
+	restoreName    = restoreCmd.Arg("name", "Name of the VM to restore").Required().String()
+	restoreCluster = restoreCmd.Flag("cluster", "Name of the cluster the VM was deployed on").String()
+
+	startCmd    = kingpin.Command("start", "Starts a VM")
+	startTarget = newVMSelector(startCmd)
+
+	stopCmd     = kingpin.Command("stop", "Shuts down a VM")
+	stopTarget  = newVMSelector(stopCmd)
+	stopForce   = stopCmd.Flag("force", "Stop the VM immediately instead of shutting it down").Bool()
+	stopTimeout = stopCmd.Flag("shutdown-timeout", "Time to wait for the VM to shut down (default defined by server)").Duration()
+
+	rebootCmd    = kingpin.Command("reboot", "Reboots a VM")
+	rebootTarget = newVMSelector(rebootCmd)
+
+	resizeCmd    = kingpin.Command("resize", "Changes CPU cores and memory of a VM")
+	resizeTarget = newVMSelector(resizeCmd)
+	resizeCores  = resizeCmd.Flag("cores", "Number of CPU cores").Uint()
+	resizeMemory = resizeCmd.Flag("memory", "Memory in MB").Uint()
+
+	describeCmd    = kingpin.Command("describe", "Shows the state of a VM across all backends")
+	describeTarget = newVMSelector(describeCmd)
+	describeFqdn   = describeCmd.Flag("fqdn", "Full qualified domain name of the VM (default reported by oVirt)").String()
+
+	listCmd      = kingpin.Command("list", "Lists existing VMs")
+	listCluster  = listCmd.Flag("cluster", "Only list VMs deployed on this cluster").String()
+	listTemplate = listCmd.Flag("template", "Only list VMs created from this template").String()
+	listDetails  = listCmd.Flag("details", "Query the state of every VM from all backends").Bool()
 )
 
 func main() {
-	kingpin.Parse()
+	cmd := kingpin.Parse()
 
 	if *showVersion {
 		printVersion()
 		os.Exit(0)
 	}
 
-	success, err := startProvisioning()
+	var success bool
+	var err error
+
+	switch cmd {
+	case templatesCmd.FullCommand():
+		success, err = listTemplates()
+	case templateCmd.FullCommand():
+		success, err = describeTemplate()
+	case restoreCmd.FullCommand():
+		success, err = restore()
+	case startCmd.FullCommand():
+		success, err = startVM()
+	case stopCmd.FullCommand():
+		success, err = stopVM()
+	case rebootCmd.FullCommand():
+		success, err = rebootVM()
+	case resizeCmd.FullCommand():
+		success, err = resizeVM()
+	case describeCmd.FullCommand():
+		success, err = describeVM()
+	case listCmd.FullCommand():
+		success, err = listVMs()
+	default:
+		success, err = startProvisioning()
+	}
+
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -64,21 +142,41 @@ func printVersion() {
 	fmt.Println("Copyright: Mauve Mailorder Software, 2019. Licensed under MIT license")
 }
 
-func startProvisioning() (bool, error) {
+func connect() (*grpc.ClientConn, proto.ProvisionizeServiceClient, error) {
 	conn, err := grpc.Dial(*apiAddress, grpc.WithInsecure())
 	if err != nil {
-		return false, errors.Wrap(err, "could not connect to service")
+		return nil, nil, errors.Wrap(err, "could not connect to service")
+	}
+
+	return conn, proto.NewProvisionizeServiceClient(conn), nil
+}
+
+func startProvisioning() (bool, error) {
+	conn, client, err := connect()
+	if err != nil {
+		return false, err
 	}
 	defer conn.Close()
 
-	client := proto.NewProvisionizeServiceClient(conn)
+	req, err := requestFromParameters()
+	if err != nil {
+		return false, err
+	}
 
-	req := requestFromParameters()
 	stream, err := client.Provisionize(context.Background(), req)
 	if err != nil {
 		return false, errors.Wrap(err, "error on provisionize call")
 	}
 
+	return receiveUpdates(stream)
+}
+
+type updateStream interface {
+	Recv() (*proto.StatusUpdate, error)
+}
+
+// receiveUpdates logs all status updates received until the stream ends or a step failed
+func receiveUpdates(stream updateStream) (bool, error) {
 	for {
 		in, err := stream.Recv()
 		if err == io.EOF {
@@ -97,8 +195,8 @@ func startProvisioning() (bool, error) {
 	}
 }
 
-func requestFromParameters() *proto.ProvisionizeRequest {
-	return &proto.ProvisionizeRequest{
+func requestFromParameters() (*proto.ProvisionizeRequest, error) {
+	req := &proto.ProvisionizeRequest{
 		RequestId: uuid.New().String(),
 		VirtualMachine: &proto.VirtualMachine{
 			ClusterName: *clusterName,
@@ -106,18 +204,61 @@ func requestFromParameters() *proto.ProvisionizeRequest {
 			Id:          *id,
 			Fqdn:        *fqdn,
 			Ipv4: &proto.IPConfig{
-				Address:      (*ipv4).String(),
+				Address:      ipString(*ipv4),
 				PrefixLength: uint32(*ipv4PfxLen),
-				Gateway:      (*ipv4Gateway).String(),
+				Gateway:      ipString(*ipv4Gateway),
 			},
 			Ipv6: &proto.IPConfig{
-				Address:      (*ipv6).String(),
+				Address:      ipString(*ipv6),
 				PrefixLength: uint32(*ipv6PfxLen),
-				Gateway:      (*ipv6Gateway).String(),
+				Gateway:      ipString(*ipv6Gateway),
 			},
-			MemoryMb: uint32(*memory),
-			Name:     *vmName,
-			Template: *templateName,
+			MemoryMb:   uint32(*memory),
+			Name:       *vmName,
+			Template:   *templateName,
+			ExtraVars:  *extraVars,
+			DnsTtl:     *dnsTTL,
+			DnsAliases: *dnsAliases,
 		},
 	}
+
+	recs, err := clientutils.ParseDNSRecords(*dnsRecords)
+	if err != nil {
+		return nil, err
+	}
+	req.VirtualMachine.DnsRecords = recs
+
+	for _, s := range *interfaces {
+		n, err := parseInterface(s)
+		if err != nil {
+			return nil, err
+		}
+
+		req.VirtualMachine.Interfaces = append(req.VirtualMachine.Interfaces, n)
+	}
+
+	for _, s := range *disks {
+		d, err := parseDisk(s)
+		if err != nil {
+			return nil, err
+		}
+
+		req.VirtualMachine.Disks = append(req.VirtualMachine.Disks, d)
+	}
+
+	c, err := cloudInitFromParameters()
+	if err != nil {
+		return nil, err
+	}
+	req.VirtualMachine.CloudInit = c
+
+	return req, nil
+}
+
+func ipString(ip net.IP) string {
+	if ip == nil {
+		return ""
+	}
+
+	return ip.String()
 }
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+
+	"github.com/MauveSoftware/provisionize/pkg/api/proto"
+)
+
+func restore() (bool, error) {
+	conn, client, err := connect()
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+
+	req := &proto.ProvisionizeRequest{
+		RequestId: uuid.New().String(),
+		VirtualMachine: &proto.VirtualMachine{
+			Name:        *restoreName,
+			ClusterName: *restoreCluster,
+		},
+	}
+
+	stream, err := client.Restore(context.Background(), req)
+	if err != nil {
+		return false, errors.Wrap(err, "error on restore call")
+	}
+
+	return receiveUpdates(stream)
+}
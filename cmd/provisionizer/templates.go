@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/pkg/errors"
+
+	"github.com/MauveSoftware/provisionize/pkg/api/proto"
+)
+
+func listTemplates() (bool, error) {
+	conn, client, err := connect()
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+
+	res, err := client.ListTemplates(context.Background(), &proto.ListTemplatesRequest{})
+	if err != nil {
+		return false, errors.Wrap(err, "error on list templates call")
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tOVIRT TEMPLATE\tBOOT DISK\tTOWER JOB TEMPLATES")
+	for _, t := range res.Templates {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", t.Name, t.OvirtTemplate, t.BootDiskName, joinIDs(t.AnsibleTowerJobTemplates))
+	}
+
+	return true, w.Flush()
+}
+
+func describeTemplate() (bool, error) {
+	conn, client, err := connect()
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+
+	t, err := client.DescribeTemplate(context.Background(), &proto.DescribeTemplateRequest{Name: *describeTemplateName})
+	if err != nil {
+		return false, errors.Wrap(err, "error on describe template call")
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "Name:\t%s\n", t.Name)
+	fmt.Fprintf(w, "oVirt template:\t%s\n", t.OvirtTemplate)
+	fmt.Fprintf(w, "Boot disk:\t%s\n", t.BootDiskName)
+	fmt.Fprintf(w, "Tower job templates:\t%s\n", joinIDs(t.AnsibleTowerJobTemplates))
+	fmt.Fprintf(w, "Tower workflows:\t%s\n", joinIDs(t.AnsibleTowerWorkflows))
+	fmt.Fprintf(w, "Tower deprovisioning job templates:\t%s\n", joinIDs(t.AnsibleTowerDeprovisionJobTemplates))
+	fmt.Fprintf(w, "Skipped steps:\t%s\n", strings.Join(t.SkipSteps, ", "))
+	fmt.Fprintf(w, "Default cluster:\t%s\n", t.DefaultClusterName)
+	fmt.Fprintf(w, "CPU cores:\t%s\n", formatLimits(t.CpuCores))
+	fmt.Fprintf(w, "Memory (MB):\t%s\n", formatLimits(t.MemoryMb))
+
+	return true, w.Flush()
+}
+
+func joinIDs(ids []uint32) string {
+	s := make([]string, len(ids))
+	for i, id := range ids {
+		s[i] = fmt.Sprint(id)
+	}
+
+	return strings.Join(s, ", ")
+}
+
+func formatLimits(l *proto.ResourceLimits) string {
+	if l == nil {
+		return "-"
+	}
+
+	return fmt.Sprintf("default %d, min %d, max %d", l.Default, l.Min, l.Max)
+}
@@ -0,0 +1,91 @@
+package proto
+
+import (
+	"errors"
+	"fmt"
+)
+
+// DefaultInterfaceName is the name of the interface of VMs configured by ipv4 and ipv6 only
+const DefaultInterfaceName = "ens3"
+
+// NormalizeInterfaces ensures the VM has at least one interface and exactly one primary interface.
+// VMs without interfaces get a single interface using ipv4 and ipv6 of the VM.
+// Afterwards ipv4 and ipv6 of the VM refer to the IP configs of the primary interface.
+func (m *VirtualMachine) NormalizeInterfaces() error {
+	if len(m.Interfaces) == 0 {
+		m.Interfaces = []*NetworkInterface{
+			{Name: DefaultInterfaceName, Ipv4: m.Ipv4, Ipv6: m.Ipv6, Primary: true},
+		}
+	} else if m.hasForeignAddress(m.Ipv4) || m.hasForeignAddress(m.Ipv6) {
+		return errors.New("ipv4 and ipv6 can not be combined with interfaces")
+	}
+
+	var primary *NetworkInterface
+	names := make(map[string]bool)
+	for i, n := range m.Interfaces {
+		if len(n.Name) == 0 {
+			return fmt.Errorf("interfaces[%d]: name is required", i)
+		}
+
+		if names[n.Name] {
+			return fmt.Errorf("interface %s is defined more than once", n.Name)
+		}
+		names[n.Name] = true
+
+		if n.Ipv4 == nil {
+			n.Ipv4 = &IPConfig{}
+		}
+
+		if n.Ipv6 == nil {
+			n.Ipv6 = &IPConfig{}
+		}
+
+		if n.Primary {
+			if primary != nil {
+				return fmt.Errorf("interfaces %s and %s are both marked as primary", primary.Name, n.Name)
+			}
+
+			primary = n
+		}
+	}
+
+	if primary == nil {
+		primary = m.Interfaces[0]
+		primary.Primary = true
+	}
+
+	m.Ipv4 = primary.Ipv4
+	m.Ipv6 = primary.Ipv6
+
+	return nil
+}
+
+// hasForeignAddress checks if c contains an address and does not belong to one of the interfaces
+func (m *VirtualMachine) hasForeignAddress(c *IPConfig) bool {
+	if len(c.GetAddress()) == 0 {
+		return false
+	}
+
+	for _, n := range m.Interfaces {
+		if n.Ipv4 == c || n.Ipv6 == c {
+			return false
+		}
+	}
+
+	return true
+}
+
+// PrimaryInterface returns the interface used for the host records of the VM
+func (m *VirtualMachine) PrimaryInterface() *NetworkInterface {
+	for _, n := range m.Interfaces {
+		if n.Primary {
+			return n
+		}
+	}
+
+	if len(m.Interfaces) > 0 {
+		return m.Interfaces[0]
+	}
+
+	return &NetworkInterface{Name: DefaultInterfaceName, Ipv4: m.Ipv4, Ipv6: m.Ipv6, Primary: true}
+}
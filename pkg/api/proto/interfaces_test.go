@@ -0,0 +1,98 @@
+package proto
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeInterfaces(t *testing.T) {
+	t.Run("legacy addresses", func(t *testing.T) {
+		vm := &VirtualMachine{Ipv4: &IPConfig{Address: "192.168.0.1"}}
+		require.NoError(t, vm.NormalizeInterfaces())
+
+		require.Len(t, vm.Interfaces, 1)
+		n := vm.Interfaces[0]
+		assert.Equal(t, DefaultInterfaceName, n.Name)
+		assert.True(t, n.Primary)
+		assert.Same(t, vm.Ipv4, n.Ipv4)
+		assert.Same(t, vm.Ipv6, n.Ipv6)
+		assert.NotNil(t, vm.Ipv6)
+	})
+
+	t.Run("first interface becomes primary", func(t *testing.T) {
+		vm := &VirtualMachine{
+			Interfaces: []*NetworkInterface{
+				{Name: "ens3", Ipv4: &IPConfig{Address: "192.168.0.1"}},
+				{Name: "ens4", Ipv4: &IPConfig{Address: "10.0.0.1"}},
+			},
+		}
+		require.NoError(t, vm.NormalizeInterfaces())
+
+		assert.True(t, vm.Interfaces[0].Primary)
+		assert.Equal(t, "192.168.0.1", vm.Ipv4.Address)
+		assert.Equal(t, "ens3", vm.PrimaryInterface().Name)
+
+		require.NoError(t, vm.NormalizeInterfaces(), "normalizing twice")
+	})
+
+	t.Run("explicit primary", func(t *testing.T) {
+		vm := &VirtualMachine{
+			Interfaces: []*NetworkInterface{
+				{Name: "ens3", Ipv4: &IPConfig{Address: "10.0.0.1"}},
+				{Name: "ens4", Ipv4: &IPConfig{Address: "192.168.0.1"}, Primary: true},
+			},
+		}
+		require.NoError(t, vm.NormalizeInterfaces())
+
+		assert.Equal(t, "192.168.0.1", vm.Ipv4.Address)
+		assert.Equal(t, "ens4", vm.PrimaryInterface().Name)
+		assert.False(t, vm.Interfaces[0].Primary)
+	})
+
+	tests := []struct {
+		name        string
+		vm          *VirtualMachine
+		expectError string
+	}{
+		{
+			name: "ipv4 combined with interfaces",
+			vm: &VirtualMachine{
+				Ipv4:       &IPConfig{Address: "192.168.0.1"},
+				Interfaces: []*NetworkInterface{{Name: "ens3"}},
+			},
+			expectError: "ipv4 and ipv6 can not be combined with interfaces",
+		},
+		{
+			name: "multiple primary interfaces",
+			vm: &VirtualMachine{
+				Interfaces: []*NetworkInterface{{Name: "ens3", Primary: true}, {Name: "ens4", Primary: true}},
+			},
+			expectError: "interfaces ens3 and ens4 are both marked as primary",
+		},
+		{
+			name: "duplicate name",
+			vm: &VirtualMachine{
+				Interfaces: []*NetworkInterface{{Name: "ens3"}, {Name: "ens3"}},
+			},
+			expectError: "interface ens3 is defined more than once",
+		},
+		{
+			name: "missing name",
+			vm: &VirtualMachine{
+				Interfaces: []*NetworkInterface{{Network: "storage"}},
+			},
+			expectError: "interfaces[0]: name is required",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.vm.NormalizeInterfaces()
+			if assert.Error(t, err) {
+				assert.Equal(t, test.expectError, err.Error())
+			}
+		})
+	}
+}
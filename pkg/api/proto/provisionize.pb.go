@@ -29,6 +29,7 @@ type StatusUpdate struct {
 	Message              string   `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
 	DebugMessage         string   `protobuf:"bytes,3,opt,name=debugMessage,proto3" json:"debugMessage,omitempty"`
 	Failed               bool     `protobuf:"varint,4,opt,name=failed,proto3" json:"failed,omitempty"`
+	Warning              bool     `protobuf:"varint,5,opt,name=warning,proto3" json:"warning,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -87,6 +88,13 @@ func (m *StatusUpdate) GetFailed() bool {
 	return false
 }
 
+func (m *StatusUpdate) GetWarning() bool {
+	if m != nil {
+		return m.Warning
+	}
+	return false
+}
+
 type IPConfig struct {
 	Address              string   `protobuf:"bytes,1,opt,name=address,proto3" json:"address,omitempty"`
 	PrefixLength         uint32   `protobuf:"varint,2,opt,name=prefix_length,json=prefixLength,proto3" json:"prefix_length,omitempty"`
@@ -142,26 +150,302 @@ func (m *IPConfig) GetGateway() string {
 	return ""
 }
 
-type VirtualMachine struct {
-	Id                   string    `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
-	Template             string    `protobuf:"bytes,2,opt,name=template,proto3" json:"template,omitempty"`
-	Name                 string    `protobuf:"bytes,3,opt,name=name,proto3" json:"name,omitempty"`
-	Fqdn                 string    `protobuf:"bytes,4,opt,name=fqdn,proto3" json:"fqdn,omitempty"`
-	ClusterName          string    `protobuf:"bytes,5,opt,name=cluster_name,json=clusterName,proto3" json:"cluster_name,omitempty"`
-	MemoryMb             uint32    `protobuf:"varint,6,opt,name=memory_mb,json=memoryMb,proto3" json:"memory_mb,omitempty"`
-	CpuCores             uint32    `protobuf:"varint,7,opt,name=cpu_cores,json=cpuCores,proto3" json:"cpu_cores,omitempty"`
-	Ipv4                 *IPConfig `protobuf:"bytes,8,opt,name=ipv4,proto3" json:"ipv4,omitempty"`
-	Ipv6                 *IPConfig `protobuf:"bytes,9,opt,name=ipv6,proto3" json:"ipv6,omitempty"`
+type NetworkInterface struct {
+	Name                 string    `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	VnicProfile          string    `protobuf:"bytes,2,opt,name=vnic_profile,json=vnicProfile,proto3" json:"vnic_profile,omitempty"`
+	Network              string    `protobuf:"bytes,3,opt,name=network,proto3" json:"network,omitempty"`
+	Mac                  string    `protobuf:"bytes,4,opt,name=mac,proto3" json:"mac,omitempty"`
+	Ipv4                 *IPConfig `protobuf:"bytes,5,opt,name=ipv4,proto3" json:"ipv4,omitempty"`
+	Ipv6                 *IPConfig `protobuf:"bytes,6,opt,name=ipv6,proto3" json:"ipv6,omitempty"`
+	Primary              bool      `protobuf:"varint,7,opt,name=primary,proto3" json:"primary,omitempty"`
 	XXX_NoUnkeyedLiteral struct{}  `json:"-"`
 	XXX_unrecognized     []byte    `json:"-"`
 	XXX_sizecache        int32     `json:"-"`
 }
 
+func (m *NetworkInterface) Reset()         { *m = NetworkInterface{} }
+func (m *NetworkInterface) String() string { return proto.CompactTextString(m) }
+func (*NetworkInterface) ProtoMessage()    {}
+func (*NetworkInterface) Descriptor() ([]byte, []int) {
+	return fileDescriptor_551987ef9813264c, []int{2}
+}
+
+func (m *NetworkInterface) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_NetworkInterface.Unmarshal(m, b)
+}
+func (m *NetworkInterface) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_NetworkInterface.Marshal(b, m, deterministic)
+}
+func (m *NetworkInterface) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_NetworkInterface.Merge(m, src)
+}
+func (m *NetworkInterface) XXX_Size() int {
+	return xxx_messageInfo_NetworkInterface.Size(m)
+}
+func (m *NetworkInterface) XXX_DiscardUnknown() {
+	xxx_messageInfo_NetworkInterface.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_NetworkInterface proto.InternalMessageInfo
+
+func (m *NetworkInterface) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *NetworkInterface) GetVnicProfile() string {
+	if m != nil {
+		return m.VnicProfile
+	}
+	return ""
+}
+
+func (m *NetworkInterface) GetNetwork() string {
+	if m != nil {
+		return m.Network
+	}
+	return ""
+}
+
+func (m *NetworkInterface) GetMac() string {
+	if m != nil {
+		return m.Mac
+	}
+	return ""
+}
+
+func (m *NetworkInterface) GetIpv4() *IPConfig {
+	if m != nil {
+		return m.Ipv4
+	}
+	return nil
+}
+
+func (m *NetworkInterface) GetIpv6() *IPConfig {
+	if m != nil {
+		return m.Ipv6
+	}
+	return nil
+}
+
+func (m *NetworkInterface) GetPrimary() bool {
+	if m != nil {
+		return m.Primary
+	}
+	return false
+}
+
+type Disk struct {
+	Name                 string   `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	SizeGb               uint64   `protobuf:"varint,2,opt,name=size_gb,json=sizeGb,proto3" json:"size_gb,omitempty"`
+	StorageDomain        string   `protobuf:"bytes,3,opt,name=storage_domain,json=storageDomain,proto3" json:"storage_domain,omitempty"`
+	Interface            string   `protobuf:"bytes,4,opt,name=interface,proto3" json:"interface,omitempty"`
+	Format               string   `protobuf:"bytes,5,opt,name=format,proto3" json:"format,omitempty"`
+	Bootable             bool     `protobuf:"varint,6,opt,name=bootable,proto3" json:"bootable,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Disk) Reset()         { *m = Disk{} }
+func (m *Disk) String() string { return proto.CompactTextString(m) }
+func (*Disk) ProtoMessage()    {}
+func (*Disk) Descriptor() ([]byte, []int) {
+	return fileDescriptor_551987ef9813264c, []int{3}
+}
+
+func (m *Disk) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Disk.Unmarshal(m, b)
+}
+func (m *Disk) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Disk.Marshal(b, m, deterministic)
+}
+func (m *Disk) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Disk.Merge(m, src)
+}
+func (m *Disk) XXX_Size() int {
+	return xxx_messageInfo_Disk.Size(m)
+}
+func (m *Disk) XXX_DiscardUnknown() {
+	xxx_messageInfo_Disk.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Disk proto.InternalMessageInfo
+
+func (m *Disk) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *Disk) GetSizeGb() uint64 {
+	if m != nil {
+		return m.SizeGb
+	}
+	return 0
+}
+
+func (m *Disk) GetStorageDomain() string {
+	if m != nil {
+		return m.StorageDomain
+	}
+	return ""
+}
+
+func (m *Disk) GetInterface() string {
+	if m != nil {
+		return m.Interface
+	}
+	return ""
+}
+
+func (m *Disk) GetFormat() string {
+	if m != nil {
+		return m.Format
+	}
+	return ""
+}
+
+func (m *Disk) GetBootable() bool {
+	if m != nil {
+		return m.Bootable
+	}
+	return false
+}
+
+type CloudInit struct {
+	AuthorizedSshKeys    []string `protobuf:"bytes,1,rep,name=authorized_ssh_keys,json=authorizedSshKeys,proto3" json:"authorized_ssh_keys,omitempty"`
+	UserName             string   `protobuf:"bytes,2,opt,name=user_name,json=userName,proto3" json:"user_name,omitempty"`
+	Password             string   `protobuf:"bytes,3,opt,name=password,proto3" json:"password,omitempty"`
+	DisablePasswordAuth  bool     `protobuf:"varint,4,opt,name=disable_password_auth,json=disablePasswordAuth,proto3" json:"disable_password_auth,omitempty"`
+	ExpirePassword       bool     `protobuf:"varint,5,opt,name=expire_password,json=expirePassword,proto3" json:"expire_password,omitempty"`
+	Timezone             string   `protobuf:"bytes,6,opt,name=timezone,proto3" json:"timezone,omitempty"`
+	DnsServers           []string `protobuf:"bytes,7,rep,name=dns_servers,json=dnsServers,proto3" json:"dns_servers,omitempty"`
+	DnsSearch            []string `protobuf:"bytes,8,rep,name=dns_search,json=dnsSearch,proto3" json:"dns_search,omitempty"`
+	UserData             string   `protobuf:"bytes,9,opt,name=user_data,json=userData,proto3" json:"user_data,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *CloudInit) Reset()         { *m = CloudInit{} }
+func (m *CloudInit) String() string { return proto.CompactTextString(m) }
+func (*CloudInit) ProtoMessage()    {}
+func (*CloudInit) Descriptor() ([]byte, []int) {
+	return fileDescriptor_551987ef9813264c, []int{4}
+}
+
+func (m *CloudInit) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CloudInit.Unmarshal(m, b)
+}
+func (m *CloudInit) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CloudInit.Marshal(b, m, deterministic)
+}
+func (m *CloudInit) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CloudInit.Merge(m, src)
+}
+func (m *CloudInit) XXX_Size() int {
+	return xxx_messageInfo_CloudInit.Size(m)
+}
+func (m *CloudInit) XXX_DiscardUnknown() {
+	xxx_messageInfo_CloudInit.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CloudInit proto.InternalMessageInfo
+
+func (m *CloudInit) GetAuthorizedSshKeys() []string {
+	if m != nil {
+		return m.AuthorizedSshKeys
+	}
+	return nil
+}
+
+func (m *CloudInit) GetUserName() string {
+	if m != nil {
+		return m.UserName
+	}
+	return ""
+}
+
+func (m *CloudInit) GetPassword() string {
+	if m != nil {
+		return m.Password
+	}
+	return ""
+}
+
+func (m *CloudInit) GetDisablePasswordAuth() bool {
+	if m != nil {
+		return m.DisablePasswordAuth
+	}
+	return false
+}
+
+func (m *CloudInit) GetExpirePassword() bool {
+	if m != nil {
+		return m.ExpirePassword
+	}
+	return false
+}
+
+func (m *CloudInit) GetTimezone() string {
+	if m != nil {
+		return m.Timezone
+	}
+	return ""
+}
+
+func (m *CloudInit) GetDnsServers() []string {
+	if m != nil {
+		return m.DnsServers
+	}
+	return nil
+}
+
+func (m *CloudInit) GetDnsSearch() []string {
+	if m != nil {
+		return m.DnsSearch
+	}
+	return nil
+}
+
+func (m *CloudInit) GetUserData() string {
+	if m != nil {
+		return m.UserData
+	}
+	return ""
+}
+
+type VirtualMachine struct {
+	Id                   string              `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Template             string              `protobuf:"bytes,2,opt,name=template,proto3" json:"template,omitempty"`
+	Name                 string              `protobuf:"bytes,3,opt,name=name,proto3" json:"name,omitempty"`
+	Fqdn                 string              `protobuf:"bytes,4,opt,name=fqdn,proto3" json:"fqdn,omitempty"`
+	ClusterName          string              `protobuf:"bytes,5,opt,name=cluster_name,json=clusterName,proto3" json:"cluster_name,omitempty"`
+	MemoryMb             uint32              `protobuf:"varint,6,opt,name=memory_mb,json=memoryMb,proto3" json:"memory_mb,omitempty"`
+	CpuCores             uint32              `protobuf:"varint,7,opt,name=cpu_cores,json=cpuCores,proto3" json:"cpu_cores,omitempty"`
+	Ipv4                 *IPConfig           `protobuf:"bytes,8,opt,name=ipv4,proto3" json:"ipv4,omitempty"`
+	Ipv6                 *IPConfig           `protobuf:"bytes,9,opt,name=ipv6,proto3" json:"ipv6,omitempty"`
+	Interfaces           []*NetworkInterface `protobuf:"bytes,10,rep,name=interfaces,proto3" json:"interfaces,omitempty"`
+	Disks                []*Disk             `protobuf:"bytes,11,rep,name=disks,proto3" json:"disks,omitempty"`
+	CloudInit            *CloudInit          `protobuf:"bytes,12,opt,name=cloud_init,json=cloudInit,proto3" json:"cloud_init,omitempty"`
+	ExtraVars            map[string]string   `protobuf:"bytes,13,rep,name=extra_vars,json=extraVars,proto3" json:"extra_vars,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	DnsTtl               uint32              `protobuf:"varint,14,opt,name=dns_ttl,json=dnsTtl,proto3" json:"dns_ttl,omitempty"`
+	DnsAliases           []string            `protobuf:"bytes,15,rep,name=dns_aliases,json=dnsAliases,proto3" json:"dns_aliases,omitempty"`
+	DnsRecords           []*DNSRecord        `protobuf:"bytes,16,rep,name=dns_records,json=dnsRecords,proto3" json:"dns_records,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}            `json:"-"`
+	XXX_unrecognized     []byte              `json:"-"`
+	XXX_sizecache        int32               `json:"-"`
+}
+
 func (m *VirtualMachine) Reset()         { *m = VirtualMachine{} }
 func (m *VirtualMachine) String() string { return proto.CompactTextString(m) }
 func (*VirtualMachine) ProtoMessage()    {}
 func (*VirtualMachine) Descriptor() ([]byte, []int) {
-	return fileDescriptor_551987ef9813264c, []int{2}
+	return fileDescriptor_551987ef9813264c, []int{5}
 }
 
 func (m *VirtualMachine) XXX_Unmarshal(b []byte) error {
@@ -245,19 +529,124 @@ func (m *VirtualMachine) GetIpv6() *IPConfig {
 	return nil
 }
 
+func (m *VirtualMachine) GetInterfaces() []*NetworkInterface {
+	if m != nil {
+		return m.Interfaces
+	}
+	return nil
+}
+
+func (m *VirtualMachine) GetDisks() []*Disk {
+	if m != nil {
+		return m.Disks
+	}
+	return nil
+}
+
+func (m *VirtualMachine) GetCloudInit() *CloudInit {
+	if m != nil {
+		return m.CloudInit
+	}
+	return nil
+}
+
+func (m *VirtualMachine) GetExtraVars() map[string]string {
+	if m != nil {
+		return m.ExtraVars
+	}
+	return nil
+}
+
+func (m *VirtualMachine) GetDnsTtl() uint32 {
+	if m != nil {
+		return m.DnsTtl
+	}
+	return 0
+}
+
+func (m *VirtualMachine) GetDnsAliases() []string {
+	if m != nil {
+		return m.DnsAliases
+	}
+	return nil
+}
+
+func (m *VirtualMachine) GetDnsRecords() []*DNSRecord {
+	if m != nil {
+		return m.DnsRecords
+	}
+	return nil
+}
+
+type DeprovisionOptions struct {
+	Shutdown               bool     `protobuf:"varint,1,opt,name=shutdown,proto3" json:"shutdown,omitempty"`
+	ShutdownTimeoutSeconds uint32   `protobuf:"varint,2,opt,name=shutdown_timeout_seconds,json=shutdownTimeoutSeconds,proto3" json:"shutdown_timeout_seconds,omitempty"`
+	Force                  bool     `protobuf:"varint,3,opt,name=force,proto3" json:"force,omitempty"`
+	XXX_NoUnkeyedLiteral   struct{} `json:"-"`
+	XXX_unrecognized       []byte   `json:"-"`
+	XXX_sizecache          int32    `json:"-"`
+}
+
+func (m *DeprovisionOptions) Reset()         { *m = DeprovisionOptions{} }
+func (m *DeprovisionOptions) String() string { return proto.CompactTextString(m) }
+func (*DeprovisionOptions) ProtoMessage()    {}
+func (*DeprovisionOptions) Descriptor() ([]byte, []int) {
+	return fileDescriptor_551987ef9813264c, []int{6}
+}
+
+func (m *DeprovisionOptions) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DeprovisionOptions.Unmarshal(m, b)
+}
+func (m *DeprovisionOptions) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DeprovisionOptions.Marshal(b, m, deterministic)
+}
+func (m *DeprovisionOptions) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DeprovisionOptions.Merge(m, src)
+}
+func (m *DeprovisionOptions) XXX_Size() int {
+	return xxx_messageInfo_DeprovisionOptions.Size(m)
+}
+func (m *DeprovisionOptions) XXX_DiscardUnknown() {
+	xxx_messageInfo_DeprovisionOptions.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DeprovisionOptions proto.InternalMessageInfo
+
+func (m *DeprovisionOptions) GetShutdown() bool {
+	if m != nil {
+		return m.Shutdown
+	}
+	return false
+}
+
+func (m *DeprovisionOptions) GetShutdownTimeoutSeconds() uint32 {
+	if m != nil {
+		return m.ShutdownTimeoutSeconds
+	}
+	return 0
+}
+
+func (m *DeprovisionOptions) GetForce() bool {
+	if m != nil {
+		return m.Force
+	}
+	return false
+}
+
 type ProvisionizeRequest struct {
-	RequestId            string          `protobuf:"bytes,1,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`
-	VirtualMachine       *VirtualMachine `protobuf:"bytes,2,opt,name=virtual_machine,json=virtualMachine,proto3" json:"virtual_machine,omitempty"`
-	XXX_NoUnkeyedLiteral struct{}        `json:"-"`
-	XXX_unrecognized     []byte          `json:"-"`
-	XXX_sizecache        int32           `json:"-"`
+	RequestId            string              `protobuf:"bytes,1,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`
+	VirtualMachine       *VirtualMachine     `protobuf:"bytes,2,opt,name=virtual_machine,json=virtualMachine,proto3" json:"virtual_machine,omitempty"`
+	DeprovisionOptions   *DeprovisionOptions `protobuf:"bytes,3,opt,name=deprovision_options,json=deprovisionOptions,proto3" json:"deprovision_options,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}            `json:"-"`
+	XXX_unrecognized     []byte              `json:"-"`
+	XXX_sizecache        int32               `json:"-"`
 }
 
 func (m *ProvisionizeRequest) Reset()         { *m = ProvisionizeRequest{} }
 func (m *ProvisionizeRequest) String() string { return proto.CompactTextString(m) }
 func (*ProvisionizeRequest) ProtoMessage()    {}
 func (*ProvisionizeRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_551987ef9813264c, []int{3}
+	return fileDescriptor_551987ef9813264c, []int{7}
 }
 
 func (m *ProvisionizeRequest) XXX_Unmarshal(b []byte) error {
@@ -292,77 +681,966 @@ func (m *ProvisionizeRequest) GetVirtualMachine() *VirtualMachine {
 	return nil
 }
 
-func init() {
-	proto.RegisterType((*StatusUpdate)(nil), "proto.StatusUpdate")
-	proto.RegisterType((*IPConfig)(nil), "proto.IPConfig")
-	proto.RegisterType((*VirtualMachine)(nil), "proto.VirtualMachine")
-	proto.RegisterType((*ProvisionizeRequest)(nil), "proto.ProvisionizeRequest")
+func (m *ProvisionizeRequest) GetDeprovisionOptions() *DeprovisionOptions {
+	if m != nil {
+		return m.DeprovisionOptions
+	}
+	return nil
 }
 
-func init() { proto.RegisterFile("provisionize.proto", fileDescriptor_551987ef9813264c) }
-
-var fileDescriptor_551987ef9813264c = []byte{
-	// 439 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xa4, 0x92, 0xcf, 0x6e, 0xd3, 0x40,
-	0x10, 0xc6, 0x71, 0x48, 0x53, 0x7b, 0xe2, 0xa6, 0xd2, 0x54, 0x20, 0x2b, 0x08, 0x29, 0xb8, 0x97,
-	0x9c, 0x2a, 0x14, 0x50, 0x8f, 0x5c, 0x02, 0x87, 0x4a, 0x04, 0x55, 0xae, 0xe0, 0x6a, 0x6d, 0xec,
-	0x89, 0xbb, 0x92, 0xff, 0x6c, 0x77, 0xd7, 0x86, 0xf2, 0x02, 0x1c, 0x78, 0x0a, 0xde, 0x14, 0x79,
-	0x77, 0x1d, 0x25, 0x12, 0x9c, 0x7a, 0xca, 0x7e, 0xdf, 0xb7, 0xd9, 0x99, 0xf9, 0x79, 0x00, 0x85,
-	0x6c, 0x3a, 0xae, 0x78, 0x53, 0xf3, 0x9f, 0x74, 0x25, 0x64, 0xa3, 0x1b, 0x3c, 0x31, 0x3f, 0xf1,
-	0x2f, 0x0f, 0xc2, 0x3b, 0xcd, 0x74, 0xab, 0xbe, 0x8a, 0x9c, 0x69, 0xc2, 0x37, 0x10, 0x2a, 0x92,
-	0x1d, 0xcf, 0x28, 0xad, 0x59, 0x45, 0x91, 0xb7, 0xf0, 0x96, 0x41, 0x32, 0x75, 0xde, 0x17, 0x56,
-	0x11, 0x46, 0x70, 0x5a, 0x91, 0x52, 0xac, 0xa0, 0x68, 0x64, 0xd2, 0x41, 0x62, 0x0c, 0x61, 0x4e,
-	0xdb, 0xb6, 0xd8, 0xb8, 0xf8, 0xb9, 0x89, 0x8f, 0x3c, 0x7c, 0x09, 0x93, 0x1d, 0xe3, 0x25, 0xe5,
-	0xd1, 0x78, 0xe1, 0x2d, 0xfd, 0xc4, 0xa9, 0x38, 0x03, 0xff, 0xe6, 0x76, 0xdd, 0xd4, 0x3b, 0x5e,
-	0xf4, 0x15, 0x58, 0x9e, 0x4b, 0x52, 0xca, 0xd5, 0x1f, 0x24, 0x5e, 0xc2, 0x99, 0x90, 0xb4, 0xe3,
-	0x3f, 0xd2, 0x92, 0xea, 0x42, 0xdf, 0x9b, 0x0e, 0xce, 0x92, 0xd0, 0x9a, 0x9f, 0x8d, 0xd7, 0xff,
-	0xbd, 0x60, 0x9a, 0xbe, 0xb3, 0x47, 0xd7, 0xc1, 0x20, 0xe3, 0xdf, 0x23, 0x98, 0x7d, 0xe3, 0x52,
-	0xb7, 0xac, 0xdc, 0xb0, 0xec, 0x9e, 0xd7, 0x84, 0x33, 0x18, 0xf1, 0xdc, 0x95, 0x19, 0xf1, 0x1c,
-	0xe7, 0xe0, 0x6b, 0xaa, 0x44, 0xc9, 0xf4, 0x30, 0xde, 0x5e, 0x23, 0xc2, 0xd8, 0x40, 0xb1, 0xaf,
-	0x9a, 0x73, 0xef, 0xed, 0x1e, 0xf2, 0xda, 0x4c, 0x13, 0x24, 0xe6, 0xdc, 0x43, 0xcc, 0xca, 0x56,
-	0x69, 0x92, 0x16, 0xe2, 0x89, 0x85, 0xe8, 0x3c, 0x03, 0xf1, 0x15, 0x04, 0x15, 0x55, 0x8d, 0x7c,
-	0x4c, 0xab, 0x6d, 0x34, 0x31, 0x43, 0xf8, 0xd6, 0xd8, 0x6c, 0xfb, 0x30, 0x13, 0x6d, 0x9a, 0x35,
-	0x92, 0x54, 0x74, 0x6a, 0xc3, 0x4c, 0xb4, 0xeb, 0x5e, 0xe3, 0x25, 0x8c, 0xb9, 0xe8, 0xde, 0x47,
-	0xfe, 0xc2, 0x5b, 0x4e, 0x57, 0xe7, 0xf6, 0x7b, 0x5e, 0x0d, 0xec, 0x12, 0x13, 0xba, 0x4b, 0xd7,
-	0x51, 0xf0, 0xff, 0x4b, 0xd7, 0xb1, 0x86, 0x8b, 0xdb, 0x83, 0xcd, 0x48, 0xe8, 0xa1, 0x25, 0xa5,
-	0xf1, 0x35, 0x80, 0xb4, 0xc7, 0x74, 0x4f, 0x26, 0x70, 0xce, 0x4d, 0x8e, 0x1f, 0xe0, 0xbc, 0xb3,
-	0x08, 0xd3, 0xca, 0x32, 0x34, 0x9c, 0xa6, 0xab, 0x17, 0xae, 0xca, 0x31, 0xe0, 0x64, 0xd6, 0x1d,
-	0xe9, 0xd5, 0x1f, 0xef, 0xb8, 0xec, 0x9d, 0x5d, 0x2d, 0x5c, 0x43, 0x78, 0x68, 0xe3, 0xdc, 0x3d,
-	0xf7, 0x8f, 0x16, 0xe7, 0x17, 0x2e, 0x3b, 0x5c, 0xdd, 0xf8, 0xd9, 0x5b, 0x0f, 0x3f, 0xc1, 0xec,
-	0x23, 0x89, 0xa7, 0x3e, 0xb3, 0x9d, 0x18, 0xff, 0xdd, 0xdf, 0x00, 0x00, 0x00, 0xff, 0xff, 0x7e,
-	0xb8, 0x80, 0x65, 0x3a, 0x03, 0x00, 0x00,
+type VMActionRequest struct {
+	RequestId              string          `protobuf:"bytes,1,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`
+	VirtualMachine         *VirtualMachine `protobuf:"bytes,2,opt,name=virtual_machine,json=virtualMachine,proto3" json:"virtual_machine,omitempty"`
+	Force                  bool            `protobuf:"varint,3,opt,name=force,proto3" json:"force,omitempty"`
+	ShutdownTimeoutSeconds uint32          `protobuf:"varint,4,opt,name=shutdown_timeout_seconds,json=shutdownTimeoutSeconds,proto3" json:"shutdown_timeout_seconds,omitempty"`
+	XXX_NoUnkeyedLiteral   struct{}        `json:"-"`
+	XXX_unrecognized       []byte          `json:"-"`
+	XXX_sizecache          int32           `json:"-"`
 }
 
-// Reference imports to suppress errors if they are not otherwise used.
-var _ context.Context
-var _ grpc.ClientConn
-
-// This is a compile-time assertion to ensure that this generated file
-// is compatible with the grpc package it is being compiled against.
-const _ = grpc.SupportPackageIsVersion4
-
-// ProvisionizeServiceClient is the client API for ProvisionizeService service.
-//
-// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
-type ProvisionizeServiceClient interface {
-	Provisionize(ctx context.Context, in *ProvisionizeRequest, opts ...grpc.CallOption) (ProvisionizeService_ProvisionizeClient, error)
-	Deprovisionize(ctx context.Context, in *ProvisionizeRequest, opts ...grpc.CallOption) (ProvisionizeService_DeprovisionizeClient, error)
+func (m *VMActionRequest) Reset()         { *m = VMActionRequest{} }
+func (m *VMActionRequest) String() string { return proto.CompactTextString(m) }
+func (*VMActionRequest) ProtoMessage()    {}
+func (*VMActionRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_551987ef9813264c, []int{8}
 }
 
-type provisionizeServiceClient struct {
-	cc *grpc.ClientConn
+func (m *VMActionRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_VMActionRequest.Unmarshal(m, b)
 }
-
+func (m *VMActionRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_VMActionRequest.Marshal(b, m, deterministic)
+}
+func (m *VMActionRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_VMActionRequest.Merge(m, src)
+}
+func (m *VMActionRequest) XXX_Size() int {
+	return xxx_messageInfo_VMActionRequest.Size(m)
+}
+func (m *VMActionRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_VMActionRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_VMActionRequest proto.InternalMessageInfo
+
+func (m *VMActionRequest) GetRequestId() string {
+	if m != nil {
+		return m.RequestId
+	}
+	return ""
+}
+
+func (m *VMActionRequest) GetVirtualMachine() *VirtualMachine {
+	if m != nil {
+		return m.VirtualMachine
+	}
+	return nil
+}
+
+func (m *VMActionRequest) GetForce() bool {
+	if m != nil {
+		return m.Force
+	}
+	return false
+}
+
+func (m *VMActionRequest) GetShutdownTimeoutSeconds() uint32 {
+	if m != nil {
+		return m.ShutdownTimeoutSeconds
+	}
+	return 0
+}
+
+type DNSRecord struct {
+	Name                 string   `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Type                 string   `protobuf:"bytes,2,opt,name=type,proto3" json:"type,omitempty"`
+	Values               []string `protobuf:"bytes,3,rep,name=values,proto3" json:"values,omitempty"`
+	Ttl                  int64    `protobuf:"varint,4,opt,name=ttl,proto3" json:"ttl,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DNSRecord) Reset()         { *m = DNSRecord{} }
+func (m *DNSRecord) String() string { return proto.CompactTextString(m) }
+func (*DNSRecord) ProtoMessage()    {}
+func (*DNSRecord) Descriptor() ([]byte, []int) {
+	return fileDescriptor_551987ef9813264c, []int{9}
+}
+
+func (m *DNSRecord) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DNSRecord.Unmarshal(m, b)
+}
+func (m *DNSRecord) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DNSRecord.Marshal(b, m, deterministic)
+}
+func (m *DNSRecord) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DNSRecord.Merge(m, src)
+}
+func (m *DNSRecord) XXX_Size() int {
+	return xxx_messageInfo_DNSRecord.Size(m)
+}
+func (m *DNSRecord) XXX_DiscardUnknown() {
+	xxx_messageInfo_DNSRecord.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DNSRecord proto.InternalMessageInfo
+
+func (m *DNSRecord) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *DNSRecord) GetType() string {
+	if m != nil {
+		return m.Type
+	}
+	return ""
+}
+
+func (m *DNSRecord) GetValues() []string {
+	if m != nil {
+		return m.Values
+	}
+	return nil
+}
+
+func (m *DNSRecord) GetTtl() int64 {
+	if m != nil {
+		return m.Ttl
+	}
+	return 0
+}
+
+type TowerJob struct {
+	Id                   uint32   `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name                 string   `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Status               string   `protobuf:"bytes,3,opt,name=status,proto3" json:"status,omitempty"`
+	Finished             string   `protobuf:"bytes,4,opt,name=finished,proto3" json:"finished,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *TowerJob) Reset()         { *m = TowerJob{} }
+func (m *TowerJob) String() string { return proto.CompactTextString(m) }
+func (*TowerJob) ProtoMessage()    {}
+func (*TowerJob) Descriptor() ([]byte, []int) {
+	return fileDescriptor_551987ef9813264c, []int{10}
+}
+
+func (m *TowerJob) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_TowerJob.Unmarshal(m, b)
+}
+func (m *TowerJob) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_TowerJob.Marshal(b, m, deterministic)
+}
+func (m *TowerJob) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_TowerJob.Merge(m, src)
+}
+func (m *TowerJob) XXX_Size() int {
+	return xxx_messageInfo_TowerJob.Size(m)
+}
+func (m *TowerJob) XXX_DiscardUnknown() {
+	xxx_messageInfo_TowerJob.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_TowerJob proto.InternalMessageInfo
+
+func (m *TowerJob) GetId() uint32 {
+	if m != nil {
+		return m.Id
+	}
+	return 0
+}
+
+func (m *TowerJob) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *TowerJob) GetStatus() string {
+	if m != nil {
+		return m.Status
+	}
+	return ""
+}
+
+func (m *TowerJob) GetFinished() string {
+	if m != nil {
+		return m.Finished
+	}
+	return ""
+}
+
+type BackendError struct {
+	ServiceName          string   `protobuf:"bytes,1,opt,name=service_name,json=serviceName,proto3" json:"service_name,omitempty"`
+	Message              string   `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *BackendError) Reset()         { *m = BackendError{} }
+func (m *BackendError) String() string { return proto.CompactTextString(m) }
+func (*BackendError) ProtoMessage()    {}
+func (*BackendError) Descriptor() ([]byte, []int) {
+	return fileDescriptor_551987ef9813264c, []int{11}
+}
+
+func (m *BackendError) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_BackendError.Unmarshal(m, b)
+}
+func (m *BackendError) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_BackendError.Marshal(b, m, deterministic)
+}
+func (m *BackendError) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_BackendError.Merge(m, src)
+}
+func (m *BackendError) XXX_Size() int {
+	return xxx_messageInfo_BackendError.Size(m)
+}
+func (m *BackendError) XXX_DiscardUnknown() {
+	xxx_messageInfo_BackendError.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_BackendError proto.InternalMessageInfo
+
+func (m *BackendError) GetServiceName() string {
+	if m != nil {
+		return m.ServiceName
+	}
+	return ""
+}
+
+func (m *BackendError) GetMessage() string {
+	if m != nil {
+		return m.Message
+	}
+	return ""
+}
+
+type VMState struct {
+	VirtualMachine       *VirtualMachine `protobuf:"bytes,1,opt,name=virtual_machine,json=virtualMachine,proto3" json:"virtual_machine,omitempty"`
+	Status               string          `protobuf:"bytes,2,opt,name=status,proto3" json:"status,omitempty"`
+	DnsRecords           []*DNSRecord    `protobuf:"bytes,3,rep,name=dns_records,json=dnsRecords,proto3" json:"dns_records,omitempty"`
+	TowerJobs            []*TowerJob     `protobuf:"bytes,4,rep,name=tower_jobs,json=towerJobs,proto3" json:"tower_jobs,omitempty"`
+	Errors               []*BackendError `protobuf:"bytes,5,rep,name=errors,proto3" json:"errors,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}        `json:"-"`
+	XXX_unrecognized     []byte          `json:"-"`
+	XXX_sizecache        int32           `json:"-"`
+}
+
+func (m *VMState) Reset()         { *m = VMState{} }
+func (m *VMState) String() string { return proto.CompactTextString(m) }
+func (*VMState) ProtoMessage()    {}
+func (*VMState) Descriptor() ([]byte, []int) {
+	return fileDescriptor_551987ef9813264c, []int{12}
+}
+
+func (m *VMState) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_VMState.Unmarshal(m, b)
+}
+func (m *VMState) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_VMState.Marshal(b, m, deterministic)
+}
+func (m *VMState) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_VMState.Merge(m, src)
+}
+func (m *VMState) XXX_Size() int {
+	return xxx_messageInfo_VMState.Size(m)
+}
+func (m *VMState) XXX_DiscardUnknown() {
+	xxx_messageInfo_VMState.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_VMState proto.InternalMessageInfo
+
+func (m *VMState) GetVirtualMachine() *VirtualMachine {
+	if m != nil {
+		return m.VirtualMachine
+	}
+	return nil
+}
+
+func (m *VMState) GetStatus() string {
+	if m != nil {
+		return m.Status
+	}
+	return ""
+}
+
+func (m *VMState) GetDnsRecords() []*DNSRecord {
+	if m != nil {
+		return m.DnsRecords
+	}
+	return nil
+}
+
+func (m *VMState) GetTowerJobs() []*TowerJob {
+	if m != nil {
+		return m.TowerJobs
+	}
+	return nil
+}
+
+func (m *VMState) GetErrors() []*BackendError {
+	if m != nil {
+		return m.Errors
+	}
+	return nil
+}
+
+type DescribeVMRequest struct {
+	VirtualMachine       *VirtualMachine `protobuf:"bytes,1,opt,name=virtual_machine,json=virtualMachine,proto3" json:"virtual_machine,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}        `json:"-"`
+	XXX_unrecognized     []byte          `json:"-"`
+	XXX_sizecache        int32           `json:"-"`
+}
+
+func (m *DescribeVMRequest) Reset()         { *m = DescribeVMRequest{} }
+func (m *DescribeVMRequest) String() string { return proto.CompactTextString(m) }
+func (*DescribeVMRequest) ProtoMessage()    {}
+func (*DescribeVMRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_551987ef9813264c, []int{13}
+}
+
+func (m *DescribeVMRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DescribeVMRequest.Unmarshal(m, b)
+}
+func (m *DescribeVMRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DescribeVMRequest.Marshal(b, m, deterministic)
+}
+func (m *DescribeVMRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DescribeVMRequest.Merge(m, src)
+}
+func (m *DescribeVMRequest) XXX_Size() int {
+	return xxx_messageInfo_DescribeVMRequest.Size(m)
+}
+func (m *DescribeVMRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_DescribeVMRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DescribeVMRequest proto.InternalMessageInfo
+
+func (m *DescribeVMRequest) GetVirtualMachine() *VirtualMachine {
+	if m != nil {
+		return m.VirtualMachine
+	}
+	return nil
+}
+
+type ListVMsRequest struct {
+	ClusterName          string   `protobuf:"bytes,1,opt,name=cluster_name,json=clusterName,proto3" json:"cluster_name,omitempty"`
+	Template             string   `protobuf:"bytes,2,opt,name=template,proto3" json:"template,omitempty"`
+	Details              bool     `protobuf:"varint,3,opt,name=details,proto3" json:"details,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ListVMsRequest) Reset()         { *m = ListVMsRequest{} }
+func (m *ListVMsRequest) String() string { return proto.CompactTextString(m) }
+func (*ListVMsRequest) ProtoMessage()    {}
+func (*ListVMsRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_551987ef9813264c, []int{14}
+}
+
+func (m *ListVMsRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListVMsRequest.Unmarshal(m, b)
+}
+func (m *ListVMsRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListVMsRequest.Marshal(b, m, deterministic)
+}
+func (m *ListVMsRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListVMsRequest.Merge(m, src)
+}
+func (m *ListVMsRequest) XXX_Size() int {
+	return xxx_messageInfo_ListVMsRequest.Size(m)
+}
+func (m *ListVMsRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListVMsRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListVMsRequest proto.InternalMessageInfo
+
+func (m *ListVMsRequest) GetClusterName() string {
+	if m != nil {
+		return m.ClusterName
+	}
+	return ""
+}
+
+func (m *ListVMsRequest) GetTemplate() string {
+	if m != nil {
+		return m.Template
+	}
+	return ""
+}
+
+func (m *ListVMsRequest) GetDetails() bool {
+	if m != nil {
+		return m.Details
+	}
+	return false
+}
+
+type ListVMsResponse struct {
+	Vms                  []*VMState `protobuf:"bytes,1,rep,name=vms,proto3" json:"vms,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}   `json:"-"`
+	XXX_unrecognized     []byte     `json:"-"`
+	XXX_sizecache        int32      `json:"-"`
+}
+
+func (m *ListVMsResponse) Reset()         { *m = ListVMsResponse{} }
+func (m *ListVMsResponse) String() string { return proto.CompactTextString(m) }
+func (*ListVMsResponse) ProtoMessage()    {}
+func (*ListVMsResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_551987ef9813264c, []int{15}
+}
+
+func (m *ListVMsResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListVMsResponse.Unmarshal(m, b)
+}
+func (m *ListVMsResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListVMsResponse.Marshal(b, m, deterministic)
+}
+func (m *ListVMsResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListVMsResponse.Merge(m, src)
+}
+func (m *ListVMsResponse) XXX_Size() int {
+	return xxx_messageInfo_ListVMsResponse.Size(m)
+}
+func (m *ListVMsResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListVMsResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListVMsResponse proto.InternalMessageInfo
+
+func (m *ListVMsResponse) GetVms() []*VMState {
+	if m != nil {
+		return m.Vms
+	}
+	return nil
+}
+
+type Template struct {
+	Name                                string          `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	OvirtTemplate                       string          `protobuf:"bytes,2,opt,name=ovirt_template,json=ovirtTemplate,proto3" json:"ovirt_template,omitempty"`
+	BootDiskName                        string          `protobuf:"bytes,3,opt,name=boot_disk_name,json=bootDiskName,proto3" json:"boot_disk_name,omitempty"`
+	AnsibleTowerJobTemplates            []uint32        `protobuf:"varint,4,rep,packed,name=ansible_tower_job_templates,json=ansibleTowerJobTemplates,proto3" json:"ansible_tower_job_templates,omitempty"`
+	SkipSteps                           []string        `protobuf:"bytes,5,rep,name=skip_steps,json=skipSteps,proto3" json:"skip_steps,omitempty"`
+	CpuCores                            *ResourceLimits `protobuf:"bytes,6,opt,name=cpu_cores,json=cpuCores,proto3" json:"cpu_cores,omitempty"`
+	MemoryMb                            *ResourceLimits `protobuf:"bytes,7,opt,name=memory_mb,json=memoryMb,proto3" json:"memory_mb,omitempty"`
+	DefaultClusterName                  string          `protobuf:"bytes,8,opt,name=default_cluster_name,json=defaultClusterName,proto3" json:"default_cluster_name,omitempty"`
+	AnsibleTowerWorkflows               []uint32        `protobuf:"varint,9,rep,packed,name=ansible_tower_workflows,json=ansibleTowerWorkflows,proto3" json:"ansible_tower_workflows,omitempty"`
+	AnsibleTowerDeprovisionJobTemplates []uint32        `protobuf:"varint,10,rep,packed,name=ansible_tower_deprovision_job_templates,json=ansibleTowerDeprovisionJobTemplates,proto3" json:"ansible_tower_deprovision_job_templates,omitempty"`
+	XXX_NoUnkeyedLiteral                struct{}        `json:"-"`
+	XXX_unrecognized                    []byte          `json:"-"`
+	XXX_sizecache                       int32           `json:"-"`
+}
+
+func (m *Template) Reset()         { *m = Template{} }
+func (m *Template) String() string { return proto.CompactTextString(m) }
+func (*Template) ProtoMessage()    {}
+func (*Template) Descriptor() ([]byte, []int) {
+	return fileDescriptor_551987ef9813264c, []int{16}
+}
+
+func (m *Template) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Template.Unmarshal(m, b)
+}
+func (m *Template) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Template.Marshal(b, m, deterministic)
+}
+func (m *Template) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Template.Merge(m, src)
+}
+func (m *Template) XXX_Size() int {
+	return xxx_messageInfo_Template.Size(m)
+}
+func (m *Template) XXX_DiscardUnknown() {
+	xxx_messageInfo_Template.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Template proto.InternalMessageInfo
+
+func (m *Template) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *Template) GetOvirtTemplate() string {
+	if m != nil {
+		return m.OvirtTemplate
+	}
+	return ""
+}
+
+func (m *Template) GetBootDiskName() string {
+	if m != nil {
+		return m.BootDiskName
+	}
+	return ""
+}
+
+func (m *Template) GetAnsibleTowerJobTemplates() []uint32 {
+	if m != nil {
+		return m.AnsibleTowerJobTemplates
+	}
+	return nil
+}
+
+func (m *Template) GetSkipSteps() []string {
+	if m != nil {
+		return m.SkipSteps
+	}
+	return nil
+}
+
+func (m *Template) GetCpuCores() *ResourceLimits {
+	if m != nil {
+		return m.CpuCores
+	}
+	return nil
+}
+
+func (m *Template) GetMemoryMb() *ResourceLimits {
+	if m != nil {
+		return m.MemoryMb
+	}
+	return nil
+}
+
+func (m *Template) GetDefaultClusterName() string {
+	if m != nil {
+		return m.DefaultClusterName
+	}
+	return ""
+}
+
+func (m *Template) GetAnsibleTowerWorkflows() []uint32 {
+	if m != nil {
+		return m.AnsibleTowerWorkflows
+	}
+	return nil
+}
+
+func (m *Template) GetAnsibleTowerDeprovisionJobTemplates() []uint32 {
+	if m != nil {
+		return m.AnsibleTowerDeprovisionJobTemplates
+	}
+	return nil
+}
+
+type ResourceLimits struct {
+	Default              uint32   `protobuf:"varint,1,opt,name=default,proto3" json:"default,omitempty"`
+	Min                  uint32   `protobuf:"varint,2,opt,name=min,proto3" json:"min,omitempty"`
+	Max                  uint32   `protobuf:"varint,3,opt,name=max,proto3" json:"max,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ResourceLimits) Reset()         { *m = ResourceLimits{} }
+func (m *ResourceLimits) String() string { return proto.CompactTextString(m) }
+func (*ResourceLimits) ProtoMessage()    {}
+func (*ResourceLimits) Descriptor() ([]byte, []int) {
+	return fileDescriptor_551987ef9813264c, []int{17}
+}
+
+func (m *ResourceLimits) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ResourceLimits.Unmarshal(m, b)
+}
+func (m *ResourceLimits) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ResourceLimits.Marshal(b, m, deterministic)
+}
+func (m *ResourceLimits) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ResourceLimits.Merge(m, src)
+}
+func (m *ResourceLimits) XXX_Size() int {
+	return xxx_messageInfo_ResourceLimits.Size(m)
+}
+func (m *ResourceLimits) XXX_DiscardUnknown() {
+	xxx_messageInfo_ResourceLimits.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ResourceLimits proto.InternalMessageInfo
+
+func (m *ResourceLimits) GetDefault() uint32 {
+	if m != nil {
+		return m.Default
+	}
+	return 0
+}
+
+func (m *ResourceLimits) GetMin() uint32 {
+	if m != nil {
+		return m.Min
+	}
+	return 0
+}
+
+func (m *ResourceLimits) GetMax() uint32 {
+	if m != nil {
+		return m.Max
+	}
+	return 0
+}
+
+type ListTemplatesRequest struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ListTemplatesRequest) Reset()         { *m = ListTemplatesRequest{} }
+func (m *ListTemplatesRequest) String() string { return proto.CompactTextString(m) }
+func (*ListTemplatesRequest) ProtoMessage()    {}
+func (*ListTemplatesRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_551987ef9813264c, []int{18}
+}
+
+func (m *ListTemplatesRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListTemplatesRequest.Unmarshal(m, b)
+}
+func (m *ListTemplatesRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListTemplatesRequest.Marshal(b, m, deterministic)
+}
+func (m *ListTemplatesRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListTemplatesRequest.Merge(m, src)
+}
+func (m *ListTemplatesRequest) XXX_Size() int {
+	return xxx_messageInfo_ListTemplatesRequest.Size(m)
+}
+func (m *ListTemplatesRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListTemplatesRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListTemplatesRequest proto.InternalMessageInfo
+
+type ListTemplatesResponse struct {
+	Templates            []*Template `protobuf:"bytes,1,rep,name=templates,proto3" json:"templates,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}    `json:"-"`
+	XXX_unrecognized     []byte      `json:"-"`
+	XXX_sizecache        int32       `json:"-"`
+}
+
+func (m *ListTemplatesResponse) Reset()         { *m = ListTemplatesResponse{} }
+func (m *ListTemplatesResponse) String() string { return proto.CompactTextString(m) }
+func (*ListTemplatesResponse) ProtoMessage()    {}
+func (*ListTemplatesResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_551987ef9813264c, []int{19}
+}
+
+func (m *ListTemplatesResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListTemplatesResponse.Unmarshal(m, b)
+}
+func (m *ListTemplatesResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListTemplatesResponse.Marshal(b, m, deterministic)
+}
+func (m *ListTemplatesResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListTemplatesResponse.Merge(m, src)
+}
+func (m *ListTemplatesResponse) XXX_Size() int {
+	return xxx_messageInfo_ListTemplatesResponse.Size(m)
+}
+func (m *ListTemplatesResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListTemplatesResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListTemplatesResponse proto.InternalMessageInfo
+
+func (m *ListTemplatesResponse) GetTemplates() []*Template {
+	if m != nil {
+		return m.Templates
+	}
+	return nil
+}
+
+type DescribeTemplateRequest struct {
+	Name                 string   `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DescribeTemplateRequest) Reset()         { *m = DescribeTemplateRequest{} }
+func (m *DescribeTemplateRequest) String() string { return proto.CompactTextString(m) }
+func (*DescribeTemplateRequest) ProtoMessage()    {}
+func (*DescribeTemplateRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_551987ef9813264c, []int{20}
+}
+
+func (m *DescribeTemplateRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DescribeTemplateRequest.Unmarshal(m, b)
+}
+func (m *DescribeTemplateRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DescribeTemplateRequest.Marshal(b, m, deterministic)
+}
+func (m *DescribeTemplateRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DescribeTemplateRequest.Merge(m, src)
+}
+func (m *DescribeTemplateRequest) XXX_Size() int {
+	return xxx_messageInfo_DescribeTemplateRequest.Size(m)
+}
+func (m *DescribeTemplateRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_DescribeTemplateRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DescribeTemplateRequest proto.InternalMessageInfo
+
+func (m *DescribeTemplateRequest) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func init() {
+	proto.RegisterType((*StatusUpdate)(nil), "proto.StatusUpdate")
+	proto.RegisterType((*IPConfig)(nil), "proto.IPConfig")
+	proto.RegisterType((*NetworkInterface)(nil), "proto.NetworkInterface")
+	proto.RegisterType((*Disk)(nil), "proto.Disk")
+	proto.RegisterType((*CloudInit)(nil), "proto.CloudInit")
+	proto.RegisterType((*VirtualMachine)(nil), "proto.VirtualMachine")
+	proto.RegisterMapType((map[string]string)(nil), "proto.VirtualMachine.ExtraVarsEntry")
+	proto.RegisterType((*DeprovisionOptions)(nil), "proto.DeprovisionOptions")
+	proto.RegisterType((*ProvisionizeRequest)(nil), "proto.ProvisionizeRequest")
+	proto.RegisterType((*VMActionRequest)(nil), "proto.VMActionRequest")
+	proto.RegisterType((*DNSRecord)(nil), "proto.DNSRecord")
+	proto.RegisterType((*TowerJob)(nil), "proto.TowerJob")
+	proto.RegisterType((*BackendError)(nil), "proto.BackendError")
+	proto.RegisterType((*VMState)(nil), "proto.VMState")
+	proto.RegisterType((*DescribeVMRequest)(nil), "proto.DescribeVMRequest")
+	proto.RegisterType((*ListVMsRequest)(nil), "proto.ListVMsRequest")
+	proto.RegisterType((*ListVMsResponse)(nil), "proto.ListVMsResponse")
+	proto.RegisterType((*Template)(nil), "proto.Template")
+	proto.RegisterType((*ResourceLimits)(nil), "proto.ResourceLimits")
+	proto.RegisterType((*ListTemplatesRequest)(nil), "proto.ListTemplatesRequest")
+	proto.RegisterType((*ListTemplatesResponse)(nil), "proto.ListTemplatesResponse")
+	proto.RegisterType((*DescribeTemplateRequest)(nil), "proto.DescribeTemplateRequest")
+}
+
+func init() { proto.RegisterFile("provisionize.proto", fileDescriptor_551987ef9813264c) }
+
+var fileDescriptor_551987ef9813264c = []byte{
+	// 1654 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xbc, 0x57, 0x5f, 0x73, 0x23, 0x47,
+	0x11, 0x3f, 0x59, 0xb6, 0xa4, 0x6d, 0x59, 0xb2, 0x33, 0xbe, 0xf3, 0x2d, 0xba, 0x00, 0xce, 0x5e,
+	0x52, 0xb9, 0x2a, 0x2a, 0x26, 0x28, 0xd4, 0x71, 0x04, 0x48, 0xd5, 0x61, 0x1b, 0xea, 0x92, 0xf3,
+	0x71, 0x35, 0x32, 0xe6, 0x89, 0xda, 0x1a, 0xed, 0x8e, 0xac, 0xc1, 0xd2, 0xce, 0x66, 0x66, 0x56,
+	0xfe, 0xf3, 0xc4, 0x23, 0x5f, 0x83, 0x77, 0x3e, 0x04, 0xcf, 0x7c, 0x03, 0x1e, 0xf8, 0x10, 0xf9,
+	0x06, 0xd4, 0xfc, 0x5b, 0xed, 0xea, 0x14, 0x03, 0x4e, 0x15, 0x4f, 0x9a, 0xfe, 0x75, 0xcf, 0xce,
+	0x74, 0xf7, 0xaf, 0xbb, 0x47, 0x80, 0x72, 0xc1, 0x17, 0x4c, 0x32, 0x9e, 0xb1, 0x5b, 0x7a, 0x98,
+	0x0b, 0xae, 0x38, 0xda, 0x32, 0x3f, 0xd1, 0x5f, 0x1b, 0xb0, 0x3d, 0x52, 0x44, 0x15, 0xf2, 0xf7,
+	0x79, 0x4a, 0x14, 0x45, 0x1f, 0xc0, 0xb6, 0xa4, 0x62, 0xc1, 0x12, 0x1a, 0x67, 0x64, 0x4e, 0xc3,
+	0xc6, 0x41, 0xe3, 0x59, 0x80, 0xbb, 0x0e, 0x7b, 0x43, 0xe6, 0x14, 0x85, 0xd0, 0x9e, 0x53, 0x29,
+	0xc9, 0x05, 0x0d, 0x37, 0x8c, 0xd6, 0x8b, 0x28, 0x82, 0xed, 0x94, 0x8e, 0x8b, 0x8b, 0x53, 0xa7,
+	0x6e, 0x1a, 0x75, 0x0d, 0x43, 0xfb, 0xd0, 0x9a, 0x10, 0x36, 0xa3, 0x69, 0xb8, 0x79, 0xd0, 0x78,
+	0xd6, 0xc1, 0x4e, 0xd2, 0x5f, 0xbd, 0x22, 0x22, 0x63, 0xd9, 0x45, 0xb8, 0x65, 0x14, 0x5e, 0x8c,
+	0x12, 0xe8, 0xbc, 0x7a, 0x7b, 0xc4, 0xb3, 0x09, 0xbb, 0xd0, 0x56, 0x24, 0x4d, 0x05, 0x95, 0xd2,
+	0xdd, 0xcc, 0x8b, 0xe8, 0x29, 0xf4, 0x72, 0x41, 0x27, 0xec, 0x3a, 0x9e, 0xd1, 0xec, 0x42, 0x4d,
+	0xcd, 0xdd, 0x7a, 0x78, 0xdb, 0x82, 0xaf, 0x0d, 0xa6, 0xb7, 0x5f, 0x10, 0x45, 0xaf, 0xc8, 0x8d,
+	0xbb, 0x9b, 0x17, 0xa3, 0x7f, 0x36, 0x60, 0xf7, 0x0d, 0x55, 0x57, 0x5c, 0x5c, 0xbe, 0xca, 0x14,
+	0x15, 0x13, 0x92, 0x50, 0x84, 0x60, 0xb3, 0x12, 0x04, 0xb3, 0xd6, 0x01, 0x5a, 0x64, 0x2c, 0x89,
+	0x73, 0xc1, 0x27, 0x6c, 0xe6, 0x43, 0xd0, 0xd5, 0xd8, 0x5b, 0x0b, 0xe9, 0x53, 0x32, 0xfb, 0x29,
+	0x7f, 0x8a, 0x13, 0xd1, 0x2e, 0x34, 0xe7, 0x24, 0x31, 0x9e, 0x07, 0x58, 0x2f, 0xd1, 0x53, 0xd8,
+	0x64, 0xf9, 0xe2, 0xa7, 0xc6, 0xe7, 0xee, 0x70, 0xc7, 0x66, 0xe7, 0xd0, 0xfb, 0x8b, 0x8d, 0xd2,
+	0x19, 0x3d, 0x0f, 0x5b, 0xdf, 0x6e, 0xf4, 0x5c, 0x9f, 0x9a, 0x0b, 0x36, 0x27, 0xe2, 0x26, 0x6c,
+	0xdb, 0x00, 0x3a, 0x31, 0xfa, 0x5b, 0x03, 0x36, 0x8f, 0x99, 0xbc, 0x5c, 0xeb, 0xcf, 0x63, 0x68,
+	0x4b, 0x76, 0x4b, 0xe3, 0x8b, 0xb1, 0x71, 0x65, 0x13, 0xb7, 0xb4, 0xf8, 0xdb, 0x31, 0xfa, 0x08,
+	0xfa, 0x52, 0x71, 0x41, 0x2e, 0x68, 0x9c, 0xf2, 0x39, 0x61, 0x99, 0x73, 0xa6, 0xe7, 0xd0, 0x63,
+	0x03, 0xa2, 0xf7, 0x21, 0x60, 0x3e, 0x60, 0xce, 0xb1, 0x25, 0x60, 0xb2, 0xcd, 0xc5, 0x9c, 0x28,
+	0xe3, 0x60, 0x80, 0x9d, 0x84, 0x06, 0xd0, 0x19, 0x73, 0xae, 0xc8, 0x78, 0x46, 0x8d, 0x57, 0x1d,
+	0x5c, 0xca, 0xd1, 0x3f, 0x36, 0x20, 0x38, 0x9a, 0xf1, 0x22, 0x7d, 0x95, 0x31, 0x85, 0x0e, 0x61,
+	0x8f, 0x14, 0x6a, 0xca, 0x05, 0xbb, 0xa5, 0x69, 0x2c, 0xe5, 0x34, 0xbe, 0xa4, 0x37, 0x3a, 0xfb,
+	0xcd, 0x67, 0x01, 0x7e, 0x6f, 0xa9, 0x1a, 0xc9, 0xe9, 0x57, 0xf4, 0x46, 0xa2, 0x27, 0x10, 0x14,
+	0x92, 0x0a, 0xcb, 0x5e, 0x9b, 0x9c, 0x8e, 0x06, 0x0c, 0x75, 0x07, 0xd0, 0xc9, 0x89, 0x94, 0x57,
+	0x5c, 0xa4, 0xce, 0x9b, 0x52, 0x46, 0x43, 0x78, 0x94, 0x32, 0xa9, 0x6f, 0x10, 0x7b, 0x2c, 0xd6,
+	0x9f, 0x77, 0x3c, 0xdd, 0x73, 0xca, 0xb7, 0x4e, 0xf7, 0xb2, 0x50, 0x53, 0xf4, 0x31, 0xec, 0xd0,
+	0xeb, 0x9c, 0x89, 0xe5, 0x16, 0x47, 0xde, 0xbe, 0x85, 0xbd, 0xb1, 0x3e, 0x58, 0xb1, 0x39, 0xbd,
+	0xe5, 0x99, 0xf5, 0x37, 0xc0, 0xa5, 0x8c, 0x7e, 0x08, 0xdd, 0x34, 0x93, 0xb1, 0x2e, 0x31, 0x2a,
+	0x64, 0xd8, 0x36, 0x9e, 0x41, 0x9a, 0xc9, 0x91, 0x45, 0xd0, 0xf7, 0x01, 0xac, 0x01, 0x11, 0xc9,
+	0x34, 0xec, 0x18, 0x7d, 0x60, 0xf4, 0x1a, 0x28, 0x3d, 0x4e, 0x89, 0x22, 0x61, 0xb0, 0xf4, 0xf8,
+	0x98, 0x28, 0x12, 0xfd, 0x65, 0x0b, 0xfa, 0xe7, 0x4c, 0xa8, 0x82, 0xcc, 0x4e, 0x49, 0x32, 0x65,
+	0x19, 0x45, 0x7d, 0xd8, 0x60, 0xa9, 0xe3, 0xc0, 0x06, 0xb3, 0x77, 0xa3, 0xf3, 0x7c, 0x46, 0x54,
+	0x19, 0x30, 0x2f, 0x97, 0x8c, 0x69, 0x56, 0x18, 0x83, 0x60, 0x73, 0xf2, 0x75, 0x9a, 0xb9, 0x64,
+	0x9b, 0xb5, 0xae, 0x8a, 0x64, 0x56, 0x48, 0xe5, 0x03, 0x6f, 0xb3, 0xdd, 0x75, 0x98, 0x89, 0xfd,
+	0x13, 0x08, 0xe6, 0x74, 0xce, 0xc5, 0x4d, 0x3c, 0x1f, 0x9b, 0x18, 0xf4, 0x70, 0xc7, 0x02, 0xa7,
+	0x63, 0xad, 0x4c, 0xf2, 0x22, 0x4e, 0xb8, 0xa0, 0xd2, 0xd0, 0xb7, 0x87, 0x3b, 0x49, 0x5e, 0x1c,
+	0x69, 0xb9, 0xac, 0x91, 0xce, 0x7f, 0x53, 0x23, 0xc1, 0x5d, 0x35, 0xf2, 0x33, 0x80, 0x92, 0x9b,
+	0x32, 0x84, 0x83, 0xe6, 0xb3, 0xee, 0xf0, 0xb1, 0x33, 0x5d, 0xad, 0x7e, 0x5c, 0x31, 0x45, 0x1f,
+	0xc0, 0x56, 0xca, 0xe4, 0xa5, 0x0c, 0xbb, 0x66, 0x4f, 0xd7, 0xed, 0xd1, 0x55, 0x85, 0xad, 0x06,
+	0xfd, 0x18, 0x20, 0xd1, 0xac, 0x8d, 0x59, 0xc6, 0x54, 0xb8, 0x6d, 0xae, 0xb1, 0xeb, 0xec, 0x4a,
+	0x3a, 0xe3, 0x20, 0xf1, 0x4b, 0x74, 0x04, 0x40, 0xaf, 0x95, 0x20, 0xf1, 0x82, 0x08, 0x19, 0xf6,
+	0xcc, 0x87, 0x3f, 0x74, 0x1b, 0xea, 0x29, 0x3b, 0x3c, 0xd1, 0x76, 0xe7, 0x44, 0xc8, 0x93, 0x4c,
+	0x89, 0x1b, 0x1c, 0x50, 0x2f, 0xeb, 0xf2, 0xd5, 0xdc, 0x50, 0x6a, 0x16, 0xf6, 0x4d, 0xd8, 0x5a,
+	0x69, 0x26, 0xcf, 0xd4, 0xcc, 0xb3, 0x8a, 0xcc, 0x18, 0x91, 0x54, 0x86, 0x3b, 0x25, 0xab, 0x5e,
+	0x5a, 0x04, 0xfd, 0xc4, 0x1a, 0x08, 0x9a, 0x70, 0x91, 0xca, 0x70, 0xf7, 0xa0, 0x59, 0xb9, 0xf0,
+	0xf1, 0x9b, 0x11, 0x36, 0x0a, 0xb3, 0xc5, 0x2e, 0xe5, 0xe0, 0x97, 0xd0, 0xaf, 0xdf, 0x44, 0x37,
+	0xb4, 0x4b, 0x7a, 0xe3, 0xc8, 0xa4, 0x97, 0xe8, 0x21, 0x6c, 0x2d, 0xc8, 0xac, 0xf0, 0x54, 0xb2,
+	0xc2, 0xe7, 0x1b, 0x2f, 0x1a, 0xd1, 0x9f, 0x1b, 0x80, 0x8e, 0x69, 0x39, 0x8b, 0x7e, 0x97, 0x2b,
+	0xc6, 0x33, 0xa9, 0xe9, 0x27, 0xa7, 0x85, 0x4a, 0xf9, 0x55, 0x66, 0xbe, 0xd3, 0xc1, 0xa5, 0x8c,
+	0x5e, 0x40, 0xe8, 0xd7, 0xb1, 0xae, 0x17, 0x5e, 0xa8, 0x58, 0xd2, 0x84, 0x67, 0xa9, 0x74, 0xfd,
+	0x7d, 0xdf, 0xeb, 0xcf, 0xac, 0x7a, 0x64, 0xb5, 0xfa, 0x1a, 0x13, 0x2e, 0x12, 0xcb, 0xdc, 0x0e,
+	0xb6, 0x42, 0xf4, 0xf7, 0x06, 0xec, 0xbd, 0xad, 0x0c, 0x43, 0x4c, 0xbf, 0x2e, 0xa8, 0x54, 0xba,
+	0xc2, 0x84, 0x5d, 0xc6, 0x65, 0x69, 0x04, 0x0e, 0x79, 0x95, 0xa2, 0x2f, 0x60, 0x67, 0x61, 0x13,
+	0x12, 0xcf, 0x6d, 0x46, 0xcc, 0xe9, 0xdd, 0xe1, 0xa3, 0xb5, 0xe9, 0xc2, 0xfd, 0x45, 0x4d, 0x46,
+	0x5f, 0xc2, 0x5e, 0xba, 0x74, 0x3c, 0xe6, 0xd6, 0x73, 0x73, 0xb5, 0xee, 0xf0, 0x7b, 0x3e, 0xe4,
+	0xef, 0x84, 0x06, 0xa3, 0xf4, 0x1d, 0x4c, 0xbb, 0xb0, 0x73, 0x7e, 0xfa, 0x32, 0xd1, 0xd2, 0xff,
+	0xe9, 0xfa, 0x6b, 0x63, 0x79, 0x67, 0x6e, 0x36, 0xef, 0xca, 0x4d, 0xf4, 0x47, 0x08, 0x4a, 0x7e,
+	0xad, 0x9d, 0x49, 0x08, 0x36, 0xd5, 0x4d, 0xee, 0x29, 0x64, 0xd6, 0x7a, 0x92, 0x18, 0x2a, 0xe9,
+	0xb0, 0x69, 0x2a, 0x3b, 0x49, 0x33, 0x50, 0x93, 0x5f, 0x9f, 0xd8, 0xc4, 0x7a, 0x19, 0x8d, 0xa1,
+	0x73, 0xc6, 0xaf, 0xa8, 0xf8, 0x92, 0x8f, 0x2b, 0xbd, 0xae, 0x67, 0x7a, 0x9d, 0x3f, 0x6d, 0xa3,
+	0x72, 0xda, 0x3e, 0xb4, 0xa4, 0x79, 0x02, 0xb9, 0x2e, 0xe7, 0x24, 0x4d, 0xcc, 0x09, 0xcb, 0x98,
+	0x9c, 0xba, 0xb7, 0x4a, 0x80, 0x4b, 0x39, 0xfa, 0x0a, 0xb6, 0x7f, 0x4d, 0x92, 0x4b, 0x9a, 0xa5,
+	0x27, 0x42, 0x70, 0xf1, 0x9d, 0x9e, 0x4d, 0xd1, 0x37, 0x0d, 0x68, 0x9f, 0x9f, 0xea, 0x67, 0x18,
+	0x5d, 0x97, 0xab, 0xc6, 0xff, 0x92, 0xab, 0xa5, 0x33, 0x1b, 0x35, 0x67, 0x56, 0xaa, 0xbd, 0xf9,
+	0x9f, 0xab, 0x1d, 0x1d, 0x02, 0x28, 0x1d, 0xc7, 0xf8, 0x4f, 0x7c, 0xac, 0x53, 0xda, 0xac, 0xf4,
+	0x55, 0x1f, 0x60, 0x1c, 0x28, 0xb7, 0x92, 0xe8, 0x47, 0xd0, 0xa2, 0x3a, 0x18, 0x32, 0xdc, 0x32,
+	0xb6, 0x7b, 0xce, 0xb6, 0x1a, 0x28, 0xec, 0x4c, 0xa2, 0x11, 0xbc, 0x77, 0x4c, 0x65, 0x22, 0xd8,
+	0x98, 0x9e, 0x9f, 0x7a, 0x1e, 0x7f, 0x47, 0xe7, 0x23, 0x06, 0xfd, 0xd7, 0x4c, 0xaa, 0xf3, 0x53,
+	0xe9, 0xbf, 0xb8, 0x3a, 0x97, 0x1a, 0xef, 0xce, 0xa5, 0xbb, 0xc6, 0x5f, 0x08, 0xed, 0x94, 0x2a,
+	0xc2, 0x66, 0xd2, 0x71, 0xdf, 0x8b, 0xd1, 0x67, 0xb0, 0x53, 0x1e, 0x25, 0x73, 0x9e, 0x49, 0x8a,
+	0x0e, 0xa0, 0xb9, 0x98, 0xdb, 0x97, 0x49, 0x77, 0xd8, 0xf7, 0x37, 0xb6, 0x79, 0xc5, 0x5a, 0x15,
+	0x7d, 0xd3, 0x84, 0xce, 0xd9, 0xea, 0x68, 0xad, 0x12, 0xff, 0x23, 0xe8, 0x73, 0xed, 0x53, 0xbc,
+	0x72, 0xa3, 0x9e, 0x41, 0xcb, 0xad, 0x1f, 0x42, 0x5f, 0xbf, 0x96, 0x62, 0x3d, 0x78, 0xe2, 0xca,
+	0x7c, 0xde, 0xd6, 0xa8, 0x9e, 0x49, 0xc6, 0xb1, 0x5f, 0xc1, 0x13, 0x92, 0x49, 0xa6, 0x1f, 0x34,
+	0x65, 0x1e, 0xcb, 0x0f, 0xdb, 0x84, 0xf6, 0x70, 0xe8, 0x4c, 0x7c, 0x42, 0xfd, 0x19, 0xe6, 0xd5,
+	0x21, 0x2f, 0x59, 0x1e, 0x4b, 0x45, 0x73, 0x9b, 0xd2, 0x00, 0x07, 0x1a, 0x19, 0x69, 0x00, 0x0d,
+	0xab, 0x13, 0xbb, 0x55, 0xcb, 0x12, 0xa6, 0x92, 0x17, 0x22, 0xa1, 0xaf, 0xd9, 0x9c, 0x29, 0x59,
+	0x19, 0xe4, 0xc3, 0xea, 0x13, 0xa0, 0x7d, 0xe7, 0x9e, 0xf2, 0x65, 0xf0, 0x29, 0x3c, 0x4c, 0xe9,
+	0x84, 0x14, 0x33, 0x15, 0xd7, 0x32, 0xd9, 0x31, 0x1e, 0x23, 0xa7, 0x3b, 0xaa, 0x24, 0xf4, 0x39,
+	0x3c, 0xae, 0xfb, 0xad, 0xc7, 0xfa, 0x64, 0xc6, 0xaf, 0x64, 0x18, 0x18, 0x9f, 0x1f, 0x55, 0x7d,
+	0xfe, 0x83, 0x57, 0xa2, 0x33, 0xf8, 0xb8, 0xbe, 0xaf, 0xda, 0xb3, 0xeb, 0xb1, 0x03, 0xf3, 0x9d,
+	0xa7, 0xd5, 0xef, 0x54, 0xda, 0x77, 0x35, 0x8c, 0xd1, 0x1b, 0xe8, 0xd7, 0x7d, 0xb3, 0xa4, 0x32,
+	0xb7, 0x76, 0x8d, 0xc9, 0x8b, 0xe6, 0xef, 0x01, 0xcb, 0xdc, 0x64, 0xd3, 0x4b, 0x83, 0x90, 0xeb,
+	0xb0, 0xe9, 0x10, 0x72, 0x1d, 0xed, 0xc3, 0x43, 0x4d, 0xbc, 0xf2, 0x00, 0xc7, 0xf4, 0xe8, 0x37,
+	0xf0, 0x68, 0x05, 0x77, 0xb4, 0xfc, 0x04, 0x82, 0xe5, 0xc5, 0x1b, 0xf5, 0x2a, 0x76, 0x38, 0x5e,
+	0x5a, 0x44, 0x9f, 0xc0, 0x63, 0x5f, 0x98, 0xa5, 0xda, 0x15, 0xd3, 0x1a, 0xc6, 0x0e, 0xff, 0xb5,
+	0x55, 0x9f, 0xa8, 0x23, 0xdb, 0xf1, 0xd0, 0x11, 0x6c, 0x57, 0x61, 0x34, 0x70, 0x47, 0xae, 0x99,
+	0xbe, 0x03, 0xdf, 0x28, 0xaa, 0x7f, 0x44, 0xa3, 0x07, 0x9f, 0x36, 0xd0, 0x09, 0xf4, 0x2b, 0x61,
+	0xbd, 0xf7, 0x67, 0xbe, 0x80, 0x36, 0xa6, 0x52, 0x71, 0x71, 0xcf, 0xfd, 0x9f, 0x43, 0x7b, 0xa4,
+	0x88, 0x50, 0xe7, 0xa7, 0x68, 0xbf, 0x2c, 0xeb, 0xda, 0x04, 0xfe, 0xf6, 0xbd, 0x3f, 0x87, 0xd6,
+	0x48, 0xf1, 0xfc, 0x3e, 0x5b, 0x7f, 0x01, 0x1d, 0x4c, 0x75, 0x45, 0xdf, 0x7b, 0xb3, 0xfe, 0x27,
+	0x77, 0x9f, 0xcd, 0x2f, 0x00, 0x96, 0xcd, 0x19, 0x85, 0xe5, 0x03, 0x65, 0xa5, 0x5f, 0x0f, 0x56,
+	0x9a, 0x5c, 0xf4, 0x40, 0x87, 0xca, 0xb5, 0x45, 0xe4, 0x2b, 0xbb, 0xde, 0x91, 0x07, 0xfb, 0xab,
+	0xb0, 0xa5, 0x69, 0xf4, 0x00, 0xbd, 0x86, 0x5e, 0x8d, 0xc1, 0xe8, 0x49, 0xc5, 0x74, 0x95, 0xef,
+	0x83, 0xf7, 0xd7, 0x2b, 0xcb, 0xaf, 0x9d, 0xc0, 0xee, 0x2a, 0x8f, 0xd1, 0x0f, 0x56, 0x3c, 0x59,
+	0x21, 0xf8, 0x60, 0xb5, 0x2e, 0xa2, 0x07, 0xe3, 0x96, 0x41, 0x3e, 0xfb, 0xf7, 0x00, 0xa7, 0x86,
+	0x40, 0x36, 0x44, 0x11, 0x00, 0x00,
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConn
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion4
+
+// ProvisionizeServiceClient is the client API for ProvisionizeService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type ProvisionizeServiceClient interface {
+	Provisionize(ctx context.Context, in *ProvisionizeRequest, opts ...grpc.CallOption) (ProvisionizeService_ProvisionizeClient, error)
+	Deprovisionize(ctx context.Context, in *ProvisionizeRequest, opts ...grpc.CallOption) (ProvisionizeService_DeprovisionizeClient, error)
+	Restore(ctx context.Context, in *ProvisionizeRequest, opts ...grpc.CallOption) (ProvisionizeService_RestoreClient, error)
+	StartVM(ctx context.Context, in *VMActionRequest, opts ...grpc.CallOption) (ProvisionizeService_StartVMClient, error)
+	StopVM(ctx context.Context, in *VMActionRequest, opts ...grpc.CallOption) (ProvisionizeService_StopVMClient, error)
+	RebootVM(ctx context.Context, in *VMActionRequest, opts ...grpc.CallOption) (ProvisionizeService_RebootVMClient, error)
+	ResizeVM(ctx context.Context, in *VMActionRequest, opts ...grpc.CallOption) (ProvisionizeService_ResizeVMClient, error)
+	DescribeVM(ctx context.Context, in *DescribeVMRequest, opts ...grpc.CallOption) (*VMState, error)
+	ListVMs(ctx context.Context, in *ListVMsRequest, opts ...grpc.CallOption) (*ListVMsResponse, error)
+	ListTemplates(ctx context.Context, in *ListTemplatesRequest, opts ...grpc.CallOption) (*ListTemplatesResponse, error)
+	DescribeTemplate(ctx context.Context, in *DescribeTemplateRequest, opts ...grpc.CallOption) (*Template, error)
+}
+
+type provisionizeServiceClient struct {
+	cc *grpc.ClientConn
+}
+
 func NewProvisionizeServiceClient(cc *grpc.ClientConn) ProvisionizeServiceClient {
 	return &provisionizeServiceClient{cc}
 }
 
-func (c *provisionizeServiceClient) Provisionize(ctx context.Context, in *ProvisionizeRequest, opts ...grpc.CallOption) (ProvisionizeService_ProvisionizeClient, error) {
-	stream, err := c.cc.NewStream(ctx, &_ProvisionizeService_serviceDesc.Streams[0], "/proto.ProvisionizeService/Provisionize", opts...)
+func (c *provisionizeServiceClient) Provisionize(ctx context.Context, in *ProvisionizeRequest, opts ...grpc.CallOption) (ProvisionizeService_ProvisionizeClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_ProvisionizeService_serviceDesc.Streams[0], "/proto.ProvisionizeService/Provisionize", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &provisionizeServiceProvisionizeClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type ProvisionizeService_ProvisionizeClient interface {
+	Recv() (*StatusUpdate, error)
+	grpc.ClientStream
+}
+
+type provisionizeServiceProvisionizeClient struct {
+	grpc.ClientStream
+}
+
+func (x *provisionizeServiceProvisionizeClient) Recv() (*StatusUpdate, error) {
+	m := new(StatusUpdate)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *provisionizeServiceClient) Deprovisionize(ctx context.Context, in *ProvisionizeRequest, opts ...grpc.CallOption) (ProvisionizeService_DeprovisionizeClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_ProvisionizeService_serviceDesc.Streams[1], "/proto.ProvisionizeService/Deprovisionize", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &provisionizeServiceDeprovisionizeClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type ProvisionizeService_DeprovisionizeClient interface {
+	Recv() (*StatusUpdate, error)
+	grpc.ClientStream
+}
+
+type provisionizeServiceDeprovisionizeClient struct {
+	grpc.ClientStream
+}
+
+func (x *provisionizeServiceDeprovisionizeClient) Recv() (*StatusUpdate, error) {
+	m := new(StatusUpdate)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *provisionizeServiceClient) Restore(ctx context.Context, in *ProvisionizeRequest, opts ...grpc.CallOption) (ProvisionizeService_RestoreClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_ProvisionizeService_serviceDesc.Streams[2], "/proto.ProvisionizeService/Restore", opts...)
 	if err != nil {
 		return nil, err
 	}
-	x := &provisionizeServiceProvisionizeClient{stream}
+	x := &provisionizeServiceRestoreClient{stream}
 	if err := x.ClientStream.SendMsg(in); err != nil {
 		return nil, err
 	}
@@ -372,16 +1650,16 @@ func (c *provisionizeServiceClient) Provisionize(ctx context.Context, in *Provis
 	return x, nil
 }
 
-type ProvisionizeService_ProvisionizeClient interface {
+type ProvisionizeService_RestoreClient interface {
 	Recv() (*StatusUpdate, error)
 	grpc.ClientStream
 }
 
-type provisionizeServiceProvisionizeClient struct {
+type provisionizeServiceRestoreClient struct {
 	grpc.ClientStream
 }
 
-func (x *provisionizeServiceProvisionizeClient) Recv() (*StatusUpdate, error) {
+func (x *provisionizeServiceRestoreClient) Recv() (*StatusUpdate, error) {
 	m := new(StatusUpdate)
 	if err := x.ClientStream.RecvMsg(m); err != nil {
 		return nil, err
@@ -389,12 +1667,12 @@ func (x *provisionizeServiceProvisionizeClient) Recv() (*StatusUpdate, error) {
 	return m, nil
 }
 
-func (c *provisionizeServiceClient) Deprovisionize(ctx context.Context, in *ProvisionizeRequest, opts ...grpc.CallOption) (ProvisionizeService_DeprovisionizeClient, error) {
-	stream, err := c.cc.NewStream(ctx, &_ProvisionizeService_serviceDesc.Streams[1], "/proto.ProvisionizeService/Deprovisionize", opts...)
+func (c *provisionizeServiceClient) StartVM(ctx context.Context, in *VMActionRequest, opts ...grpc.CallOption) (ProvisionizeService_StartVMClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_ProvisionizeService_serviceDesc.Streams[3], "/proto.ProvisionizeService/StartVM", opts...)
 	if err != nil {
 		return nil, err
 	}
-	x := &provisionizeServiceDeprovisionizeClient{stream}
+	x := &provisionizeServiceStartVMClient{stream}
 	if err := x.ClientStream.SendMsg(in); err != nil {
 		return nil, err
 	}
@@ -404,16 +1682,112 @@ func (c *provisionizeServiceClient) Deprovisionize(ctx context.Context, in *Prov
 	return x, nil
 }
 
-type ProvisionizeService_DeprovisionizeClient interface {
+type ProvisionizeService_StartVMClient interface {
 	Recv() (*StatusUpdate, error)
 	grpc.ClientStream
 }
 
-type provisionizeServiceDeprovisionizeClient struct {
+type provisionizeServiceStartVMClient struct {
 	grpc.ClientStream
 }
 
-func (x *provisionizeServiceDeprovisionizeClient) Recv() (*StatusUpdate, error) {
+func (x *provisionizeServiceStartVMClient) Recv() (*StatusUpdate, error) {
+	m := new(StatusUpdate)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *provisionizeServiceClient) StopVM(ctx context.Context, in *VMActionRequest, opts ...grpc.CallOption) (ProvisionizeService_StopVMClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_ProvisionizeService_serviceDesc.Streams[4], "/proto.ProvisionizeService/StopVM", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &provisionizeServiceStopVMClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type ProvisionizeService_StopVMClient interface {
+	Recv() (*StatusUpdate, error)
+	grpc.ClientStream
+}
+
+type provisionizeServiceStopVMClient struct {
+	grpc.ClientStream
+}
+
+func (x *provisionizeServiceStopVMClient) Recv() (*StatusUpdate, error) {
+	m := new(StatusUpdate)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *provisionizeServiceClient) RebootVM(ctx context.Context, in *VMActionRequest, opts ...grpc.CallOption) (ProvisionizeService_RebootVMClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_ProvisionizeService_serviceDesc.Streams[5], "/proto.ProvisionizeService/RebootVM", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &provisionizeServiceRebootVMClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type ProvisionizeService_RebootVMClient interface {
+	Recv() (*StatusUpdate, error)
+	grpc.ClientStream
+}
+
+type provisionizeServiceRebootVMClient struct {
+	grpc.ClientStream
+}
+
+func (x *provisionizeServiceRebootVMClient) Recv() (*StatusUpdate, error) {
+	m := new(StatusUpdate)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *provisionizeServiceClient) ResizeVM(ctx context.Context, in *VMActionRequest, opts ...grpc.CallOption) (ProvisionizeService_ResizeVMClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_ProvisionizeService_serviceDesc.Streams[6], "/proto.ProvisionizeService/ResizeVM", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &provisionizeServiceResizeVMClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type ProvisionizeService_ResizeVMClient interface {
+	Recv() (*StatusUpdate, error)
+	grpc.ClientStream
+}
+
+type provisionizeServiceResizeVMClient struct {
+	grpc.ClientStream
+}
+
+func (x *provisionizeServiceResizeVMClient) Recv() (*StatusUpdate, error) {
 	m := new(StatusUpdate)
 	if err := x.ClientStream.RecvMsg(m); err != nil {
 		return nil, err
@@ -421,10 +1795,55 @@ func (x *provisionizeServiceDeprovisionizeClient) Recv() (*StatusUpdate, error)
 	return m, nil
 }
 
+func (c *provisionizeServiceClient) DescribeVM(ctx context.Context, in *DescribeVMRequest, opts ...grpc.CallOption) (*VMState, error) {
+	out := new(VMState)
+	err := c.cc.Invoke(ctx, "/proto.ProvisionizeService/DescribeVM", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *provisionizeServiceClient) ListVMs(ctx context.Context, in *ListVMsRequest, opts ...grpc.CallOption) (*ListVMsResponse, error) {
+	out := new(ListVMsResponse)
+	err := c.cc.Invoke(ctx, "/proto.ProvisionizeService/ListVMs", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *provisionizeServiceClient) ListTemplates(ctx context.Context, in *ListTemplatesRequest, opts ...grpc.CallOption) (*ListTemplatesResponse, error) {
+	out := new(ListTemplatesResponse)
+	err := c.cc.Invoke(ctx, "/proto.ProvisionizeService/ListTemplates", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *provisionizeServiceClient) DescribeTemplate(ctx context.Context, in *DescribeTemplateRequest, opts ...grpc.CallOption) (*Template, error) {
+	out := new(Template)
+	err := c.cc.Invoke(ctx, "/proto.ProvisionizeService/DescribeTemplate", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // ProvisionizeServiceServer is the server API for ProvisionizeService service.
 type ProvisionizeServiceServer interface {
 	Provisionize(*ProvisionizeRequest, ProvisionizeService_ProvisionizeServer) error
 	Deprovisionize(*ProvisionizeRequest, ProvisionizeService_DeprovisionizeServer) error
+	Restore(*ProvisionizeRequest, ProvisionizeService_RestoreServer) error
+	StartVM(*VMActionRequest, ProvisionizeService_StartVMServer) error
+	StopVM(*VMActionRequest, ProvisionizeService_StopVMServer) error
+	RebootVM(*VMActionRequest, ProvisionizeService_RebootVMServer) error
+	ResizeVM(*VMActionRequest, ProvisionizeService_ResizeVMServer) error
+	DescribeVM(context.Context, *DescribeVMRequest) (*VMState, error)
+	ListVMs(context.Context, *ListVMsRequest) (*ListVMsResponse, error)
+	ListTemplates(context.Context, *ListTemplatesRequest) (*ListTemplatesResponse, error)
+	DescribeTemplate(context.Context, *DescribeTemplateRequest) (*Template, error)
 }
 
 // UnimplementedProvisionizeServiceServer can be embedded to have forward compatible implementations.
@@ -437,6 +1856,33 @@ func (*UnimplementedProvisionizeServiceServer) Provisionize(req *ProvisionizeReq
 func (*UnimplementedProvisionizeServiceServer) Deprovisionize(req *ProvisionizeRequest, srv ProvisionizeService_DeprovisionizeServer) error {
 	return status.Errorf(codes.Unimplemented, "method Deprovisionize not implemented")
 }
+func (*UnimplementedProvisionizeServiceServer) Restore(req *ProvisionizeRequest, srv ProvisionizeService_RestoreServer) error {
+	return status.Errorf(codes.Unimplemented, "method Restore not implemented")
+}
+func (*UnimplementedProvisionizeServiceServer) StartVM(req *VMActionRequest, srv ProvisionizeService_StartVMServer) error {
+	return status.Errorf(codes.Unimplemented, "method StartVM not implemented")
+}
+func (*UnimplementedProvisionizeServiceServer) StopVM(req *VMActionRequest, srv ProvisionizeService_StopVMServer) error {
+	return status.Errorf(codes.Unimplemented, "method StopVM not implemented")
+}
+func (*UnimplementedProvisionizeServiceServer) RebootVM(req *VMActionRequest, srv ProvisionizeService_RebootVMServer) error {
+	return status.Errorf(codes.Unimplemented, "method RebootVM not implemented")
+}
+func (*UnimplementedProvisionizeServiceServer) ResizeVM(req *VMActionRequest, srv ProvisionizeService_ResizeVMServer) error {
+	return status.Errorf(codes.Unimplemented, "method ResizeVM not implemented")
+}
+func (*UnimplementedProvisionizeServiceServer) DescribeVM(ctx context.Context, req *DescribeVMRequest) (*VMState, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DescribeVM not implemented")
+}
+func (*UnimplementedProvisionizeServiceServer) ListVMs(ctx context.Context, req *ListVMsRequest) (*ListVMsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListVMs not implemented")
+}
+func (*UnimplementedProvisionizeServiceServer) ListTemplates(ctx context.Context, req *ListTemplatesRequest) (*ListTemplatesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListTemplates not implemented")
+}
+func (*UnimplementedProvisionizeServiceServer) DescribeTemplate(ctx context.Context, req *DescribeTemplateRequest) (*Template, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DescribeTemplate not implemented")
+}
 
 func RegisterProvisionizeServiceServer(s *grpc.Server, srv ProvisionizeServiceServer) {
 	s.RegisterService(&_ProvisionizeService_serviceDesc, srv)
@@ -484,10 +1930,204 @@ func (x *provisionizeServiceDeprovisionizeServer) Send(m *StatusUpdate) error {
 	return x.ServerStream.SendMsg(m)
 }
 
+func _ProvisionizeService_Restore_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ProvisionizeRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ProvisionizeServiceServer).Restore(m, &provisionizeServiceRestoreServer{stream})
+}
+
+type ProvisionizeService_RestoreServer interface {
+	Send(*StatusUpdate) error
+	grpc.ServerStream
+}
+
+type provisionizeServiceRestoreServer struct {
+	grpc.ServerStream
+}
+
+func (x *provisionizeServiceRestoreServer) Send(m *StatusUpdate) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _ProvisionizeService_StartVM_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(VMActionRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ProvisionizeServiceServer).StartVM(m, &provisionizeServiceStartVMServer{stream})
+}
+
+type ProvisionizeService_StartVMServer interface {
+	Send(*StatusUpdate) error
+	grpc.ServerStream
+}
+
+type provisionizeServiceStartVMServer struct {
+	grpc.ServerStream
+}
+
+func (x *provisionizeServiceStartVMServer) Send(m *StatusUpdate) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _ProvisionizeService_StopVM_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(VMActionRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ProvisionizeServiceServer).StopVM(m, &provisionizeServiceStopVMServer{stream})
+}
+
+type ProvisionizeService_StopVMServer interface {
+	Send(*StatusUpdate) error
+	grpc.ServerStream
+}
+
+type provisionizeServiceStopVMServer struct {
+	grpc.ServerStream
+}
+
+func (x *provisionizeServiceStopVMServer) Send(m *StatusUpdate) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _ProvisionizeService_RebootVM_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(VMActionRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ProvisionizeServiceServer).RebootVM(m, &provisionizeServiceRebootVMServer{stream})
+}
+
+type ProvisionizeService_RebootVMServer interface {
+	Send(*StatusUpdate) error
+	grpc.ServerStream
+}
+
+type provisionizeServiceRebootVMServer struct {
+	grpc.ServerStream
+}
+
+func (x *provisionizeServiceRebootVMServer) Send(m *StatusUpdate) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _ProvisionizeService_ResizeVM_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(VMActionRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ProvisionizeServiceServer).ResizeVM(m, &provisionizeServiceResizeVMServer{stream})
+}
+
+type ProvisionizeService_ResizeVMServer interface {
+	Send(*StatusUpdate) error
+	grpc.ServerStream
+}
+
+type provisionizeServiceResizeVMServer struct {
+	grpc.ServerStream
+}
+
+func (x *provisionizeServiceResizeVMServer) Send(m *StatusUpdate) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _ProvisionizeService_DescribeVM_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DescribeVMRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProvisionizeServiceServer).DescribeVM(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.ProvisionizeService/DescribeVM",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProvisionizeServiceServer).DescribeVM(ctx, req.(*DescribeVMRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ProvisionizeService_ListVMs_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListVMsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProvisionizeServiceServer).ListVMs(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.ProvisionizeService/ListVMs",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProvisionizeServiceServer).ListVMs(ctx, req.(*ListVMsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ProvisionizeService_ListTemplates_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListTemplatesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProvisionizeServiceServer).ListTemplates(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.ProvisionizeService/ListTemplates",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProvisionizeServiceServer).ListTemplates(ctx, req.(*ListTemplatesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ProvisionizeService_DescribeTemplate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DescribeTemplateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProvisionizeServiceServer).DescribeTemplate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.ProvisionizeService/DescribeTemplate",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProvisionizeServiceServer).DescribeTemplate(ctx, req.(*DescribeTemplateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _ProvisionizeService_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "proto.ProvisionizeService",
 	HandlerType: (*ProvisionizeServiceServer)(nil),
-	Methods:     []grpc.MethodDesc{},
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "DescribeVM",
+			Handler:    _ProvisionizeService_DescribeVM_Handler,
+		},
+		{
+			MethodName: "ListVMs",
+			Handler:    _ProvisionizeService_ListVMs_Handler,
+		},
+		{
+			MethodName: "ListTemplates",
+			Handler:    _ProvisionizeService_ListTemplates_Handler,
+		},
+		{
+			MethodName: "DescribeTemplate",
+			Handler:    _ProvisionizeService_DescribeTemplate_Handler,
+		},
+	},
 	Streams: []grpc.StreamDesc{
 		{
 			StreamName:    "Provisionize",
@@ -499,6 +2139,31 @@ var _ProvisionizeService_serviceDesc = grpc.ServiceDesc{
 			Handler:       _ProvisionizeService_Deprovisionize_Handler,
 			ServerStreams: true,
 		},
+		{
+			StreamName:    "Restore",
+			Handler:       _ProvisionizeService_Restore_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "StartVM",
+			Handler:       _ProvisionizeService_StartVM_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "StopVM",
+			Handler:       _ProvisionizeService_StopVM_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "RebootVM",
+			Handler:       _ProvisionizeService_RebootVM_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "ResizeVM",
+			Handler:       _ProvisionizeService_ResizeVM_Handler,
+			ServerStreams: true,
+		},
 	},
 	Metadata: "provisionize.proto",
 }
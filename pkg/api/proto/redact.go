@@ -0,0 +1,45 @@
+package proto
+
+import protobuf "github.com/golang/protobuf/proto"
+
+// Redacted returns a copy of the request without secrets, e.g. for logging
+func (m *ProvisionizeRequest) Redacted() *ProvisionizeRequest {
+	if m == nil {
+		return nil
+	}
+
+	r := protobuf.Clone(m).(*ProvisionizeRequest)
+	r.VirtualMachine.clearSecrets()
+
+	return r
+}
+
+// Redacted returns a copy of the request without secrets, e.g. for logging
+func (m *VMActionRequest) Redacted() *VMActionRequest {
+	if m == nil {
+		return nil
+	}
+
+	r := protobuf.Clone(m).(*VMActionRequest)
+	r.VirtualMachine.clearSecrets()
+
+	return r
+}
+
+// Redacted returns a copy of the VM without secrets, e.g. for sending it to external systems
+func (m *VirtualMachine) Redacted() *VirtualMachine {
+	if m == nil {
+		return nil
+	}
+
+	vm := protobuf.Clone(m).(*VirtualMachine)
+	vm.clearSecrets()
+
+	return vm
+}
+
+func (m *VirtualMachine) clearSecrets() {
+	if m != nil && m.GetCloudInit() != nil {
+		m.CloudInit.Password = ""
+	}
+}
@@ -0,0 +1,28 @@
+package proto
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedacted(t *testing.T) {
+	req := &ProvisionizeRequest{
+		RequestId: "req1",
+		VirtualMachine: &VirtualMachine{
+			Name:      "web1",
+			CloudInit: &CloudInit{Password: "secret", UserData: "#cloud-config"},
+		},
+	}
+
+	r := req.Redacted()
+	assert.Equal(t, "req1", r.RequestId)
+	assert.Equal(t, "web1", r.VirtualMachine.Name)
+	assert.Empty(t, r.VirtualMachine.CloudInit.Password)
+	assert.Equal(t, "#cloud-config", r.VirtualMachine.CloudInit.UserData)
+	assert.Equal(t, "secret", req.VirtualMachine.CloudInit.Password, "original is not modified")
+
+	assert.Empty(t, req.VirtualMachine.Redacted().CloudInit.Password)
+	assert.Nil(t, (&VirtualMachine{Name: "web2"}).Redacted().CloudInit)
+	assert.Nil(t, (&ProvisionizeRequest{}).Redacted().VirtualMachine)
+}
@@ -0,0 +1,38 @@
+package clientutils
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/MauveSoftware/provisionize/pkg/api/proto"
+)
+
+// ParseDNSRecords parses record definitions in the form `name type value` (@ refers to the FQDN of the VM), e.g. "@ SSHFP 1 2 abc...".
+// Definitions with the same name and type are merged into one record with multiple values.
+func ParseDNSRecords(defs []string) ([]*proto.DNSRecord, error) {
+	recs := []*proto.DNSRecord{}
+	byKey := make(map[string]*proto.DNSRecord)
+
+	for _, s := range defs {
+		t := strings.Fields(s)
+		if len(t) < 3 {
+			return nil, fmt.Errorf("invalid DNS record %s (expected: name type value)", s)
+		}
+
+		name := t[0]
+		recType := strings.ToUpper(t[1])
+		value := strings.Join(t[2:], " ")
+
+		key := name + " " + recType
+		if r, found := byKey[key]; found {
+			r.Values = append(r.Values, value)
+			continue
+		}
+
+		r := &proto.DNSRecord{Name: name, Type: recType, Values: []string{value}}
+		byKey[key] = r
+		recs = append(recs, r)
+	}
+
+	return recs, nil
+}
@@ -0,0 +1,45 @@
+package clientutils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/MauveSoftware/provisionize/pkg/api/proto"
+)
+
+func TestParseDNSRecords(t *testing.T) {
+	tests := []struct {
+		name        string
+		defs        []string
+		expected    []*proto.DNSRecord
+		expectError bool
+	}{
+		{
+			name: "records",
+			defs: []string{"@ sshfp 1 2 abc", "_acme.web1.example.com TXT \"token\"", "@ SSHFP 4 2 def"},
+			expected: []*proto.DNSRecord{
+				{Name: "@", Type: "SSHFP", Values: []string{"1 2 abc", "4 2 def"}},
+				{Name: "_acme.web1.example.com", Type: "TXT", Values: []string{`"token"`}},
+			},
+		},
+		{
+			name:        "missing value",
+			defs:        []string{"@ TXT"},
+			expectError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			recs, err := ParseDNSRecords(test.defs)
+			if test.expectError {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, recs)
+		})
+	}
+}
@@ -13,7 +13,9 @@ func LogServiceResult(service *proto.StatusUpdate, debug bool) {
 		log.Println("Failed!")
 	}
 
-	if len(service.Message) != 0 {
+	if len(service.Message) != 0 && service.Warning {
+		log.Warnln(service.Message)
+	} else if len(service.Message) != 0 {
 		log.Println(service.Message)
 	}
 
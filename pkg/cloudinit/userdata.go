@@ -0,0 +1,98 @@
+package cloudinit
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	yaml "gopkg.in/yaml.v2"
+)
+
+const header = "#cloud-config"
+
+// Validate checks that doc is a cloud-config document (a YAML mapping)
+func Validate(doc string) error {
+	_, err := parse(doc)
+	return err
+}
+
+// Merge merges the cloud-config documents in the given order. Mappings are merged recursively,
+// lists are concatenated and scalar values of later documents replace values of earlier ones.
+func Merge(docs ...string) (string, error) {
+	var res map[interface{}]interface{}
+
+	for i, doc := range docs {
+		m, err := parse(doc)
+		if err != nil {
+			return "", errors.Wrapf(err, "document %d", i+1)
+		}
+
+		if m == nil {
+			continue
+		}
+
+		if res == nil {
+			res = make(map[interface{}]interface{})
+		}
+		mergeMaps(res, m)
+	}
+
+	if res == nil {
+		return "", nil
+	}
+
+	b, err := yaml.Marshal(res)
+	if err != nil {
+		return "", errors.Wrap(err, "could not serialize user data")
+	}
+
+	return header + "\n" + string(b), nil
+}
+
+func parse(doc string) (map[interface{}]interface{}, error) {
+	if len(strings.TrimSpace(doc)) == 0 {
+		return nil, nil
+	}
+
+	var v interface{}
+	err := yaml.Unmarshal([]byte(doc), &v)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid user data")
+	}
+
+	if v == nil {
+		return nil, nil
+	}
+
+	m, ok := v.(map[interface{}]interface{})
+	if !ok {
+		return nil, fmt.Errorf("invalid user data: expected a YAML mapping, got %T", v)
+	}
+
+	return m, nil
+}
+
+func mergeMaps(dst, src map[interface{}]interface{}) {
+	for k, v := range src {
+		existing, found := dst[k]
+		if !found {
+			dst[k] = v
+			continue
+		}
+
+		switch x := existing.(type) {
+		case map[interface{}]interface{}:
+			if m, ok := v.(map[interface{}]interface{}); ok {
+				mergeMaps(x, m)
+				continue
+			}
+		case []interface{}:
+			if l, ok := v.([]interface{}); ok {
+				dst[k] = append(x, l...)
+				continue
+			}
+		}
+
+		dst[k] = v
+	}
+}
@@ -0,0 +1,93 @@
+package cloudinit
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name        string
+		doc         string
+		expectError bool
+	}{
+		{
+			name: "empty",
+		},
+		{
+			name: "cloud-config",
+			doc:  "#cloud-config\npackages:\n  - htop\n",
+		},
+		{
+			name:        "invalid YAML",
+			doc:         "packages: [htop",
+			expectError: true,
+		},
+		{
+			name:        "no mapping",
+			doc:         "- htop\n",
+			expectError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := Validate(test.doc)
+			if test.expectError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestMerge(t *testing.T) {
+	base := `#cloud-config
+packages:
+  - htop
+write_files:
+  - path: /etc/motd
+    content: managed by provisionize
+ntp:
+  enabled: true
+  servers:
+    - ntp1.example.com
+`
+	overlay := `packages:
+  - postgresql
+ntp:
+  servers:
+    - ntp2.example.com
+timezone: Europe/Berlin
+`
+
+	res, err := Merge(base, "", overlay)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `#cloud-config
+ntp:
+  enabled: true
+  servers:
+  - ntp1.example.com
+  - ntp2.example.com
+packages:
+- htop
+- postgresql
+timezone: Europe/Berlin
+write_files:
+- content: managed by provisionize
+  path: /etc/motd
+`
+	assert.Equal(t, expected, res)
+
+	res, err = Merge("", " ")
+	assert.NoError(t, err)
+	assert.Equal(t, "", res)
+
+	_, err = Merge(base, "- foo")
+	assert.EqualError(t, err, "document 2: invalid user data: expected a YAML mapping, got []interface {}")
+}
@@ -0,0 +1,17 @@
+package ansible
+
+import (
+	"github.com/MauveSoftware/provisionize/pkg/api/proto"
+)
+
+// ConfigService encapsulates the configuration which depents on the type of VM
+type ConfigService interface {
+	// PlaybooksForVM returns the playbooks to run for the VM
+	PlaybooksForVM(vm *proto.VirtualMachine) []string
+
+	// DeprovisionPlaybooksForVM returns the playbooks to run before the VM is deleted
+	DeprovisionPlaybooksForVM(vm *proto.VirtualMachine) []string
+
+	// ExtraVarsForVM returns the static extra vars passed to the playbooks
+	ExtraVarsForVM(vm *proto.VirtualMachine) map[string]interface{}
+}
@@ -0,0 +1,167 @@
+package ansible
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/MauveSoftware/provisionize/pkg/api/proto"
+	"github.com/MauveSoftware/provisionize/pkg/configuration/extravars"
+	"github.com/MauveSoftware/provisionize/pkg/utils"
+)
+
+const (
+	maxLineLength = 4096
+
+	// waitDelay limits the time to wait for processes started by ansible (e.g. SSH control masters) keeping the output open
+	waitDelay = 10 * time.Second
+)
+
+// inventory is the YAML inventory (serialized as JSON) containing only the VM
+type inventory struct {
+	All struct {
+		Hosts map[string]map[string]string `json:"hosts"`
+	} `json:"all"`
+}
+
+// runPlaybook runs the playbook limited to the VM and streams its output line by line
+func (s *AnsibleService) runPlaybook(ctx context.Context, vm *proto.VirtualMachine, playbook string, ch chan<- *proto.StatusUpdate) error {
+	dir, err := ioutil.TempDir("", "provisionize-ansible")
+	if err != nil {
+		return errors.Wrap(err, "could not create working directory")
+	}
+	defer os.RemoveAll(dir)
+
+	host := hostName(vm)
+	inventoryFile, varsFile, err := writeRunFiles(dir, vm, host, s.configService.ExtraVarsForVM(vm))
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	path := s.playbookPath(playbook)
+	cmd := exec.CommandContext(ctx, s.binary, "-i", inventoryFile, "--limit", host, "--extra-vars", "@"+varsFile, path)
+	cmd.Dir = s.playbookDir
+	cmd.Env = append(os.Environ(), "ANSIBLE_FORCE_COLOR=false", "ANSIBLE_NOCOLOR=true")
+	cmd.WaitDelay = waitDelay
+
+	r, w := io.Pipe()
+	cmd.Stdout = w
+	cmd.Stderr = w
+
+	ch <- &proto.StatusUpdate{
+		ServiceName:  serviceName,
+		Message:      fmt.Sprintf("Running playbook %s", playbook),
+		DebugMessage: strings.Join(cmd.Args, " "),
+	}
+
+	err = cmd.Start()
+	if err != nil {
+		return errors.Wrapf(err, "could not start %s", s.binary)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		streamOutput(r, ch)
+		close(done)
+	}()
+
+	err = cmd.Wait()
+	w.Close()
+	<-done
+
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("playbook %s timed out after %s", playbook, s.timeout)
+	}
+
+	if ctx.Err() != nil {
+		return errors.Wrapf(ctx.Err(), "playbook %s canceled", playbook)
+	}
+
+	if err != nil {
+		return errors.Wrapf(err, "playbook %s failed", playbook)
+	}
+
+	return nil
+}
+
+func hostName(vm *proto.VirtualMachine) string {
+	if len(vm.Fqdn) > 0 {
+		return vm.Fqdn
+	}
+
+	return vm.Name
+}
+
+func writeRunFiles(dir string, vm *proto.VirtualMachine, host string, static map[string]interface{}) (inventoryFile, varsFile string, err error) {
+	vars, err := extravars.ForVM(vm, static)
+	if err != nil {
+		return "", "", errors.Wrap(err, "could not determine extra vars")
+	}
+
+	inv := &inventory{}
+	inv.All.Hosts = map[string]map[string]string{host: {}}
+	if addr := vm.PrimaryInterface().Ipv4.GetAddress(); len(addr) > 0 {
+		inv.All.Hosts[host]["ansible_host"] = addr
+	}
+
+	inventoryFile = filepath.Join(dir, "inventory.yml")
+	err = writeJSON(inventoryFile, inv)
+	if err != nil {
+		return "", "", errors.Wrap(err, "could not write inventory")
+	}
+
+	varsFile = filepath.Join(dir, "extra_vars.json")
+	err = writeJSON(varsFile, vars)
+	if err != nil {
+		return "", "", errors.Wrap(err, "could not write extra vars")
+	}
+
+	return inventoryFile, varsFile, nil
+}
+
+func writeJSON(path string, v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, b, 0600)
+}
+
+// streamOutput sends every non empty line as status update. Failed tasks are sent as warning.
+func streamOutput(r io.Reader, ch chan<- *proto.StatusUpdate) {
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadString('\n')
+		line = strings.TrimRight(line, "\r\n")
+
+		if len(strings.TrimSpace(line)) > 0 {
+			ch <- &proto.StatusUpdate{
+				ServiceName: serviceName,
+				Message:     utils.Truncate(line, maxLineLength),
+				Warning:     isFailure(line),
+			}
+		}
+
+		if err != nil {
+			return
+		}
+	}
+}
+
+func isFailure(line string) bool {
+	return strings.HasPrefix(line, "fatal:") || strings.HasPrefix(line, "failed:") || strings.Contains(line, "UNREACHABLE!")
+}
@@ -0,0 +1,116 @@
+package ansible
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	"go.opencensus.io/trace"
+
+	"github.com/MauveSoftware/provisionize/pkg/api/proto"
+)
+
+const (
+	serviceName = "Ansible"
+
+	defaultBinary  = "ansible-playbook"
+	defaultTimeout = 30 * time.Minute
+)
+
+// AnsibleService configures the VM by running ansible-playbook locally
+type AnsibleService struct {
+	binary        string
+	playbookDir   string
+	timeout       time.Duration
+	configService ConfigService
+}
+
+// Option configures optional behavior of AnsibleService
+type Option func(s *AnsibleService)
+
+// WithBinary sets the path of the ansible-playbook executable
+func WithBinary(path string) Option {
+	return func(s *AnsibleService) {
+		s.binary = path
+	}
+}
+
+// WithTimeout sets the maximum duration of a playbook run
+func WithTimeout(timeout time.Duration) Option {
+	return func(s *AnsibleService) {
+		s.timeout = timeout
+	}
+}
+
+// NewService returns a new instance of AnsibleService. Relative playbook paths are resolved in playbookDir.
+func NewService(playbookDir string, configService ConfigService, opts ...Option) *AnsibleService {
+	s := &AnsibleService{
+		binary:        defaultBinary,
+		playbookDir:   playbookDir,
+		timeout:       defaultTimeout,
+		configService: configService,
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// Provision runs the playbooks of the template against the VM
+func (s *AnsibleService) Provision(ctx context.Context, vm *proto.VirtualMachine, ch chan<- *proto.StatusUpdate) bool {
+	ctx, span := trace.StartSpan(ctx, "AnsibleService.Provision")
+	defer span.End()
+
+	return s.runPlaybooks(ctx, vm, s.configService.PlaybooksForVM(vm), ch)
+}
+
+// PreDeprovision runs the deprovisioning playbooks of the template against the VM while it still exists
+func (s *AnsibleService) PreDeprovision(ctx context.Context, vm *proto.VirtualMachine, ch chan<- *proto.StatusUpdate) bool {
+	ctx, span := trace.StartSpan(ctx, "AnsibleService.PreDeprovision")
+	defer span.End()
+
+	return s.runPlaybooks(ctx, vm, s.configService.DeprovisionPlaybooksForVM(vm), ch)
+}
+
+// Deprovision does nothing, the deprovisioning playbooks already ran in PreDeprovision
+func (s *AnsibleService) Deprovision(ctx context.Context, vm *proto.VirtualMachine, ch chan<- *proto.StatusUpdate) bool {
+	return true
+}
+
+// Restore provisions a VM taken from the recycle bin again to revert the changes of the deprovisioning playbooks
+func (s *AnsibleService) Restore(ctx context.Context, vm *proto.VirtualMachine, ch chan<- *proto.StatusUpdate) bool {
+	return s.Provision(ctx, vm, ch)
+}
+
+// PlaybookExists checks if the playbook exists in the playbook directory
+func (s *AnsibleService) PlaybookExists(ctx context.Context, playbook string) (bool, error) {
+	_, err := os.Stat(s.playbookPath(playbook))
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+func (s *AnsibleService) runPlaybooks(ctx context.Context, vm *proto.VirtualMachine, playbooks []string, ch chan<- *proto.StatusUpdate) bool {
+	for _, p := range playbooks {
+		err := s.runPlaybook(ctx, vm, p, ch)
+		if err != nil {
+			ch <- &proto.StatusUpdate{ServiceName: serviceName, Failed: true, Message: err.Error()}
+			return false
+		}
+	}
+
+	return true
+}
+
+func (s *AnsibleService) playbookPath(playbook string) string {
+	if filepath.IsAbs(playbook) {
+		return playbook
+	}
+
+	return filepath.Join(s.playbookDir, playbook)
+}
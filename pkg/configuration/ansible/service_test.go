@@ -0,0 +1,181 @@
+package ansible
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/MauveSoftware/provisionize/pkg/api/proto"
+)
+
+// fakePlaybook records its arguments, the inventory and the extra vars and behaves depending on the playbook name
+const fakePlaybook = `#!/bin/sh
+out="$FAKE_ANSIBLE_OUT"
+echo "$@" >> "$out/args"
+cat "$2" > "$out/inventory"
+cat "${6#@}" > "$out/vars"
+
+case "$7" in
+  */slow.yml)
+    exec sleep 5
+    ;;
+  */fail.yml)
+    echo "TASK [start nginx] ***"
+    echo "fatal: [web1.example.com]: FAILED! => {\"msg\": \"failed\"}" >&2
+    exit 2
+    ;;
+esac
+
+echo "PLAY [all] ***"
+echo ""
+echo "TASK [install packages] ***"
+echo "changed: [web1.example.com]"
+`
+
+type mockConfigService struct {
+	playbooks   []string
+	deprovision []string
+}
+
+func (m *mockConfigService) PlaybooksForVM(vm *proto.VirtualMachine) []string {
+	return m.playbooks
+}
+
+func (m *mockConfigService) DeprovisionPlaybooksForVM(vm *proto.VirtualMachine) []string {
+	return m.deprovision
+}
+
+func (m *mockConfigService) ExtraVarsForVM(vm *proto.VirtualMachine) map[string]interface{} {
+	return map[string]interface{}{"environment": "production"}
+}
+
+func newTestService(t *testing.T, cfg *mockConfigService, timeout time.Duration) (*AnsibleService, string) {
+	dir := t.TempDir()
+	bin := filepath.Join(dir, "ansible-playbook")
+	require.NoError(t, ioutil.WriteFile(bin, []byte(fakePlaybook), 0755))
+
+	out := t.TempDir()
+	t.Setenv("FAKE_ANSIBLE_OUT", out)
+
+	return NewService(dir, cfg, WithBinary(bin), WithTimeout(timeout)), out
+}
+
+func runAndCollect(f func(ch chan<- *proto.StatusUpdate) bool) (bool, []*proto.StatusUpdate) {
+	ch := make(chan *proto.StatusUpdate)
+	updates := []*proto.StatusUpdate{}
+	done := make(chan struct{})
+	go func() {
+		for u := range ch {
+			updates = append(updates, u)
+		}
+		close(done)
+	}()
+
+	result := f(ch)
+	close(ch)
+	<-done
+
+	return result, updates
+}
+
+func messages(updates []*proto.StatusUpdate) []string {
+	m := make([]string, len(updates))
+	for i, u := range updates {
+		m[i] = u.Message
+	}
+
+	return m
+}
+
+func TestProvision(t *testing.T) {
+	svc, out := newTestService(t, &mockConfigService{playbooks: []string{"base.yml"}}, time.Minute)
+
+	vm := &proto.VirtualMachine{Name: "web1", Fqdn: "web1.example.com", Ipv4: &proto.IPConfig{Address: "192.168.1.10"}}
+	require.NoError(t, vm.NormalizeInterfaces())
+
+	result, updates := runAndCollect(func(ch chan<- *proto.StatusUpdate) bool {
+		return svc.Provision(context.Background(), vm, ch)
+	})
+	assert.True(t, result)
+
+	expected := []string{
+		"Running playbook base.yml",
+		"PLAY [all] ***",
+		"TASK [install packages] ***",
+		"changed: [web1.example.com]",
+	}
+	assert.Equal(t, expected, messages(updates))
+
+	args, err := ioutil.ReadFile(filepath.Join(out, "args"))
+	require.NoError(t, err)
+	assert.Contains(t, string(args), "--limit web1.example.com --extra-vars @")
+	assert.True(t, strings.HasSuffix(strings.TrimSpace(string(args)), filepath.Join(svc.playbookDir, "base.yml")))
+
+	inv, err := ioutil.ReadFile(filepath.Join(out, "inventory"))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"all":{"hosts":{"web1.example.com":{"ansible_host":"192.168.1.10"}}}}`, string(inv))
+
+	b, err := ioutil.ReadFile(filepath.Join(out, "vars"))
+	require.NoError(t, err)
+	vars := map[string]interface{}{}
+	require.NoError(t, json.Unmarshal(b, &vars))
+	assert.Equal(t, "production", vars["environment"])
+	assert.Equal(t, "web1.example.com", vars["provisionize_fqdn"])
+}
+
+func TestProvisionFailedPlaybook(t *testing.T) {
+	svc, _ := newTestService(t, &mockConfigService{playbooks: []string{"fail.yml", "base.yml"}}, time.Minute)
+
+	result, updates := runAndCollect(func(ch chan<- *proto.StatusUpdate) bool {
+		return svc.Provision(context.Background(), &proto.VirtualMachine{Name: "web1", Fqdn: "web1.example.com"}, ch)
+	})
+	assert.False(t, result)
+
+	require.Len(t, updates, 4)
+	assert.True(t, updates[2].Warning, "fatal line should be a warning")
+	assert.Equal(t, &proto.StatusUpdate{ServiceName: serviceName, Failed: true, Message: "playbook fail.yml failed: exit status 2"}, updates[3])
+}
+
+func TestProvisionTimeout(t *testing.T) {
+	svc, _ := newTestService(t, &mockConfigService{playbooks: []string{"slow.yml"}}, 200*time.Millisecond)
+
+	result, updates := runAndCollect(func(ch chan<- *proto.StatusUpdate) bool {
+		return svc.Provision(context.Background(), &proto.VirtualMachine{Name: "web1"}, ch)
+	})
+	assert.False(t, result)
+	assert.Equal(t, "playbook slow.yml timed out after 200ms", updates[len(updates)-1].Message)
+}
+
+func TestPreDeprovision(t *testing.T) {
+	svc, out := newTestService(t, &mockConfigService{playbooks: []string{"base.yml"}, deprovision: []string{"/opt/decommission.yml"}}, time.Minute)
+
+	result, _ := runAndCollect(func(ch chan<- *proto.StatusUpdate) bool {
+		return svc.PreDeprovision(context.Background(), &proto.VirtualMachine{Name: "web1"}, ch)
+	})
+	assert.True(t, result)
+
+	args, err := ioutil.ReadFile(filepath.Join(out, "args"))
+	require.NoError(t, err)
+	assert.Contains(t, string(args), "--limit web1 --extra-vars @")
+	assert.True(t, strings.HasSuffix(strings.TrimSpace(string(args)), "/opt/decommission.yml"))
+}
+
+func TestPlaybookExists(t *testing.T) {
+	svc, _ := newTestService(t, &mockConfigService{}, time.Minute)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(svc.playbookDir, "base.yml"), []byte("- hosts: all\n"), 0644))
+
+	found, err := svc.PlaybookExists(context.Background(), "base.yml")
+	require.NoError(t, err)
+	assert.True(t, found)
+
+	found, err = svc.PlaybookExists(context.Background(), "missing.yml")
+	require.NoError(t, err)
+	assert.False(t, found)
+}
@@ -0,0 +1,143 @@
+// Package extravars derives the variables passed to Ansible playbooks from a VM
+package extravars
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/MauveSoftware/provisionize/pkg/api/proto"
+)
+
+// vars are derived from the VM. Static vars of the template and vars of the request are merged into them (see README).
+type vars struct {
+	AnsibleSSHHost string           `json:"ansible_ssh_host"`
+	ID             string           `json:"provisionize_id"`
+	Name           string           `json:"provisionize_name"`
+	FQDN           string           `json:"provisionize_fqdn"`
+	Cluster        string           `json:"provisionize_cluster"`
+	Template       string           `json:"provisionize_template"`
+	CPUCores       uint32           `json:"provisionize_cpu_cores"`
+	MemoryMB       uint32           `json:"provisionize_memory_mb"`
+	IPv4           *ipVars          `json:"provisionize_ipv4,omitempty"`
+	IPv6           *ipVars          `json:"provisionize_ipv6,omitempty"`
+	Interfaces     []*interfaceVars `json:"interfaces"`
+	Disks          []*diskVars      `json:"provisionize_disks"`
+}
+
+type interfaceVars struct {
+	Name    string  `json:"name"`
+	Network string  `json:"network,omitempty"`
+	MAC     string  `json:"mac,omitempty"`
+	Primary bool    `json:"primary"`
+	IPv4    *ipVars `json:"ipv4,omitempty"`
+	IPv6    *ipVars `json:"ipv6,omitempty"`
+}
+
+type ipVars struct {
+	Address      string `json:"address"`
+	PrefixLength uint32 `json:"prefix_length"`
+	Gateway      string `json:"gateway,omitempty"`
+}
+
+type diskVars struct {
+	Name          string `json:"name"`
+	SizeGB        uint64 `json:"size_gb"`
+	StorageDomain string `json:"storage_domain,omitempty"`
+}
+
+// reservedPrefix marks variables controlling how Ansible connects to and executes on the host (e.g. ansible_connection).
+// Requests must not set them since they could be used to run commands on the Ansible controller.
+const reservedPrefix = "ansible_"
+
+// ForVM returns the vars derived from the VM overridden by the static vars and the vars of the request
+func ForVM(vm *proto.VirtualMachine, static map[string]interface{}) (map[string]interface{}, error) {
+	err := ValidateRequestVars(vm.ExtraVars)
+	if err != nil {
+		return nil, err
+	}
+
+	v, err := vmVars(vm)
+	if err != nil {
+		return nil, err
+	}
+
+	for k, val := range static {
+		v[k] = val
+	}
+
+	for k, val := range vm.ExtraVars {
+		v[k] = val
+	}
+
+	return v, nil
+}
+
+// ValidateRequestVars returns an error if a var passed with a request is reserved
+func ValidateRequestVars(vars map[string]string) error {
+	for k := range vars {
+		if strings.HasPrefix(strings.ToLower(k), reservedPrefix) {
+			return fmt.Errorf("extra var %s is not allowed: vars starting with %s can only be set in templates", k, reservedPrefix)
+		}
+	}
+
+	return nil
+}
+
+func vmVars(vm *proto.VirtualMachine) (map[string]interface{}, error) {
+	primary := vm.PrimaryInterface()
+	v := &vars{
+		AnsibleSSHHost: primary.Ipv4.GetAddress(),
+		ID:             vm.Id,
+		Name:           vm.Name,
+		FQDN:           vm.Fqdn,
+		Cluster:        vm.ClusterName,
+		Template:       vm.Template,
+		CPUCores:       vm.CpuCores,
+		MemoryMB:       vm.MemoryMb,
+		IPv4:           ipVarsForConfig(primary.GetIpv4()),
+		IPv6:           ipVarsForConfig(primary.GetIpv6()),
+		Interfaces:     make([]*interfaceVars, len(vm.Interfaces)),
+		Disks:          make([]*diskVars, len(vm.Disks)),
+	}
+
+	for i, n := range vm.Interfaces {
+		v.Interfaces[i] = &interfaceVars{
+			Name:    n.Name,
+			Network: n.Network,
+			MAC:     n.Mac,
+			Primary: n.Primary,
+			IPv4:    ipVarsForConfig(n.Ipv4),
+			IPv6:    ipVarsForConfig(n.Ipv6),
+		}
+	}
+
+	for i, d := range vm.Disks {
+		v.Disks[i] = &diskVars{
+			Name:          d.Name,
+			SizeGB:        d.SizeGb,
+			StorageDomain: d.StorageDomain,
+		}
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	m := make(map[string]interface{})
+	err = json.Unmarshal(b, &m)
+	return m, err
+}
+
+func ipVarsForConfig(c *proto.IPConfig) *ipVars {
+	if len(c.GetAddress()) == 0 {
+		return nil
+	}
+
+	return &ipVars{
+		Address:      c.Address,
+		PrefixLength: c.PrefixLength,
+		Gateway:      c.Gateway,
+	}
+}
@@ -0,0 +1,107 @@
+package extravars
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/MauveSoftware/provisionize/pkg/api/proto"
+)
+
+func TestForVM(t *testing.T) {
+	vm := &proto.VirtualMachine{
+		Id:          "123",
+		Name:        "db1",
+		Fqdn:        "db1.example.com",
+		ClusterName: "cluster1",
+		Template:    "db",
+		CpuCores:    4,
+		MemoryMb:    8192,
+		Interfaces: []*proto.NetworkInterface{
+			{
+				Name:    "ens3",
+				Primary: true,
+				Ipv4:    &proto.IPConfig{Address: "192.168.1.100", PrefixLength: 24, Gateway: "192.168.1.1"},
+				Ipv6:    &proto.IPConfig{},
+			},
+			{
+				Name:    "ens4",
+				Network: "storage",
+				Mac:     "00:1a:4a:16:01:51",
+				Ipv4:    &proto.IPConfig{Address: "10.0.0.100", PrefixLength: 24},
+			},
+		},
+		Disks: []*proto.Disk{
+			{Name: "data", SizeGb: 100, StorageDomain: "ssd"},
+		},
+	}
+
+	vars, err := ForVM(vm, map[string]interface{}{"environment": "production"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := json.Marshal(vars)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `{
+		"ansible_ssh_host": "192.168.1.100",
+		"provisionize_id": "123",
+		"provisionize_name": "db1",
+		"provisionize_fqdn": "db1.example.com",
+		"provisionize_cluster": "cluster1",
+		"provisionize_template": "db",
+		"provisionize_cpu_cores": 4,
+		"provisionize_memory_mb": 8192,
+		"provisionize_ipv4": {"address": "192.168.1.100", "prefix_length": 24, "gateway": "192.168.1.1"},
+		"interfaces": [
+			{"name": "ens3", "primary": true, "ipv4": {"address": "192.168.1.100", "prefix_length": 24, "gateway": "192.168.1.1"}},
+			{"name": "ens4", "network": "storage", "mac": "00:1a:4a:16:01:51", "primary": false, "ipv4": {"address": "10.0.0.100", "prefix_length": 24}}
+		],
+		"provisionize_disks": [
+			{"name": "data", "size_gb": 100, "storage_domain": "ssd"}
+		],
+		"environment": "production"
+	}`
+	assert.JSONEq(t, expected, string(b))
+}
+
+func TestForVMRejectsReservedRequestVars(t *testing.T) {
+	tests := []struct {
+		name        string
+		vars        map[string]string
+		expectError bool
+	}{
+		{
+			name: "allowed",
+			vars: map[string]string{"environment": "staging"},
+		},
+		{
+			name:        "connection",
+			vars:        map[string]string{"ansible_connection": "local"},
+			expectError: true,
+		},
+		{
+			name:        "upper case",
+			vars:        map[string]string{"ANSIBLE_SSH_COMMON_ARGS": "-o ProxyCommand=id"},
+			expectError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			vm := &proto.VirtualMachine{Name: "db1", ExtraVars: test.vars}
+
+			_, err := ForVM(vm, map[string]interface{}{"ansible_user": "deploy"})
+			if test.expectError {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+		})
+	}
+}
@@ -0,0 +1,231 @@
+package tower
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.opencensus.io/plugin/ochttp"
+
+	"github.com/MauveSoftware/provisionize/pkg/request"
+)
+
+const defaultRequestTimeout = 30 * time.Second
+
+type apiResponse struct {
+	statusCode int
+	body       []byte
+}
+
+// APIError represents an error response of the Tower/AWX API
+type APIError struct {
+	StatusCode int
+	Detail     string
+	Fields     map[string][]string
+}
+
+func (e *APIError) Error() string {
+	msg := e.Detail
+	if len(msg) == 0 {
+		fields := make([]string, 0, len(e.Fields))
+		for f, errs := range e.Fields {
+			fields = append(fields, fmt.Sprintf("%s: %s", f, strings.Join(errs, " ")))
+		}
+		sort.Strings(fields)
+		msg = strings.Join(fields, "; ")
+	}
+
+	if len(msg) == 0 {
+		return fmt.Sprintf("status code %d", e.StatusCode)
+	}
+
+	return fmt.Sprintf("status code %d: %s", e.StatusCode, msg)
+}
+
+// apiError returns the error described by the body of a failed request
+func (r *apiResponse) apiError() error {
+	e := &APIError{StatusCode: r.statusCode}
+
+	var body map[string]interface{}
+	if json.Unmarshal(r.body, &body) != nil {
+		return e
+	}
+
+	for k, v := range body {
+		switch x := v.(type) {
+		case string:
+			if k == "detail" || k == "error" {
+				e.Detail = x
+			}
+		case []interface{}:
+			if e.Fields == nil {
+				e.Fields = make(map[string][]string)
+			}
+
+			for _, msg := range x {
+				e.Fields[k] = append(e.Fields[k], fmt.Sprint(msg))
+			}
+		}
+	}
+
+	return e
+}
+
+// NewHTTPClient returns a client trusting the certificates in caFile (if set) in addition to the system pool
+func NewHTTPClient(caFile string, insecure bool, timeout time.Duration) (*http.Client, error) {
+	cfg := &tls.Config{InsecureSkipVerify: insecure}
+
+	if len(caFile) > 0 {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+
+		b, err := ioutil.ReadFile(caFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not read CA file")
+		}
+
+		if !pool.AppendCertsFromPEM(b) {
+			return nil, fmt.Errorf("no certificates found in %s", caFile)
+		}
+
+		cfg.RootCAs = pool
+	}
+
+	if timeout == 0 {
+		timeout = defaultRequestTimeout
+	}
+
+	return &http.Client{
+		Transport: &ochttp.Transport{Base: &http.Transport{TLSClientConfig: cfg, Proxy: http.ProxyFromEnvironment}},
+		Timeout:   timeout,
+	}, nil
+}
+
+// sendRequest sends a request to the API. Failed requests are retried with exponential backoff on connection errors
+// and server errors. POST requests are only retried if they did not reach Tower (connection not established, 502, 503),
+// since e.g. a timed out launch request may have started a job anyway.
+func (s *TowerService) sendRequest(ctx context.Context, method, url, contentType, body string) (*apiResponse, error) {
+	backoff := s.retryBackoff
+
+	for attempt := 0; ; attempt++ {
+		res, err := s.doRequest(ctx, method, url, contentType, body)
+		if attempt >= s.maxRetries || !retryable(method, res, err) {
+			return res, err
+		}
+
+		request.Logger(ctx).Warnf("%s %s failed (attempt %d), retrying in %s", method, url, attempt+1, backoff)
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(backoff):
+			backoff *= 2
+		}
+	}
+}
+
+func retryable(method string, res *apiResponse, err error) bool {
+	if method != http.MethodPost {
+		return err != nil || res.statusCode >= 500
+	}
+
+	if err != nil {
+		return isDialError(err)
+	}
+
+	return res.statusCode == http.StatusBadGateway || res.statusCode == http.StatusServiceUnavailable
+}
+
+// isDialError checks if err occurred while connecting, so the request was not sent
+func isDialError(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+func (s *TowerService) doRequest(ctx context.Context, method, url, contentType, body string) (*apiResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, strings.NewReader(body))
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not create request with URI %s", url)
+	}
+
+	if len(s.token) > 0 {
+		req.Header.Set("Authorization", "Bearer "+s.token)
+	} else {
+		req.SetBasicAuth(s.username, s.password)
+	}
+
+	req.Header.Set("content-type", contentType)
+
+	if id := request.ID(ctx); len(id) > 0 {
+		req.Header.Set("X-Request-ID", id)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not read from response")
+	}
+
+	return &apiResponse{body: b, statusCode: resp.StatusCode}, nil
+}
+
+// getAllPages requests url and all following pages, the results of every page are passed to f
+func (s *TowerService) getAllPages(ctx context.Context, url string, f func(results json.RawMessage) error) error {
+	for len(url) > 0 {
+		page := &struct {
+			Next    string          `json:"next"`
+			Results json.RawMessage `json:"results"`
+		}{}
+
+		_, err := s.getAndParse(ctx, url, page)
+		if err != nil {
+			return err
+		}
+
+		err = f(page.Results)
+		if err != nil {
+			return errors.Wrap(err, "could not parse results")
+		}
+
+		url = ""
+		if len(page.Next) > 0 {
+			url = s.rootURL + page.Next
+		}
+	}
+
+	return nil
+}
+
+func (s *TowerService) getAndParse(ctx context.Context, url string, v interface{}) (debugMessage string, err error) {
+	res, err := s.sendRequest(ctx, "GET", url, "application/json", "")
+	if err != nil {
+		return "", err
+	}
+
+	if res.statusCode != http.StatusOK {
+		return string(res.body), res.apiError()
+	}
+
+	err = json.Unmarshal(res.body, v)
+	if err != nil {
+		return string(res.body), errors.Wrap(err, "could not parse response")
+	}
+
+	return string(res.body), nil
+}
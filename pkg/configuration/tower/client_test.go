@@ -0,0 +1,206 @@
+package tower
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSendRequestRetries(t *testing.T) {
+	tests := []struct {
+		name          string
+		method        string
+		statusCodes   []int
+		expectedCalls int
+		expectedCode  int
+	}{
+		{
+			name:          "GET retried on server error",
+			method:        "GET",
+			statusCodes:   []int{500, 502, 200},
+			expectedCalls: 3,
+			expectedCode:  200,
+		},
+		{
+			name:          "GET gives up after max retries",
+			method:        "GET",
+			statusCodes:   []int{503, 503, 503, 503, 200},
+			expectedCalls: 4,
+			expectedCode:  503,
+		},
+		{
+			name:          "POST not retried on internal server error",
+			method:        "POST",
+			statusCodes:   []int{500, 201},
+			expectedCalls: 1,
+			expectedCode:  500,
+		},
+		{
+			name:          "POST retried on unavailable service",
+			method:        "POST",
+			statusCodes:   []int{503, 201},
+			expectedCalls: 2,
+			expectedCode:  201,
+		},
+		{
+			name:          "POST not retried on gateway timeout",
+			method:        "POST",
+			statusCodes:   []int{504, 201},
+			expectedCalls: 1,
+			expectedCode:  504,
+		},
+		{
+			name:          "client error not retried",
+			method:        "GET",
+			statusCodes:   []int{404, 200},
+			expectedCalls: 1,
+			expectedCode:  404,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			calls := 0
+			s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(test.statusCodes[calls])
+				calls++
+			}))
+			defer s.Close()
+
+			svc := NewService(s.URL, "test", "foo", &mockConfigService{})
+			svc.retryBackoff = time.Millisecond
+
+			res, err := svc.sendRequest(context.Background(), test.method, s.URL, "application/json", "")
+			require.NoError(t, err)
+			assert.Equal(t, test.expectedCode, res.statusCode)
+			assert.Equal(t, test.expectedCalls, calls)
+		})
+	}
+}
+
+func TestSendRequestPOSTTimeout(t *testing.T) {
+	var calls int32
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the job is launched but the response arrives after the client gave up
+		atomic.AddInt32(&calls, 1)
+		time.Sleep(100 * time.Millisecond)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer s.Close()
+
+	svc := NewService(s.URL, "test", "foo", &mockConfigService{}, WithHTTPClient(&http.Client{Timeout: 20 * time.Millisecond}))
+	svc.retryBackoff = time.Millisecond
+
+	_, err := svc.sendRequest(context.Background(), "POST", s.URL, "application/json", "")
+	assert.Error(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls), "POST must not be sent again")
+
+	atomic.StoreInt32(&calls, 0)
+	_, err = svc.sendRequest(context.Background(), "GET", s.URL, "application/json", "")
+	assert.Error(t, err)
+	assert.Equal(t, int32(4), atomic.LoadInt32(&calls), "GET is retried")
+}
+
+func TestSendRequestPOSTRetriedOnConnectionError(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	url := "http://" + l.Addr().String()
+	l.Close()
+
+	svc := NewService(url, "test", "foo", &mockConfigService{})
+	svc.retryBackoff = time.Millisecond
+	svc.maxRetries = 1
+
+	_, err = svc.sendRequest(context.Background(), "POST", url, "application/json", "")
+	require.Error(t, err)
+	assert.True(t, retryable("POST", nil, err))
+}
+
+func TestAuthentication(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Header.Get("Authorization")))
+	}))
+	defer s.Close()
+
+	svc := NewService(s.URL, "test", "foo", &mockConfigService{})
+	res, err := svc.sendRequest(context.Background(), "GET", s.URL, "application/json", "")
+	require.NoError(t, err)
+	assert.Equal(t, "Basic dGVzdDpmb28=", string(res.body))
+
+	svc = NewService(s.URL, "", "", &mockConfigService{}, WithToken("secret"))
+	res, err = svc.sendRequest(context.Background(), "GET", s.URL, "application/json", "")
+	require.NoError(t, err)
+	assert.Equal(t, "Bearer secret", string(res.body))
+}
+
+func TestAPIError(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		expected string
+	}{
+		{
+			name:     "detail",
+			body:     `{"detail":"You do not have permission to perform this action."}`,
+			expected: "status code 403: You do not have permission to perform this action.",
+		},
+		{
+			name:     "field errors",
+			body:     `{"name":["This field is required."],"inventory":["Invalid pk \"3\" - object does not exist."]}`,
+			expected: `status code 403: inventory: Invalid pk "3" - object does not exist.; name: This field is required.`,
+		},
+		{
+			name:     "no JSON",
+			body:     `<html>Forbidden</html>`,
+			expected: "status code 403",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			res := &apiResponse{statusCode: 403, body: []byte(test.body)}
+			assert.EqualError(t, res.apiError(), test.expected)
+		})
+	}
+}
+
+func TestGetAllPages(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("page") == "2" {
+			w.Write([]byte(`{"next":null,"results":[3]}`))
+			return
+		}
+
+		w.Write([]byte(`{"next":"/api/v2/hosts/?page=2","results":[1,2]}`))
+	}))
+	defer s.Close()
+
+	svc := NewService(s.URL, "test", "foo", &mockConfigService{})
+
+	values := []int{}
+	err := svc.getAllPages(context.Background(), s.URL+"/api/v2/hosts/", func(results json.RawMessage) error {
+		page := []int{}
+		err := json.Unmarshal(results, &page)
+		values = append(values, page...)
+		return err
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []int{1, 2, 3}, values)
+}
+
+func TestNewHTTPClient(t *testing.T) {
+	c, err := NewHTTPClient("", true, 0)
+	require.NoError(t, err)
+	assert.Equal(t, defaultRequestTimeout, c.Timeout)
+
+	_, err = NewHTTPClient("/does/not/exist.pem", false, time.Second)
+	assert.Error(t, err)
+}
@@ -8,4 +8,13 @@ import (
 type ConfigService interface {
 	// TowerTemplateIDsForVM return the job template ids to launch for the VM
 	TowerTemplateIDsForVM(vm *proto.VirtualMachine) []uint
+
+	// TowerWorkflowIDsForVM return the workflow job template ids to launch for the VM (after the job templates)
+	TowerWorkflowIDsForVM(vm *proto.VirtualMachine) []uint
+
+	// TowerDeprovisionTemplateIDsForVM return the job template ids to launch before the VM is deleted
+	TowerDeprovisionTemplateIDsForVM(vm *proto.VirtualMachine) []uint
+
+	// TowerExtraVarsForVM returns the static extra vars passed to the job templates
+	TowerExtraVarsForVM(vm *proto.VirtualMachine) map[string]interface{}
 }
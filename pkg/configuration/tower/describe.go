@@ -0,0 +1,53 @@
+package tower
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/pkg/errors"
+	"go.opencensus.io/trace"
+
+	"github.com/MauveSoftware/provisionize/pkg/api/proto"
+)
+
+const describedJobsCount = 5
+
+// Describe adds the latest jobs run against the VM to state
+func (s *TowerService) Describe(ctx context.Context, vm *proto.VirtualMachine, state *proto.VMState) error {
+	ctx, span := trace.StartSpan(ctx, "TowerService.Describe")
+	defer span.End()
+
+	if len(vm.Fqdn) == 0 {
+		return nil
+	}
+
+	u := fmt.Sprintf("%s/jobs/?limit=%s&order_by=-created&page_size=%d", s.baseURL, url.QueryEscape(vm.Fqdn), describedJobsCount)
+	res, err := s.sendRequest(ctx, "GET", u, "application/json", "")
+	if err != nil {
+		return errors.Wrap(err, "could not retrieve jobs")
+	}
+
+	if res.statusCode != http.StatusOK {
+		return errors.Wrap(res.apiError(), "could not retrieve jobs")
+	}
+
+	list := &jobList{}
+	err = json.Unmarshal(res.body, list)
+	if err != nil {
+		return errors.Wrap(err, "could not parse job list")
+	}
+
+	for _, job := range list.Results {
+		state.TowerJobs = append(state.TowerJobs, &proto.TowerJob{
+			Id:       uint32(job.ID),
+			Name:     job.Name,
+			Status:   job.Status,
+			Finished: job.Finished,
+		})
+	}
+
+	return nil
+}
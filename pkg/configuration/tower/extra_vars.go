@@ -0,0 +1,24 @@
+package tower
+
+import (
+	"encoding/json"
+
+	"github.com/MauveSoftware/provisionize/pkg/api/proto"
+	"github.com/MauveSoftware/provisionize/pkg/configuration/extravars"
+)
+
+type launchRequest struct {
+	Limit     string                 `json:"limit"`
+	ExtraVars map[string]interface{} `json:"extra_vars"`
+}
+
+// launchRequestBody returns the body to launch a job or workflow for the VM
+func launchRequestBody(vm *proto.VirtualMachine, static map[string]interface{}) (string, error) {
+	vars, err := extravars.ForVM(vm, static)
+	if err != nil {
+		return "", err
+	}
+
+	b, err := json.Marshal(&launchRequest{Limit: vm.Fqdn, ExtraVars: vars})
+	return string(b), err
+}
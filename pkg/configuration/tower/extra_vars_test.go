@@ -0,0 +1,60 @@
+package tower
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/MauveSoftware/provisionize/pkg/api/proto"
+)
+
+func TestLaunchRequestBodyMergesVars(t *testing.T) {
+	vm := &proto.VirtualMachine{
+		Name: "web1",
+		Fqdn: `web1".example.com`,
+		Ipv4: &proto.IPConfig{Address: "192.168.1.10", PrefixLength: 24},
+		ExtraVars: map[string]string{
+			"environment": "staging",
+		},
+	}
+	static := map[string]interface{}{
+		"environment":      "production",
+		"ansible_ssh_host": "10.0.0.10",
+		"ntp":              map[string]interface{}{"servers": []interface{}{"ntp1"}},
+	}
+
+	body, err := launchRequestBody(vm, static)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `{
+		"limit": "web1\".example.com",
+		"extra_vars": {
+			"ansible_ssh_host": "10.0.0.10",
+			"provisionize_id": "",
+			"provisionize_name": "web1",
+			"provisionize_fqdn": "web1\".example.com",
+			"provisionize_cluster": "",
+			"provisionize_template": "",
+			"provisionize_cpu_cores": 0,
+			"provisionize_memory_mb": 0,
+			"provisionize_ipv4": {"address": "192.168.1.10", "prefix_length": 24},
+			"interfaces": [],
+			"provisionize_disks": [],
+			"environment": "staging",
+			"ntp": {"servers": ["ntp1"]}
+		}
+	}`
+	assert.JSONEq(t, expected, body)
+}
+
+func TestLaunchRequestBodyRejectsReservedRequestVars(t *testing.T) {
+	vm := &proto.VirtualMachine{
+		Name:      "web1",
+		ExtraVars: map[string]string{"ansible_connection": "local"},
+	}
+
+	_, err := launchRequestBody(vm, nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,191 @@
+package tower
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/pkg/errors"
+
+	"github.com/MauveSoftware/provisionize/pkg/api/proto"
+	"github.com/MauveSoftware/provisionize/pkg/configuration/extravars"
+)
+
+// Host represents a host in a Tower inventory
+type Host struct {
+	ID        uint   `json:"id,omitempty"`
+	Name      string `json:"name,omitempty"`
+	Variables string `json:"variables"`
+}
+
+// addHost adds the VM to the inventory (or updates its variables) and to the group if configured.
+// The host variables are the extra vars passed to the job templates.
+func (s *TowerService) addHost(ctx context.Context, vm *proto.VirtualMachine, ch chan<- *proto.StatusUpdate) error {
+	if len(vm.Fqdn) == 0 {
+		return errors.New("FQDN is required to add the VM to the inventory")
+	}
+
+	vars, err := extravars.ForVM(vm, s.configService.TowerExtraVarsForVM(vm))
+	if err != nil {
+		return errors.Wrap(err, "could not determine host variables")
+	}
+
+	b, err := json.Marshal(vars)
+	if err != nil {
+		return errors.Wrap(err, "could not serialize host variables")
+	}
+
+	host, err := s.findHost(ctx, vm.Fqdn)
+	if err != nil {
+		return err
+	}
+
+	if host == nil {
+		host, err = s.createHost(ctx, &Host{Name: vm.Fqdn, Variables: string(b)})
+	} else {
+		err = s.updateHostVariables(ctx, host.ID, string(b))
+	}
+	if err != nil {
+		return err
+	}
+
+	ch <- &proto.StatusUpdate{
+		ServiceName: serviceName,
+		Message:     fmt.Sprintf("Host %s (%d) added to inventory %d", vm.Fqdn, host.ID, s.inventoryID),
+	}
+
+	if s.groupID == 0 {
+		return nil
+	}
+
+	return s.addHostToGroup(ctx, host.ID)
+}
+
+func (s *TowerService) removeHost(ctx context.Context, vm *proto.VirtualMachine, ch chan<- *proto.StatusUpdate) error {
+	if len(vm.Fqdn) == 0 {
+		return nil
+	}
+
+	host, err := s.findHost(ctx, vm.Fqdn)
+	if err != nil {
+		return err
+	}
+
+	if host == nil {
+		ch <- &proto.StatusUpdate{
+			ServiceName: serviceName,
+			Message:     fmt.Sprintf("Host %s not found in inventory %d: skipping", vm.Fqdn, s.inventoryID),
+		}
+		return nil
+	}
+
+	u := fmt.Sprintf("%s/hosts/%d/", s.baseURL, host.ID)
+	res, err := s.sendRequest(ctx, "DELETE", u, "application/json", "")
+	if err != nil {
+		return errors.Wrapf(err, "could not delete host %s", vm.Fqdn)
+	}
+
+	if res.statusCode != http.StatusNoContent && res.statusCode != http.StatusAccepted && res.statusCode != http.StatusNotFound {
+		return errors.Wrapf(res.apiError(), "could not delete host %s", vm.Fqdn)
+	}
+
+	ch <- &proto.StatusUpdate{
+		ServiceName: serviceName,
+		Message:     fmt.Sprintf("Host %s removed from inventory %d", vm.Fqdn, s.inventoryID),
+	}
+
+	return nil
+}
+
+func (s *TowerService) findHost(ctx context.Context, name string) (*Host, error) {
+	u := fmt.Sprintf("%s/inventories/%d/hosts/?name=%s", s.baseURL, s.inventoryID, url.QueryEscape(name))
+
+	var found *Host
+	err := s.getAllPages(ctx, u, func(results json.RawMessage) error {
+		hosts := []*Host{}
+		err := json.Unmarshal(results, &hosts)
+		if err != nil {
+			return err
+		}
+
+		for _, h := range hosts {
+			if h.Name == name {
+				found = h
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not retrieve host %s", name)
+	}
+
+	return found, nil
+}
+
+func (s *TowerService) createHost(ctx context.Context, host *Host) (*Host, error) {
+	b, err := json.Marshal(host)
+	if err != nil {
+		return nil, err
+	}
+
+	u := fmt.Sprintf("%s/inventories/%d/hosts/", s.baseURL, s.inventoryID)
+	res, err := s.sendRequest(ctx, "POST", u, "application/json", string(b))
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not create host %s", host.Name)
+	}
+
+	if res.statusCode != http.StatusCreated {
+		return nil, errors.Wrapf(res.apiError(), "could not create host %s", host.Name)
+	}
+
+	created := &Host{}
+	err = json.Unmarshal(res.body, created)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not parse created host")
+	}
+
+	return created, nil
+}
+
+func (s *TowerService) updateHostVariables(ctx context.Context, id uint, variables string) error {
+	b, err := json.Marshal(&Host{Variables: variables})
+	if err != nil {
+		return err
+	}
+
+	u := fmt.Sprintf("%s/hosts/%d/", s.baseURL, id)
+	res, err := s.sendRequest(ctx, "PATCH", u, "application/json", string(b))
+	if err != nil {
+		return errors.Wrapf(err, "could not update host %d", id)
+	}
+
+	if res.statusCode != http.StatusOK {
+		return errors.Wrapf(res.apiError(), "could not update host %d", id)
+	}
+
+	return nil
+}
+
+func (s *TowerService) addHostToGroup(ctx context.Context, id uint) error {
+	b, err := json.Marshal(&struct {
+		ID uint `json:"id"`
+	}{ID: id})
+	if err != nil {
+		return err
+	}
+
+	u := fmt.Sprintf("%s/groups/%d/hosts/", s.baseURL, s.groupID)
+	res, err := s.sendRequest(ctx, "POST", u, "application/json", string(b))
+	if err != nil {
+		return errors.Wrapf(err, "could not add host %d to group %d", id, s.groupID)
+	}
+
+	if res.statusCode != http.StatusNoContent {
+		return errors.Wrapf(res.apiError(), "could not add host %d to group %d", id, s.groupID)
+	}
+
+	return nil
+}
@@ -0,0 +1,117 @@
+package tower
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/MauveSoftware/provisionize/pkg/api/proto"
+)
+
+func TestInventoryHosts(t *testing.T) {
+	tests := []struct {
+		name          string
+		existingHosts string
+		groupID       uint
+		deprovision   bool
+		expectedCalls []string
+	}{
+		{
+			name:          "new host",
+			existingHosts: `{"results":[]}`,
+			groupID:       3,
+			expectedCalls: []string{
+				"GET /api/v2/inventories/2/hosts/",
+				"POST /api/v2/inventories/2/hosts/",
+				"POST /api/v2/groups/3/hosts/",
+			},
+		},
+		{
+			name:          "existing host without group",
+			existingHosts: `{"results":[{"id":5,"name":"web1.example.com"}]}`,
+			expectedCalls: []string{
+				"GET /api/v2/inventories/2/hosts/",
+				"PATCH /api/v2/hosts/5/",
+			},
+		},
+		{
+			name:          "remove host",
+			existingHosts: `{"results":[{"id":5,"name":"web1.example.com"}]}`,
+			deprovision:   true,
+			expectedCalls: []string{
+				"GET /api/v2/inventories/2/hosts/",
+				"DELETE /api/v2/hosts/5/",
+			},
+		},
+		{
+			name:          "remove unknown host",
+			existingHosts: `{"results":[{"id":6,"name":"web10.example.com"}]}`,
+			deprovision:   true,
+			expectedCalls: []string{
+				"GET /api/v2/inventories/2/hosts/",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			calls := []string{}
+			s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls = append(calls, r.Method+" "+r.URL.Path)
+
+				switch r.Method {
+				case "GET":
+					assert.Equal(t, "web1.example.com", r.URL.Query().Get("name"))
+					w.Write([]byte(test.existingHosts))
+				case "POST":
+					b, _ := ioutil.ReadAll(r.Body)
+					if r.URL.Path == "/api/v2/groups/3/hosts/" {
+						assert.JSONEq(t, `{"id":7}`, string(b))
+						w.WriteHeader(http.StatusNoContent)
+						return
+					}
+
+					host := &Host{}
+					assert.NoError(t, json.Unmarshal(b, host))
+					assert.Equal(t, "web1.example.com", host.Name)
+					assert.Contains(t, host.Variables, `"environment":"production"`)
+					w.WriteHeader(http.StatusCreated)
+					w.Write([]byte(`{"id":7,"name":"web1.example.com"}`))
+				case "PATCH":
+					w.Write([]byte(`{"id":5}`))
+				case "DELETE":
+					w.WriteHeader(http.StatusNoContent)
+				}
+			}))
+			defer s.Close()
+
+			ch := make(chan *proto.StatusUpdate)
+			defer close(ch)
+
+			go func() {
+				for update := range ch {
+					t.Log(update.Message)
+				}
+			}()
+
+			cfg := &mockConfigService{extraVars: map[string]interface{}{"environment": "production"}}
+			svc := NewService(s.URL, "test", "foo", cfg, WithInventory(2, test.groupID))
+			vm := &proto.VirtualMachine{Name: "web1", Fqdn: "web1.example.com"}
+
+			var result bool
+			if test.deprovision {
+				result = svc.Deprovision(context.Background(), vm, ch)
+			} else {
+				result = svc.Provision(context.Background(), vm, ch)
+			}
+
+			assert.True(t, result)
+			assert.Equal(t, test.expectedCalls, calls)
+		})
+	}
+}
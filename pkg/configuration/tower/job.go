@@ -6,4 +6,9 @@ type Job struct {
 	Name     string `json:"name"`
 	Playbook string `json:"playbook"`
 	Status   string `json:"status"`
+	Finished string `json:"finished"`
+}
+
+type jobList struct {
+	Results []*Job `json:"results"`
 }
@@ -0,0 +1,90 @@
+package tower
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+
+	"github.com/MauveSoftware/provisionize/pkg/api/proto"
+	"github.com/MauveSoftware/provisionize/pkg/utils"
+)
+
+const (
+	jobEventsPageSize = 100
+	maxOutputLength   = 4096
+)
+
+type jobEvent struct {
+	Counter uint   `json:"counter"`
+	Event   string `json:"event"`
+	Play    string `json:"play"`
+	Task    string `json:"task"`
+	Host    string `json:"host_name"`
+	Changed bool   `json:"changed"`
+	Stdout  string `json:"stdout"`
+}
+
+type jobEventList struct {
+	Next    string      `json:"next"`
+	Results []*jobEvent `json:"results"`
+}
+
+// pushJobEvents sends the task results of the job with a counter greater than after as status updates.
+// It returns the counter of the last event received.
+func (s *TowerService) pushJobEvents(ctx context.Context, id uint, after uint, ch chan<- *proto.StatusUpdate) (uint, error) {
+	for {
+		url := fmt.Sprintf("%s/jobs/%d/job_events/?counter__gt=%d&order_by=counter&page_size=%d", s.baseURL, id, after, jobEventsPageSize)
+		list := &jobEventList{}
+		_, err := s.getAndParse(ctx, url, list)
+		if err != nil {
+			return after, errors.Wrapf(err, "could not get events of job %d", id)
+		}
+
+		for _, e := range list.Results {
+			after = e.Counter
+
+			if update := e.statusUpdate(); update != nil {
+				ch <- update
+			}
+		}
+
+		if len(list.Next) == 0 || len(list.Results) == 0 {
+			return after, nil
+		}
+	}
+}
+
+// statusUpdate returns the update for a task result or nil for other events
+func (e *jobEvent) statusUpdate() *proto.StatusUpdate {
+	status := ""
+	switch e.Event {
+	case "runner_on_ok":
+		status = "ok"
+		if e.Changed {
+			status = "changed"
+		}
+	case "runner_on_skipped":
+		status = "skipped"
+	case "runner_on_failed":
+		status = "failed"
+	case "runner_on_unreachable":
+		status = "unreachable"
+	default:
+		return nil
+	}
+
+	update := &proto.StatusUpdate{
+		ServiceName:  serviceName,
+		Message:      fmt.Sprintf("[%s] %s | %s: %s", e.Play, e.Task, e.Host, status),
+		DebugMessage: utils.Truncate(e.Stdout, maxOutputLength),
+	}
+
+	if status == "failed" || status == "unreachable" {
+		update.Warning = true
+		update.Message += "\n" + utils.Truncate(e.Stdout, maxOutputLength)
+		update.DebugMessage = ""
+	}
+
+	return update
+}
@@ -0,0 +1,54 @@
+package tower
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/MauveSoftware/provisionize/pkg/api/proto"
+)
+
+func TestPushJobEvents(t *testing.T) {
+	pages := map[string]string{
+		"3": `{"next":"/api/v2/jobs/1/job_events/?page=2","results":[
+			{"counter":4,"event":"playbook_on_task_start","play":"base","task":"install packages"},
+			{"counter":5,"event":"runner_on_ok","play":"base","task":"install packages","host_name":"web1","changed":true,"stdout":"changed: [web1]"}]}`,
+		"5": `{"next":null,"results":[
+			{"counter":6,"event":"runner_on_failed","play":"base","task":"start nginx","host_name":"web1","stdout":"fatal: [web1]: FAILED!"}]}`,
+	}
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v2/jobs/1/job_events/", r.URL.Path)
+		assert.Equal(t, "counter", r.URL.Query().Get("order_by"))
+
+		page, found := pages[r.URL.Query().Get("counter__gt")]
+		if !found {
+			page = `{"results":[]}`
+		}
+		w.Write([]byte(page))
+	}))
+	defer s.Close()
+
+	ch := make(chan *proto.StatusUpdate, 10)
+	svc := NewService(s.URL, "test", "foo", &mockConfigService{})
+
+	counter, err := svc.pushJobEvents(context.Background(), 1, 3, ch)
+	require.NoError(t, err)
+	close(ch)
+
+	updates := []*proto.StatusUpdate{}
+	for u := range ch {
+		updates = append(updates, u)
+	}
+
+	expected := []*proto.StatusUpdate{
+		{ServiceName: serviceName, Message: "[base] install packages | web1: changed", DebugMessage: "changed: [web1]"},
+		{ServiceName: serviceName, Message: "[base] start nginx | web1: failed\nfatal: [web1]: FAILED!", Warning: true},
+	}
+	assert.Equal(t, uint(6), counter)
+	assert.Equal(t, expected, updates)
+}
@@ -4,7 +4,6 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
 	"net/http"
 	"strings"
 	"time"
@@ -12,7 +11,9 @@ import (
 	"github.com/pkg/errors"
 
 	"github.com/MauveSoftware/provisionize/pkg/api/proto"
+	"github.com/MauveSoftware/provisionize/pkg/utils"
 
+	"go.opencensus.io/plugin/ochttp"
 	"go.opencensus.io/trace"
 )
 
@@ -22,18 +23,44 @@ const (
 
 // TowerService is the service responsible for configuring the VM by using ansible tower
 type TowerService struct {
+	rootURL         string
 	baseURL         string
 	username        string
 	password        string
+	token           string
 	configService   ConfigService
 	client          *http.Client
 	waitTimeout     time.Duration
 	pollingInterval time.Duration
+	maxRetries      int
+	retryBackoff    time.Duration
+	inventoryID     uint
+	groupID         uint
 }
 
-type apiResponse struct {
-	statusCode int
-	body       []byte
+// Option configures optional behavior of TowerService
+type Option func(s *TowerService)
+
+// WithToken authenticates with an OAuth2 personal access token instead of username and password
+func WithToken(token string) Option {
+	return func(s *TowerService) {
+		s.token = token
+	}
+}
+
+// WithHTTPClient replaces the default HTTP client (e.g. to use a custom CA, see NewHTTPClient)
+func WithHTTPClient(c *http.Client) Option {
+	return func(s *TowerService) {
+		s.client = c
+	}
+}
+
+// WithInventory adds provisioned VMs as host to the inventory (and the group if groupID is not 0)
+func WithInventory(inventoryID, groupID uint) Option {
+	return func(s *TowerService) {
+		s.inventoryID = inventoryID
+		s.groupID = groupID
+	}
 }
 
 type jobFuncResult struct {
@@ -43,16 +70,25 @@ type jobFuncResult struct {
 }
 
 // NewService returns a new instance of TowerService
-func NewService(url, username, password string, configService ConfigService) *TowerService {
-	return &TowerService{
+func NewService(url, username, password string, configService ConfigService, opts ...Option) *TowerService {
+	s := &TowerService{
+		rootURL:         strings.TrimRight(url, "/"),
 		baseURL:         completeAPIURL(url),
 		username:        username,
 		password:        password,
 		configService:   configService,
-		client:          &http.Client{},
+		client:          &http.Client{Transport: &ochttp.Transport{}, Timeout: defaultRequestTimeout},
 		waitTimeout:     2 * time.Minute,
 		pollingInterval: 10 * time.Second,
+		maxRetries:      3,
+		retryBackoff:    time.Second,
 	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
 }
 
 func completeAPIURL(url string) string {
@@ -64,15 +100,38 @@ func (s *TowerService) Provision(ctx context.Context, vm *proto.VirtualMachine,
 	ctx, span := trace.StartSpan(ctx, "TowerService.Provision")
 	defer span.End()
 
+	if !s.ensureHost(ctx, vm, ch) {
+		return false
+	}
+
 	for _, id := range s.configService.TowerTemplateIDsForVM(vm) {
-		debugInfo, err := s.startJob(vm, id, ch)
+		debugInfo, err := s.startJob(ctx, vm, id, ch)
 		if err != nil {
-			ch <- &proto.StatusUpdate{
-				Failed:       true,
-				Message:      err.Error(),
-				ServiceName:  serviceName,
-				DebugMessage: debugInfo,
-			}
+			s.sendError(err, debugInfo, ch)
+			return false
+		}
+	}
+
+	for _, id := range s.configService.TowerWorkflowIDsForVM(vm) {
+		debugInfo, err := s.startWorkflow(ctx, vm, id, ch)
+		if err != nil {
+			s.sendError(err, debugInfo, ch)
+			return false
+		}
+	}
+
+	return true
+}
+
+// PreDeprovision runs the deprovisioning job templates while the VM still exists
+func (s *TowerService) PreDeprovision(ctx context.Context, vm *proto.VirtualMachine, ch chan<- *proto.StatusUpdate) bool {
+	ctx, span := trace.StartSpan(ctx, "TowerService.PreDeprovision")
+	defer span.End()
+
+	for _, id := range s.configService.TowerDeprovisionTemplateIDsForVM(vm) {
+		debugInfo, err := s.startJob(ctx, vm, id, ch)
+		if err != nil {
+			s.sendError(err, debugInfo, ch)
 			return false
 		}
 	}
@@ -80,14 +139,87 @@ func (s *TowerService) Provision(ctx context.Context, vm *proto.VirtualMachine,
 	return true
 }
 
-// Deprovision is called when VM is beeing removed
+// Deprovision removes the host of the VM from the inventory
 func (s *TowerService) Deprovision(ctx context.Context, vm *proto.VirtualMachine, ch chan<- *proto.StatusUpdate) bool {
-	// nothing to clean up in Tower
+	ctx, span := trace.StartSpan(ctx, "TowerService.Deprovision")
+	defer span.End()
+
+	if s.inventoryID == 0 {
+		return true
+	}
+
+	err := s.removeHost(ctx, vm, ch)
+	if err != nil {
+		s.sendError(err, "", ch)
+		return false
+	}
+
 	return true
 }
 
-func (s *TowerService) startJob(vm *proto.VirtualMachine, templateID uint, ch chan<- *proto.StatusUpdate) (debugInfo string, err error) {
-	res := s.postStartRequest(vm, templateID, ch)
+// Restore provisions a VM taken from the recycle bin again to revert the changes of the deprovisioning job templates
+func (s *TowerService) Restore(ctx context.Context, vm *proto.VirtualMachine, ch chan<- *proto.StatusUpdate) bool {
+	return s.Provision(ctx, vm, ch)
+}
+
+func (s *TowerService) ensureHost(ctx context.Context, vm *proto.VirtualMachine, ch chan<- *proto.StatusUpdate) bool {
+	if s.inventoryID == 0 {
+		return true
+	}
+
+	err := s.addHost(ctx, vm, ch)
+	if err != nil {
+		s.sendError(err, "", ch)
+		return false
+	}
+
+	return true
+}
+
+func (s *TowerService) sendError(err error, debugInfo string, ch chan<- *proto.StatusUpdate) {
+	ch <- &proto.StatusUpdate{
+		Failed:       true,
+		Message:      err.Error(),
+		ServiceName:  serviceName,
+		DebugMessage: debugInfo,
+	}
+}
+
+// JobTemplateExists checks if a job template with the given ID exists in Tower
+func (s *TowerService) JobTemplateExists(ctx context.Context, id uint) (bool, error) {
+	ctx, span := trace.StartSpan(ctx, "TowerService.JobTemplateExists")
+	defer span.End()
+
+	return s.templateExists(ctx, "job_templates", "job template", id)
+}
+
+// WorkflowTemplateExists checks if a workflow job template with the given ID exists in Tower
+func (s *TowerService) WorkflowTemplateExists(ctx context.Context, id uint) (bool, error) {
+	ctx, span := trace.StartSpan(ctx, "TowerService.WorkflowTemplateExists")
+	defer span.End()
+
+	return s.templateExists(ctx, "workflow_job_templates", "workflow job template", id)
+}
+
+func (s *TowerService) templateExists(ctx context.Context, path, kind string, id uint) (bool, error) {
+	url := fmt.Sprintf("%s/%s/%d/", s.baseURL, path, id)
+	res, err := s.sendRequest(ctx, "GET", url, "application/json", "")
+	if err != nil {
+		return false, errors.Wrapf(err, "could not retrieve %s %d", kind, id)
+	}
+
+	switch res.statusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	default:
+		return false, errors.Wrapf(res.apiError(), "could not retrieve %s %d", kind, id)
+	}
+}
+
+func (s *TowerService) startJob(ctx context.Context, vm *proto.VirtualMachine, templateID uint, ch chan<- *proto.StatusUpdate) (debugInfo string, err error) {
+	res := s.postStartRequest(ctx, vm, templateID, ch)
 	if res.err != nil {
 		return res.debugMessage, res.err
 	}
@@ -99,16 +231,15 @@ func (s *TowerService) startJob(vm *proto.VirtualMachine, templateID uint, ch ch
 		DebugMessage: res.debugMessage,
 	}
 
-	d, err := s.waitForJobToComplete(job, ch)
+	return s.waitForJobToComplete(ctx, job, ch)
+}
+
+func (s *TowerService) postStartRequest(ctx context.Context, vm *proto.VirtualMachine, templateID uint, ch chan<- *proto.StatusUpdate) *jobFuncResult {
+	body, err := launchRequestBody(vm, s.configService.TowerExtraVarsForVM(vm))
 	if err != nil {
-		return d, err
+		return &jobFuncResult{err: errors.Wrap(err, "could not serialize launch request")}
 	}
 
-	return d, s.pushStdOut(job.ID, ch)
-}
-
-func (s *TowerService) postStartRequest(vm *proto.VirtualMachine, templateID uint, ch chan<- *proto.StatusUpdate) *jobFuncResult {
-	body := fmt.Sprintf(`{"limit": "%s", "extra_vars": "ansible_ssh_host: %s"}`, vm.Fqdn, vm.Ipv4.Address)
 	url := fmt.Sprintf("%s/job_templates/%d/launch/", s.baseURL, templateID)
 
 	ch <- &proto.StatusUpdate{
@@ -117,7 +248,7 @@ func (s *TowerService) postStartRequest(vm *proto.VirtualMachine, templateID uin
 		DebugMessage: fmt.Sprintf("URL: %s\nBody: %s", url, body),
 	}
 
-	res, err := s.sendRequest("POST", url, "application/json", body)
+	res, err := s.sendRequest(ctx, "POST", url, "application/json", body)
 	if err != nil {
 		return &jobFuncResult{err: err}
 	}
@@ -125,7 +256,7 @@ func (s *TowerService) postStartRequest(vm *proto.VirtualMachine, templateID uin
 	if res.statusCode != http.StatusCreated {
 		return &jobFuncResult{
 			debugMessage: string(res.body),
-			err:          errors.New(fmt.Sprintf("could not start job (status code %d)", res.statusCode)),
+			err:          errors.Wrap(res.apiError(), "could not start job"),
 		}
 	}
 
@@ -141,19 +272,29 @@ func (s *TowerService) postStartRequest(vm *proto.VirtualMachine, templateID uin
 	return &jobFuncResult{job: job, debugMessage: string(res.body)}
 }
 
-func (s *TowerService) waitForJobToComplete(job *Job, ch chan<- *proto.StatusUpdate) (debugMessage string, err error) {
+// waitForJobToComplete polls the job and streams the results of its tasks until it completed
+func (s *TowerService) waitForJobToComplete(ctx context.Context, job *Job, ch chan<- *proto.StatusUpdate) (debugMessage string, err error) {
 	status := job.Status
+	timeout := time.After(s.waitTimeout)
+	var counter uint
 
 	for {
 		select {
-		case <-time.After(s.waitTimeout):
+		case <-timeout:
 			return "", errors.New("Operation timed out")
+		case <-ctx.Done():
+			return "", ctx.Err()
 		case <-time.After(s.pollingInterval):
-			res := s.getJobUpdate(job.ID)
+			res := s.getJobUpdate(ctx, job.ID)
 			if res.err != nil {
 				return res.debugMessage, errors.Wrap(res.err, "could not get job status update")
 			}
 
+			counter, err = s.pushJobEvents(ctx, job.ID, counter, ch)
+			if err != nil {
+				return res.debugMessage, err
+			}
+
 			if status != res.job.Status {
 				status = res.job.Status
 				ch <- &proto.StatusUpdate{
@@ -163,22 +304,29 @@ func (s *TowerService) waitForJobToComplete(job *Job, ch chan<- *proto.StatusUpd
 				}
 			}
 
-			if res.job.Status == "successful" {
+			switch res.job.Status {
+			case "successful":
 				return res.debugMessage, nil
-			}
+			case "failed", "error", "canceled":
+				if counter == 0 {
+					// no task was run (e.g. syntax error), so the output is the only hint
+					s.pushStdOut(ctx, res.job.ID, ch)
+				}
+
+				if res.job.Status == "failed" {
+					return res.debugMessage, errors.New("Failed running playbook")
+				}
 
-			if res.job.Status == "failed" {
-				s.pushStdOut(res.job.ID, ch)
-				return res.debugMessage, errors.New("Failed running playbook")
+				return res.debugMessage, fmt.Errorf("Job %d %s", res.job.ID, res.job.Status)
 			}
 		}
 	}
 }
 
-func (s *TowerService) getJobUpdate(id uint) *jobFuncResult {
+func (s *TowerService) getJobUpdate(ctx context.Context, id uint) *jobFuncResult {
 	url := fmt.Sprintf("%s/jobs/%d", s.baseURL, id)
 
-	res, err := s.sendRequest("GET", url, "application/json", "")
+	res, err := s.sendRequest(ctx, "GET", url, "application/json", "")
 	if err != nil {
 		return &jobFuncResult{err: err}
 	}
@@ -186,7 +334,7 @@ func (s *TowerService) getJobUpdate(id uint) *jobFuncResult {
 	if res.statusCode != http.StatusOK {
 		return &jobFuncResult{
 			debugMessage: string(res.body),
-			err:          fmt.Errorf("could not get status update for job %d", id),
+			err:          errors.Wrapf(res.apiError(), "could not get status update for job %d", id),
 		}
 	}
 
@@ -202,41 +350,18 @@ func (s *TowerService) getJobUpdate(id uint) *jobFuncResult {
 	return &jobFuncResult{job: job, debugMessage: string(res.body)}
 }
 
-func (s *TowerService) pushStdOut(id uint, ch chan<- *proto.StatusUpdate) error {
+func (s *TowerService) pushStdOut(ctx context.Context, id uint, ch chan<- *proto.StatusUpdate) error {
 	url := fmt.Sprintf("%s/jobs/%d/stdout?format=txt", s.baseURL, id)
 
-	res, err := s.sendRequest("GET", url, "text/plain", "")
+	res, err := s.sendRequest(ctx, "GET", url, "text/plain", "")
 	if err != nil {
 		return errors.Wrap(err, "could not retrieve output for job")
 	}
 
 	ch <- &proto.StatusUpdate{
 		ServiceName: serviceName,
-		Message:     string(res.body),
+		Message:     utils.Truncate(string(res.body), maxOutputLength),
 	}
 
 	return nil
 }
-
-func (s *TowerService) sendRequest(method, url, contentType, body string) (*apiResponse, error) {
-	req, err := http.NewRequest(method, url, strings.NewReader(body))
-	if err != nil {
-		return nil, errors.Wrapf(err, "could not create request with URI %s", url)
-	}
-
-	req.SetBasicAuth(s.username, s.password)
-	req.Header.Set("content-type", contentType)
-
-	resp, err := s.client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	b, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return nil, errors.Wrap(err, "could not read from response")
-	}
-
-	return &apiResponse{body: b, statusCode: resp.StatusCode}, nil
-}
@@ -14,7 +14,10 @@ import (
 )
 
 type mockConfigService struct {
-	count uint
+	count       uint
+	workflows   []uint
+	deprovision []uint
+	extraVars   map[string]interface{}
 }
 
 func (m *mockConfigService) TowerTemplateIDsForVM(vm *proto.VirtualMachine) []uint {
@@ -26,12 +29,25 @@ func (m *mockConfigService) TowerTemplateIDsForVM(vm *proto.VirtualMachine) []ui
 	return ids
 }
 
+func (m *mockConfigService) TowerWorkflowIDsForVM(vm *proto.VirtualMachine) []uint {
+	return m.workflows
+}
+
+func (m *mockConfigService) TowerDeprovisionTemplateIDsForVM(vm *proto.VirtualMachine) []uint {
+	return m.deprovision
+}
+
+func (m *mockConfigService) TowerExtraVarsForVM(vm *proto.VirtualMachine) map[string]interface{} {
+	return m.extraVars
+}
+
 func TestProvision(t *testing.T) {
 	tests := []struct {
 		name          string
 		templateCount uint
 		expectedCalls int
 		statusCodes   []int
+		jobStatus     string
 		expectFail    bool
 	}{
 		{
@@ -54,23 +70,43 @@ func TestProvision(t *testing.T) {
 			statusCodes:   []int{201, 500},
 			expectFail:    true,
 		},
+		{
+			name:          "job error",
+			templateCount: 1,
+			expectedCalls: 1,
+			statusCodes:   []int{201},
+			jobStatus:     "error",
+			expectFail:    true,
+		},
+		{
+			name:          "job canceled",
+			templateCount: 1,
+			expectedCalls: 1,
+			statusCodes:   []int{201},
+			jobStatus:     "canceled",
+			expectFail:    true,
+		},
 	}
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
+			jobStatus := test.jobStatus
+			if len(jobStatus) == 0 {
+				jobStatus = "successful"
+			}
+
 			call := 0
 			handler := func(w http.ResponseWriter, r *http.Request) {
 				if r.Method == "GET" {
-					if strings.HasSuffix(r.URL.Path, "/jobs/1") {
+					switch {
+					case strings.HasSuffix(r.URL.Path, "/jobs/1"):
 						w.WriteHeader(http.StatusOK)
-						w.Write([]byte(`{"id":1, "status":"successful"}`))
-					} else {
-						w.WriteHeader(http.StatusNotFound)
-					}
-
-					if strings.HasPrefix(r.URL.Path, "stdout?format=txt") {
+						w.Write([]byte(`{"id":1, "status":"` + jobStatus + `"}`))
+					case strings.HasSuffix(r.URL.Path, "/jobs/1/job_events/"):
 						w.WriteHeader(http.StatusOK)
-						w.Write([]byte("All done!"))
+						w.Write([]byte(`{"results":[]}`))
+					default:
+						w.WriteHeader(http.StatusNotFound)
 					}
 
 					return
@@ -111,3 +147,132 @@ func TestProvision(t *testing.T) {
 		})
 	}
 }
+
+func TestJobTemplateExists(t *testing.T) {
+	tests := []struct {
+		name        string
+		statusCode  int
+		expected    bool
+		expectError bool
+	}{
+		{
+			name:       "existing template",
+			statusCode: http.StatusOK,
+			expected:   true,
+		},
+		{
+			name:       "unknown template",
+			statusCode: http.StatusNotFound,
+			expected:   false,
+		},
+		{
+			name:        "server error",
+			statusCode:  http.StatusInternalServerError,
+			expectError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "/api/v2/job_templates/42/", r.URL.Path)
+				w.WriteHeader(test.statusCode)
+			}))
+			defer s.Close()
+
+			svc := NewService(s.URL, "test", "foo", &mockConfigService{})
+			svc.retryBackoff = time.Millisecond
+			found, err := svc.JobTemplateExists(context.Background(), 42)
+			if test.expectError {
+				assert.Error(t, err)
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assert.Equal(t, test.expected, found)
+		})
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v2/jobs/", r.URL.Path)
+		assert.Equal(t, "web1.example.com", r.URL.Query().Get("limit"))
+		assert.Equal(t, "-created", r.URL.Query().Get("order_by"))
+
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"count":2,"results":[
+			{"id":12,"name":"base","status":"failed","finished":"2020-01-02T10:00:00Z"},
+			{"id":11,"name":"base","status":"successful","finished":"2020-01-01T10:00:00Z"}]}`))
+	}))
+	defer s.Close()
+
+	svc := NewService(s.URL, "test", "foo", &mockConfigService{})
+	state := &proto.VMState{}
+	err := svc.Describe(context.Background(), &proto.VirtualMachine{Fqdn: "web1.example.com"}, state)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []*proto.TowerJob{
+		{Id: 12, Name: "base", Status: "failed", Finished: "2020-01-02T10:00:00Z"},
+		{Id: 11, Name: "base", Status: "successful", Finished: "2020-01-01T10:00:00Z"},
+	}
+	assert.Equal(t, expected, state.TowerJobs)
+}
+
+func TestDeprovision(t *testing.T) {
+	calls := []string{}
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, r.Method+" "+r.URL.Path)
+
+		switch {
+		case r.Method == "POST":
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"id":1}`))
+		case strings.HasSuffix(r.URL.Path, "/jobs/1"):
+			w.Write([]byte(`{"id":1, "status":"successful"}`))
+		case strings.HasSuffix(r.URL.Path, "/job_events/"):
+			w.Write([]byte(`{"results":[]}`))
+		case strings.HasSuffix(r.URL.Path, "/hosts/"):
+			w.Write([]byte(`{"results":[{"id":5,"name":"web1.example.com"}]}`))
+		case r.Method == "DELETE":
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer s.Close()
+
+	ch := make(chan *proto.StatusUpdate)
+	defer close(ch)
+
+	go func() {
+		for update := range ch {
+			t.Log(update.Message)
+		}
+	}()
+
+	svc := NewService(s.URL, "test", "foo", &mockConfigService{deprovision: []uint{4}}, WithInventory(2, 0))
+	svc.pollingInterval = 10 * time.Millisecond
+
+	vm := &proto.VirtualMachine{Name: "web1", Fqdn: "web1.example.com"}
+	assert.True(t, svc.PreDeprovision(context.Background(), vm, ch))
+
+	expected := []string{
+		"POST /api/v2/job_templates/4/launch/",
+		"GET /api/v2/jobs/1",
+		"GET /api/v2/jobs/1/job_events/",
+	}
+	assert.Equal(t, expected, calls)
+
+	calls = nil
+	assert.True(t, svc.Deprovision(context.Background(), vm, ch))
+
+	expected = []string{
+		"GET /api/v2/inventories/2/hosts/",
+		"DELETE /api/v2/hosts/5/",
+	}
+	assert.Equal(t, expected, calls)
+}
@@ -0,0 +1,125 @@
+package tower
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/MauveSoftware/provisionize/pkg/api/proto"
+)
+
+// WorkflowJob represents a workflow job in tower
+type WorkflowJob struct {
+	ID     uint   `json:"id"`
+	Name   string `json:"name"`
+	Status string `json:"status"`
+}
+
+type workflowNode struct {
+	ID            uint `json:"id"`
+	SummaryFields struct {
+		Job *Job `json:"job"`
+	} `json:"summary_fields"`
+}
+
+func (s *TowerService) startWorkflow(ctx context.Context, vm *proto.VirtualMachine, templateID uint, ch chan<- *proto.StatusUpdate) (debugInfo string, err error) {
+	body, err := launchRequestBody(vm, s.configService.TowerExtraVarsForVM(vm))
+	if err != nil {
+		return "", errors.Wrap(err, "could not serialize launch request")
+	}
+
+	url := fmt.Sprintf("%s/workflow_job_templates/%d/launch/", s.baseURL, templateID)
+	ch <- &proto.StatusUpdate{
+		Message:      fmt.Sprintf("Starting workflow with template %d", templateID),
+		ServiceName:  serviceName,
+		DebugMessage: fmt.Sprintf("URL: %s\nBody: %s", url, body),
+	}
+
+	res, err := s.sendRequest(ctx, "POST", url, "application/json", body)
+	if err != nil {
+		return "", err
+	}
+
+	if res.statusCode != http.StatusCreated {
+		return string(res.body), errors.Wrap(res.apiError(), "could not start workflow")
+	}
+
+	job := &WorkflowJob{}
+	err = json.Unmarshal(res.body, job)
+	if err != nil {
+		return string(res.body), errors.Wrap(err, "could not parse result to workflow job")
+	}
+
+	ch <- &proto.StatusUpdate{
+		Message:      fmt.Sprintf("Start workflow job %d (%s)", job.ID, job.Name),
+		ServiceName:  serviceName,
+		DebugMessage: string(res.body),
+	}
+
+	return s.waitForWorkflowToComplete(ctx, job, ch)
+}
+
+// waitForWorkflowToComplete polls the workflow job and reports every status change of its nodes
+func (s *TowerService) waitForWorkflowToComplete(ctx context.Context, job *WorkflowJob, ch chan<- *proto.StatusUpdate) (debugMessage string, err error) {
+	timeout := time.After(s.waitTimeout)
+	nodeStatus := make(map[uint]string)
+
+	for {
+		select {
+		case <-timeout:
+			return "", errors.New("Operation timed out")
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(s.pollingInterval):
+			err := s.reportWorkflowNodes(ctx, job.ID, nodeStatus, ch)
+			if err != nil {
+				return "", err
+			}
+
+			current := &WorkflowJob{}
+			d, err := s.getAndParse(ctx, fmt.Sprintf("%s/workflow_jobs/%d/", s.baseURL, job.ID), current)
+			if err != nil {
+				return d, errors.Wrapf(err, "could not get status update for workflow job %d", job.ID)
+			}
+
+			switch current.Status {
+			case "successful":
+				return d, nil
+			case "failed", "error", "canceled":
+				return d, fmt.Errorf("Workflow job %d %s", job.ID, current.Status)
+			}
+		}
+	}
+}
+
+func (s *TowerService) reportWorkflowNodes(ctx context.Context, id uint, nodeStatus map[uint]string, ch chan<- *proto.StatusUpdate) error {
+	nodes := []*workflowNode{}
+	err := s.getAllPages(ctx, fmt.Sprintf("%s/workflow_jobs/%d/workflow_nodes/", s.baseURL, id), func(results json.RawMessage) error {
+		page := []*workflowNode{}
+		err := json.Unmarshal(results, &page)
+		nodes = append(nodes, page...)
+		return err
+	})
+	if err != nil {
+		return errors.Wrapf(err, "could not get nodes of workflow job %d", id)
+	}
+
+	for _, n := range nodes {
+		job := n.SummaryFields.Job
+		if job == nil || nodeStatus[n.ID] == job.Status {
+			continue
+		}
+
+		nodeStatus[n.ID] = job.Status
+		ch <- &proto.StatusUpdate{
+			Message:     fmt.Sprintf("Workflow node %d: job %d (%s) %s", n.ID, job.ID, job.Name, job.Status),
+			ServiceName: serviceName,
+		}
+	}
+
+	return nil
+}
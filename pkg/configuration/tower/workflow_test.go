@@ -0,0 +1,96 @@
+package tower
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/MauveSoftware/provisionize/pkg/api/proto"
+)
+
+func TestProvisionWorkflow(t *testing.T) {
+	tests := []struct {
+		name            string
+		finalStatus     string
+		expectFail      bool
+		expectedUpdates []string
+	}{
+		{
+			name:        "successful workflow",
+			finalStatus: "successful",
+			expectedUpdates: []string{
+				"Starting workflow with template 10",
+				"Start workflow job 20 (deploy)",
+				"Workflow node 1: job 30 (base) running",
+				"Workflow node 1: job 30 (base) successful",
+			},
+		},
+		{
+			name:        "failed workflow",
+			finalStatus: "failed",
+			expectFail:  true,
+			expectedUpdates: []string{
+				"Starting workflow with template 10",
+				"Start workflow job 20 (deploy)",
+				"Workflow node 1: job 30 (base) running",
+				"Workflow node 1: job 30 (base) failed",
+				"Workflow job 20 failed",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			polls := 0
+			s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch {
+				case r.Method == "POST" && r.URL.Path == "/api/v2/workflow_job_templates/10/launch/":
+					w.WriteHeader(http.StatusCreated)
+					w.Write([]byte(`{"id":20,"name":"deploy","status":"pending"}`))
+				case strings.HasSuffix(r.URL.Path, "/workflow_nodes/"):
+					polls++
+					status := "running"
+					if polls > 1 {
+						status = test.finalStatus
+					}
+					w.Write([]byte(`{"results":[{"id":1,"summary_fields":{"job":{"id":30,"name":"base","status":"` + status + `"}}},{"id":2,"summary_fields":{}}]}`))
+				case r.URL.Path == "/api/v2/workflow_jobs/20/":
+					status := "running"
+					if polls > 1 {
+						status = test.finalStatus
+					}
+					w.Write([]byte(`{"id":20,"status":"` + status + `"}`))
+				default:
+					w.WriteHeader(http.StatusNotFound)
+				}
+			}))
+			defer s.Close()
+
+			ch := make(chan *proto.StatusUpdate)
+			updates := []string{}
+			done := make(chan struct{})
+			go func() {
+				for update := range ch {
+					updates = append(updates, update.Message)
+				}
+				close(done)
+			}()
+
+			svc := NewService(s.URL, "test", "foo", &mockConfigService{workflows: []uint{10}})
+			svc.pollingInterval = 10 * time.Millisecond
+
+			vm := &proto.VirtualMachine{Name: "web1", Fqdn: "web1.example.com"}
+			result := svc.Provision(context.Background(), vm, ch)
+			close(ch)
+			<-done
+
+			assert.Equal(t, !test.expectFail, result, "unexpected fail")
+			assert.Equal(t, test.expectedUpdates, updates)
+		})
+	}
+}
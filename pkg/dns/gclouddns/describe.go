@@ -0,0 +1,127 @@
+package gclouddns
+
+import (
+	"context"
+	"net"
+	"strings"
+
+	"go.opencensus.io/trace"
+	"google.golang.org/api/dns/v1"
+
+	"github.com/MauveSoftware/provisionize/pkg/api/proto"
+	pdns "github.com/MauveSoftware/provisionize/pkg/dns"
+	"github.com/MauveSoftware/provisionize/pkg/utils"
+)
+
+// Describe adds the A, AAAA and PTR records, aliases and extra records of the VM present in the managed zones to state
+func (s *GoogleCloudDNSService) Describe(ctx context.Context, vm *proto.VirtualMachine, state *proto.VMState) error {
+	ctx, span := trace.StartSpan(ctx, "GoogleCloudDNSService.Describe")
+	defer span.End()
+
+	if len(vm.Fqdn) == 0 {
+		return nil
+	}
+
+	zones, err := s.listZones(ctx)
+	if err != nil {
+		return err
+	}
+
+	return s.newZoneRecords(zones, nil).describe(ctx, vm, state)
+}
+
+// DescribeAll adds the records of each VM to its state. The records of each zone are retrieved only once.
+func (s *GoogleCloudDNSService) DescribeAll(ctx context.Context, states []*proto.VMState) []error {
+	ctx, span := trace.StartSpan(ctx, "GoogleCloudDNSService.DescribeAll")
+	defer span.End()
+
+	errs := make([]error, len(states))
+
+	zones, err := s.listZones(ctx)
+	z := s.newZoneRecords(zones, nil)
+	for i, state := range states {
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+
+		if len(state.VirtualMachine.Fqdn) > 0 {
+			errs[i] = z.describe(ctx, state.VirtualMachine, state)
+		}
+	}
+
+	return errs
+}
+
+func (z *zoneRecords) describe(ctx context.Context, vm *proto.VirtualMachine, state *proto.VMState) error {
+	name := z.svc.hostDNSName(vm)
+	recs, err := z.lookup(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	hostRecords := filterRecords(recs, name, "A", "AAAA")
+	state.DnsRecords = append(state.DnsRecords, hostRecords...)
+
+	for _, addr := range describedAddresses(vm, hostRecords) {
+		ip := net.ParseIP(addr)
+		if ip == nil {
+			continue
+		}
+
+		ptrName := pdns.ReverseDomain(ip) + "."
+		if z.svc.findZone(pdns.ReverseDomain(ip), z.zones) == nil {
+			continue
+		}
+
+		recs, err := z.lookup(ctx, ptrName)
+		if err != nil {
+			return err
+		}
+
+		state.DnsRecords = append(state.DnsRecords, filterRecords(recs, ptrName, "PTR")...)
+	}
+
+	for _, r := range z.svc.additionalRecords(vm) {
+		if z.svc.findZone(strings.TrimSuffix(r.Name, "."), z.zones) == nil {
+			continue
+		}
+
+		recs, err := z.lookup(ctx, r.Name)
+		if err != nil {
+			return err
+		}
+
+		state.DnsRecords = append(state.DnsRecords, filterRecords(recs, r.Name, r.Type)...)
+	}
+
+	return nil
+}
+
+// filterRecords returns the records with the given name and one of the given types
+func filterRecords(recs []*dns.ResourceRecordSet, name string, types ...string) []*proto.DNSRecord {
+	res := []*proto.DNSRecord{}
+	for _, rec := range recs {
+		if rec.Name != name {
+			continue
+		}
+
+		for _, t := range types {
+			if rec.Type == t {
+				res = append(res, &proto.DNSRecord{Name: rec.Name, Type: rec.Type, Values: rec.Rrdatas, Ttl: rec.Ttl})
+			}
+		}
+	}
+
+	return res
+}
+
+// describedAddresses returns the addresses of the host records and the interfaces of the VM
+func describedAddresses(vm *proto.VirtualMachine, hostRecords []*proto.DNSRecord) []string {
+	addrs := []string{}
+	for _, rec := range hostRecords {
+		addrs = utils.AppendMissing(addrs, rec.Values...)
+	}
+
+	return utils.AppendMissing(addrs, interfaceAddresses(vm)...)
+}
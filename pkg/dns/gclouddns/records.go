@@ -0,0 +1,179 @@
+package gclouddns
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	"go.opencensus.io/trace"
+	"google.golang.org/api/dns/v1"
+
+	"github.com/MauveSoftware/provisionize/pkg/api/proto"
+)
+
+// managedHostTypes are the record types of the FQDN of the VM managed by the service itself
+var managedHostTypes = map[string]bool{"A": true, "AAAA": true, "CNAME": true}
+
+// forbiddenTypes are record types which can not be set as extra record since they affect the delegation of a zone
+var forbiddenTypes = map[string]bool{"NS": true, "SOA": true}
+
+// additionalRecords returns the CNAME records for the aliases and the extra records of the VM with absolute names
+func (s *GoogleCloudDNSService) additionalRecords(vm *proto.VirtualMachine) []*proto.DNSRecord {
+	host := s.hostDNSName(vm)
+
+	recs := []*proto.DNSRecord{}
+	for _, alias := range vm.DnsAliases {
+		recs = append(recs, &proto.DNSRecord{Name: absoluteName(alias, host), Type: "CNAME", Values: []string{host}})
+	}
+
+	for _, r := range vm.DnsRecords {
+		recs = append(recs, &proto.DNSRecord{
+			Name:   absoluteName(r.Name, host),
+			Type:   strings.ToUpper(r.Type),
+			Values: r.Values,
+			Ttl:    r.Ttl,
+		})
+	}
+
+	return recs
+}
+
+// absoluteName returns the FQDN (with trailing dot) for name. An empty name or @ refers to the FQDN of the VM.
+func absoluteName(name, host string) string {
+	if len(name) == 0 || name == "@" {
+		return host
+	}
+
+	return strings.Trim(name, ".") + "."
+}
+
+// validateRecords checks the aliases and extra records of the VM before any record is changed
+func (s *GoogleCloudDNSService) validateRecords(vm *proto.VirtualMachine) error {
+	host := s.hostDNSName(vm)
+	seen := make(map[string]bool)
+
+	for _, alias := range vm.DnsAliases {
+		name := absoluteName(alias, host)
+		if name == host {
+			return fmt.Errorf("alias %s must differ from the FQDN of the VM", alias)
+		}
+
+		if !s.allowedName(name, host) {
+			return fmt.Errorf("alias %s is neither a subdomain of %s nor in one of the alias domains", name, host)
+		}
+
+		// records of a zone are retrieved once, so each record may only be changed once
+		key := "CNAME " + name
+		if seen[key] {
+			return fmt.Errorf("alias %s defined more than once", name)
+		}
+		seen[key] = true
+	}
+
+	for _, r := range vm.DnsRecords {
+		name := absoluteName(r.Name, host)
+		recType := strings.ToUpper(r.Type)
+
+		if len(recType) == 0 || len(r.Values) == 0 {
+			return fmt.Errorf("record %s requires a type and at least one value", name)
+		}
+
+		if forbiddenTypes[recType] {
+			return fmt.Errorf("%s records can not be set as extra record", recType)
+		}
+
+		if !s.allowedName(name, host) {
+			return fmt.Errorf("record %s is neither named like %s or one of its subdomains nor in one of the alias domains", name, host)
+		}
+
+		if name == host && managedHostTypes[recType] {
+			return fmt.Errorf("%s record for %s is managed by provisionize and can not be set as extra record", recType, name)
+		}
+
+		key := recType + " " + name
+		if seen[key] {
+			return fmt.Errorf("%s record for %s defined more than once", recType, name)
+		}
+		seen[key] = true
+	}
+
+	return nil
+}
+
+// allowedName checks if an alias or extra record may be named name: the FQDN of the VM, its subdomains and the configured alias domains are allowed
+func (s *GoogleCloudDNSService) allowedName(name, host string) bool {
+	if inDomain(name, host) {
+		return true
+	}
+
+	for _, d := range s.aliasDomains {
+		if inDomain(name, d) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// inDomain checks if the absolute name equals or is a subdomain of the absolute domain
+func inDomain(name, domain string) bool {
+	name = strings.ToLower(name)
+	domain = strings.ToLower(domain)
+
+	return name == domain || strings.HasSuffix(name, "."+domain)
+}
+
+func (s *GoogleCloudDNSService) ensureAdditionalRecordsExist(ctx context.Context, vm *proto.VirtualMachine, zones []*dns.ManagedZone,
+	ch chan<- *proto.StatusUpdate) error {
+	ctx, span := trace.StartSpan(ctx, "GoogleCloudDNSService.ensureAdditionalRecordsExist")
+	defer span.End()
+
+	zr := s.newZoneRecords(zones, ch)
+	for _, r := range s.additionalRecords(vm) {
+		z, recs, err := zr.zone(ctx, r.Name)
+		if err != nil {
+			return err
+		}
+
+		ttl := r.Ttl
+		if ttl == 0 {
+			ttl = int64(vm.DnsTtl)
+		}
+
+		err = z.ensureRecordExists(z.record(r.Name, r.Type, ttl, r.Values...), recs)
+		if err != nil {
+			return errors.Wrapf(err, "could not create %s record for %s in %s", r.Type, r.Name, z.name)
+		}
+	}
+
+	return nil
+}
+
+// ensureAdditionalRecordsAbsent removes the aliases and extra records. Records not named like the VM are only removed if their values match.
+func (s *GoogleCloudDNSService) ensureAdditionalRecordsAbsent(ctx context.Context, vm *proto.VirtualMachine, zones []*dns.ManagedZone,
+	ch chan<- *proto.StatusUpdate) error {
+	ctx, span := trace.StartSpan(ctx, "GoogleCloudDNSService.ensureAdditionalRecordsAbsent")
+	defer span.End()
+
+	host := s.hostDNSName(vm)
+	zr := s.newZoneRecords(zones, ch)
+	for _, r := range s.additionalRecords(vm) {
+		z, recs, err := zr.zone(ctx, r.Name)
+		if err != nil {
+			return err
+		}
+
+		values := r.Values
+		if r.Name == host {
+			values = nil
+		}
+
+		err = z.ensureRecordAbsent(r.Name, r.Type, recs, values...)
+		if err != nil {
+			return errors.Wrapf(err, "could not remove %s record for %s in %s", r.Type, r.Name, z.name)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,511 @@
+package gclouddns
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/dns/v1"
+
+	"github.com/MauveSoftware/provisionize/pkg/api/proto"
+)
+
+// fakeCloudDNS is a minimal in memory implementation of the Cloud DNS API
+type fakeCloudDNS struct {
+	mu      sync.Mutex
+	zones   []*dns.ManagedZone
+	records map[string][]*dns.ResourceRecordSet
+	changes int
+	lists   int
+
+	// pageSize limits the number of record sets per page of a list response (0 returns all in one page)
+	pageSize int
+}
+
+func (f *fakeCloudDNS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/dns/v1"), "/"), "/")
+	switch {
+	case len(parts) == 3:
+		json.NewEncoder(w).Encode(&dns.ManagedZonesListResponse{ManagedZones: f.zones})
+	case len(parts) == 5 && parts[4] == "rrsets":
+		f.lists++
+		json.NewEncoder(w).Encode(f.page(parts[3], r.URL.Query().Get("pageToken")))
+	case len(parts) == 5 && parts[4] == "changes":
+		c := &dns.Change{}
+		json.NewDecoder(r.Body).Decode(c)
+		f.apply(parts[3], c)
+		json.NewEncoder(w).Encode(c)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (f *fakeCloudDNS) page(zone, token string) *dns.ResourceRecordSetsListResponse {
+	recs := f.records[zone]
+	if f.pageSize == 0 {
+		return &dns.ResourceRecordSetsListResponse{Rrsets: recs}
+	}
+
+	start, _ := strconv.Atoi(token)
+	end := start + f.pageSize
+	if end >= len(recs) {
+		return &dns.ResourceRecordSetsListResponse{Rrsets: recs[start:]}
+	}
+
+	return &dns.ResourceRecordSetsListResponse{Rrsets: recs[start:end], NextPageToken: strconv.Itoa(end)}
+}
+
+func (f *fakeCloudDNS) apply(zone string, c *dns.Change) {
+	f.changes++
+
+	recs := []*dns.ResourceRecordSet{}
+	for _, rec := range f.records[zone] {
+		deleted := false
+		for _, d := range c.Deletions {
+			deleted = deleted || (d.Name == rec.Name && d.Type == rec.Type)
+		}
+
+		if !deleted {
+			recs = append(recs, rec)
+		}
+	}
+
+	f.records[zone] = append(recs, c.Additions...)
+}
+
+func (f *fakeCloudDNS) find(zone, name, recType string) *dns.ResourceRecordSet {
+	for _, rec := range f.records[zone] {
+		if rec.Name == name && rec.Type == recType {
+			return rec
+		}
+	}
+
+	return nil
+}
+
+type fakeResolver struct {
+	ips []net.IP
+}
+
+func (r *fakeResolver) LookupIP(ctx context.Context, network, host string) ([]net.IP, error) {
+	return r.ips, nil
+}
+
+func newTestService(t *testing.T, f *fakeCloudDNS, opts ...Option) *GoogleCloudDNSService {
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+
+	svc, err := dns.New(srv.Client())
+	require.NoError(t, err)
+	svc.BasePath = srv.URL + "/"
+
+	s := &GoogleCloudDNSService{service: svc, projectID: "test", ttl: defaultTTL, zoneTTLs: make(map[string]int64), resolver: &fakeResolver{}}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+func newFakeCloudDNS() *fakeCloudDNS {
+	return &fakeCloudDNS{
+		zones: []*dns.ManagedZone{
+			{Name: "example", DnsName: "example.com."},
+			{Name: "reverse", DnsName: "1.168.192.in-addr.arpa."},
+			{Name: "services", DnsName: "services.example.org."},
+		},
+		records: map[string][]*dns.ResourceRecordSet{},
+	}
+}
+
+func drain(ch chan *proto.StatusUpdate) []*proto.StatusUpdate {
+	close(ch)
+
+	updates := []*proto.StatusUpdate{}
+	for u := range ch {
+		updates = append(updates, u)
+	}
+
+	return updates
+}
+
+func TestProvisionRecords(t *testing.T) {
+	f := newFakeCloudDNS()
+	s := newTestService(t, f, WithTTL(600), WithZoneTTL("services.example.org", 60), WithAliasDomains("example.com", "services.example.org."))
+
+	vm := &proto.VirtualMachine{
+		Name:       "web1",
+		Fqdn:       "web1.example.com",
+		Ipv4:       &proto.IPConfig{Address: "192.168.1.10"},
+		DnsAliases: []string{"www.example.com", "shop.services.example.org."},
+		DnsRecords: []*proto.DNSRecord{
+			{Type: "sshfp", Values: []string{"1 2 abcdef"}},
+			{Name: "_acme.web1.example.com", Type: "TXT", Values: []string{`"token"`}, Ttl: 30},
+		},
+	}
+	require.NoError(t, vm.NormalizeInterfaces())
+
+	ch := make(chan *proto.StatusUpdate, 100)
+	require.True(t, s.Provision(context.Background(), vm, ch), drain(ch))
+
+	expected := map[string]*dns.ResourceRecordSet{
+		"example A web1.example.com.":               {Ttl: 600, Rrdatas: []string{"192.168.1.10"}},
+		"reverse PTR 10.1.168.192.in-addr.arpa.":    {Ttl: 600, Rrdatas: []string{"web1.example.com."}},
+		"example CNAME www.example.com.":            {Ttl: 600, Rrdatas: []string{"web1.example.com."}},
+		"services CNAME shop.services.example.org.": {Ttl: 60, Rrdatas: []string{"web1.example.com."}},
+		"example SSHFP web1.example.com.":           {Ttl: 600, Rrdatas: []string{"1 2 abcdef"}},
+		"example TXT _acme.web1.example.com.":       {Ttl: 30, Rrdatas: []string{`"token"`}},
+	}
+	for key, e := range expected {
+		p := strings.Split(key, " ")
+		rec := f.find(p[0], p[2], p[1])
+		if assert.NotNil(t, rec, key) {
+			assert.Equal(t, e.Ttl, rec.Ttl, key)
+			assert.Equal(t, e.Rrdatas, rec.Rrdatas, key)
+		}
+	}
+
+	t.Run("idempotent", func(t *testing.T) {
+		changes := f.changes
+
+		ch := make(chan *proto.StatusUpdate, 100)
+		assert.True(t, s.Provision(context.Background(), vm, ch))
+		assert.Equal(t, changes, f.changes)
+	})
+
+	t.Run("request TTL", func(t *testing.T) {
+		vm.DnsTtl = 120
+		vm.DnsRecords = nil
+		vm.DnsAliases = nil
+
+		ch := make(chan *proto.StatusUpdate, 100)
+		assert.True(t, s.Provision(context.Background(), vm, ch))
+
+		updates := drain(ch)
+		assert.True(t, updates[0].Warning)
+		assert.Equal(t, "A record for web1.example.com. already exists with different value (web1.example.com.\t600\tA\t192.168.1.10): skipping", updates[0].Message)
+		assert.Equal(t, int64(600), f.find("example", "web1.example.com.", "A").Ttl)
+	})
+}
+
+func TestProvisionUpdateExisting(t *testing.T) {
+	f := newFakeCloudDNS()
+	f.records["example"] = []*dns.ResourceRecordSet{
+		{Name: "web1.example.com.", Type: "A", Ttl: 300, Rrdatas: []string{"192.168.1.99"}},
+		{Name: "www.example.com.", Type: "CNAME", Ttl: 300, Rrdatas: []string{"web2.example.com."}},
+	}
+	s := newTestService(t, f, WithUpdateExisting(), WithAliasDomains("example.com"))
+
+	vm := &proto.VirtualMachine{
+		Name:       "web1",
+		Fqdn:       "web1.example.com",
+		Ipv4:       &proto.IPConfig{Address: "192.168.1.10"},
+		DnsAliases: []string{"www.example.com"},
+	}
+	require.NoError(t, vm.NormalizeInterfaces())
+
+	ch := make(chan *proto.StatusUpdate, 100)
+	assert.True(t, s.Provision(context.Background(), vm, ch))
+
+	assert.Equal(t, []string{"192.168.1.10"}, f.find("example", "web1.example.com.", "A").Rrdatas)
+	assert.Equal(t, []string{"web1.example.com."}, f.find("example", "www.example.com.", "CNAME").Rrdatas)
+	assert.Contains(t, drain(ch)[0].Message, "Updated: web1.example.com.\t300\tA\t192.168.1.10")
+}
+
+func TestProvisionRecordsNormalized(t *testing.T) {
+	f := newFakeCloudDNS()
+	f.records["example"] = []*dns.ResourceRecordSet{
+		{Name: "_acme.web1.example.com.", Type: "TXT", Ttl: 300, Rrdatas: []string{`"token"`}},
+	}
+	s := newTestService(t, f, WithAliasDomains("example.com"))
+
+	vm := &proto.VirtualMachine{
+		Name: "web1",
+		Fqdn: "web1.example.com",
+		DnsRecords: []*proto.DNSRecord{
+			{Name: "_acme.web1.example.com", Type: "TXT", Values: []string{"token"}},
+			{Name: "mail.web1.example.com", Type: "MX", Values: []string{"10 mx.example.com"}},
+		},
+	}
+	require.NoError(t, vm.NormalizeInterfaces())
+
+	zones, err := s.listZones(context.Background())
+	require.NoError(t, err)
+
+	ch := make(chan *proto.StatusUpdate, 100)
+	require.NoError(t, s.ensureAdditionalRecordsExist(context.Background(), vm, zones, ch))
+
+	updates := drain(ch)
+	assert.Equal(t, "TXT record for _acme.web1.example.com. already exists: skipping", updates[0].Message)
+	assert.False(t, updates[0].Warning)
+	assert.Equal(t, []string{"10 mx.example.com."}, f.find("example", "mail.web1.example.com.", "MX").Rrdatas)
+}
+
+func TestProvisionRecordsPaged(t *testing.T) {
+	f := newFakeCloudDNS()
+	f.pageSize = 1
+	f.records["example"] = []*dns.ResourceRecordSet{
+		{Name: "example.com.", Type: "SOA", Ttl: 300, Rrdatas: []string{"ns1 admin 1 2 3 4 5"}},
+		{Name: "example.com.", Type: "NS", Ttl: 300, Rrdatas: []string{"ns1.example.com."}},
+		{Name: "www.example.com.", Type: "CNAME", Ttl: 300, Rrdatas: []string{"web1.example.com."}},
+	}
+	s := newTestService(t, f, WithAliasDomains("example.com"))
+
+	vm := &proto.VirtualMachine{
+		Name:       "web1",
+		Fqdn:       "web1.example.com",
+		DnsAliases: []string{"www.example.com", "api.example.com"},
+		DnsRecords: []*proto.DNSRecord{{Type: "TXT", Values: []string{"x"}}},
+	}
+	require.NoError(t, vm.NormalizeInterfaces())
+
+	zones, err := s.listZones(context.Background())
+	require.NoError(t, err)
+
+	ch := make(chan *proto.StatusUpdate, 100)
+	require.NoError(t, s.ensureAdditionalRecordsExist(context.Background(), vm, zones, ch))
+
+	assert.Equal(t, "CNAME record for www.example.com. already exists: skipping", drain(ch)[0].Message)
+	assert.NotNil(t, f.find("example", "api.example.com.", "CNAME"))
+	assert.NotNil(t, f.find("example", "web1.example.com.", "TXT"))
+	assert.Equal(t, 3, f.lists, "zone is retrieved once (3 pages)")
+}
+
+func TestAdditionalRecordsAbsent(t *testing.T) {
+	f := newFakeCloudDNS()
+	f.records["example"] = []*dns.ResourceRecordSet{
+		{Name: "www.example.com.", Type: "CNAME", Ttl: 300, Rrdatas: []string{"web1.example.com."}},
+		{Name: "api.example.com.", Type: "CNAME", Ttl: 300, Rrdatas: []string{"web2.example.com."}},
+		{Name: "web1.example.com.", Type: "SSHFP", Ttl: 300, Rrdatas: []string{"1 2 000000"}},
+	}
+	s := newTestService(t, f, WithAliasDomains("example.com"))
+
+	vm := &proto.VirtualMachine{
+		Name:       "web1",
+		Fqdn:       "web1.example.com",
+		DnsAliases: []string{"www.example.com", "api.example.com"},
+		DnsRecords: []*proto.DNSRecord{{Type: "SSHFP", Values: []string{"1 2 abcdef"}}},
+	}
+
+	zones, err := s.listZones(context.Background())
+	require.NoError(t, err)
+
+	ch := make(chan *proto.StatusUpdate, 100)
+	require.NoError(t, s.ensureAdditionalRecordsAbsent(context.Background(), vm, zones, ch))
+
+	assert.Nil(t, f.find("example", "www.example.com.", "CNAME"))
+	assert.NotNil(t, f.find("example", "api.example.com.", "CNAME"), "alias pointing to another host must be kept")
+	assert.Nil(t, f.find("example", "web1.example.com.", "SSHFP"))
+}
+
+func TestDeprovisionRecords(t *testing.T) {
+	f := newFakeCloudDNS()
+	s := newTestService(t, f, WithAliasDomains("example.com", "services.example.org"))
+
+	vm := &proto.VirtualMachine{
+		Name:       "web1",
+		Fqdn:       "web1.example.com",
+		Ipv4:       &proto.IPConfig{Address: "192.168.1.10"},
+		DnsAliases: []string{"www.example.com", "shop.services.example.org"},
+		DnsRecords: []*proto.DNSRecord{
+			{Name: "@", Type: "SSHFP", Values: []string{"1 2 abcdef"}},
+			{Name: "_acme.web1.example.com", Type: "TXT", Values: []string{`"token"`}},
+		},
+	}
+	require.NoError(t, vm.NormalizeInterfaces())
+
+	ch := make(chan *proto.StatusUpdate, 100)
+	require.True(t, s.Provision(context.Background(), vm, ch), drain(ch))
+
+	// the deprovisionizer passes the aliases and records again, addresses are resolved
+	s.resolver = &fakeResolver{ips: []net.IP{net.ParseIP("192.168.1.10")}}
+	req := &proto.VirtualMachine{
+		Name:       "web1",
+		Fqdn:       "web1.example.com",
+		DnsAliases: vm.DnsAliases,
+		DnsRecords: vm.DnsRecords,
+	}
+	require.NoError(t, req.NormalizeInterfaces())
+
+	ch = make(chan *proto.StatusUpdate, 100)
+	require.True(t, s.Deprovision(context.Background(), req, ch), drain(ch))
+
+	assert.Empty(t, f.records["example"])
+	assert.Empty(t, f.records["services"])
+	assert.Empty(t, f.records["reverse"])
+}
+
+func TestRecycleAndRestoreRecords(t *testing.T) {
+	f := newFakeCloudDNS()
+	s := newTestService(t, f)
+
+	vm := &proto.VirtualMachine{
+		Name: "web1",
+		Fqdn: "web1.example.com",
+		Ipv4: &proto.IPConfig{Address: "192.168.1.10"},
+	}
+	require.NoError(t, vm.NormalizeInterfaces())
+
+	ch := make(chan *proto.StatusUpdate, 100)
+	require.True(t, s.Provision(context.Background(), vm, ch), drain(ch))
+
+	// the deprovision request contains no addresses, they are taken from the kept records
+	req := &proto.VirtualMachine{Name: "web1", Fqdn: "web1.example.com"}
+	require.NoError(t, req.NormalizeInterfaces())
+
+	ch = make(chan *proto.StatusUpdate, 100)
+	require.True(t, s.Recycle(context.Background(), req, ch), drain(ch))
+
+	assert.NotNil(t, f.find("example", "web1.example.com.", "A"))
+	assert.NotNil(t, f.find("reverse", "10.1.168.192.in-addr.arpa.", "PTR"))
+	assert.Equal(t, "192.168.1.10", req.PrimaryInterface().Ipv4.Address)
+
+	f.records = make(map[string][]*dns.ResourceRecordSet)
+
+	ch = make(chan *proto.StatusUpdate, 100)
+	require.True(t, s.Restore(context.Background(), req, ch), drain(ch))
+
+	assert.NotNil(t, f.find("example", "web1.example.com.", "A"))
+	assert.NotNil(t, f.find("reverse", "10.1.168.192.in-addr.arpa.", "PTR"))
+
+	ch = make(chan *proto.StatusUpdate, 100)
+	require.True(t, s.Purge(context.Background(), req, ch), drain(ch))
+
+	assert.Empty(t, f.records["example"])
+	assert.Empty(t, f.records["reverse"])
+}
+
+func TestValidateRecords(t *testing.T) {
+	s := newTestService(t, newFakeCloudDNS(), WithAliasDomains("example.com"))
+
+	tests := []struct {
+		name        string
+		vm          *proto.VirtualMachine
+		expectError string
+	}{
+		{
+			name: "valid",
+			vm: &proto.VirtualMachine{
+				Fqdn:       "web1.example.com",
+				DnsAliases: []string{"www.example.com"},
+				DnsRecords: []*proto.DNSRecord{{Type: "SSHFP", Values: []string{"1 2 abc"}}, {Name: "@", Type: "TXT", Values: []string{"x"}}},
+			},
+		},
+		{
+			name:        "alias of itself",
+			vm:          &proto.VirtualMachine{Fqdn: "web1.example.com", DnsAliases: []string{"web1.example.com."}},
+			expectError: "alias web1.example.com. must differ from the FQDN of the VM",
+		},
+		{
+			name: "subdomain of the VM",
+			vm:   &proto.VirtualMachine{Fqdn: "web1.example.com", DnsAliases: []string{"api.web1.example.com"}},
+		},
+		{
+			name:        "alias outside alias domains",
+			vm:          &proto.VirtualMachine{Fqdn: "web1.example.com", DnsAliases: []string{"www.example.net"}},
+			expectError: "alias www.example.net. is neither a subdomain of web1.example.com. nor in one of the alias domains",
+		},
+		{
+			name:        "record outside alias domains",
+			vm:          &proto.VirtualMachine{Fqdn: "web1.example.com", DnsRecords: []*proto.DNSRecord{{Name: "example.net", Type: "TXT", Values: []string{"x"}}}},
+			expectError: "record example.net. is neither named like web1.example.com. or one of its subdomains nor in one of the alias domains",
+		},
+		{
+			name:        "NS record",
+			vm:          &proto.VirtualMachine{Fqdn: "web1.example.com", DnsRecords: []*proto.DNSRecord{{Name: "sub.web1.example.com", Type: "ns", Values: []string{"ns1.example.net."}}}},
+			expectError: "NS records can not be set as extra record",
+		},
+		{
+			name:        "SOA record",
+			vm:          &proto.VirtualMachine{Fqdn: "web1.example.com", DnsRecords: []*proto.DNSRecord{{Type: "SOA", Values: []string{"ns1 admin 1 2 3 4 5"}}}},
+			expectError: "SOA records can not be set as extra record",
+		},
+		{
+			name:        "managed type",
+			vm:          &proto.VirtualMachine{Fqdn: "web1.example.com", DnsRecords: []*proto.DNSRecord{{Type: "a", Values: []string{"192.168.1.1"}}}},
+			expectError: "A record for web1.example.com. is managed by provisionize and can not be set as extra record",
+		},
+		{
+			name:        "missing values",
+			vm:          &proto.VirtualMachine{Fqdn: "web1.example.com", DnsRecords: []*proto.DNSRecord{{Name: "x.example.com", Type: "TXT"}}},
+			expectError: "record x.example.com. requires a type and at least one value",
+		},
+		{
+			name: "duplicate",
+			vm: &proto.VirtualMachine{Fqdn: "web1.example.com", DnsRecords: []*proto.DNSRecord{
+				{Type: "TXT", Values: []string{"a"}},
+				{Name: "web1.example.com", Type: "txt", Values: []string{"b"}},
+			}},
+			expectError: "TXT record for web1.example.com. defined more than once",
+		},
+		{
+			name:        "duplicate alias",
+			vm:          &proto.VirtualMachine{Fqdn: "web1.example.com", DnsAliases: []string{"www.example.com", "www.example.com."}},
+			expectError: "alias www.example.com. defined more than once",
+		},
+		{
+			name: "alias defined as record",
+			vm: &proto.VirtualMachine{
+				Fqdn:       "web1.example.com",
+				DnsAliases: []string{"www.example.com"},
+				DnsRecords: []*proto.DNSRecord{{Name: "www.example.com", Type: "CNAME", Values: []string{"web2.example.com."}}},
+			},
+			expectError: "CNAME record for www.example.com. defined more than once",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := s.validateRecords(test.vm)
+			if len(test.expectError) == 0 {
+				assert.NoError(t, err)
+				return
+			}
+
+			assert.EqualError(t, err, test.expectError)
+		})
+	}
+}
+
+func TestDescribeAll(t *testing.T) {
+	f := newFakeCloudDNS()
+	f.records["example"] = []*dns.ResourceRecordSet{
+		{Name: "web1.example.com.", Type: "A", Ttl: 300, Rrdatas: []string{"192.168.1.10"}},
+		{Name: "web2.example.com.", Type: "A", Ttl: 300, Rrdatas: []string{"192.168.1.11"}},
+	}
+	f.records["reverse"] = []*dns.ResourceRecordSet{
+		{Name: "10.1.168.192.in-addr.arpa.", Type: "PTR", Ttl: 300, Rrdatas: []string{"web1.example.com."}},
+	}
+	s := newTestService(t, f)
+
+	states := []*proto.VMState{
+		{VirtualMachine: &proto.VirtualMachine{Name: "web1", Fqdn: "web1.example.com"}},
+		{VirtualMachine: &proto.VirtualMachine{Name: "web2", Fqdn: "web2.example.com"}},
+		{VirtualMachine: &proto.VirtualMachine{Name: "web3"}},
+		{VirtualMachine: &proto.VirtualMachine{Name: "mail", Fqdn: "mail.example.net"}},
+	}
+	errs := s.DescribeAll(context.Background(), states)
+
+	assert.Equal(t, []*proto.DNSRecord{
+		{Name: "web1.example.com.", Type: "A", Ttl: 300, Values: []string{"192.168.1.10"}},
+		{Name: "10.1.168.192.in-addr.arpa.", Type: "PTR", Ttl: 300, Values: []string{"web1.example.com."}},
+	}, states[0].DnsRecords)
+	assert.Equal(t, []*proto.DNSRecord{{Name: "web2.example.com.", Type: "A", Ttl: 300, Values: []string{"192.168.1.11"}}}, states[1].DnsRecords)
+	assert.Empty(t, states[2].DnsRecords)
+	assert.NoError(t, errs[0])
+	assert.NoError(t, errs[1])
+	assert.NoError(t, errs[2])
+	assert.EqualError(t, errs[3], "no zone found for mail.example.net")
+	assert.Equal(t, 2, f.lists, "each zone is retrieved once")
+}
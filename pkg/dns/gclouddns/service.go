@@ -6,6 +6,7 @@ import (
 	"io"
 	"io/ioutil"
 	"net"
+	"net/http"
 	"strings"
 
 	"github.com/pkg/errors"
@@ -14,27 +15,72 @@ import (
 	pdns "github.com/MauveSoftware/provisionize/pkg/dns"
 	"github.com/MauveSoftware/provisionize/pkg/utils"
 
+	"go.opencensus.io/plugin/ochttp"
 	"go.opencensus.io/trace"
+	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/google"
 	"google.golang.org/api/dns/v1"
 )
 
 const serviceName = "Google Cloud DNS"
 
+// Resolver is used to determine the addresses of a VM not known from the request when its records are removed
+type Resolver interface {
+	LookupIP(ctx context.Context, network, host string) ([]net.IP, error)
+}
+
 // GoogleCloudDNSService creates DNS records in Google Cloud DNS
 type GoogleCloudDNSService struct {
-	service   *dns.Service
-	projectID string
+	service        *dns.Service
+	projectID      string
+	ttl            int64
+	zoneTTLs       map[string]int64
+	updateExisting bool
+	aliasDomains   []string
+	resolver       Resolver
+}
+
+// Option configures optional behavior of GoogleCloudDNSService
+type Option func(s *GoogleCloudDNSService)
+
+// WithTTL sets the TTL of records created if neither the request nor the zone define one
+func WithTTL(ttl uint32) Option {
+	return func(s *GoogleCloudDNSService) {
+		s.ttl = int64(ttl)
+	}
+}
+
+// WithZoneTTL sets the TTL of records created in the zone with the given DNS name
+func WithZoneTTL(zone string, ttl uint32) Option {
+	return func(s *GoogleCloudDNSService) {
+		s.zoneTTLs[strings.Trim(zone, ".")+"."] = int64(ttl)
+	}
+}
+
+// WithUpdateExisting updates existing records whose value or TTL differs instead of skipping them
+func WithUpdateExisting() Option {
+	return func(s *GoogleCloudDNSService) {
+		s.updateExisting = true
+	}
+}
+
+// WithAliasDomains allows aliases and extra records in the given domains (and their subdomains) besides the FQDN of the VM and its subdomains
+func WithAliasDomains(domains ...string) Option {
+	return func(s *GoogleCloudDNSService) {
+		for _, d := range domains {
+			s.aliasDomains = append(s.aliasDomains, strings.Trim(d, ".")+".")
+		}
+	}
 }
 
 // NewDNSService creates a new instance of GoogleCloudDNSService
-func NewDNSService(projectID string, serviceAccountJSON io.Reader) (*GoogleCloudDNSService, error) {
+func NewDNSService(projectID string, serviceAccountJSON io.Reader, opts ...Option) (*GoogleCloudDNSService, error) {
 	b, err := ioutil.ReadAll(serviceAccountJSON)
 	if err != nil {
 		return nil, errors.Wrap(err, "could not read credentials")
 	}
 
-	ctx := context.Background()
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, &http.Client{Transport: &ochttp.Transport{}})
 	cfg, err := google.JWTConfigFromJSON(b, dns.CloudPlatformScope)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed get credentials from JSON file")
@@ -45,10 +91,19 @@ func NewDNSService(projectID string, serviceAccountJSON io.Reader) (*GoogleCloud
 		return nil, errors.Wrap(err, "could not initialize DNS service")
 	}
 
-	return &GoogleCloudDNSService{
+	s := &GoogleCloudDNSService{
 		service:   service,
 		projectID: projectID,
-	}, nil
+		ttl:       defaultTTL,
+		zoneTTLs:  make(map[string]int64),
+		resolver:  net.DefaultResolver,
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s, nil
 }
 
 // Provision creates DNS records for the virtual machine
@@ -61,6 +116,12 @@ func (s *GoogleCloudDNSService) Provision(ctx context.Context, vm *proto.Virtual
 		return true
 	}
 
+	err := s.validateRecords(vm)
+	if err != nil {
+		ch <- &proto.StatusUpdate{ServiceName: serviceName, Failed: true, Message: err.Error()}
+		return false
+	}
+
 	zones, err := s.listZones(ctx)
 	if err != nil {
 		ch <- &proto.StatusUpdate{ServiceName: serviceName, Failed: true, Message: err.Error()}
@@ -79,6 +140,12 @@ func (s *GoogleCloudDNSService) Provision(ctx context.Context, vm *proto.Virtual
 		return false
 	}
 
+	err = s.ensureAdditionalRecordsExist(ctx, vm, zones, ch)
+	if err != nil {
+		ch <- &proto.StatusUpdate{ServiceName: serviceName, Failed: true, Message: err.Error()}
+		return false
+	}
+
 	return true
 }
 
@@ -92,12 +159,24 @@ func (s *GoogleCloudDNSService) Deprovision(ctx context.Context, vm *proto.Virtu
 		return true
 	}
 
+	err := s.validateRecords(vm)
+	if err != nil {
+		ch <- &proto.StatusUpdate{ServiceName: serviceName, Failed: true, Message: err.Error()}
+		return false
+	}
+
 	zones, err := s.listZones(ctx)
 	if err != nil {
 		ch <- &proto.StatusUpdate{ServiceName: serviceName, Failed: true, Message: err.Error()}
 		return false
 	}
 
+	err = s.ensureAdditionalRecordsAbsent(ctx, vm, zones, ch)
+	if err != nil {
+		ch <- &proto.StatusUpdate{ServiceName: serviceName, Failed: true, Message: err.Error()}
+		return false
+	}
+
 	err = s.ensureHostRecordsAbsent(ctx, vm, zones, ch)
 	if err != nil {
 		ch <- &proto.StatusUpdate{ServiceName: serviceName, Failed: true, Message: err.Error()}
@@ -113,11 +192,80 @@ func (s *GoogleCloudDNSService) Deprovision(ctx context.Context, vm *proto.Virtu
 	return true
 }
 
+// Recycle keeps the DNS records of a VM put into the recycle bin and records its addresses in vm,
+// so they are known when the VM is restored
+func (s *GoogleCloudDNSService) Recycle(ctx context.Context, vm *proto.VirtualMachine, ch chan<- *proto.StatusUpdate) bool {
+	ctx, span := trace.StartSpan(ctx, "GoogleCloudDNSService.Recycle")
+	defer span.End()
+
+	if len(vm.Fqdn) == 0 {
+		ch <- &proto.StatusUpdate{ServiceName: serviceName, Message: "No FQDN defined => skipping"}
+		return true
+	}
+
+	zones, err := s.listZones(ctx)
+	if err != nil {
+		ch <- &proto.StatusUpdate{ServiceName: serviceName, Failed: true, Message: err.Error()}
+		return false
+	}
+
+	ips, err := s.hostAddresses(ctx, vm, zones, ch)
+	if err != nil {
+		ch <- &proto.StatusUpdate{ServiceName: serviceName, Failed: true, Message: err.Error()}
+		return false
+	}
+
+	rememberAddresses(vm, ips)
+
+	ch <- &proto.StatusUpdate{ServiceName: serviceName, Message: fmt.Sprintf("Keeping DNS records of %s while VM is in recycle bin", vm.Fqdn)}
+	return true
+}
+
+// Restore ensures the DNS records of a VM taken from the recycle bin exist
+func (s *GoogleCloudDNSService) Restore(ctx context.Context, vm *proto.VirtualMachine, ch chan<- *proto.StatusUpdate) bool {
+	return s.Provision(ctx, vm, ch)
+}
+
+// Purge deletes the DNS records kept for a VM in the recycle bin
+func (s *GoogleCloudDNSService) Purge(ctx context.Context, vm *proto.VirtualMachine, ch chan<- *proto.StatusUpdate) bool {
+	return s.Deprovision(ctx, vm, ch)
+}
+
+// hostAddresses returns the addresses of the A and AAAA records of the VM
+func (s *GoogleCloudDNSService) hostAddresses(ctx context.Context, vm *proto.VirtualMachine, zones []*dns.ManagedZone, ch chan<- *proto.StatusUpdate) ([]net.IP, error) {
+	z, err := s.zoneForFQDN(ctx, vm.Fqdn, zones, ch)
+	if err != nil {
+		return nil, err
+	}
+
+	recs, err := z.records()
+	if err != nil {
+		return nil, err
+	}
+
+	name := s.hostDNSName(vm)
+	ips := []net.IP{}
+	for _, recType := range []string{"A", "AAAA"} {
+		rec, found := z.findRecordSet(name, recType, recs)
+		if !found {
+			continue
+		}
+
+		for _, v := range rec.Rrdatas {
+			if ip := net.ParseIP(v); ip != nil {
+				ips = append(ips, ip)
+			}
+		}
+	}
+
+	return ips, nil
+}
+
 func (s *GoogleCloudDNSService) listZones(ctx context.Context) ([]*dns.ManagedZone, error) {
 	ctx, span := trace.StartSpan(ctx, "GoogleCloudDNSService.listZones")
 	defer span.End()
 
-	resp, err := s.service.ManagedZones.List(s.projectID).Do()
+	resp, err := s.service.ManagedZones.List(s.projectID).Context(ctx).Do()
 	if err != nil {
 		return nil, errors.Wrap(err, "could not retrieve list of managed domains")
 	}
@@ -129,7 +277,7 @@ func (s *GoogleCloudDNSService) ensureHostRecordsExists(ctx context.Context, vm
 	ctx, span := trace.StartSpan(ctx, "GoogleCloudDNSService.ensureHostRecordsExists")
 	defer span.End()
 
-	z, err := s.zoneForFQDN(vm.Fqdn, zones, ch)
+	z, err := s.zoneForFQDN(ctx, vm.Fqdn, zones, ch)
 	if err != nil {
 		return err
 	}
@@ -140,15 +288,20 @@ func (s *GoogleCloudDNSService) ensureHostRecordsExists(ctx context.Context, vm
 	}
 
 	name := s.hostDNSName(vm)
+	primary := vm.PrimaryInterface()
 
-	err = z.ensureRecordExists(name, "A", vm.Ipv4.Address, recs)
-	if err != nil {
-		return errors.Wrapf(err, "could not create A record for %s in %s", name, z.name)
+	if addr := primary.Ipv4.GetAddress(); len(addr) > 0 {
+		err = z.ensureRecordExists(z.record(name, "A", int64(vm.DnsTtl), addr), recs)
+		if err != nil {
+			return errors.Wrapf(err, "could not create A record for %s in %s", name, z.name)
+		}
 	}
 
-	err = z.ensureRecordExists(name, "AAAA", vm.Ipv6.Address, recs)
-	if err != nil {
-		return errors.Wrapf(err, "could not create AAAA record for %s in %s", name, z.name)
+	if addr := primary.Ipv6.GetAddress(); len(addr) > 0 {
+		err = z.ensureRecordExists(z.record(name, "AAAA", int64(vm.DnsTtl), addr), recs)
+		if err != nil {
+			return errors.Wrapf(err, "could not create AAAA record for %s in %s", name, z.name)
+		}
 	}
 
 	return nil
@@ -158,7 +311,7 @@ func (s *GoogleCloudDNSService) ensureHostRecordsAbsent(ctx context.Context, vm
 	ctx, span := trace.StartSpan(ctx, "GoogleCloudDNSService.ensureHostRecordsAbsent")
 	defer span.End()
 
-	z, err := s.zoneForFQDN(vm.Fqdn, zones, ch)
+	z, err := s.zoneForFQDN(ctx, vm.Fqdn, zones, ch)
 	if err != nil {
 		return err
 	}
@@ -187,17 +340,25 @@ func (s *GoogleCloudDNSService) hostDNSName(vm *proto.VirtualMachine) string {
 	return strings.Trim(vm.Fqdn, ".") + "."
 }
 
-func (s *GoogleCloudDNSService) zoneForFQDN(fqdn string, zones []*dns.ManagedZone, ch chan<- *proto.StatusUpdate) (*zone, error) {
+func (s *GoogleCloudDNSService) zoneForFQDN(ctx context.Context, fqdn string, zones []*dns.ManagedZone, ch chan<- *proto.StatusUpdate) (*zone, error) {
 	managedZone := s.findZone(fqdn, zones)
 	if managedZone == nil {
 		return nil, fmt.Errorf("no zone found for %s", fqdn)
 	}
 
+	ttl, found := s.zoneTTLs[managedZone.DnsName]
+	if !found {
+		ttl = s.ttl
+	}
+
 	z := &zone{
-		name:      managedZone.Name,
-		projectID: s.projectID,
-		service:   s.service,
-		ch:        ch,
+		name:           managedZone.Name,
+		projectID:      s.projectID,
+		service:        s.service,
+		ttl:            ttl,
+		updateExisting: s.updateExisting,
+		ch:             ch,
+		ctx:            ctx,
 	}
 
 	return z, nil
@@ -209,27 +370,39 @@ func (s *GoogleCloudDNSService) ensurePTRRecordsExists(ctx context.Context, vm *
 	defer span.End()
 
 	name := s.hostDNSName(vm)
-	err := s.ensurePTRRecordExists(vm.Ipv4.Address, name, zones, ch)
-	if err != nil {
-		return errors.Wrap(err, "could not create PTR record for IPv4")
+	for _, addr := range interfaceAddresses(vm) {
+		err := s.ensurePTRRecordExists(ctx, addr, name, int64(vm.DnsTtl), zones, ch)
+		if err != nil {
+			return errors.Wrapf(err, "could not create PTR record for %s", addr)
+		}
 	}
 
-	err = s.ensurePTRRecordExists(vm.Ipv6.Address, name, zones, ch)
-	if err != nil {
-		return errors.Wrap(err, "could not create PTR record for IPv6")
+	return nil
+}
+
+// interfaceAddresses returns the addresses of all interfaces of the VM
+func interfaceAddresses(vm *proto.VirtualMachine) []string {
+	addrs := []string{}
+	for _, n := range vm.Interfaces {
+		for _, c := range []*proto.IPConfig{n.Ipv4, n.Ipv6} {
+			if len(c.GetAddress()) > 0 {
+				addrs = append(addrs, c.Address)
+			}
+		}
 	}
 
-	return nil
+	return addrs
 }
 
-func (s *GoogleCloudDNSService) ensurePTRRecordExists(addr string, value string, zones []*dns.ManagedZone, ch chan<- *proto.StatusUpdate) error {
+func (s *GoogleCloudDNSService) ensurePTRRecordExists(ctx context.Context, addr string, value string, ttl int64, zones []*dns.ManagedZone,
+	ch chan<- *proto.StatusUpdate) error {
 	ip := net.ParseIP(addr)
 	if ip == nil {
 		return fmt.Errorf("could not parse IP %s", ip)
 	}
 
 	fqdn := pdns.ReverseDomain(ip)
-	z, err := s.zoneForFQDN(fqdn, zones, ch)
+	z, err := s.zoneForFQDN(ctx, fqdn, zones, ch)
 	if err != nil {
 		return err
 	}
@@ -239,7 +412,7 @@ func (s *GoogleCloudDNSService) ensurePTRRecordExists(addr string, value string,
 		return errors.Wrapf(err, "could not retrieve record set for zone %s", z.name)
 	}
 
-	return z.ensureRecordExists(fqdn+".", "PTR", value, recs)
+	return z.ensureRecordExists(z.record(fqdn+".", "PTR", ttl, value), recs)
 }
 
 func (s *GoogleCloudDNSService) ensurePTRRecordsAbsent(ctx context.Context, vm *proto.VirtualMachine, zones []*dns.ManagedZone,
@@ -249,25 +422,61 @@ func (s *GoogleCloudDNSService) ensurePTRRecordsAbsent(ctx context.Context, vm *
 
 	name := s.hostDNSName(vm)
 
-	ips, err := net.LookupIP(vm.Fqdn)
+	ips, err := s.resolver.LookupIP(ctx, "ip", vm.Fqdn)
 	if err != nil {
 		return errors.Wrapf(err, "could not lookup A and AAAA records for %s", vm.Fqdn)
 	}
 
+	for _, addr := range interfaceAddresses(vm) {
+		ip := net.ParseIP(addr)
+		if ip != nil && !containsIP(ips, ip) {
+			ips = append(ips, ip)
+		}
+	}
+
+	rememberAddresses(vm, ips)
+
 	for _, ip := range ips {
-		s.ensurePTRRecordAbsent(ip, name, zones, ch)
+		s.ensurePTRRecordAbsent(ctx, ip, name, zones, ch)
 	}
 
 	return nil
 }
 
-func (s *GoogleCloudDNSService) ensurePTRRecordAbsent(ip net.IP, value string, zones []*dns.ManagedZone, ch chan<- *proto.StatusUpdate) error {
+// rememberAddresses sets the addresses of the primary interface not known from the request,
+// so the records can be restored if the VM is taken from the recycle bin
+func rememberAddresses(vm *proto.VirtualMachine, ips []net.IP) {
+	primary := vm.PrimaryInterface()
+
+	for _, ip := range ips {
+		cfg := primary.Ipv6
+		if ip.To4() != nil {
+			cfg = primary.Ipv4
+		}
+
+		if cfg != nil && len(cfg.Address) == 0 {
+			cfg.Address = ip.String()
+		}
+	}
+}
+
+func containsIP(ips []net.IP, ip net.IP) bool {
+	for _, x := range ips {
+		if x.Equal(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s *GoogleCloudDNSService) ensurePTRRecordAbsent(ctx context.Context, ip net.IP, value string, zones []*dns.ManagedZone, ch chan<- *proto.StatusUpdate) error {
 	if ip == nil {
 		return nil
 	}
 
 	fqdn := pdns.ReverseDomain(ip)
-	z, err := s.zoneForFQDN(fqdn, zones, ch)
+	z, err := s.zoneForFQDN(ctx, fqdn, zones, ch)
 	if err != nil {
 		return err
 	}
@@ -1,9 +1,13 @@
 package gclouddns
 
 import (
+	"net"
+	"testing"
+
 	"github.com/stretchr/testify/assert"
 	"google.golang.org/api/dns/v1"
-	"testing"
+
+	"github.com/MauveSoftware/provisionize/pkg/api/proto"
 )
 
 func TestFindZone(t *testing.T) {
@@ -64,3 +68,55 @@ func TestFindZone(t *testing.T) {
 		})
 	}
 }
+
+func TestInterfaceAddresses(t *testing.T) {
+	vm := &proto.VirtualMachine{
+		Interfaces: []*proto.NetworkInterface{
+			{
+				Name: "ens3",
+				Ipv4: &proto.IPConfig{Address: "192.168.1.100"},
+				Ipv6: &proto.IPConfig{Address: "2001:678:1e0::f00"},
+			},
+			{
+				Name: "ens4",
+				Ipv4: &proto.IPConfig{Address: "10.0.0.100"},
+				Ipv6: &proto.IPConfig{},
+			},
+			{
+				Name: "ens5",
+			},
+		},
+	}
+
+	assert.Equal(t, []string{"192.168.1.100", "2001:678:1e0::f00", "10.0.0.100"}, interfaceAddresses(vm))
+}
+
+func TestRememberAddresses(t *testing.T) {
+	vm := &proto.VirtualMachine{Ipv4: &proto.IPConfig{Address: "192.168.1.100"}}
+	assert.NoError(t, vm.NormalizeInterfaces())
+
+	rememberAddresses(vm, []net.IP{net.ParseIP("192.168.1.200"), net.ParseIP("2001:678:1e0::f00")})
+
+	assert.Equal(t, "192.168.1.100", vm.Ipv4.Address)
+	assert.Equal(t, "2001:678:1e0::f00", vm.Ipv6.Address)
+}
+
+func TestFilterRecords(t *testing.T) {
+	recs := []*dns.ResourceRecordSet{
+		{Name: "web1.example.com.", Type: "A", Rrdatas: []string{"192.168.1.10"}, Ttl: 300},
+		{Name: "web1.example.com.", Type: "TXT", Rrdatas: []string{"foo"}, Ttl: 300},
+		{Name: "web2.example.com.", Type: "A", Rrdatas: []string{"192.168.1.11"}, Ttl: 300},
+		{Name: "web1.example.com.", Type: "AAAA", Rrdatas: []string{"2001:db8::10"}, Ttl: 60},
+	}
+
+	expected := []*proto.DNSRecord{
+		{Name: "web1.example.com.", Type: "A", Values: []string{"192.168.1.10"}, Ttl: 300},
+		{Name: "web1.example.com.", Type: "AAAA", Values: []string{"2001:db8::10"}, Ttl: 60},
+	}
+	filtered := filterRecords(recs, "web1.example.com.", "A", "AAAA")
+	assert.Equal(t, expected, filtered)
+
+	vm := &proto.VirtualMachine{Ipv4: &proto.IPConfig{Address: "192.168.1.10"}, Ipv6: &proto.IPConfig{Address: "2001:db8::20"}}
+	assert.NoError(t, vm.NormalizeInterfaces())
+	assert.Equal(t, []string{"192.168.1.10", "2001:db8::10", "2001:db8::20"}, describedAddresses(vm, filtered))
+}
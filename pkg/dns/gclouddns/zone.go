@@ -1,13 +1,16 @@
 package gclouddns
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"sort"
+	"strings"
 
 	"github.com/MauveSoftware/provisionize/pkg/api/proto"
+	"github.com/MauveSoftware/provisionize/pkg/request"
 
 	"github.com/pkg/errors"
-	log "github.com/sirupsen/logrus"
 	"google.golang.org/api/dns/v1"
 )
 
@@ -16,47 +19,126 @@ const (
 )
 
 type zone struct {
-	service   *dns.Service
-	projectID string
-	name      string
-	ch        chan<- *proto.StatusUpdate
+	ctx            context.Context
+	service        *dns.Service
+	projectID      string
+	name           string
+	ttl            int64
+	updateExisting bool
+	ch             chan<- *proto.StatusUpdate
 }
 
+// zoneRecords caches the records of the managed zones for the duration of a request, so each zone is retrieved once
+type zoneRecords struct {
+	svc     *GoogleCloudDNSService
+	zones   []*dns.ManagedZone
+	ch      chan<- *proto.StatusUpdate
+	records map[string][]*dns.ResourceRecordSet
+}
+
+func (s *GoogleCloudDNSService) newZoneRecords(zones []*dns.ManagedZone, ch chan<- *proto.StatusUpdate) *zoneRecords {
+	return &zoneRecords{
+		svc:     s,
+		zones:   zones,
+		ch:      ch,
+		records: make(map[string][]*dns.ResourceRecordSet),
+	}
+}
+
+// zone returns the zone the absolute name belongs to and its records
+func (z *zoneRecords) zone(ctx context.Context, name string) (*zone, []*dns.ResourceRecordSet, error) {
+	zone, err := z.svc.zoneForFQDN(ctx, strings.TrimSuffix(name, "."), z.zones, z.ch)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if recs, found := z.records[zone.name]; found {
+		return zone, recs, nil
+	}
+
+	recs, err := zone.records()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	z.records[zone.name] = recs
+	return zone, recs, nil
+}
+
+// lookup returns the records of the zone the absolute name belongs to
+func (z *zoneRecords) lookup(ctx context.Context, name string) ([]*dns.ResourceRecordSet, error) {
+	_, recs, err := z.zone(ctx, name)
+	return recs, err
+}
+
+// records retrieves all record sets of the zone
 func (z *zone) records() ([]*dns.ResourceRecordSet, error) {
-	resp, err := z.service.ResourceRecordSets.List(z.projectID, z.name).Do()
+	recs := []*dns.ResourceRecordSet{}
+	err := z.service.ResourceRecordSets.List(z.projectID, z.name).Pages(z.ctx, func(page *dns.ResourceRecordSetsListResponse) error {
+		recs = append(recs, page.Rrsets...)
+		return nil
+	})
 	if err != nil {
 		return nil, errors.Wrapf(err, "could not retrieve record set for zone %s", z.name)
 	}
 
-	return resp.Rrsets, nil
+	return recs, nil
+}
+
+// record returns a record set for the zone. If ttl is 0 the TTL of the zone is used.
+func (z *zone) record(name, recType string, ttl int64, values ...string) *dns.ResourceRecordSet {
+	if ttl == 0 {
+		ttl = z.ttl
+	}
+
+	return &dns.ResourceRecordSet{
+		Type:    recType,
+		Name:    name,
+		Ttl:     ttl,
+		Rrdatas: normalizeValues(recType, values),
+	}
 }
 
-func (z *zone) ensureRecordExists(name, recType, value string, recs []*dns.ResourceRecordSet) error {
-	_, found := z.findRecordSet(name, recType, recs)
-	if found {
-		message := fmt.Sprintf("%s record for %s already exists: skipping", recType, name)
-		log.Info(message)
-		z.ch <- &proto.StatusUpdate{ServiceName: serviceName, Message: message}
+func (z *zone) ensureRecordExists(record *dns.ResourceRecordSet, recs []*dns.ResourceRecordSet) error {
+	existing, found := z.findRecordSet(record.Name, record.Type, recs)
+	if !found {
+		return z.createRecord(record)
+	}
+
+	if equalRecords(existing, record) {
+		z.skip(fmt.Sprintf("%s record for %s already exists: skipping", record.Type, record.Name), false)
+		return nil
+	}
 
+	if !z.updateExisting {
+		z.skip(fmt.Sprintf("%s record for %s already exists with different value (%s): skipping", record.Type, record.Name, describeRecord(existing)), true)
 		return nil
 	}
 
-	return z.createRecord(name, recType, value)
+	return z.replaceRecord(existing, record)
 }
 
-func (z *zone) ensureRecordAbsent(name, recType string, recs []*dns.ResourceRecordSet) error {
+// ensureRecordAbsent removes the record set. If values are given, it is only removed if its values match.
+func (z *zone) ensureRecordAbsent(name, recType string, recs []*dns.ResourceRecordSet, values ...string) error {
 	rec, found := z.findRecordSet(name, recType, recs)
 	if !found {
-		message := fmt.Sprintf("%s record for %s already removed: skipping", recType, name)
-		log.Info(message)
-		z.ch <- &proto.StatusUpdate{ServiceName: serviceName, Message: message}
+		z.skip(fmt.Sprintf("%s record for %s already removed: skipping", recType, name), false)
+		return nil
+	}
 
+	if len(values) > 0 && !equalValues(recType, rec.Rrdatas, values) {
+		z.skip(fmt.Sprintf("%s record for %s has a different value (%s): skipping", recType, name, describeRecord(rec)), true)
 		return nil
 	}
 
 	return z.removeRecord(rec)
 }
 
+func (z *zone) skip(message string, warning bool) {
+	request.Logger(z.ctx).Info(message)
+	z.ch <- &proto.StatusUpdate{ServiceName: serviceName, Message: message, Warning: warning}
+}
+
 func (z *zone) findRecordSet(name, recType string, recs []*dns.ResourceRecordSet) (record *dns.ResourceRecordSet, found bool) {
 	for _, rec := range recs {
 		if rec.Type == recType && rec.Name == name {
@@ -67,49 +149,112 @@ func (z *zone) findRecordSet(name, recType string, recs []*dns.ResourceRecordSet
 	return nil, false
 }
 
-func (z *zone) createRecord(name, recType, value string) error {
-	log.Infof("Creating %s record for %s with value %s", recType, name, value)
-
-	record := &dns.ResourceRecordSet{
-		Type:    recType,
-		Name:    name,
-		Ttl:     int64(defaultTTL),
-		Rrdatas: []string{value},
-	}
+func (z *zone) createRecord(record *dns.ResourceRecordSet) error {
+	request.Logger(z.ctx).Infof("Creating %s record for %s with value %s", record.Type, record.Name, record.Rrdatas)
 
 	change := &dns.Change{
 		Additions: []*dns.ResourceRecordSet{record},
 	}
 
-	c, err := z.service.Changes.Create(z.projectID, z.name, change).Do()
-	if err == nil {
-		b, _ := json.Marshal(c)
-		z.ch <- &proto.StatusUpdate{
-			ServiceName:  serviceName,
-			Message:      fmt.Sprintf("Created: %s\t%d\t%s\t%s", record.Name, record.Ttl, record.Type, record.Rrdatas[0]),
-			DebugMessage: string(b),
-		}
+	return z.applyChange(change, "Created: "+describeRecord(record))
+}
+
+func (z *zone) replaceRecord(existing, record *dns.ResourceRecordSet) error {
+	request.Logger(z.ctx).Infof("Updating %s record for %s from %s to %s", record.Type, record.Name, existing.Rrdatas, record.Rrdatas)
+
+	change := &dns.Change{
+		Deletions: []*dns.ResourceRecordSet{existing},
+		Additions: []*dns.ResourceRecordSet{record},
 	}
 
-	return err
+	return z.applyChange(change, "Updated: "+describeRecord(record))
 }
 
 func (z *zone) removeRecord(record *dns.ResourceRecordSet) error {
-	log.Infof("Deleting %s record for %s with value %s", record.Type, record.Name, record.Rrdatas)
+	request.Logger(z.ctx).Infof("Deleting %s record for %s with value %s", record.Type, record.Name, record.Rrdatas)
 
 	change := &dns.Change{
 		Deletions: []*dns.ResourceRecordSet{record},
 	}
 
-	c, err := z.service.Changes.Create(z.projectID, z.name, change).Do()
+	return z.applyChange(change, "Deleted: "+describeRecord(record))
+}
+
+func (z *zone) applyChange(change *dns.Change, message string) error {
+	c, err := z.service.Changes.Create(z.projectID, z.name, change).Context(z.ctx).Do()
 	if err == nil {
 		b, _ := json.Marshal(c)
 		z.ch <- &proto.StatusUpdate{
 			ServiceName:  serviceName,
-			Message:      fmt.Sprintf("Deleted: %s\t%d\t%s\t%s", record.Name, record.Ttl, record.Type, record.Rrdatas[0]),
+			Message:      message,
 			DebugMessage: string(b),
 		}
 	}
 
 	return err
 }
+
+func describeRecord(record *dns.ResourceRecordSet) string {
+	return fmt.Sprintf("%s\t%d\t%s\t%s", record.Name, record.Ttl, record.Type, strings.Join(record.Rrdatas, " "))
+}
+
+func equalRecords(a, b *dns.ResourceRecordSet) bool {
+	return a.Ttl == b.Ttl && equalValues(a.Type, a.Rrdatas, b.Rrdatas)
+}
+
+// equalValues compares the normalized values of two record sets regardless of their order
+func equalValues(recType string, a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	x := normalizeValues(recType, a)
+	y := normalizeValues(recType, b)
+	sort.Strings(x)
+	sort.Strings(y)
+
+	for i := range x {
+		if x[i] != y[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// targetField is the index of the field containing a domain name for record types referring to other names
+var targetField = map[string]int{"CNAME": 0, "DNAME": 0, "NS": 0, "PTR": 0, "MX": 1, "SRV": 3}
+
+// normalizeValues returns the values in the form used by Cloud DNS: names referred to are absolute (with trailing dot)
+// and lower case, TXT values are quoted
+func normalizeValues(recType string, values []string) []string {
+	normalized := make([]string, len(values))
+	for i, v := range values {
+		normalized[i] = normalizeValue(recType, strings.TrimSpace(v))
+	}
+
+	return normalized
+}
+
+func normalizeValue(recType, value string) string {
+	if recType == "TXT" || recType == "SPF" {
+		if strings.HasPrefix(value, `"`) {
+			return value
+		}
+
+		return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value) + `"`
+	}
+
+	idx, found := targetField[recType]
+	if !found {
+		return value
+	}
+
+	fields := strings.Fields(value)
+	if idx >= len(fields) {
+		return value
+	}
+
+	fields[idx] = strings.ToLower(strings.TrimSuffix(fields[idx], ".")) + "."
+	return strings.Join(fields, " ")
+}
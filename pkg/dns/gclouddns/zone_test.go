@@ -0,0 +1,35 @@
+package gclouddns
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeValues(t *testing.T) {
+	tests := []struct {
+		recType  string
+		values   []string
+		expected []string
+	}{
+		{recType: "CNAME", values: []string{"Web1.Example.com"}, expected: []string{"web1.example.com."}},
+		{recType: "CNAME", values: []string{"web1.example.com."}, expected: []string{"web1.example.com."}},
+		{recType: "MX", values: []string{"10 mail.example.com", "0 ."}, expected: []string{"10 mail.example.com.", "0 ."}},
+		{recType: "SRV", values: []string{"10 5 5060 sip.example.com"}, expected: []string{"10 5 5060 sip.example.com."}},
+		{recType: "TXT", values: []string{"token", `"quoted"`, `say "hi"`}, expected: []string{`"token"`, `"quoted"`, `"say \"hi\""`}},
+		{recType: "SSHFP", values: []string{" 1 2 abc "}, expected: []string{"1 2 abc"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.recType, func(t *testing.T) {
+			assert.Equal(t, test.expected, normalizeValues(test.recType, test.values))
+		})
+	}
+}
+
+func TestEqualValues(t *testing.T) {
+	assert.True(t, equalValues("CNAME", []string{"web1.example.com."}, []string{"web1.example.com"}))
+	assert.True(t, equalValues("TXT", []string{`"b"`, `"a"`}, []string{"a", "b"}))
+	assert.False(t, equalValues("TXT", []string{`"a"`}, []string{"b"}))
+	assert.False(t, equalValues("A", []string{"192.168.1.1"}, []string{"192.168.1.1", "192.168.1.2"}))
+}
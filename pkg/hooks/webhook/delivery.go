@@ -0,0 +1,152 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/MauveSoftware/provisionize/pkg/api/proto"
+	"github.com/MauveSoftware/provisionize/pkg/request"
+)
+
+const (
+	// SignatureHeader is the header carrying the HMAC-SHA256 signature of the payload
+	SignatureHeader = "X-Provisionize-Signature"
+
+	// PhaseHeader is the header carrying the phase of the event
+	PhaseHeader = "X-Provisionize-Phase"
+
+	maxErrorBodyLength = 512
+)
+
+// Payload is the JSON document POSTed to the endpoints
+type Payload struct {
+	Phase          string                `json:"phase"`
+	RequestID      string                `json:"request_id"`
+	Timestamp      time.Time             `json:"timestamp"`
+	VirtualMachine *proto.VirtualMachine `json:"virtual_machine"`
+}
+
+// redacted returns a copy of the VM without the cloud-init password and user data, which may contain secrets as well
+func redacted(vm *proto.VirtualMachine) *proto.VirtualMachine {
+	vm = vm.Redacted()
+	if vm.GetCloudInit() != nil {
+		vm.CloudInit.UserData = ""
+	}
+
+	return vm
+}
+
+// statusError is returned for responses with a status code other than 2xx
+type statusError struct {
+	statusCode int
+	body       string
+}
+
+func (e *statusError) Error() string {
+	if len(e.body) == 0 {
+		return fmt.Sprintf("unexpected status code %d", e.statusCode)
+	}
+
+	return fmt.Sprintf("unexpected status code %d: %s", e.statusCode, e.body)
+}
+
+func newPayload(ctx context.Context, phase string, vm *proto.VirtualMachine) ([]byte, error) {
+	p := &Payload{
+		Phase:          phase,
+		RequestID:      request.ID(ctx),
+		Timestamp:      time.Now().UTC(),
+		VirtualMachine: redacted(vm),
+	}
+
+	b, err := json.Marshal(p)
+	return b, errors.Wrap(err, "could not serialize payload")
+}
+
+// Sign returns the signature of body sent in the SignatureHeader (sha256=<hex encoded HMAC>)
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// send POSTs the payload to the endpoint. Connection errors, 429 and 5xx responses are retried with exponential backoff.
+func (s *WebhookService) send(ctx context.Context, e *Endpoint, phase string, body []byte) error {
+	backoff := s.retryBackoff
+
+	var err error
+	for i := 0; ; i++ {
+		err = s.post(ctx, e, phase, body)
+		if err == nil || !retryable(err) || i >= e.retries() {
+			return err
+		}
+
+		request.Logger(ctx).Warnf("Webhook %s failed (retrying in %v): %v", e.Name, backoff, err)
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+	}
+}
+
+func (s *WebhookService) post(ctx context.Context, e *Endpoint, phase string, body []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, e.timeout())
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "POST", e.URL, bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrapf(err, "could not create request with URI %s", e.URL)
+	}
+
+	for k, v := range e.Headers {
+		req.Header.Set(k, v)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(PhaseHeader, phase)
+
+	if id := request.ID(ctx); len(id) > 0 {
+		req.Header.Set("X-Request-ID", id)
+	}
+
+	if len(e.Secret) > 0 {
+		req.Header.Set(SignatureHeader, Sign(e.Secret, body))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	b, _ := ioutil.ReadAll(io.LimitReader(resp.Body, maxErrorBodyLength))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return &statusError{statusCode: resp.StatusCode, body: string(bytes.TrimSpace(b))}
+	}
+
+	return nil
+}
+
+func retryable(err error) bool {
+	se, ok := err.(*statusError)
+	if !ok {
+		return true
+	}
+
+	return se.statusCode == http.StatusTooManyRequests || se.statusCode >= 500
+}
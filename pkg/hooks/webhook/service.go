@@ -0,0 +1,247 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.opencensus.io/plugin/ochttp"
+	"go.opencensus.io/trace"
+
+	"github.com/MauveSoftware/provisionize/pkg/api/proto"
+	"github.com/MauveSoftware/provisionize/pkg/request"
+)
+
+const (
+	serviceName = "Webhook"
+
+	defaultTimeout      = 10 * time.Second
+	defaultRetries      = 3
+	defaultRetryBackoff = time.Second
+)
+
+const (
+	// PhaseProvision is sent when a VM is provisioned
+	PhaseProvision = "provision"
+
+	// PhaseDeprovision is sent when a VM is deprovisioned
+	PhaseDeprovision = "deprovision"
+
+	// PhaseRestore is sent when a VM is restored from the recycle bin
+	PhaseRestore = "restore"
+)
+
+// Endpoint is a URL notified about provisioning events
+type Endpoint struct {
+	// Name of the endpoint shown in status updates
+	Name string
+
+	// URL the payload is POSTed to
+	URL string
+
+	// Secret used to sign the payload (no signature is sent if empty)
+	Secret string
+
+	// Async sends the payload in background without waiting for the response. Failures do not fail the provisioning.
+	Async bool
+
+	// Phases the endpoint is notified about (empty matches all phases)
+	Phases []string
+
+	// Headers are additional HTTP headers sent with every request
+	Headers map[string]string
+
+	// Timeout of a single request (default: 10s)
+	Timeout time.Duration
+
+	// Retries is the number of retries after a failed request (default: 3, negative disables retries)
+	Retries int
+}
+
+// WebhookService notifies external systems (e.g. CMDB, monitoring, ticketing) about provisioning events
+type WebhookService struct {
+	endpoints    []*Endpoint
+	client       *http.Client
+	retryBackoff time.Duration
+
+	mu      sync.Mutex
+	pending int
+	drained chan struct{}
+}
+
+// Option configures optional behavior of WebhookService
+type Option func(s *WebhookService)
+
+// WithHTTPClient sets the HTTP client used to send the requests
+func WithHTTPClient(client *http.Client) Option {
+	return func(s *WebhookService) {
+		s.client = client
+	}
+}
+
+// NewService returns a new instance of WebhookService
+func NewService(endpoints []*Endpoint, opts ...Option) *WebhookService {
+	s := &WebhookService{
+		endpoints:    endpoints,
+		client:       &http.Client{Transport: &ochttp.Transport{}, Timeout: maxTimeout(endpoints)},
+		retryBackoff: defaultRetryBackoff,
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// Provision notifies the endpoints about the provisioning of the VM
+func (s *WebhookService) Provision(ctx context.Context, vm *proto.VirtualMachine, ch chan<- *proto.StatusUpdate) bool {
+	ctx, span := trace.StartSpan(ctx, "WebhookService.Provision")
+	defer span.End()
+
+	return s.notify(ctx, PhaseProvision, vm, ch)
+}
+
+// Deprovision notifies the endpoints about the deprovisioning of the VM
+func (s *WebhookService) Deprovision(ctx context.Context, vm *proto.VirtualMachine, ch chan<- *proto.StatusUpdate) bool {
+	ctx, span := trace.StartSpan(ctx, "WebhookService.Deprovision")
+	defer span.End()
+
+	return s.notify(ctx, PhaseDeprovision, vm, ch)
+}
+
+// Restore notifies the endpoints about a VM restored from the recycle bin
+func (s *WebhookService) Restore(ctx context.Context, vm *proto.VirtualMachine, ch chan<- *proto.StatusUpdate) bool {
+	ctx, span := trace.StartSpan(ctx, "WebhookService.Restore")
+	defer span.End()
+
+	return s.notify(ctx, PhaseRestore, vm, ch)
+}
+
+func (s *WebhookService) notify(ctx context.Context, phase string, vm *proto.VirtualMachine, ch chan<- *proto.StatusUpdate) bool {
+	body, err := newPayload(ctx, phase, vm)
+	if err != nil {
+		ch <- &proto.StatusUpdate{ServiceName: serviceName, Failed: true, Message: err.Error()}
+		return false
+	}
+
+	for _, e := range s.endpoints {
+		if !e.matches(phase) {
+			continue
+		}
+
+		if e.Async {
+			s.sendAsync(ctx, e, phase, body)
+			ch <- &proto.StatusUpdate{ServiceName: serviceName, Message: fmt.Sprintf("Notifying %s in background", e.Name)}
+			continue
+		}
+
+		err := s.send(ctx, e, phase, body)
+		if err != nil {
+			ch <- &proto.StatusUpdate{ServiceName: serviceName, Failed: true, Message: fmt.Sprintf("%s: %v", e.Name, err)}
+			return false
+		}
+
+		ch <- &proto.StatusUpdate{ServiceName: serviceName, Message: fmt.Sprintf("Notified %s", e.Name)}
+	}
+
+	return true
+}
+
+// sendAsync sends the payload detached from the request so it is not canceled when the request is completed
+func (s *WebhookService) sendAsync(ctx context.Context, e *Endpoint, phase string, body []byte) {
+	ctx = trace.NewContext(request.WithID(context.Background(), request.ID(ctx)), trace.FromContext(ctx))
+
+	s.mu.Lock()
+	if s.pending == 0 {
+		s.drained = make(chan struct{})
+	}
+	s.pending++
+	s.mu.Unlock()
+
+	go func() {
+		defer s.done()
+
+		err := s.send(ctx, e, phase, body)
+		if err != nil {
+			request.Logger(ctx).Errorf("Webhook %s failed: %v", e.Name, err)
+		}
+	}()
+}
+
+func (s *WebhookService) done() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pending--
+	if s.pending == 0 {
+		close(s.drained)
+	}
+}
+
+// Close waits for payloads still sent in background until ctx is done
+func (s *WebhookService) Close(ctx context.Context) error {
+	s.mu.Lock()
+	if s.pending == 0 {
+		s.mu.Unlock()
+		return nil
+	}
+	drained := s.drained
+	s.mu.Unlock()
+
+	select {
+	case <-drained:
+		return nil
+	case <-ctx.Done():
+		return errors.Wrap(ctx.Err(), "webhooks still pending")
+	}
+}
+
+func (e *Endpoint) matches(phase string) bool {
+	if len(e.Phases) == 0 {
+		return true
+	}
+
+	for _, p := range e.Phases {
+		if p == phase {
+			return true
+		}
+	}
+
+	return false
+}
+
+// maxTimeout returns the longest request timeout of the endpoints, used as timeout of the default client
+func maxTimeout(endpoints []*Endpoint) time.Duration {
+	max := defaultTimeout
+	for _, e := range endpoints {
+		if e.timeout() > max {
+			max = e.timeout()
+		}
+	}
+
+	return max
+}
+
+func (e *Endpoint) timeout() time.Duration {
+	if e.Timeout > 0 {
+		return e.Timeout
+	}
+
+	return defaultTimeout
+}
+
+func (e *Endpoint) retries() int {
+	if e.Retries < 0 {
+		return 0
+	}
+
+	if e.Retries == 0 {
+		return defaultRetries
+	}
+
+	return e.Retries
+}
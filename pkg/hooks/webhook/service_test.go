@@ -0,0 +1,241 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/MauveSoftware/provisionize/pkg/api/proto"
+	"github.com/MauveSoftware/provisionize/pkg/request"
+)
+
+type receivedRequest struct {
+	header  http.Header
+	body    []byte
+	payload *Payload
+}
+
+type testReceiver struct {
+	mu       sync.Mutex
+	statuses []int
+	requests []*receivedRequest
+}
+
+func (r *testReceiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	b, _ := ioutil.ReadAll(req.Body)
+	p := &Payload{}
+	json.Unmarshal(b, p)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	status := http.StatusOK
+	if len(r.requests) < len(r.statuses) {
+		status = r.statuses[len(r.requests)]
+	}
+
+	r.requests = append(r.requests, &receivedRequest{header: req.Header, body: b, payload: p})
+	w.WriteHeader(status)
+}
+
+func collect(f func(ch chan<- *proto.StatusUpdate) bool) (bool, []*proto.StatusUpdate) {
+	ch := make(chan *proto.StatusUpdate, 10)
+	result := f(ch)
+	close(ch)
+
+	updates := []*proto.StatusUpdate{}
+	for u := range ch {
+		updates = append(updates, u)
+	}
+
+	return result, updates
+}
+
+func TestProvision(t *testing.T) {
+	tests := []struct {
+		name           string
+		statuses       []int
+		endpoint       *Endpoint
+		expectResult   bool
+		expectRequests int
+		expectMessage  string
+	}{
+		{
+			name:           "sync",
+			endpoint:       &Endpoint{Name: "cmdb", Secret: "s3cr3t", Headers: map[string]string{"Authorization": "Bearer abc"}},
+			expectResult:   true,
+			expectRequests: 1,
+			expectMessage:  "Notified cmdb",
+		},
+		{
+			name:           "sync retried",
+			statuses:       []int{http.StatusServiceUnavailable, http.StatusTooManyRequests},
+			endpoint:       &Endpoint{Name: "cmdb"},
+			expectResult:   true,
+			expectRequests: 3,
+			expectMessage:  "Notified cmdb",
+		},
+		{
+			name:           "sync retries exhausted",
+			statuses:       []int{http.StatusBadGateway, http.StatusBadGateway},
+			endpoint:       &Endpoint{Name: "cmdb", Retries: 1},
+			expectResult:   false,
+			expectRequests: 2,
+			expectMessage:  "cmdb: unexpected status code 502",
+		},
+		{
+			name:           "sync client error",
+			statuses:       []int{http.StatusBadRequest},
+			endpoint:       &Endpoint{Name: "cmdb"},
+			expectResult:   false,
+			expectRequests: 1,
+			expectMessage:  "cmdb: unexpected status code 400",
+		},
+		{
+			name:           "async failure does not fail",
+			statuses:       []int{http.StatusBadRequest},
+			endpoint:       &Endpoint{Name: "monitoring", Async: true},
+			expectResult:   true,
+			expectRequests: 1,
+			expectMessage:  "Notifying monitoring in background",
+		},
+		{
+			name:           "phase not matching",
+			endpoint:       &Endpoint{Name: "ticketing", Phases: []string{PhaseDeprovision}},
+			expectResult:   true,
+			expectRequests: 0,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := &testReceiver{statuses: test.statuses}
+			srv := httptest.NewServer(r)
+			defer srv.Close()
+
+			test.endpoint.URL = srv.URL
+			svc := NewService([]*Endpoint{test.endpoint})
+			svc.retryBackoff = time.Millisecond
+
+			vm := &proto.VirtualMachine{Name: "web1", Fqdn: "web1.example.com"}
+			ctx := request.WithID(context.Background(), "req-1")
+
+			result, updates := collect(func(ch chan<- *proto.StatusUpdate) bool {
+				return svc.Provision(ctx, vm, ch)
+			})
+			require.NoError(t, svc.Close(context.Background()))
+
+			assert.Equal(t, test.expectResult, result)
+			require.Len(t, r.requests, test.expectRequests)
+
+			if test.expectRequests == 0 {
+				assert.Empty(t, updates)
+				return
+			}
+
+			require.Len(t, updates, 1)
+			assert.Equal(t, test.expectMessage, updates[0].Message)
+			assert.Equal(t, !test.expectResult, updates[0].Failed)
+
+			req := r.requests[0]
+			assert.Equal(t, PhaseProvision, req.payload.Phase)
+			assert.Equal(t, "req-1", req.payload.RequestID)
+			assert.Equal(t, "web1.example.com", req.payload.VirtualMachine.Fqdn)
+			assert.Equal(t, "req-1", req.header.Get("X-Request-ID"))
+			assert.Equal(t, PhaseProvision, req.header.Get(PhaseHeader))
+
+			for k, v := range test.endpoint.Headers {
+				assert.Equal(t, v, req.header.Get(k))
+			}
+
+			if len(test.endpoint.Secret) > 0 {
+				assert.Equal(t, Sign(test.endpoint.Secret, req.body), req.header.Get(SignatureHeader))
+			} else {
+				assert.Empty(t, req.header.Get(SignatureHeader))
+			}
+		})
+	}
+}
+
+func TestDeprovision(t *testing.T) {
+	r := &testReceiver{}
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	svc := NewService([]*Endpoint{
+		{Name: "cmdb", URL: srv.URL, Phases: []string{PhaseProvision}},
+		{Name: "ticketing", URL: srv.URL, Phases: []string{PhaseDeprovision}},
+	})
+
+	result, updates := collect(func(ch chan<- *proto.StatusUpdate) bool {
+		return svc.Deprovision(context.Background(), &proto.VirtualMachine{Name: "web1"}, ch)
+	})
+	assert.True(t, result)
+
+	require.Len(t, updates, 1)
+	assert.Equal(t, "Notified ticketing", updates[0].Message)
+	require.Len(t, r.requests, 1)
+	assert.Equal(t, PhaseDeprovision, r.requests[0].payload.Phase)
+}
+
+func TestPayloadOmitsSecrets(t *testing.T) {
+	r := &testReceiver{}
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	svc := NewService([]*Endpoint{{Name: "cmdb", URL: srv.URL, Phases: []string{PhaseProvision}}})
+
+	vm := &proto.VirtualMachine{
+		Name:      "web1",
+		CloudInit: &proto.CloudInit{UserName: "admin", Password: "secret", UserData: "#cloud-config"},
+	}
+	result, _ := collect(func(ch chan<- *proto.StatusUpdate) bool {
+		return svc.Provision(context.Background(), vm, ch)
+	})
+	assert.True(t, result)
+
+	require.Len(t, r.requests, 1)
+	assert.NotContains(t, string(r.requests[0].body), "secret")
+	assert.NotContains(t, string(r.requests[0].body), "cloud-config")
+	assert.Equal(t, "admin", r.requests[0].payload.VirtualMachine.CloudInit.UserName)
+	assert.Equal(t, "secret", vm.CloudInit.Password, "VM is not modified")
+}
+
+func TestSign(t *testing.T) {
+	assert.Equal(t, "sha256=77325902caca812dc259733aacd046b73817372c777b8d95b402647474516e13", Sign("secret", []byte(`{}`)))
+}
+
+func TestClose(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer srv.Close()
+
+	svc := NewService([]*Endpoint{{Name: "cmdb", URL: srv.URL, Async: true}})
+	assert.NoError(t, svc.Close(context.Background()), "nothing pending")
+
+	collect(func(ch chan<- *proto.StatusUpdate) bool {
+		return svc.Provision(context.Background(), &proto.VirtualMachine{Name: "web1"}, ch)
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.Error(t, svc.Close(ctx), "deadline exceeded")
+
+	close(release)
+	assert.NoError(t, svc.Close(context.Background()))
+}
+
+func TestDefaultClientTimeout(t *testing.T) {
+	svc := NewService([]*Endpoint{{Name: "a"}, {Name: "b", Timeout: time.Minute}})
+	assert.Equal(t, time.Minute, svc.client.Timeout)
+}
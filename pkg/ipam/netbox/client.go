@@ -0,0 +1,105 @@
+package netbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.opencensus.io/plugin/ochttp"
+
+	"github.com/MauveSoftware/provisionize/pkg/request"
+)
+
+const defaultRequestTimeout = 30 * time.Second
+
+// client is a minimal client for the NetBox REST API
+type client struct {
+	baseURL string
+	token   string
+	client  *http.Client
+}
+
+type listResponse struct {
+	Count   int             `json:"count"`
+	Results json.RawMessage `json:"results"`
+}
+
+func newClient(baseURL, token string, timeout time.Duration) *client {
+	if timeout == 0 {
+		timeout = defaultRequestTimeout
+	}
+
+	return &client{
+		baseURL: strings.TrimRight(baseURL, "/") + "/api",
+		token:   token,
+		client:  &http.Client{Transport: &ochttp.Transport{}, Timeout: timeout},
+	}
+}
+
+// list retrieves all objects at path matching the query and unmarshals them into v
+func (c *client) list(ctx context.Context, path string, query url.Values, v interface{}) error {
+	res := &listResponse{}
+	err := c.do(ctx, "GET", path+"?"+query.Encode(), nil, res)
+	if err != nil {
+		return err
+	}
+
+	return errors.Wrapf(json.Unmarshal(res.Results, v), "could not parse response from %s", path)
+}
+
+func (c *client) do(ctx context.Context, method, path string, body, v interface{}) error {
+	var r io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return errors.Wrap(err, "could not serialize request body")
+		}
+
+		r = bytes.NewReader(b)
+	}
+
+	u := c.baseURL + path
+	req, err := http.NewRequestWithContext(ctx, method, u, r)
+	if err != nil {
+		return errors.Wrapf(err, "could not create request with URI %s", u)
+	}
+
+	req.Header.Set("Authorization", "Token "+c.token)
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	if id := request.ID(ctx); len(id) > 0 {
+		req.Header.Set("X-Request-ID", id)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return errors.Wrapf(err, "%s %s failed", method, path)
+	}
+	defer resp.Body.Close()
+
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return errors.Wrap(err, "could not read from response")
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%s %s failed (status code %d): %s", method, path, resp.StatusCode, strings.TrimSpace(string(b)))
+	}
+
+	if v == nil || len(b) == 0 {
+		return nil
+	}
+
+	return errors.Wrapf(json.Unmarshal(b, v), "could not parse response from %s", path)
+}
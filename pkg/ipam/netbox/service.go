@@ -0,0 +1,509 @@
+package netbox
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.opencensus.io/trace"
+
+	"github.com/MauveSoftware/provisionize/pkg/api/proto"
+)
+
+const serviceName = "NetBox"
+
+// Prefix is a prefix in NetBox addresses are allocated from
+type Prefix struct {
+	// Cluster the prefix is used for (empty matches all clusters)
+	Cluster string
+
+	// Network the prefix is used for (empty matches the primary interface only)
+	Network string
+
+	// Prefix in CIDR notation as defined in NetBox
+	Prefix string
+
+	// Gateway configured for addresses allocated from the prefix
+	Gateway string
+
+	ipv4 bool
+}
+
+// Options controls how VMs are represented in NetBox
+type Options struct {
+	// Decommission marks VMs as decommissioning on deprovisioning instead of deleting them
+	Decommission bool
+
+	// Timeout of requests to the NetBox API (default: 30s)
+	Timeout time.Duration
+}
+
+// NetBoxService allocates addresses from NetBox prefixes and maintains the virtual machine objects in NetBox
+type NetBoxService struct {
+	client   *client
+	prefixes []*Prefix
+	opts     Options
+}
+
+// NewService creates a new instance of NetBoxService
+func NewService(baseURL, token string, prefixes []*Prefix, opts Options) (*NetBoxService, error) {
+	for _, p := range prefixes {
+		ip, _, err := net.ParseCIDR(p.Prefix)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid prefix %s", p.Prefix)
+		}
+
+		p.ipv4 = ip.To4() != nil
+	}
+
+	return &NetBoxService{
+		client:   newClient(baseURL, token, opts.Timeout),
+		prefixes: prefixes,
+		opts:     opts,
+	}, nil
+}
+
+// Provision allocates addresses for the VM if not specified and creates or repairs the VM with its interfaces and addresses in NetBox.
+// Objects created by a failed provisioning are removed again.
+func (s *NetBoxService) Provision(ctx context.Context, vm *proto.VirtualMachine, ch chan<- *proto.StatusUpdate) bool {
+	ctx, span := trace.StartSpan(ctx, "NetBoxService.Provision")
+	defer span.End()
+
+	err := s.provision(ctx, vm, ch)
+	if err != nil {
+		ch <- &proto.StatusUpdate{ServiceName: serviceName, Failed: true, Message: err.Error()}
+		return false
+	}
+
+	return true
+}
+
+func (s *NetBoxService) provision(ctx context.Context, vm *proto.VirtualMachine, ch chan<- *proto.StatusUpdate) (err error) {
+	existing, err := s.findVM(ctx, vm)
+	if err != nil {
+		return err
+	}
+
+	var created *virtualMachine
+	allocated := []*ipAddress{}
+	defer func() {
+		if err != nil {
+			s.rollback(ctx, created, allocated, ch)
+		}
+	}()
+
+	if existing == nil {
+		created, err = s.createVM(ctx, vm, ch)
+		if err != nil {
+			return err
+		}
+
+		existing = created
+	} else {
+		ch <- &proto.StatusUpdate{ServiceName: serviceName, Message: fmt.Sprintf("Virtual machine %s already exists (ID %d)", vm.Name, existing.ID)}
+	}
+
+	ifaceIDs, err := s.ensureInterfaces(ctx, vm, existing, ch)
+	if err != nil {
+		return err
+	}
+
+	ips := []*ipAddress{}
+	err = s.client.list(ctx, "/ipam/ip-addresses/", url.Values{"virtual_machine_id": {strconv.Itoa(existing.ID)}}, &ips)
+	if err != nil {
+		return errors.Wrap(err, "could not retrieve addresses of virtual machine")
+	}
+
+	primary := map[string]interface{}{}
+	for _, iface := range vm.Interfaces {
+		for _, cfg := range []*proto.IPConfig{iface.Ipv4, iface.Ipv6} {
+			ipv4 := cfg == iface.Ipv4
+
+			ip := assignedAddress(ips, ifaceIDs[iface], cfg, ipv4)
+			if ip != nil {
+				s.applyAddress(vm, iface, cfg, ip)
+			} else {
+				ip, err = s.addressFor(ctx, vm, iface, cfg, ipv4, ifaceIDs[iface], ch)
+				if err != nil {
+					return err
+				}
+
+				if ip == nil {
+					continue
+				}
+
+				allocated = append(allocated, ip)
+			}
+
+			if !iface.Primary {
+				continue
+			}
+
+			if ipv4 && !sameAddress(existing.PrimaryIP4, ip) {
+				primary["primary_ip4"] = ip.ID
+			}
+
+			if !ipv4 && !sameAddress(existing.PrimaryIP6, ip) {
+				primary["primary_ip6"] = ip.ID
+			}
+		}
+	}
+
+	if len(primary) == 0 {
+		return nil
+	}
+
+	err = s.client.do(ctx, "PATCH", fmt.Sprintf("/virtualization/virtual-machines/%d/", existing.ID), primary, nil)
+	return errors.Wrap(err, "could not set primary addresses")
+}
+
+func (s *NetBoxService) createVM(ctx context.Context, vm *proto.VirtualMachine, ch chan<- *proto.StatusUpdate) (*virtualMachine, error) {
+	clusterID, err := s.clusterID(ctx, vm.ClusterName)
+	if err != nil {
+		return nil, err
+	}
+
+	created := &virtualMachine{}
+	err = s.client.do(ctx, "POST", "/virtualization/virtual-machines/", map[string]interface{}{
+		"name":    vm.Name,
+		"cluster": clusterID,
+		"vcpus":   vm.CpuCores,
+		"memory":  vm.MemoryMb,
+		"status":  statusActive,
+	}, created)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not create virtual machine")
+	}
+
+	ch <- &proto.StatusUpdate{ServiceName: serviceName, Message: fmt.Sprintf("Created virtual machine %s (ID %d)", vm.Name, created.ID)}
+	return created, nil
+}
+
+// ensureInterfaces creates the interfaces missing on the VM in NetBox and returns the IDs of all interfaces
+func (s *NetBoxService) ensureInterfaces(ctx context.Context, vm *proto.VirtualMachine, existing *virtualMachine,
+	ch chan<- *proto.StatusUpdate) (map[*proto.NetworkInterface]int, error) {
+	objs := []*object{}
+	err := s.client.list(ctx, "/virtualization/interfaces/", url.Values{"virtual_machine_id": {strconv.Itoa(existing.ID)}}, &objs)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not retrieve interfaces of virtual machine")
+	}
+
+	byName := make(map[string]int)
+	for _, o := range objs {
+		byName[o.Name] = o.ID
+	}
+
+	ifaceIDs := make(map[*proto.NetworkInterface]int)
+	for _, iface := range vm.Interfaces {
+		if id, found := byName[iface.Name]; found {
+			ifaceIDs[iface] = id
+			continue
+		}
+
+		body := map[string]interface{}{
+			"virtual_machine": existing.ID,
+			"name":            iface.Name,
+		}
+		if len(iface.Mac) > 0 {
+			body["mac_address"] = iface.Mac
+		}
+
+		obj := &object{}
+		err = s.client.do(ctx, "POST", "/virtualization/interfaces/", body, obj)
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not create interface %s", iface.Name)
+		}
+
+		ch <- &proto.StatusUpdate{ServiceName: serviceName, Message: fmt.Sprintf("Created interface %s", iface.Name)}
+		ifaceIDs[iface] = obj.ID
+	}
+
+	return ifaceIDs, nil
+}
+
+// addressFor allocates an address from the prefix matching the interface if cfg is empty or creates the configured one.
+// The address is assigned to the interface in the same request.
+func (s *NetBoxService) addressFor(ctx context.Context, vm *proto.VirtualMachine, iface *proto.NetworkInterface, cfg *proto.IPConfig, ipv4 bool,
+	ifaceID int, ch chan<- *proto.StatusUpdate) (*ipAddress, error) {
+	body := map[string]interface{}{
+		"status":               statusActive,
+		"dns_name":             vm.Fqdn,
+		"description":          vm.Name,
+		"assigned_object_type": vmInterfaceType,
+		"assigned_object_id":   ifaceID,
+	}
+
+	if len(cfg.Address) > 0 {
+		address := fmt.Sprintf("%s/%d", cfg.Address, cfg.PrefixLength)
+		body["address"] = address
+
+		ip := &ipAddress{}
+		err := s.client.do(ctx, "POST", "/ipam/ip-addresses/", body, ip)
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not create address %s", address)
+		}
+
+		return ip, nil
+	}
+
+	p := s.prefixFor(vm, iface, ipv4)
+	if p == nil {
+		return nil, nil
+	}
+
+	pfx := []*prefix{}
+	err := s.client.list(ctx, "/ipam/prefixes/", url.Values{"prefix": {p.Prefix}}, &pfx)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not retrieve prefix %s", p.Prefix)
+	}
+
+	if len(pfx) == 0 {
+		return nil, fmt.Errorf("prefix %s not found", p.Prefix)
+	}
+
+	ip := &ipAddress{}
+	err = s.client.do(ctx, "POST", fmt.Sprintf("/ipam/prefixes/%d/available-ips/", pfx[0].ID), body, ip)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not allocate address from prefix %s", p.Prefix)
+	}
+
+	s.applyAddress(vm, iface, cfg, ip)
+	ch <- &proto.StatusUpdate{ServiceName: serviceName, Message: fmt.Sprintf("Allocated %s for interface %s from prefix %s", ip.Address, iface.Name, p.Prefix)}
+
+	return ip, nil
+}
+
+// rollback removes the addresses and the VM created by a failed provisioning
+func (s *NetBoxService) rollback(ctx context.Context, created *virtualMachine, allocated []*ipAddress, ch chan<- *proto.StatusUpdate) {
+	for _, ip := range allocated {
+		err := s.client.do(ctx, "DELETE", fmt.Sprintf("/ipam/ip-addresses/%d/", ip.ID), nil, nil)
+		if err != nil {
+			ch <- &proto.StatusUpdate{ServiceName: serviceName, Warning: true, Message: fmt.Sprintf("Could not release address %s: %v", ip.Address, err)}
+			continue
+		}
+
+		ch <- &proto.StatusUpdate{ServiceName: serviceName, Message: fmt.Sprintf("Released address %s", ip.Address)}
+	}
+
+	if created == nil {
+		return
+	}
+
+	err := s.client.do(ctx, "DELETE", fmt.Sprintf("/virtualization/virtual-machines/%d/", created.ID), nil, nil)
+	if err != nil {
+		ch <- &proto.StatusUpdate{ServiceName: serviceName, Warning: true, Message: fmt.Sprintf("Could not delete virtual machine %s: %v", created.Name, err)}
+		return
+	}
+
+	ch <- &proto.StatusUpdate{ServiceName: serviceName, Message: fmt.Sprintf("Deleted virtual machine %s", created.Name)}
+}
+
+// assignedAddress returns the address of the family assigned to the interface. Configured addresses have to match exactly.
+func assignedAddress(ips []*ipAddress, ifaceID int, cfg *proto.IPConfig, ipv4 bool) *ipAddress {
+	for _, ip := range ips {
+		if ip.AssignedObjectID != ifaceID || ip.isIPv4() != ipv4 {
+			continue
+		}
+
+		if len(cfg.Address) == 0 || ip.Address == fmt.Sprintf("%s/%d", cfg.Address, cfg.PrefixLength) {
+			return ip
+		}
+	}
+
+	return nil
+}
+
+func sameAddress(current, ip *ipAddress) bool {
+	return current != nil && current.ID == ip.ID
+}
+
+// applyAddress sets the address allocated in NetBox if cfg is empty
+func (s *NetBoxService) applyAddress(vm *proto.VirtualMachine, iface *proto.NetworkInterface, cfg *proto.IPConfig, ip *ipAddress) {
+	if ip == nil || len(cfg.Address) > 0 {
+		return
+	}
+
+	t := strings.SplitN(ip.Address, "/", 2)
+	cfg.Address = t[0]
+	if len(t) == 2 {
+		l, _ := strconv.Atoi(t[1])
+		cfg.PrefixLength = uint32(l)
+	}
+
+	if p := s.prefixFor(vm, iface, cfg == iface.Ipv4); p != nil && len(p.Gateway) > 0 {
+		cfg.Gateway = p.Gateway
+	}
+}
+
+func (s *NetBoxService) prefixFor(vm *proto.VirtualMachine, iface *proto.NetworkInterface, ipv4 bool) *Prefix {
+	for _, p := range s.prefixes {
+		if p.ipv4 == ipv4 && p.matches(vm, iface) {
+			return p
+		}
+	}
+
+	return nil
+}
+
+func (p *Prefix) matches(vm *proto.VirtualMachine, iface *proto.NetworkInterface) bool {
+	if len(p.Cluster) > 0 && p.Cluster != vm.ClusterName {
+		return false
+	}
+
+	if len(p.Network) == 0 {
+		return iface.Primary
+	}
+
+	return p.Network == iface.Network
+}
+
+func (s *NetBoxService) clusterID(ctx context.Context, name string) (int, error) {
+	if len(name) == 0 {
+		return 0, errors.New("cluster name is required to create the virtual machine")
+	}
+
+	clusters := []*object{}
+	err := s.client.list(ctx, "/virtualization/clusters/", url.Values{"name": {name}}, &clusters)
+	if err != nil {
+		return 0, errors.Wrapf(err, "could not retrieve cluster %s", name)
+	}
+
+	if len(clusters) == 0 {
+		return 0, fmt.Errorf("cluster %s not found", name)
+	}
+
+	return clusters[0].ID, nil
+}
+
+func (s *NetBoxService) findVM(ctx context.Context, vm *proto.VirtualMachine) (*virtualMachine, error) {
+	query := url.Values{"name": {vm.Name}}
+	if len(vm.ClusterName) > 0 {
+		query.Set("cluster", vm.ClusterName)
+	}
+
+	vms := []*virtualMachine{}
+	err := s.client.list(ctx, "/virtualization/virtual-machines/", query, &vms)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not retrieve virtual machine %s", vm.Name)
+	}
+
+	switch len(vms) {
+	case 0:
+		return nil, nil
+	case 1:
+		return vms[0], nil
+	default:
+		return nil, fmt.Errorf("found %d virtual machines named %s", len(vms), vm.Name)
+	}
+}
+
+// Deprovision deletes the VM and its addresses or marks the VM as decommissioning
+func (s *NetBoxService) Deprovision(ctx context.Context, vm *proto.VirtualMachine, ch chan<- *proto.StatusUpdate) bool {
+	ctx, span := trace.StartSpan(ctx, "NetBoxService.Deprovision")
+	defer span.End()
+
+	err := s.deprovision(ctx, vm, ch)
+	if err != nil {
+		ch <- &proto.StatusUpdate{ServiceName: serviceName, Failed: true, Message: err.Error()}
+		return false
+	}
+
+	return true
+}
+
+func (s *NetBoxService) deprovision(ctx context.Context, vm *proto.VirtualMachine, ch chan<- *proto.StatusUpdate) error {
+	existing, err := s.findVM(ctx, vm)
+	if err != nil {
+		return err
+	}
+
+	if existing == nil {
+		ch <- &proto.StatusUpdate{ServiceName: serviceName, Message: fmt.Sprintf("Virtual machine %s not found => skipping", vm.Name)}
+		return nil
+	}
+
+	path := fmt.Sprintf("/virtualization/virtual-machines/%d/", existing.ID)
+
+	if s.opts.Decommission {
+		return s.setStatus(ctx, existing, statusDecommissioning, ch)
+	}
+
+	ips := []*ipAddress{}
+	err = s.client.list(ctx, "/ipam/ip-addresses/", url.Values{"virtual_machine_id": {strconv.Itoa(existing.ID)}}, &ips)
+	if err != nil {
+		return errors.Wrap(err, "could not retrieve addresses of virtual machine")
+	}
+
+	for _, ip := range ips {
+		err = s.client.do(ctx, "DELETE", fmt.Sprintf("/ipam/ip-addresses/%d/", ip.ID), nil, nil)
+		if err != nil {
+			return errors.Wrapf(err, "could not delete address %s", ip.Address)
+		}
+
+		ch <- &proto.StatusUpdate{ServiceName: serviceName, Message: fmt.Sprintf("Deleted address %s", ip.Address)}
+	}
+
+	err = s.client.do(ctx, "DELETE", path, nil, nil)
+	if err != nil {
+		return errors.Wrap(err, "could not delete virtual machine")
+	}
+
+	ch <- &proto.StatusUpdate{ServiceName: serviceName, Message: fmt.Sprintf("Deleted virtual machine %s", vm.Name)}
+	return nil
+}
+
+// Recycle marks the VM as decommissioning while it is in the recycle bin
+func (s *NetBoxService) Recycle(ctx context.Context, vm *proto.VirtualMachine, ch chan<- *proto.StatusUpdate) bool {
+	ctx, span := trace.StartSpan(ctx, "NetBoxService.Recycle")
+	defer span.End()
+
+	return s.updateStatus(ctx, vm, statusDecommissioning, ch)
+}
+
+// Restore marks a VM taken from the recycle bin as active again
+func (s *NetBoxService) Restore(ctx context.Context, vm *proto.VirtualMachine, ch chan<- *proto.StatusUpdate) bool {
+	ctx, span := trace.StartSpan(ctx, "NetBoxService.Restore")
+	defer span.End()
+
+	return s.updateStatus(ctx, vm, statusActive, ch)
+}
+
+// Purge removes a VM from NetBox after the retention period of the recycle bin expired
+func (s *NetBoxService) Purge(ctx context.Context, vm *proto.VirtualMachine, ch chan<- *proto.StatusUpdate) bool {
+	return s.Deprovision(ctx, vm, ch)
+}
+
+func (s *NetBoxService) updateStatus(ctx context.Context, vm *proto.VirtualMachine, status string, ch chan<- *proto.StatusUpdate) bool {
+	existing, err := s.findVM(ctx, vm)
+	if err == nil && existing == nil {
+		ch <- &proto.StatusUpdate{ServiceName: serviceName, Message: fmt.Sprintf("Virtual machine %s not found => skipping", vm.Name)}
+		return true
+	}
+
+	if err == nil {
+		err = s.setStatus(ctx, existing, status, ch)
+	}
+
+	if err != nil {
+		ch <- &proto.StatusUpdate{ServiceName: serviceName, Failed: true, Message: err.Error()}
+		return false
+	}
+
+	return true
+}
+
+func (s *NetBoxService) setStatus(ctx context.Context, vm *virtualMachine, status string, ch chan<- *proto.StatusUpdate) error {
+	path := fmt.Sprintf("/virtualization/virtual-machines/%d/", vm.ID)
+	err := s.client.do(ctx, "PATCH", path, map[string]interface{}{"status": status}, nil)
+	if err != nil {
+		return errors.Wrapf(err, "could not set status of virtual machine to %s", status)
+	}
+
+	ch <- &proto.StatusUpdate{ServiceName: serviceName, Message: fmt.Sprintf("Marked virtual machine %s as %s", vm.Name, status)}
+	return nil
+}
@@ -0,0 +1,430 @@
+package netbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/MauveSoftware/provisionize/pkg/api/proto"
+)
+
+const testToken = "secret-token"
+
+type fakeObject map[string]interface{}
+
+// fakeNetBox implements the parts of the NetBox REST API used by NetBoxService
+type fakeNetBox struct {
+	mu        sync.Mutex
+	nextID    int
+	available map[string][]string
+	objects   map[string]map[int]fakeObject
+	fail      map[string]bool
+}
+
+func newFakeNetBox() *fakeNetBox {
+	f := &fakeNetBox{
+		nextID: 100,
+		available: map[string][]string{
+			"192.168.0.0/24": {"192.168.0.10/24", "192.168.0.11/24"},
+			"2001:db8::/64":  {"2001:db8::10/64"},
+		},
+		objects: map[string]map[int]fakeObject{},
+		fail:    map[string]bool{},
+	}
+
+	f.add("virtualization/clusters", fakeObject{"name": "cluster1"})
+	f.add("ipam/prefixes", fakeObject{"prefix": "192.168.0.0/24"})
+	f.add("ipam/prefixes", fakeObject{"prefix": "2001:db8::/64"})
+
+	return f
+}
+
+func (f *fakeNetBox) add(kind string, o fakeObject) fakeObject {
+	if f.objects[kind] == nil {
+		f.objects[kind] = map[int]fakeObject{}
+	}
+
+	f.nextID++
+	o["id"] = f.nextID
+	f.objects[kind][f.nextID] = o
+
+	return o
+}
+
+func (f *fakeNetBox) find(kind, key string, value interface{}) []fakeObject {
+	return f.filter(kind, map[string]string{key: fmt.Sprint(value)})
+}
+
+func (f *fakeNetBox) filter(kind string, query map[string]string) []fakeObject {
+	res := []fakeObject{}
+	for _, o := range f.objects[kind] {
+		if f.matches(o, query) {
+			res = append(res, o)
+		}
+	}
+
+	return res
+}
+
+func (f *fakeNetBox) matches(o fakeObject, query map[string]string) bool {
+	for key, value := range query {
+		switch key {
+		case "cluster":
+			c := f.find("virtualization/clusters", "name", value)
+			if len(c) == 0 || fmt.Sprint(o["cluster"]) != fmt.Sprint(c[0]["id"]) {
+				return false
+			}
+		case "virtual_machine_id":
+			if fmt.Sprint(o["virtual_machine"]) != value {
+				return false
+			}
+		default:
+			if fmt.Sprint(o[key]) != value {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// assignVM sets the VM of the interface an address is assigned to, so addresses can be filtered by virtual_machine_id
+func (f *fakeNetBox) assignVM(o fakeObject) {
+	if k, ok := o["assigned_object_id"]; ok {
+		o["virtual_machine"] = f.objects["virtualization/interfaces"][int(k.(float64))]["virtual_machine"]
+	}
+}
+
+func (f *fakeNetBox) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if r.Header.Get("Authorization") != "Token "+testToken {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	body := fakeObject{}
+	if r.Body != nil {
+		json.NewDecoder(r.Body).Decode(&body)
+	}
+
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/"), "/")
+	allocate := strings.HasSuffix(path, "/available-ips")
+	path = strings.TrimSuffix(path, "/available-ips")
+	kind := path
+	id := 0
+	if i := strings.LastIndex(path, "/"); i > 0 {
+		if n, err := strconv.Atoi(path[i+1:]); err == nil {
+			kind, id = path[:i], n
+		}
+	}
+
+	if f.fail[r.Method+" "+kind] {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	switch {
+	case r.Method == "GET" && id == 0:
+		query := map[string]string{}
+		for k, v := range r.URL.Query() {
+			query[k] = v[0]
+		}
+
+		res := f.filter(kind, query)
+		json.NewEncoder(w).Encode(map[string]interface{}{"count": len(res), "results": res})
+
+	case r.Method == "POST" && allocate:
+		p := f.objects["ipam/prefixes"][id]
+		free := f.available[p["prefix"].(string)]
+		if len(free) == 0 {
+			w.WriteHeader(http.StatusConflict)
+			w.Write([]byte(`{"detail": "insufficient space"}`))
+			return
+		}
+
+		f.available[p["prefix"].(string)] = free[1:]
+		body["address"] = free[0]
+		f.assignVM(body)
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(f.add("ipam/ip-addresses", body))
+
+	case r.Method == "POST":
+		f.assignVM(body)
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(f.add(kind, body))
+
+	case r.Method == "PATCH" && f.objects[kind][id] != nil:
+		o := f.objects[kind][id]
+		for k, v := range body {
+			o[k] = v
+
+			if strings.HasPrefix(k, "primary_ip") {
+				ip := f.objects["ipam/ip-addresses"][int(v.(float64))]
+				o[k] = fakeObject{"id": ip["id"], "address": ip["address"]}
+			}
+		}
+
+		f.assignVM(o)
+		json.NewEncoder(w).Encode(o)
+
+	case r.Method == "DELETE" && f.objects[kind][id] != nil:
+		delete(f.objects[kind], id)
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func testService(t *testing.T, opts Options) (*NetBoxService, *fakeNetBox) {
+	fake := newFakeNetBox()
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+
+	svc, err := NewService(srv.URL, testToken, []*Prefix{
+		{Cluster: "cluster1", Prefix: "192.168.0.0/24", Gateway: "192.168.0.1"},
+		{Prefix: "2001:db8::/64", Gateway: "2001:db8::1"},
+	}, opts)
+	require.NoError(t, err)
+
+	return svc, fake
+}
+
+func testVM() *proto.VirtualMachine {
+	vm := &proto.VirtualMachine{
+		Name:        "vm1",
+		Fqdn:        "vm1.example.com",
+		ClusterName: "cluster1",
+		CpuCores:    2,
+		MemoryMb:    2048,
+	}
+	vm.NormalizeInterfaces()
+
+	return vm
+}
+
+func updates() chan *proto.StatusUpdate {
+	return make(chan *proto.StatusUpdate, 100)
+}
+
+func TestProvision(t *testing.T) {
+	svc, fake := testService(t, Options{})
+
+	vm := testVM()
+	require.True(t, svc.Provision(context.Background(), vm, updates()))
+
+	assert.Equal(t, &proto.IPConfig{Address: "192.168.0.10", PrefixLength: 24, Gateway: "192.168.0.1"}, vm.Ipv4)
+	assert.Equal(t, &proto.IPConfig{Address: "2001:db8::10", PrefixLength: 64, Gateway: "2001:db8::1"}, vm.Ipv6)
+
+	vms := fake.find("virtualization/virtual-machines", "name", "vm1")
+	require.Len(t, vms, 1)
+	assert.Equal(t, float64(2), vms[0]["vcpus"])
+	assert.Equal(t, float64(2048), vms[0]["memory"])
+	assert.Equal(t, statusActive, vms[0]["status"])
+
+	ifaces := fake.find("virtualization/interfaces", "virtual_machine", vms[0]["id"])
+	require.Len(t, ifaces, 1)
+	assert.Equal(t, "ens3", ifaces[0]["name"])
+
+	assert.Len(t, fake.find("ipam/ip-addresses", "assigned_object_id", ifaces[0]["id"]), 2)
+
+	ipv4 := fake.find("ipam/ip-addresses", "address", "192.168.0.10/24")
+	require.Len(t, ipv4, 1)
+	assert.Equal(t, "vm1.example.com", ipv4[0]["dns_name"])
+	assert.Equal(t, ipv4[0]["id"], vms[0]["primary_ip4"].(fakeObject)["id"])
+
+	t.Run("existing VM is reused", func(t *testing.T) {
+		vm := testVM()
+		assert.True(t, svc.Provision(context.Background(), vm, updates()))
+		assert.Equal(t, &proto.IPConfig{Address: "192.168.0.10", PrefixLength: 24, Gateway: "192.168.0.1"}, vm.Ipv4)
+		assert.Equal(t, "2001:db8::10", vm.Ipv6.Address)
+		assert.Len(t, fake.objects["virtualization/virtual-machines"], 1)
+		assert.Len(t, fake.objects["ipam/ip-addresses"], 2)
+	})
+}
+
+func TestProvisionRepairsExistingVM(t *testing.T) {
+	svc, fake := testService(t, Options{})
+	cluster := fake.find("virtualization/clusters", "name", "cluster1")[0]
+	existing := fake.add("virtualization/virtual-machines", fakeObject{"name": "vm1", "cluster": cluster["id"], "status": statusActive})
+
+	vm := testVM()
+	require.True(t, svc.Provision(context.Background(), vm, updates()))
+	assert.Equal(t, "192.168.0.10", vm.Ipv4.Address)
+	assert.Len(t, fake.objects["virtualization/virtual-machines"], 1)
+
+	ifaces := fake.find("virtualization/interfaces", "virtual_machine", existing["id"])
+	require.Len(t, ifaces, 1)
+	assert.Len(t, fake.find("ipam/ip-addresses", "assigned_object_id", ifaces[0]["id"]), 2)
+	assert.NotNil(t, existing["primary_ip4"])
+	assert.NotNil(t, existing["primary_ip6"])
+}
+
+func TestProvisionRollback(t *testing.T) {
+	svc, fake := testService(t, Options{})
+	fake.fail["PATCH virtualization/virtual-machines"] = true
+
+	ch := updates()
+	assert.False(t, svc.Provision(context.Background(), testVM(), ch))
+	close(ch)
+
+	var last *proto.StatusUpdate
+	for u := range ch {
+		last = u
+	}
+
+	assert.True(t, last.Failed)
+	assert.Contains(t, last.Message, "could not set primary addresses")
+	assert.Empty(t, fake.objects["ipam/ip-addresses"])
+	assert.Empty(t, fake.objects["virtualization/virtual-machines"])
+
+	t.Run("existing VM is kept", func(t *testing.T) {
+		cluster := fake.find("virtualization/clusters", "name", "cluster1")[0]
+		fake.add("virtualization/virtual-machines", fakeObject{"name": "vm1", "cluster": cluster["id"], "status": statusActive})
+
+		assert.False(t, svc.Provision(context.Background(), testVM(), updates()))
+		assert.Empty(t, fake.objects["ipam/ip-addresses"])
+		assert.Len(t, fake.objects["virtualization/virtual-machines"], 1)
+	})
+}
+
+func TestProvisionMultipleInterfaces(t *testing.T) {
+	svc, fake := testService(t, Options{})
+	fake.add("ipam/prefixes", fakeObject{"prefix": "10.0.0.0/24"})
+	fake.available["10.0.0.0/24"] = []string{"10.0.0.5/24"}
+	svc.prefixes = append(svc.prefixes, &Prefix{Network: "storage", Prefix: "10.0.0.0/24", ipv4: true})
+
+	vm := testVM()
+	vm.Interfaces = []*proto.NetworkInterface{
+		{Name: "ens3", Ipv4: &proto.IPConfig{Address: "192.168.0.50", PrefixLength: 24}},
+		{Name: "ens4", Network: "storage", Mac: "00:1a:4a:16:01:51"},
+	}
+	vm.Ipv4, vm.Ipv6 = nil, nil
+	require.NoError(t, vm.NormalizeInterfaces())
+	require.True(t, svc.Provision(context.Background(), vm, updates()))
+
+	assert.Equal(t, "2001:db8::10", vm.Interfaces[0].Ipv6.Address)
+	assert.Equal(t, &proto.IPConfig{Address: "10.0.0.5", PrefixLength: 24}, vm.Interfaces[1].Ipv4)
+	assert.Empty(t, vm.Interfaces[1].Ipv6.Address, "IPv6 prefixes without network are used for the primary interface only")
+
+	created := fake.find("ipam/ip-addresses", "address", "192.168.0.50/24")
+	require.Len(t, created, 1)
+
+	storage := fake.find("virtualization/interfaces", "name", "ens4")
+	require.Len(t, storage, 1)
+	assert.Equal(t, "00:1a:4a:16:01:51", storage[0]["mac_address"])
+	assert.Len(t, fake.find("ipam/ip-addresses", "assigned_object_id", storage[0]["id"]), 1)
+
+	vms := fake.find("virtualization/virtual-machines", "name", "vm1")
+	require.Len(t, vms, 1)
+	assert.Equal(t, created[0]["id"], vms[0]["primary_ip4"].(fakeObject)["id"])
+}
+
+func TestProvisionFails(t *testing.T) {
+	tests := []struct {
+		name    string
+		vm      func() *proto.VirtualMachine
+		prepare func(f *fakeNetBox)
+		message string
+	}{
+		{
+			name: "unknown cluster",
+			vm: func() *proto.VirtualMachine {
+				vm := testVM()
+				vm.ClusterName = "cluster2"
+				return vm
+			},
+			message: "cluster cluster2 not found",
+		},
+		{
+			name: "prefix exhausted",
+			vm:   testVM,
+			prepare: func(f *fakeNetBox) {
+				f.available["192.168.0.0/24"] = nil
+			},
+			message: "could not allocate address from prefix 192.168.0.0/24",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			svc, fake := testService(t, Options{})
+			if test.prepare != nil {
+				test.prepare(fake)
+			}
+
+			ch := updates()
+			assert.False(t, svc.Provision(context.Background(), test.vm(), ch))
+			close(ch)
+
+			var last *proto.StatusUpdate
+			for u := range ch {
+				last = u
+			}
+
+			assert.True(t, last.Failed)
+			assert.Contains(t, last.Message, test.message)
+		})
+	}
+}
+
+func TestDeprovision(t *testing.T) {
+	t.Run("delete", func(t *testing.T) {
+		svc, fake := testService(t, Options{})
+		require.True(t, svc.Provision(context.Background(), testVM(), updates()))
+
+		assert.True(t, svc.Deprovision(context.Background(), testVM(), updates()))
+		assert.Empty(t, fake.objects["virtualization/virtual-machines"])
+		assert.Empty(t, fake.objects["ipam/ip-addresses"])
+	})
+
+	t.Run("decommission", func(t *testing.T) {
+		svc, fake := testService(t, Options{Decommission: true})
+		require.True(t, svc.Provision(context.Background(), testVM(), updates()))
+
+		assert.True(t, svc.Deprovision(context.Background(), testVM(), updates()))
+		vms := fake.find("virtualization/virtual-machines", "name", "vm1")
+		require.Len(t, vms, 1)
+		assert.Equal(t, statusDecommissioning, vms[0]["status"])
+		assert.Len(t, fake.objects["ipam/ip-addresses"], 2)
+	})
+
+	t.Run("unknown VM", func(t *testing.T) {
+		svc, _ := testService(t, Options{})
+		assert.True(t, svc.Deprovision(context.Background(), testVM(), updates()))
+	})
+}
+
+func TestRecycleAndRestore(t *testing.T) {
+	svc, fake := testService(t, Options{})
+	require.True(t, svc.Provision(context.Background(), testVM(), updates()))
+
+	assert.True(t, svc.Recycle(context.Background(), testVM(), updates()))
+	vms := fake.find("virtualization/virtual-machines", "name", "vm1")
+	require.Len(t, vms, 1)
+	assert.Equal(t, statusDecommissioning, vms[0]["status"])
+	assert.Len(t, fake.objects["ipam/ip-addresses"], 2)
+
+	assert.True(t, svc.Restore(context.Background(), testVM(), updates()))
+	assert.Equal(t, statusActive, vms[0]["status"])
+}
+
+func TestRequestTimeout(t *testing.T) {
+	svc, _ := testService(t, Options{})
+	assert.Equal(t, defaultRequestTimeout, svc.client.client.Timeout)
+
+	svc, _ = testService(t, Options{Timeout: 5 * time.Second})
+	assert.Equal(t, 5*time.Second, svc.client.client.Timeout)
+}
@@ -0,0 +1,41 @@
+package netbox
+
+import (
+	"net"
+	"strings"
+)
+
+const (
+	statusActive          = "active"
+	statusDecommissioning = "decommissioning"
+
+	vmInterfaceType = "virtualization.vminterface"
+)
+
+type object struct {
+	ID   int    `json:"id"`
+	Name string `json:"name,omitempty"`
+}
+
+type ipAddress struct {
+	ID               int    `json:"id"`
+	Address          string `json:"address"`
+	AssignedObjectID int    `json:"assigned_object_id,omitempty"`
+}
+
+func (ip *ipAddress) isIPv4() bool {
+	addr := net.ParseIP(strings.SplitN(ip.Address, "/", 2)[0])
+	return addr != nil && addr.To4() != nil
+}
+
+type virtualMachine struct {
+	ID         int        `json:"id"`
+	Name       string     `json:"name"`
+	PrimaryIP4 *ipAddress `json:"primary_ip4"`
+	PrimaryIP6 *ipAddress `json:"primary_ip6"`
+}
+
+type prefix struct {
+	ID     int    `json:"id"`
+	Prefix string `json:"prefix"`
+}
@@ -0,0 +1,167 @@
+package ipam
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"net"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/MauveSoftware/provisionize/pkg/api/proto"
+)
+
+// Pool is a prefix addresses can be allocated from
+type Pool struct {
+	Name    string
+	Cluster string
+	Network string
+	Prefix  *net.IPNet
+	Gateway net.IP
+	Exclude []*Range
+}
+
+// Range is an inclusive range of IP addresses
+type Range struct {
+	From net.IP
+	To   net.IP
+}
+
+// NewPool creates a new pool. Excluded ranges are given as single addresses or ranges in the form from-to
+func NewPool(name, cluster, network, prefix, gateway string, exclude []string) (*Pool, error) {
+	_, pfx, err := net.ParseCIDR(prefix)
+	if err != nil {
+		return nil, errors.Wrapf(err, "pool %s: invalid prefix", name)
+	}
+
+	p := &Pool{
+		Name:    name,
+		Cluster: cluster,
+		Network: network,
+		Prefix:  pfx,
+	}
+
+	if len(gateway) > 0 {
+		p.Gateway = net.ParseIP(gateway)
+		if p.Gateway == nil || !pfx.Contains(p.Gateway) {
+			return nil, fmt.Errorf("pool %s: gateway %s is not a valid address in %s", name, gateway, prefix)
+		}
+	}
+
+	for _, e := range exclude {
+		r, err := parseRange(e)
+		if err != nil {
+			return nil, errors.Wrapf(err, "pool %s", name)
+		}
+
+		p.Exclude = append(p.Exclude, r)
+	}
+
+	return p, nil
+}
+
+func parseRange(s string) (*Range, error) {
+	t := strings.SplitN(s, "-", 2)
+
+	from := net.ParseIP(strings.TrimSpace(t[0]))
+	to := from
+	if len(t) == 2 {
+		to = net.ParseIP(strings.TrimSpace(t[1]))
+	}
+
+	if from == nil || to == nil {
+		return nil, fmt.Errorf("invalid address range %s", s)
+	}
+
+	return &Range{From: from, To: to}, nil
+}
+
+// Contains checks if the range contains the address
+func (r *Range) Contains(ip net.IP) bool {
+	return bytes.Compare(ip.To16(), r.From.To16()) >= 0 && bytes.Compare(ip.To16(), r.To.To16()) <= 0
+}
+
+// Matches checks if addresses for the interface of the VM can be allocated from the pool.
+// Pools without network are used for the primary interface only.
+func (p *Pool) Matches(vm *proto.VirtualMachine, iface *proto.NetworkInterface) bool {
+	if len(p.Cluster) > 0 && p.Cluster != vm.ClusterName {
+		return false
+	}
+
+	if len(p.Network) == 0 {
+		return iface.Primary
+	}
+
+	return p.Network == iface.Network
+}
+
+// IsIPv4 returns true if the pool is an IPv4 prefix
+func (p *Pool) IsIPv4() bool {
+	return p.Prefix.IP.To4() != nil
+}
+
+// PrefixLength returns the length of the prefix of the pool
+func (p *Pool) PrefixLength() uint32 {
+	l, _ := p.Prefix.Mask.Size()
+	return uint32(l)
+}
+
+// usable checks if an address of the pool can be assigned to a host
+func (p *Pool) usable(ip net.IP) bool {
+	if !p.Prefix.Contains(ip) || ip.Equal(p.Gateway) || ip.Equal(p.Prefix.IP) {
+		return false
+	}
+
+	if p.IsIPv4() && p.PrefixLength() < 31 && ip.Equal(p.broadcast()) {
+		return false
+	}
+
+	for _, r := range p.Exclude {
+		if r.Contains(ip) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (p *Pool) broadcast() net.IP {
+	ip := make(net.IP, len(p.Prefix.IP))
+	for i := range ip {
+		ip[i] = p.Prefix.IP[i] | ^p.Prefix.Mask[i]
+	}
+
+	return ip
+}
+
+// candidates calls fn for each usable address of the pool in ascending order until fn returns false or max addresses were checked
+func (p *Pool) candidates(max int, fn func(ip net.IP) bool) {
+	ip := p.Prefix.IP
+	for i := 0; i < max; i++ {
+		ip = next(ip)
+		if ip == nil || !p.Prefix.Contains(ip) {
+			return
+		}
+
+		if p.usable(ip) && !fn(ip) {
+			return
+		}
+	}
+}
+
+// next returns the address following ip or nil if ip is the last address of the address space
+func next(ip net.IP) net.IP {
+	i := new(big.Int).SetBytes(ip)
+	i.Add(i, big.NewInt(1))
+
+	b := i.Bytes()
+	if len(b) > len(ip) {
+		return nil
+	}
+
+	res := make(net.IP, len(ip))
+	copy(res[len(res)-len(b):], b)
+
+	return res
+}
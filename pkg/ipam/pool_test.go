@@ -0,0 +1,106 @@
+package ipam
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewPool(t *testing.T) {
+	tests := []struct {
+		name        string
+		prefix      string
+		gateway     string
+		exclude     []string
+		expectError bool
+	}{
+		{
+			name:    "valid",
+			prefix:  "192.168.0.0/24",
+			gateway: "192.168.0.1",
+			exclude: []string{"192.168.0.10-192.168.0.20", "192.168.0.100"},
+		},
+		{
+			name:        "invalid prefix",
+			prefix:      "192.168.0.0",
+			expectError: true,
+		},
+		{
+			name:        "gateway outside prefix",
+			prefix:      "192.168.0.0/24",
+			gateway:     "192.168.1.1",
+			expectError: true,
+		},
+		{
+			name:        "invalid range",
+			prefix:      "192.168.0.0/24",
+			exclude:     []string{"192.168.0.10-foo"},
+			expectError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := NewPool("test", "", "", test.prefix, test.gateway, test.exclude)
+			if test.expectError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestCandidates(t *testing.T) {
+	tests := []struct {
+		name     string
+		prefix   string
+		gateway  string
+		exclude  []string
+		expected []string
+	}{
+		{
+			name:     "IPv4",
+			prefix:   "192.168.0.0/29",
+			gateway:  "192.168.0.1",
+			exclude:  []string{"192.168.0.3-192.168.0.4"},
+			expected: []string{"192.168.0.2", "192.168.0.5", "192.168.0.6"},
+		},
+		{
+			name:     "IPv6",
+			prefix:   "2001:db8::/125",
+			gateway:  "2001:db8::1",
+			exclude:  []string{"2001:db8::5"},
+			expected: []string{"2001:db8::2", "2001:db8::3", "2001:db8::4", "2001:db8::6", "2001:db8::7"},
+		},
+		{
+			name:     "end of IPv4 address space",
+			prefix:   "255.255.255.252/30",
+			gateway:  "255.255.255.253",
+			expected: []string{"255.255.255.254"},
+		},
+		{
+			name:     "end of IPv6 address space",
+			prefix:   "ffff:ffff:ffff:ffff:ffff:ffff:ffff:fffc/126",
+			gateway:  "ffff:ffff:ffff:ffff:ffff:ffff:ffff:fffd",
+			expected: []string{"ffff:ffff:ffff:ffff:ffff:ffff:ffff:fffe", "ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			p, err := NewPool("test", "", "", test.prefix, test.gateway, test.exclude)
+			require.NoError(t, err)
+
+			res := []string{}
+			p.candidates(maxCandidates, func(ip net.IP) bool {
+				res = append(res, ip.String())
+				return true
+			})
+
+			assert.Equal(t, test.expected, res)
+		})
+	}
+}
@@ -0,0 +1,201 @@
+package ipam
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	"go.opencensus.io/trace"
+
+	"github.com/MauveSoftware/provisionize/pkg/api/proto"
+)
+
+const (
+	serviceName = "IPAM"
+
+	// maxCandidates limits the number of addresses checked in a pool (relevant for IPv6 prefixes)
+	maxCandidates = 65536
+)
+
+// Resolver is used to check if an address is already in use by looking up its PTR record
+type Resolver interface {
+	LookupAddr(ctx context.Context, addr string) ([]string, error)
+}
+
+// Store persists the leases of the VMs
+type Store interface {
+	// Leases returns the leases of a VM
+	Leases(vm string) []*Lease
+
+	// IsLeased checks if an address is leased by any VM
+	IsLeased(address string) bool
+
+	// Add stores a new lease for a VM. ErrAddressLeased is returned if the address is already leased.
+	Add(vm string, lease *Lease) error
+
+	// Release removes all leases of a VM
+	Release(vm string) ([]*Lease, error)
+}
+
+// IPAMService allocates addresses for VMs without IP configuration from the configured pools
+type IPAMService struct {
+	pools    []*Pool
+	store    Store
+	resolver Resolver
+}
+
+// NewService creates a new instance of IPAMService. If resolver is nil, DNS is not checked before allocating an address.
+func NewService(pools []*Pool, store Store, resolver Resolver) *IPAMService {
+	return &IPAMService{
+		pools:    pools,
+		store:    store,
+		resolver: resolver,
+	}
+}
+
+// Provision allocates addresses for all interfaces and address families not configured in the VM
+func (s *IPAMService) Provision(ctx context.Context, vm *proto.VirtualMachine, ch chan<- *proto.StatusUpdate) bool {
+	ctx, span := trace.StartSpan(ctx, "IPAMService.Provision")
+	defer span.End()
+
+	for _, iface := range vm.Interfaces {
+		if !s.ensureAddress(ctx, vm, iface, iface.Ipv4, true, ch) ||
+			!s.ensureAddress(ctx, vm, iface, iface.Ipv6, false, ch) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Deprovision releases all addresses leased by the VM
+func (s *IPAMService) Deprovision(ctx context.Context, vm *proto.VirtualMachine, ch chan<- *proto.StatusUpdate) bool {
+	_, span := trace.StartSpan(ctx, "IPAMService.Deprovision")
+	defer span.End()
+
+	leases, err := s.store.Release(vm.Name)
+	if err != nil {
+		ch <- &proto.StatusUpdate{ServiceName: serviceName, Failed: true, Message: err.Error()}
+		return false
+	}
+
+	for _, l := range leases {
+		ch <- &proto.StatusUpdate{ServiceName: serviceName, Message: fmt.Sprintf("Released %s (pool %s)", l.Address, l.Pool)}
+	}
+
+	return true
+}
+
+// Recycle keeps the leases of the VM while it is in the recycle bin
+func (s *IPAMService) Recycle(ctx context.Context, vm *proto.VirtualMachine, ch chan<- *proto.StatusUpdate) bool {
+	for _, l := range s.store.Leases(vm.Name) {
+		ch <- &proto.StatusUpdate{ServiceName: serviceName, Message: fmt.Sprintf("Keeping %s (pool %s) until VM is purged", l.Address, l.Pool)}
+	}
+
+	return true
+}
+
+// Restore does nothing since the leases are kept while the VM is in the recycle bin
+func (s *IPAMService) Restore(ctx context.Context, vm *proto.VirtualMachine, ch chan<- *proto.StatusUpdate) bool {
+	return true
+}
+
+// Purge releases the leases of a VM after the retention period of the recycle bin expired
+func (s *IPAMService) Purge(ctx context.Context, vm *proto.VirtualMachine, ch chan<- *proto.StatusUpdate) bool {
+	return s.Deprovision(ctx, vm, ch)
+}
+
+func (s *IPAMService) ensureAddress(ctx context.Context, vm *proto.VirtualMachine, iface *proto.NetworkInterface, cfg *proto.IPConfig, ipv4 bool,
+	ch chan<- *proto.StatusUpdate) bool {
+	if len(cfg.Address) > 0 {
+		return true
+	}
+
+	pool := s.poolFor(vm, iface, ipv4)
+	if pool == nil {
+		ch <- &proto.StatusUpdate{ServiceName: serviceName, Message: fmt.Sprintf("No %s pool for interface %s => skipping", familyName(ipv4), iface.Name)}
+		return true
+	}
+
+	ip, err := s.leaseFor(ctx, vm, iface, pool)
+	if err != nil {
+		ch <- &proto.StatusUpdate{ServiceName: serviceName, Failed: true, Message: err.Error()}
+		return false
+	}
+
+	cfg.Address = ip.String()
+	cfg.PrefixLength = pool.PrefixLength()
+	if pool.Gateway != nil {
+		cfg.Gateway = pool.Gateway.String()
+	}
+
+	ch <- &proto.StatusUpdate{ServiceName: serviceName, Message: fmt.Sprintf("Allocated %s/%d for interface %s (pool %s)", cfg.Address, cfg.PrefixLength, iface.Name, pool.Name)}
+	return true
+}
+
+func (s *IPAMService) poolFor(vm *proto.VirtualMachine, iface *proto.NetworkInterface, ipv4 bool) *Pool {
+	for _, p := range s.pools {
+		if p.IsIPv4() == ipv4 && p.Matches(vm, iface) {
+			return p
+		}
+	}
+
+	return nil
+}
+
+// leaseFor returns the address already leased for the interface in the pool or allocates the next free one
+func (s *IPAMService) leaseFor(ctx context.Context, vm *proto.VirtualMachine, iface *proto.NetworkInterface, pool *Pool) (net.IP, error) {
+	for _, l := range s.store.Leases(vm.Name) {
+		if l.Pool == pool.Name && l.Interface == iface.Name {
+			return net.ParseIP(l.Address), nil
+		}
+	}
+
+	var ip net.IP
+	var err error
+	pool.candidates(maxCandidates, func(candidate net.IP) bool {
+		if s.store.IsLeased(candidate.String()) || s.inDNS(ctx, candidate) {
+			return true
+		}
+
+		// the address may have been leased by a concurrent request since the check above
+		err = s.store.Add(vm.Name, &Lease{Address: candidate.String(), Pool: pool.Name, Interface: iface.Name})
+		if err == ErrAddressLeased {
+			err = nil
+			return true
+		}
+
+		if err == nil {
+			ip = candidate
+		}
+
+		return false
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if ip == nil {
+		return nil, fmt.Errorf("no free address left in pool %s", pool.Name)
+	}
+
+	return ip, nil
+}
+
+func (s *IPAMService) inDNS(ctx context.Context, ip net.IP) bool {
+	if s.resolver == nil {
+		return false
+	}
+
+	names, err := s.resolver.LookupAddr(ctx, ip.String())
+	return err == nil && len(names) > 0
+}
+
+func familyName(ipv4 bool) string {
+	if ipv4 {
+		return "IPv4"
+	}
+
+	return "IPv6"
+}
@@ -0,0 +1,163 @@
+package ipam
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/MauveSoftware/provisionize/pkg/api/proto"
+)
+
+type mockResolver struct {
+	names map[string][]string
+}
+
+func (r *mockResolver) LookupAddr(ctx context.Context, addr string) ([]string, error) {
+	return r.names[addr], nil
+}
+
+func testService(t *testing.T, resolver Resolver) (*IPAMService, string) {
+	v4, err := NewPool("v4", "", "", "192.168.0.0/29", "192.168.0.1", []string{"192.168.0.2"})
+	require.NoError(t, err)
+
+	v6, err := NewPool("v6", "cluster2", "", "2001:db8::/64", "2001:db8::1", nil)
+	require.NoError(t, err)
+
+	storage, err := NewPool("storage", "", "storage", "10.0.0.0/24", "", nil)
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "leases.json")
+	store, err := NewFileStore(path)
+	require.NoError(t, err)
+
+	return NewService([]*Pool{v4, v6, storage}, store, resolver), path
+}
+
+func provision(t *testing.T, svc *IPAMService, vm *proto.VirtualMachine) bool {
+	ch := make(chan *proto.StatusUpdate, 10)
+	defer close(ch)
+
+	require.NoError(t, vm.NormalizeInterfaces())
+
+	return svc.Provision(context.Background(), vm, ch)
+}
+
+func TestProvision(t *testing.T) {
+	resolver := &mockResolver{
+		names: map[string][]string{
+			"192.168.0.3": {"existing.example.com."},
+		},
+	}
+	svc, path := testService(t, resolver)
+
+	vm := &proto.VirtualMachine{Name: "vm1", ClusterName: "cluster1"}
+	assert.True(t, provision(t, svc, vm))
+	assert.Equal(t, &proto.IPConfig{Address: "192.168.0.4", PrefixLength: 29, Gateway: "192.168.0.1"}, vm.Ipv4)
+	assert.Equal(t, &proto.IPConfig{}, vm.Ipv6, "no IPv6 pool for cluster1")
+
+	vm2 := &proto.VirtualMachine{Name: "vm2", ClusterName: "cluster2", Ipv4: &proto.IPConfig{Address: "10.0.0.1", PrefixLength: 32}}
+	assert.True(t, provision(t, svc, vm2))
+	assert.Equal(t, "10.0.0.1", vm2.Ipv4.Address, "configured address is kept")
+	assert.Equal(t, &proto.IPConfig{Address: "2001:db8::2", PrefixLength: 64, Gateway: "2001:db8::1"}, vm2.Ipv6)
+
+	t.Run("existing lease is reused", func(t *testing.T) {
+		vm := &proto.VirtualMachine{Name: "vm1", ClusterName: "cluster1"}
+		assert.True(t, provision(t, svc, vm))
+		assert.Equal(t, "192.168.0.4", vm.Ipv4.Address)
+	})
+
+	t.Run("leases are persisted", func(t *testing.T) {
+		store, err := NewFileStore(path)
+		require.NoError(t, err)
+		assert.Equal(t, []*Lease{{Address: "192.168.0.4", Pool: "v4", Interface: "ens3"}}, store.Leases("vm1"))
+		assert.True(t, store.IsLeased("2001:db8::2"))
+	})
+}
+
+func TestProvisionMultipleInterfaces(t *testing.T) {
+	svc, _ := testService(t, nil)
+
+	vm := &proto.VirtualMachine{
+		Name: "db1",
+		Interfaces: []*proto.NetworkInterface{
+			{Name: "ens3"},
+			{Name: "ens4", Network: "storage"},
+			{Name: "ens5", Network: "backup"},
+		},
+	}
+	assert.True(t, provision(t, svc, vm))
+
+	assert.Equal(t, "192.168.0.3", vm.Interfaces[0].Ipv4.Address)
+	assert.Equal(t, &proto.IPConfig{Address: "10.0.0.1", PrefixLength: 24}, vm.Interfaces[1].Ipv4)
+	assert.Empty(t, vm.Interfaces[2].Ipv4.Address, "no pool for network backup")
+}
+
+func TestProvisionPoolExhausted(t *testing.T) {
+	svc, _ := testService(t, nil)
+
+	for _, name := range []string{"vm1", "vm2", "vm3", "vm4"} {
+		assert.True(t, provision(t, svc, &proto.VirtualMachine{Name: name}))
+	}
+
+	assert.False(t, provision(t, svc, &proto.VirtualMachine{Name: "vm5"}))
+}
+
+func TestDeprovision(t *testing.T) {
+	svc, _ := testService(t, nil)
+
+	vm := &proto.VirtualMachine{Name: "vm1"}
+	require.True(t, provision(t, svc, vm))
+
+	ch := make(chan *proto.StatusUpdate, 10)
+	assert.True(t, svc.Deprovision(context.Background(), vm, ch))
+	close(ch)
+
+	assert.Empty(t, svc.store.Leases("vm1"))
+	assert.False(t, svc.store.IsLeased(vm.Ipv4.Address))
+
+	vm2 := &proto.VirtualMachine{Name: "vm2"}
+	require.True(t, provision(t, svc, vm2))
+	assert.Equal(t, vm.Ipv4.Address, vm2.Ipv4.Address, "released address is allocated again")
+}
+
+// racingStore leases the first free address to another VM right after it was checked
+type racingStore struct {
+	*FileStore
+	raced bool
+}
+
+func (s *racingStore) IsLeased(address string) bool {
+	leased := s.FileStore.IsLeased(address)
+	if !leased && !s.raced {
+		s.raced = true
+		s.FileStore.Add("other", &Lease{Address: address, Pool: "v4"})
+	}
+
+	return leased
+}
+
+func TestProvisionConcurrentLease(t *testing.T) {
+	pool, err := NewPool("v4", "", "", "192.168.0.0/29", "192.168.0.1", nil)
+	require.NoError(t, err)
+
+	fs, err := NewFileStore(filepath.Join(t.TempDir(), "leases.json"))
+	require.NoError(t, err)
+	svc := NewService([]*Pool{pool}, &racingStore{FileStore: fs}, nil)
+
+	vm := &proto.VirtualMachine{Name: "vm1"}
+	assert.True(t, provision(t, svc, vm))
+	assert.Equal(t, "192.168.0.3", vm.Ipv4.Address)
+	assert.Equal(t, "192.168.0.2", fs.Leases("other")[0].Address)
+}
+
+func TestFileStoreAddLeasedAddress(t *testing.T) {
+	s, err := NewFileStore(filepath.Join(t.TempDir(), "leases.json"))
+	require.NoError(t, err)
+
+	require.NoError(t, s.Add("vm1", &Lease{Address: "192.168.0.2", Pool: "v4"}))
+	assert.Equal(t, ErrAddressLeased, s.Add("vm2", &Lease{Address: "192.168.0.2", Pool: "v4"}))
+	assert.Empty(t, s.Leases("vm2"))
+}
@@ -0,0 +1,130 @@
+package ipam
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// ErrAddressLeased is returned when adding a lease for an address already leased by a VM
+var ErrAddressLeased = errors.New("address is already leased")
+
+// Lease is an address allocated for a VM
+type Lease struct {
+	Address   string `json:"address"`
+	Pool      string `json:"pool"`
+	Interface string `json:"interface,omitempty"`
+}
+
+// FileStore persists leases in a JSON file
+type FileStore struct {
+	path   string
+	mu     sync.Mutex
+	leases map[string][]*Lease
+}
+
+// NewFileStore creates a new store backed by the file at path. Existing leases are loaded from the file.
+func NewFileStore(path string) (*FileStore, error) {
+	s := &FileStore{
+		path:   path,
+		leases: make(map[string][]*Lease),
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+
+	if err != nil {
+		return nil, errors.Wrap(err, "could not read lease file")
+	}
+
+	err = json.Unmarshal(b, &s.leases)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not parse lease file")
+	}
+
+	return s, nil
+}
+
+// Leases returns the leases of a VM
+func (s *FileStore) Leases(vm string) []*Lease {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.leases[vm]
+}
+
+// IsLeased checks if an address is leased by any VM
+func (s *FileStore) IsLeased(address string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, leases := range s.leases {
+		for _, l := range leases {
+			if l.Address == address {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// Add stores a new lease for a VM. ErrAddressLeased is returned if the address is already leased.
+func (s *FileStore) Add(vm string, lease *Lease) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, leases := range s.leases {
+		for _, l := range leases {
+			if l.Address == lease.Address {
+				return ErrAddressLeased
+			}
+		}
+	}
+
+	s.leases[vm] = append(s.leases[vm], lease)
+	return s.save()
+}
+
+// Release removes all leases of a VM
+func (s *FileStore) Release(vm string) ([]*Lease, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	leases := s.leases[vm]
+	delete(s.leases, vm)
+
+	return leases, s.save()
+}
+
+// save writes the leases to a temporary file which replaces the lease file afterwards
+func (s *FileStore) save() error {
+	b, err := json.MarshalIndent(s.leases, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "could not serialize leases")
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(s.path), ".leases")
+	if err != nil {
+		return errors.Wrap(err, "could not create temporary lease file")
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(b)
+	closeErr := tmp.Close()
+	if err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		return errors.Wrap(err, "could not write lease file")
+	}
+
+	return os.Rename(tmp.Name(), s.path)
+}
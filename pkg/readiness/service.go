@@ -0,0 +1,203 @@
+package readiness
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.opencensus.io/trace"
+
+	"github.com/MauveSoftware/provisionize/pkg/api/proto"
+)
+
+const (
+	serviceName = "Readiness"
+
+	defaultTimeout  = 10 * time.Minute
+	defaultInterval = 5 * time.Second
+	dialTimeout     = 3 * time.Second
+)
+
+// GuestAgent is implemented by services able to report if the guest agent of a VM finished its initialization
+type GuestAgent interface {
+	// GuestAgentReady returns true if the VM is initialized. The message describes the current state.
+	GuestAgentReady(ctx context.Context, vm *proto.VirtualMachine) (bool, string, error)
+}
+
+// ReadinessService waits until a new VM is reachable before the following steps (e.g. Ansible) are performed
+type ReadinessService struct {
+	ports      []uint16
+	timeout    time.Duration
+	interval   time.Duration
+	guestAgent GuestAgent
+	dialer     *net.Dialer
+}
+
+// Option configures optional behavior of ReadinessService
+type Option func(s *ReadinessService)
+
+// WithTimeout sets the maximum duration to wait for the VM
+func WithTimeout(timeout time.Duration) Option {
+	return func(s *ReadinessService) {
+		s.timeout = timeout
+	}
+}
+
+// WithInterval sets the interval between two checks
+func WithInterval(interval time.Duration) Option {
+	return func(s *ReadinessService) {
+		s.interval = interval
+	}
+}
+
+// WithGuestAgent waits for the guest agent to report the VM is initialized after all ports are reachable
+func WithGuestAgent(g GuestAgent) Option {
+	return func(s *ReadinessService) {
+		s.guestAgent = g
+	}
+}
+
+// NewService returns a new instance of ReadinessService waiting for the TCP ports to accept connections
+func NewService(ports []uint16, opts ...Option) *ReadinessService {
+	s := &ReadinessService{
+		ports:    ports,
+		timeout:  defaultTimeout,
+		interval: defaultInterval,
+		dialer:   &net.Dialer{Timeout: dialTimeout},
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// Provision waits until the ports are reachable on the addresses of the primary interface and the guest agent reports the VM is initialized
+func (s *ReadinessService) Provision(ctx context.Context, vm *proto.VirtualMachine, ch chan<- *proto.StatusUpdate) bool {
+	ctx, span := trace.StartSpan(ctx, "ReadinessService.Provision")
+	defer span.End()
+
+	err := s.waitForVM(ctx, vm, ch)
+	if err != nil {
+		ch <- &proto.StatusUpdate{ServiceName: serviceName, Failed: true, Message: err.Error()}
+		return false
+	}
+
+	ch <- &proto.StatusUpdate{ServiceName: serviceName, Message: "VM is ready"}
+	return true
+}
+
+// Deprovision does nothing since there is nothing to wait for when a VM is deleted
+func (s *ReadinessService) Deprovision(ctx context.Context, vm *proto.VirtualMachine, ch chan<- *proto.StatusUpdate) bool {
+	return true
+}
+
+// Restore waits until a VM taken from the recycle bin is ready again
+func (s *ReadinessService) Restore(ctx context.Context, vm *proto.VirtualMachine, ch chan<- *proto.StatusUpdate) bool {
+	return s.Provision(ctx, vm, ch)
+}
+
+func (s *ReadinessService) waitForVM(ctx context.Context, vm *proto.VirtualMachine, ch chan<- *proto.StatusUpdate) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	if len(s.ports) > 0 {
+		addresses := addressesOf(vm)
+		if len(addresses) == 0 {
+			return errors.New("VM has no address to check")
+		}
+
+		for _, a := range addresses {
+			for _, p := range s.ports {
+				err := s.waitForPort(ctx, net.JoinHostPort(a, strconv.Itoa(int(p))), ch)
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	if s.guestAgent != nil {
+		return s.waitForGuestAgent(ctx, vm, ch)
+	}
+
+	return nil
+}
+
+func (s *ReadinessService) waitForPort(ctx context.Context, address string, ch chan<- *proto.StatusUpdate) error {
+	ch <- &proto.StatusUpdate{ServiceName: serviceName, Message: fmt.Sprintf("Waiting for %s to accept connections", address)}
+
+	return s.poll(ctx, func() error {
+		conn, err := s.dialer.DialContext(ctx, "tcp", address)
+		if err != nil {
+			return err
+		}
+		conn.Close()
+
+		ch <- &proto.StatusUpdate{ServiceName: serviceName, Message: fmt.Sprintf("%s is reachable", address)}
+		return nil
+	}, address)
+}
+
+func (s *ReadinessService) waitForGuestAgent(ctx context.Context, vm *proto.VirtualMachine, ch chan<- *proto.StatusUpdate) error {
+	if len(vm.Fqdn) == 0 {
+		return errors.New("VM has no FQDN the guest agent could report")
+	}
+
+	last := ""
+
+	return s.poll(ctx, func() error {
+		ready, msg, err := s.guestAgent.GuestAgentReady(ctx, vm)
+		if err != nil {
+			return err
+		}
+
+		if msg != last {
+			ch <- &proto.StatusUpdate{ServiceName: serviceName, Message: msg}
+			last = msg
+		}
+
+		if !ready {
+			return errors.New(msg)
+		}
+
+		return nil
+	}, "guest agent")
+}
+
+// poll calls f until it succeeds. When the timeout is exceeded the last error of f is returned.
+func (s *ReadinessService) poll(ctx context.Context, f func() error, target string) error {
+	for {
+		err := f()
+		if err == nil {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			if ctx.Err() == context.DeadlineExceeded {
+				return errors.Wrapf(err, "%s not ready after %v", target, s.timeout)
+			}
+
+			return ctx.Err()
+		case <-time.After(s.interval):
+		}
+	}
+}
+
+func addressesOf(vm *proto.VirtualMachine) []string {
+	addresses := []string{}
+
+	n := vm.PrimaryInterface()
+	for _, ip := range []*proto.IPConfig{n.Ipv4, n.Ipv6} {
+		if ip != nil && len(ip.Address) > 0 {
+			addresses = append(addresses, ip.Address)
+		}
+	}
+
+	return addresses
+}
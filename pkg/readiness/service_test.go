@@ -0,0 +1,132 @@
+package readiness
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/MauveSoftware/provisionize/pkg/api/proto"
+)
+
+// mockGuestAgent reports the states one after another, the last one is repeated and considered ready if ready is set
+type mockGuestAgent struct {
+	states []string
+	ready  bool
+	calls  int
+}
+
+func (m *mockGuestAgent) GuestAgentReady(ctx context.Context, vm *proto.VirtualMachine) (bool, string, error) {
+	i := m.calls
+	if i >= len(m.states) {
+		i = len(m.states) - 1
+	}
+	m.calls++
+
+	return m.ready && i == len(m.states)-1, m.states[i], nil
+}
+
+func messages(ch chan *proto.StatusUpdate) []string {
+	close(ch)
+
+	m := []string{}
+	for u := range ch {
+		m = append(m, u.Message)
+	}
+
+	return m
+}
+
+func openPort(t *testing.T) uint16 {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { l.Close() })
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	return uint16(l.Addr().(*net.TCPAddr).Port)
+}
+
+func closedPort(t *testing.T) uint16 {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := uint16(l.Addr().(*net.TCPAddr).Port)
+	l.Close()
+
+	return port
+}
+
+func TestProvision(t *testing.T) {
+	vm := &proto.VirtualMachine{Name: "web1", Fqdn: "web1.example.com", Ipv4: &proto.IPConfig{Address: "127.0.0.1"}}
+	port := openPort(t)
+
+	t.Run("port and guest agent ready", func(t *testing.T) {
+		g := &mockGuestAgent{states: []string{"VM status: up", "VM status: up", "Guest agent reports hostname web1"}, ready: true}
+		svc := NewService([]uint16{port}, WithGuestAgent(g), WithInterval(time.Millisecond))
+
+		ch := make(chan *proto.StatusUpdate, 10)
+		assert.True(t, svc.Provision(context.Background(), vm, ch))
+
+		addr := net.JoinHostPort("127.0.0.1", strconv.Itoa(int(port)))
+		assert.Equal(t, []string{
+			"Waiting for " + addr + " to accept connections",
+			addr + " is reachable",
+			"VM status: up",
+			"Guest agent reports hostname web1",
+			"VM is ready",
+		}, messages(ch))
+		assert.Equal(t, 3, g.calls)
+	})
+
+	t.Run("port not reachable", func(t *testing.T) {
+		svc := NewService([]uint16{closedPort(t)}, WithTimeout(50*time.Millisecond), WithInterval(10*time.Millisecond))
+
+		ch := make(chan *proto.StatusUpdate, 10)
+		assert.False(t, svc.Provision(context.Background(), vm, ch))
+
+		m := messages(ch)
+		assert.Contains(t, m[len(m)-1], "not ready after 50ms")
+	})
+
+	t.Run("guest agent timeout", func(t *testing.T) {
+		g := &mockGuestAgent{states: []string{"Waiting for guest agent to report"}}
+		svc := NewService(nil, WithGuestAgent(g), WithTimeout(50*time.Millisecond), WithInterval(10*time.Millisecond))
+
+		ch := make(chan *proto.StatusUpdate, 10)
+		assert.False(t, svc.Provision(context.Background(), vm, ch))
+		assert.Equal(t, []string{
+			"Waiting for guest agent to report",
+			"guest agent not ready after 50ms: Waiting for guest agent to report",
+		}, messages(ch))
+	})
+
+	t.Run("guest agent without FQDN", func(t *testing.T) {
+		g := &mockGuestAgent{states: []string{"Guest agent reports hostname localhost"}, ready: true}
+		svc := NewService(nil, WithGuestAgent(g))
+
+		ch := make(chan *proto.StatusUpdate, 10)
+		assert.False(t, svc.Provision(context.Background(), &proto.VirtualMachine{Name: "web1"}, ch))
+		assert.Equal(t, []string{"VM has no FQDN the guest agent could report"}, messages(ch))
+		assert.Equal(t, 0, g.calls)
+	})
+
+	t.Run("no address", func(t *testing.T) {
+		svc := NewService([]uint16{port})
+
+		ch := make(chan *proto.StatusUpdate, 10)
+		assert.False(t, svc.Provision(context.Background(), &proto.VirtualMachine{Name: "web1"}, ch))
+		assert.Equal(t, []string{"VM has no address to check"}, messages(ch))
+	})
+}
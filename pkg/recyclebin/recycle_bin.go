@@ -0,0 +1,200 @@
+package recyclebin
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/MauveSoftware/provisionize/pkg/api/proto"
+)
+
+// Entry is a deprovisioned VM kept in the recycle bin
+type Entry struct {
+	RequestID      string                `json:"request_id"`
+	DeletedAt      time.Time             `json:"deleted_at"`
+	VirtualMachine *proto.VirtualMachine `json:"virtual_machine"`
+}
+
+// RecycleBin keeps track of deprovisioned VMs until their retention period expired. Entries are persisted in a JSON file.
+type RecycleBin struct {
+	path      string
+	retention time.Duration
+	mu        sync.Mutex
+	entries   []*Entry
+}
+
+// New creates a new recycle bin backed by the file at path. Existing entries are loaded from the file.
+func New(path string, retention time.Duration) (*RecycleBin, error) {
+	b := &RecycleBin{
+		path:      path,
+		retention: retention,
+		entries:   []*Entry{},
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return b, nil
+	}
+
+	if err != nil {
+		return nil, errors.Wrap(err, "could not read recycle bin file")
+	}
+
+	err = json.Unmarshal(data, &b.entries)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not parse recycle bin file")
+	}
+
+	for _, e := range b.entries {
+		err = normalize(e.VirtualMachine)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid recycle bin entry %s", e.RequestID)
+		}
+	}
+
+	return b, nil
+}
+
+// stored returns the copy of the VM kept in the recycle bin.
+// Password and user data are not needed to restore the VM and must not be written to disk.
+func stored(vm *proto.VirtualMachine) *proto.VirtualMachine {
+	c := vm.Redacted()
+	if c.CloudInit != nil {
+		c.CloudInit.UserData = ""
+	}
+
+	normalize(c)
+	return c
+}
+
+// normalize makes ipv4 and ipv6 refer to the primary interface again after copying or deserialization
+func normalize(vm *proto.VirtualMachine) error {
+	vm.Ipv4 = nil
+	vm.Ipv6 = nil
+
+	return vm.NormalizeInterfaces()
+}
+
+// Retention returns the time a VM is kept in the recycle bin
+func (b *RecycleBin) Retention() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.retention
+}
+
+// SetRetention changes the time a VM is kept in the recycle bin (e.g. after the config was reloaded)
+func (b *RecycleBin) SetRetention(retention time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.retention = retention
+}
+
+// Add puts a deprovisioned VM into the recycle bin
+func (b *RecycleBin) Add(requestID string, vm *proto.VirtualMachine, deletedAt time.Time) (*Entry, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	e := &Entry{RequestID: requestID, DeletedAt: deletedAt, VirtualMachine: stored(vm)}
+	b.entries = append(b.entries, e)
+
+	return e, b.save()
+}
+
+// Update replaces the VM of the entry created by the given request (e.g. after a later step recorded its ID) and persists it
+func (b *RecycleBin) Update(requestID string, vm *proto.VirtualMachine) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, e := range b.entries {
+		if e.RequestID == requestID {
+			e.VirtualMachine = stored(vm)
+			return b.save()
+		}
+	}
+
+	return fmt.Errorf("entry %s not found in recycle bin", requestID)
+}
+
+// Find returns the most recently deleted VM with the given name (in the cluster if set) or nil if there is none
+func (b *RecycleBin) Find(name, cluster string) *Entry {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var found *Entry
+	for _, e := range b.entries {
+		if e.VirtualMachine.Name != name || (len(cluster) > 0 && e.VirtualMachine.ClusterName != cluster) {
+			continue
+		}
+
+		if found == nil || e.DeletedAt.After(found.DeletedAt) {
+			found = e
+		}
+	}
+
+	return found
+}
+
+// Expired returns all entries whose retention period expired at the given time
+func (b *RecycleBin) Expired(now time.Time) []*Entry {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	expired := []*Entry{}
+	for _, e := range b.entries {
+		if !now.Before(e.DeletedAt.Add(b.retention)) {
+			expired = append(expired, e)
+		}
+	}
+
+	return expired
+}
+
+// Remove removes the entry created by the given request
+func (b *RecycleBin) Remove(requestID string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	entries := make([]*Entry, 0, len(b.entries))
+	for _, e := range b.entries {
+		if e.RequestID != requestID {
+			entries = append(entries, e)
+		}
+	}
+	b.entries = entries
+
+	return b.save()
+}
+
+// save writes the entries to a temporary file which replaces the recycle bin file afterwards
+func (b *RecycleBin) save() error {
+	data, err := json.MarshalIndent(b.entries, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "could not serialize recycle bin")
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(b.path), ".recycle_bin")
+	if err != nil {
+		return errors.Wrap(err, "could not create temporary recycle bin file")
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(data)
+	closeErr := tmp.Close()
+	if err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		return errors.Wrap(err, "could not write recycle bin file")
+	}
+
+	return os.Rename(tmp.Name(), b.path)
+}
@@ -0,0 +1,95 @@
+package recyclebin
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/MauveSoftware/provisionize/pkg/api/proto"
+)
+
+func newVM(name, cluster, ipv4 string) *proto.VirtualMachine {
+	vm := &proto.VirtualMachine{Name: name, ClusterName: cluster, Ipv4: &proto.IPConfig{Address: ipv4}}
+	vm.NormalizeInterfaces()
+	return vm
+}
+
+func TestRecycleBin(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "recycle_bin.json")
+	deletedAt := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	b, err := New(path, 24*time.Hour)
+	require.NoError(t, err)
+
+	_, err = b.Add("req1", newVM("web1", "cluster1", "192.168.1.10"), deletedAt)
+	require.NoError(t, err)
+	_, err = b.Add("req2", newVM("web1", "cluster1", "192.168.1.11"), deletedAt.Add(time.Hour))
+	require.NoError(t, err)
+	_, err = b.Add("req3", newVM("web2", "cluster2", "192.168.1.12"), deletedAt.Add(2*time.Hour))
+	require.NoError(t, err)
+
+	t.Run("find most recent", func(t *testing.T) {
+		assert.Equal(t, "req2", b.Find("web1", "").RequestID)
+		assert.Equal(t, "req2", b.Find("web1", "cluster1").RequestID)
+		assert.Nil(t, b.Find("web1", "cluster2"))
+		assert.Nil(t, b.Find("web3", ""))
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		assert.Empty(t, b.Expired(deletedAt.Add(23*time.Hour)))
+
+		expired := b.Expired(deletedAt.Add(25 * time.Hour))
+		require.Len(t, expired, 2)
+		assert.Equal(t, "req1", expired[0].RequestID)
+		assert.Equal(t, "req2", expired[1].RequestID)
+	})
+
+	t.Run("update", func(t *testing.T) {
+		vm := newVM("web2", "cluster2", "192.168.1.12")
+		vm.Id = "123"
+		require.NoError(t, b.Update("req3", vm))
+		assert.Error(t, b.Update("req4", vm))
+
+		loaded, err := New(path, 24*time.Hour)
+		require.NoError(t, err)
+		assert.Equal(t, "123", loaded.Find("web2", "").VirtualMachine.Id)
+	})
+
+	t.Run("reload from file", func(t *testing.T) {
+		require.NoError(t, b.Remove("req2"))
+
+		loaded, err := New(path, 24*time.Hour)
+		require.NoError(t, err)
+
+		e := loaded.Find("web1", "")
+		require.NotNil(t, e)
+		assert.Equal(t, "req1", e.RequestID)
+		assert.True(t, deletedAt.Equal(e.DeletedAt))
+		assert.Equal(t, "192.168.1.10", e.VirtualMachine.PrimaryInterface().Ipv4.Address)
+		assert.Same(t, e.VirtualMachine.Interfaces[0].Ipv4, e.VirtualMachine.Ipv4)
+	})
+}
+
+func TestRecycleBinDropsSecrets(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "recycle_bin.json")
+	b, err := New(path, time.Hour)
+	require.NoError(t, err)
+
+	vm := newVM("web1", "cluster1", "192.168.1.10")
+	vm.CloudInit = &proto.CloudInit{UserName: "admin", Password: "secret", UserData: "#cloud-config\npassword: secret\n"}
+	_, err = b.Add("req1", vm, time.Now())
+	require.NoError(t, err)
+
+	data, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "secret")
+
+	e := b.Find("web1", "")
+	assert.Equal(t, "admin", e.VirtualMachine.CloudInit.UserName)
+	assert.Same(t, e.VirtualMachine.Interfaces[0].Ipv4, e.VirtualMachine.Ipv4)
+	assert.Equal(t, "secret", vm.CloudInit.Password, "the VM of the request is not changed")
+}
@@ -0,0 +1,61 @@
+package request
+
+import (
+	"context"
+
+	log "github.com/sirupsen/logrus"
+	"go.opencensus.io/trace"
+
+	"github.com/MauveSoftware/provisionize/pkg/api/proto"
+)
+
+type contextKey int
+
+const (
+	idKey contextKey = iota
+	deprovisionOptionsKey
+)
+
+// WithID returns a copy of ctx carrying the ID of the request being processed
+func WithID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, idKey, id)
+}
+
+// ID returns the ID of the request being processed or an empty string if ctx does not carry one
+func ID(ctx context.Context) string {
+	id, _ := ctx.Value(idKey).(string)
+	return id
+}
+
+// WithDeprovisionOptions returns a copy of ctx carrying the options of the deprovisioning being processed
+func WithDeprovisionOptions(ctx context.Context, opts *proto.DeprovisionOptions) context.Context {
+	return context.WithValue(ctx, deprovisionOptionsKey, opts)
+}
+
+// DeprovisionOptions returns the options of the deprovisioning being processed.
+// If ctx does not carry options, the default options are returned.
+func DeprovisionOptions(ctx context.Context) *proto.DeprovisionOptions {
+	opts, _ := ctx.Value(deprovisionOptionsKey).(*proto.DeprovisionOptions)
+	if opts == nil {
+		return &proto.DeprovisionOptions{}
+	}
+
+	return opts
+}
+
+// Logger returns a log entry annotated with the request ID and the trace context found in ctx
+func Logger(ctx context.Context) *log.Entry {
+	fields := log.Fields{}
+
+	if id := ID(ctx); len(id) > 0 {
+		fields["request_id"] = id
+	}
+
+	if span := trace.FromContext(ctx); span != nil {
+		sc := span.SpanContext()
+		fields["trace_id"] = sc.TraceID.String()
+		fields["span_id"] = sc.SpanID.String()
+	}
+
+	return log.WithFields(fields)
+}
@@ -0,0 +1,38 @@
+package request
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opencensus.io/trace"
+
+	"github.com/MauveSoftware/provisionize/pkg/api/proto"
+)
+
+func TestID(t *testing.T) {
+	ctx := context.Background()
+	assert.Equal(t, "", ID(ctx))
+
+	ctx = WithID(ctx, "abc")
+	assert.Equal(t, "abc", ID(ctx))
+}
+
+func TestDeprovisionOptions(t *testing.T) {
+	ctx := context.Background()
+	assert.Equal(t, &proto.DeprovisionOptions{}, DeprovisionOptions(ctx))
+
+	ctx = WithDeprovisionOptions(ctx, &proto.DeprovisionOptions{Shutdown: true, Force: true})
+	assert.Equal(t, &proto.DeprovisionOptions{Shutdown: true, Force: true}, DeprovisionOptions(ctx))
+}
+
+func TestLogger(t *testing.T) {
+	ctx := WithID(context.Background(), "abc")
+	ctx, span := trace.StartSpan(ctx, "test", trace.WithSampler(trace.AlwaysSample()))
+	defer span.End()
+
+	entry := Logger(ctx)
+	assert.Equal(t, "abc", entry.Data["request_id"])
+	assert.Equal(t, span.SpanContext().TraceID.String(), entry.Data["trace_id"])
+	assert.Equal(t, span.SpanContext().SpanID.String(), entry.Data["span_id"])
+}
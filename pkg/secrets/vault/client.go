@@ -0,0 +1,95 @@
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const defaultMount = "secret"
+
+// Client reads secrets from the KV (version 2) secrets engine of HashiCorp Vault
+type Client struct {
+	address string
+	token   string
+	mount   string
+	client  *http.Client
+}
+
+type kvResponse struct {
+	Data struct {
+		Data map[string]interface{} `json:"data"`
+	} `json:"data"`
+}
+
+type errorResponse struct {
+	Errors []string `json:"errors"`
+}
+
+// NewClient creates a new instance of Client
+func NewClient(address, token, mount string) *Client {
+	if len(mount) == 0 {
+		mount = defaultMount
+	}
+
+	return &Client{
+		address: strings.TrimRight(address, "/"),
+		token:   token,
+		mount:   strings.Trim(mount, "/"),
+		client:  &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Secret retrieves the value for key of the secret stored at path
+func (c *Client) Secret(path, key string) (string, error) {
+	url := fmt.Sprintf("%s/v1/%s/data/%s", c.address, c.mount, strings.Trim(path, "/"))
+
+	req, err := http.NewRequestWithContext(context.Background(), "GET", url, nil)
+	if err != nil {
+		return "", errors.Wrapf(err, "could not create request with URI %s", url)
+	}
+	req.Header.Set("X-Vault-Token", c.token)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return "", errors.Wrapf(err, "could not read secret %s from vault", path)
+	}
+	defer resp.Body.Close()
+
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", errors.Wrap(err, "could not read from response")
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("could not read secret %s from vault (status code %d)%s", path, resp.StatusCode, vaultErrors(b))
+	}
+
+	res := &kvResponse{}
+	err = json.Unmarshal(b, res)
+	if err != nil {
+		return "", errors.Wrapf(err, "could not parse secret %s", path)
+	}
+
+	v, found := res.Data.Data[key]
+	if !found {
+		return "", fmt.Errorf("secret %s has no key %s", path, key)
+	}
+
+	return fmt.Sprint(v), nil
+}
+
+func vaultErrors(b []byte) string {
+	res := &errorResponse{}
+	if json.Unmarshal(b, res) != nil || len(res.Errors) == 0 {
+		return ""
+	}
+
+	return ": " + strings.Join(res.Errors, ", ")
+}
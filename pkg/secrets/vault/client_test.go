@@ -0,0 +1,75 @@
+package vault
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSecret(t *testing.T) {
+	tests := []struct {
+		name        string
+		path        string
+		key         string
+		expected    string
+		expectError bool
+	}{
+		{
+			name:     "existing key",
+			path:     "provisionize",
+			key:      "ovirt_password",
+			expected: "allTheThings",
+		},
+		{
+			name:        "missing key",
+			path:        "provisionize",
+			key:         "foo",
+			expectError: true,
+		},
+		{
+			name:        "missing secret",
+			path:        "other",
+			key:         "ovirt_password",
+			expectError: true,
+		},
+	}
+
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "s.token" {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"errors":["permission denied"]}`))
+			return
+		}
+
+		if r.URL.Path != "/v1/kv/data/provisionize" {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"errors":[]}`))
+			return
+		}
+
+		w.Write([]byte(`{"data":{"data":{"ovirt_password":"allTheThings"},"metadata":{"version":1}}}`))
+	}
+
+	s := httptest.NewServer(http.HandlerFunc(handler))
+	defer s.Close()
+
+	c := NewClient(s.URL, "s.token", "kv")
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			v, err := c.Secret(test.path, test.key)
+			if test.expectError {
+				assert.Error(t, err)
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assert.Equal(t, test.expected, v)
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package server
+
+import (
+	"context"
+
+	"go.opencensus.io/trace"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/MauveSoftware/provisionize/pkg/api/proto"
+)
+
+// DescribeVM returns the current state of a VM reported by all steps able to describe it.
+// Errors of a step are reported in the state, so the state known to the other steps is still returned.
+func (srv *Server) DescribeVM(ctx context.Context, req *proto.DescribeVMRequest) (*proto.VMState, error) {
+	ctx, span := trace.StartSpan(ctx, "API.DescribeVM")
+	defer span.End()
+
+	if req.VirtualMachine == nil {
+		return nil, status.Error(codes.InvalidArgument, "no virtual machine specified")
+	}
+
+	state := &proto.VMState{
+		VirtualMachine: &proto.VirtualMachine{
+			Id:          req.VirtualMachine.Id,
+			Name:        req.VirtualMachine.Name,
+			ClusterName: req.VirtualMachine.ClusterName,
+			Fqdn:        req.VirtualMachine.Fqdn,
+		},
+	}
+	srv.currentPipeline().describe(ctx, state, nil)
+
+	return state, nil
+}
+
+// ListVMs returns the state of all VMs matching the filters of the request.
+// With details set, the state is completed by all steps able to describe a VM.
+func (srv *Server) ListVMs(ctx context.Context, req *proto.ListVMsRequest) (*proto.ListVMsResponse, error) {
+	ctx, span := trace.StartSpan(ctx, "API.ListVMs")
+	defer span.End()
+
+	pipeline := srv.currentPipeline()
+	step, lister := pipeline.vmLister()
+	if lister == nil {
+		return nil, status.Error(codes.Unimplemented, "no step supports listing VMs")
+	}
+
+	vms, err := lister.ListVMs(ctx, req)
+	if err != nil {
+		return nil, status.Errorf(codes.Unavailable, "%s: %v", step.Name, err)
+	}
+
+	if req.Details {
+		pipeline.describeAll(ctx, vms, step)
+	}
+
+	return &proto.ListVMsResponse{Vms: vms}, nil
+}
+
+func (p *Pipeline) describe(ctx context.Context, state *proto.VMState, except *Step) {
+	for _, s := range p.Steps {
+		i, ok := s.Service.(InventoryService)
+		if !ok || s == except {
+			continue
+		}
+
+		err := i.Describe(ctx, state.VirtualMachine, state)
+		if err != nil {
+			state.Errors = append(state.Errors, &proto.BackendError{ServiceName: s.Name, Message: err.Error()})
+		}
+	}
+}
+
+// describeAll completes the states by all steps able to describe a VM. Steps supporting it describe all VMs at once.
+func (p *Pipeline) describeAll(ctx context.Context, states []*proto.VMState, except *Step) {
+	for _, s := range p.Steps {
+		if s == except {
+			continue
+		}
+
+		switch i := s.Service.(type) {
+		case BatchInventoryService:
+			for idx, err := range i.DescribeAll(ctx, states) {
+				if err != nil {
+					states[idx].Errors = append(states[idx].Errors, &proto.BackendError{ServiceName: s.Name, Message: err.Error()})
+				}
+			}
+		case InventoryService:
+			for _, state := range states {
+				err := i.Describe(ctx, state.VirtualMachine, state)
+				if err != nil {
+					state.Errors = append(state.Errors, &proto.BackendError{ServiceName: s.Name, Message: err.Error()})
+				}
+			}
+		}
+	}
+}
+
+// vmLister returns the first step able to list existing VMs
+func (p *Pipeline) vmLister() (*Step, VMLister) {
+	for _, s := range p.Steps {
+		if l, ok := s.Service.(VMLister); ok {
+			return s, l
+		}
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,125 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/MauveSoftware/provisionize/pkg/api/proto"
+)
+
+type mockInventoryService struct {
+	mockService
+	describeErr error
+}
+
+type mockVMLister struct {
+	mockInventoryService
+	vms     []*proto.VMState
+	listErr error
+}
+
+func (m *mockInventoryService) Describe(ctx context.Context, vm *proto.VirtualMachine, state *proto.VMState) error {
+	if m.describeErr != nil {
+		return m.describeErr
+	}
+
+	state.DnsRecords = append(state.DnsRecords, &proto.DNSRecord{Name: vm.Fqdn + ".", Type: "A"})
+	return nil
+}
+
+type mockBatchInventoryService struct {
+	mockInventoryService
+	batches int
+}
+
+func (m *mockBatchInventoryService) DescribeAll(ctx context.Context, states []*proto.VMState) []error {
+	m.batches++
+
+	errs := make([]error, len(states))
+	for i, state := range states {
+		if len(state.VirtualMachine.Fqdn) == 0 {
+			errs[i] = errors.New("fqdn unknown")
+			continue
+		}
+
+		errs[i] = m.Describe(ctx, state.VirtualMachine, state)
+	}
+
+	return errs
+}
+
+func (m *mockVMLister) ListVMs(ctx context.Context, req *proto.ListVMsRequest) ([]*proto.VMState, error) {
+	return m.vms, m.listErr
+}
+
+func TestDescribeVM(t *testing.T) {
+	srv := NewServer(&Pipeline{Steps: []*Step{
+		{Name: "dns", Service: &mockInventoryService{}},
+		{Name: "tower", Service: &mockInventoryService{describeErr: errors.New("unreachable")}},
+		{Name: "ipam", Service: &mockService{name: "ipam"}},
+	}})
+
+	state, err := srv.DescribeVM(context.Background(), &proto.DescribeVMRequest{
+		VirtualMachine: &proto.VirtualMachine{Name: "web1", Fqdn: "web1.example.com"},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []*proto.DNSRecord{{Name: "web1.example.com.", Type: "A"}}, state.DnsRecords)
+	assert.Equal(t, []*proto.BackendError{{ServiceName: "tower", Message: "unreachable"}}, state.Errors)
+
+	_, err = srv.DescribeVM(context.Background(), &proto.DescribeVMRequest{})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestListVMs(t *testing.T) {
+	lister := &mockVMLister{vms: []*proto.VMState{
+		{VirtualMachine: &proto.VirtualMachine{Name: "web1", Fqdn: "web1.example.com"}},
+	}}
+	srv := NewServer(&Pipeline{Steps: []*Step{
+		{Name: "dns", Service: &mockInventoryService{}},
+		{Name: "vm", Service: lister},
+	}})
+
+	resp, err := srv.ListVMs(context.Background(), &proto.ListVMsRequest{})
+	require.NoError(t, err)
+	require.Len(t, resp.Vms, 1)
+	assert.Empty(t, resp.Vms[0].DnsRecords)
+
+	resp, err = srv.ListVMs(context.Background(), &proto.ListVMsRequest{Details: true})
+	require.NoError(t, err)
+	require.Len(t, resp.Vms, 1)
+	assert.Equal(t, []*proto.DNSRecord{{Name: "web1.example.com.", Type: "A"}}, resp.Vms[0].DnsRecords)
+
+	lister.listErr = errors.New("connection refused")
+	_, err = srv.ListVMs(context.Background(), &proto.ListVMsRequest{})
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+
+	srv = NewServer(pipelineForServices([]*mockService{{name: "dns"}}))
+	_, err = srv.ListVMs(context.Background(), &proto.ListVMsRequest{})
+	assert.Equal(t, codes.Unimplemented, status.Code(err))
+}
+
+func TestListVMsBatch(t *testing.T) {
+	lister := &mockVMLister{vms: []*proto.VMState{
+		{VirtualMachine: &proto.VirtualMachine{Name: "web1", Fqdn: "web1.example.com"}},
+		{VirtualMachine: &proto.VirtualMachine{Name: "web2"}},
+	}}
+	dns := &mockBatchInventoryService{}
+	srv := NewServer(&Pipeline{Steps: []*Step{
+		{Name: "dns", Service: dns},
+		{Name: "vm", Service: lister},
+	}})
+
+	resp, err := srv.ListVMs(context.Background(), &proto.ListVMsRequest{Details: true})
+	require.NoError(t, err)
+	require.Len(t, resp.Vms, 2)
+	assert.Equal(t, 1, dns.batches)
+	assert.Equal(t, []*proto.DNSRecord{{Name: "web1.example.com.", Type: "A"}}, resp.Vms[0].DnsRecords)
+	assert.Equal(t, []*proto.BackendError{{ServiceName: "dns", Message: "fqdn unknown"}}, resp.Vms[1].Errors)
+}
@@ -0,0 +1,95 @@
+package server
+
+import (
+	"context"
+
+	"go.opencensus.io/trace"
+
+	"github.com/MauveSoftware/provisionize/pkg/api/proto"
+	"github.com/MauveSoftware/provisionize/pkg/request"
+)
+
+type actionStream interface {
+	client
+	Context() context.Context
+}
+
+type lifecycleFunc func(ctx context.Context, svc LifecycleService, req *proto.VMActionRequest, ch chan<- *proto.StatusUpdate) bool
+
+// StartVM starts an existing VM
+func (srv *Server) StartVM(req *proto.VMActionRequest, stream proto.ProvisionizeService_StartVMServer) error {
+	return srv.runLifecycleAction("StartVM", req, stream, func(ctx context.Context, svc LifecycleService, req *proto.VMActionRequest, ch chan<- *proto.StatusUpdate) bool {
+		return svc.Start(ctx, req.VirtualMachine, ch)
+	})
+}
+
+// StopVM shuts down an existing VM. With force set the VM is stopped immediately.
+func (srv *Server) StopVM(req *proto.VMActionRequest, stream proto.ProvisionizeService_StopVMServer) error {
+	return srv.runLifecycleAction("StopVM", req, stream, func(ctx context.Context, svc LifecycleService, req *proto.VMActionRequest, ch chan<- *proto.StatusUpdate) bool {
+		opts := &proto.DeprovisionOptions{
+			Shutdown:               !req.Force,
+			ShutdownTimeoutSeconds: req.ShutdownTimeoutSeconds,
+			Force:                  req.Force,
+		}
+		return svc.Stop(ctx, req.VirtualMachine, opts, ch)
+	})
+}
+
+// RebootVM reboots an existing VM
+func (srv *Server) RebootVM(req *proto.VMActionRequest, stream proto.ProvisionizeService_RebootVMServer) error {
+	return srv.runLifecycleAction("RebootVM", req, stream, func(ctx context.Context, svc LifecycleService, req *proto.VMActionRequest, ch chan<- *proto.StatusUpdate) bool {
+		return svc.Reboot(ctx, req.VirtualMachine, ch)
+	})
+}
+
+// ResizeVM changes CPU cores and memory of an existing VM
+func (srv *Server) ResizeVM(req *proto.VMActionRequest, stream proto.ProvisionizeService_ResizeVMServer) error {
+	return srv.runLifecycleAction("ResizeVM", req, stream, func(ctx context.Context, svc LifecycleService, req *proto.VMActionRequest, ch chan<- *proto.StatusUpdate) bool {
+		if req.VirtualMachine.CpuCores == 0 && req.VirtualMachine.MemoryMb == 0 {
+			ch <- &proto.StatusUpdate{ServiceName: serviceName, Failed: true, Message: "cpu_cores or memory_mb is required"}
+			return false
+		}
+
+		return svc.Resize(ctx, req.VirtualMachine, ch)
+	})
+}
+
+func (srv *Server) runLifecycleAction(name string, req *proto.VMActionRequest, stream actionStream, f lifecycleFunc) error {
+	ctx, span := trace.StartSpan(request.WithID(stream.Context(), req.RequestId), "API."+name)
+	defer span.End()
+	span.AddAttributes(trace.StringAttribute("request_id", req.RequestId))
+
+	request.Logger(ctx).Infof("Received %s request: %v", name, req.Redacted())
+
+	done := make(chan bool)
+	defer close(done)
+
+	updates := make(chan *proto.StatusUpdate)
+
+	go srv.updateHandler(ctx, stream, updates, done)
+
+	svc := srv.currentPipeline().lifecycleService()
+	switch {
+	case req.VirtualMachine == nil:
+		updates <- &proto.StatusUpdate{ServiceName: serviceName, Failed: true, Message: "no virtual machine specified"}
+	case svc == nil:
+		updates <- &proto.StatusUpdate{ServiceName: serviceName, Failed: true, Message: "no step supports lifecycle operations"}
+	default:
+		f(ctx, svc, req, updates)
+	}
+
+	close(updates)
+	<-done
+	return nil
+}
+
+// lifecycleService returns the first step able to manage existing VMs
+func (p *Pipeline) lifecycleService() LifecycleService {
+	for _, s := range p.Steps {
+		if l, ok := s.Service.(LifecycleService); ok {
+			return l
+		}
+	}
+
+	return nil
+}
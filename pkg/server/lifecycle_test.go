@@ -0,0 +1,72 @@
+package server
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/MauveSoftware/provisionize/pkg/api/proto"
+)
+
+type mockLifecycleService struct {
+	mockService
+	calls []string
+	opts  *proto.DeprovisionOptions
+}
+
+func (m *mockLifecycleService) Start(ctx context.Context, vm *proto.VirtualMachine, ch chan<- *proto.StatusUpdate) bool {
+	m.calls = append(m.calls, "start "+vm.Name)
+	return true
+}
+
+func (m *mockLifecycleService) Stop(ctx context.Context, vm *proto.VirtualMachine, opts *proto.DeprovisionOptions, ch chan<- *proto.StatusUpdate) bool {
+	m.calls = append(m.calls, "stop "+vm.Name)
+	m.opts = opts
+	return true
+}
+
+func (m *mockLifecycleService) Reboot(ctx context.Context, vm *proto.VirtualMachine, ch chan<- *proto.StatusUpdate) bool {
+	m.calls = append(m.calls, "reboot "+vm.Name)
+	return true
+}
+
+func (m *mockLifecycleService) Resize(ctx context.Context, vm *proto.VirtualMachine, ch chan<- *proto.StatusUpdate) bool {
+	m.calls = append(m.calls, "resize "+vm.Name)
+	return true
+}
+
+func TestLifecycleActions(t *testing.T) {
+	svc := &mockLifecycleService{}
+	srv := NewServer(&Pipeline{Steps: []*Step{
+		{Name: "dns", Service: &mockService{name: "dns"}},
+		{Name: "vm", Service: svc},
+	}})
+
+	vm := &proto.VirtualMachine{Name: "web1"}
+	require.NoError(t, srv.StartVM(&proto.VMActionRequest{VirtualMachine: vm}, &mockStream{}))
+	require.NoError(t, srv.RebootVM(&proto.VMActionRequest{VirtualMachine: vm}, &mockStream{}))
+
+	require.NoError(t, srv.StopVM(&proto.VMActionRequest{VirtualMachine: vm, ShutdownTimeoutSeconds: 30}, &mockStream{}))
+	assert.Equal(t, &proto.DeprovisionOptions{Shutdown: true, ShutdownTimeoutSeconds: 30}, svc.opts)
+
+	require.NoError(t, srv.StopVM(&proto.VMActionRequest{VirtualMachine: vm, Force: true}, &mockStream{}))
+	assert.Equal(t, &proto.DeprovisionOptions{Force: true}, svc.opts)
+
+	stream := &mockStream{}
+	require.NoError(t, srv.ResizeVM(&proto.VMActionRequest{VirtualMachine: vm}, stream))
+	assert.Equal(t, []*proto.StatusUpdate{{ServiceName: serviceName, Failed: true, Message: "cpu_cores or memory_mb is required"}}, stream.updates)
+
+	require.NoError(t, srv.ResizeVM(&proto.VMActionRequest{VirtualMachine: &proto.VirtualMachine{Name: "web1", CpuCores: 4}}, &mockStream{}))
+
+	assert.Equal(t, []string{"start web1", "reboot web1", "stop web1", "stop web1", "resize web1"}, svc.calls)
+}
+
+func TestLifecycleActionWithoutService(t *testing.T) {
+	srv := NewServer(pipelineForServices([]*mockService{{name: "dns"}}))
+
+	stream := &mockStream{}
+	require.NoError(t, srv.StartVM(&proto.VMActionRequest{VirtualMachine: &proto.VirtualMachine{Name: "web1"}}, stream))
+	assert.Equal(t, []*proto.StatusUpdate{{ServiceName: serviceName, Failed: true, Message: "no step supports lifecycle operations"}}, stream.updates)
+}
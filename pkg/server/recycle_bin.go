@@ -0,0 +1,174 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.opencensus.io/trace"
+
+	"github.com/MauveSoftware/provisionize/pkg/api/proto"
+	"github.com/MauveSoftware/provisionize/pkg/recyclebin"
+	"github.com/MauveSoftware/provisionize/pkg/request"
+)
+
+// Restore brings back a VM from the recycle bin
+func (srv *Server) Restore(req *proto.ProvisionizeRequest, stream proto.ProvisionizeService_RestoreServer) error {
+	ctx, span := trace.StartSpan(request.WithID(stream.Context(), req.RequestId), "API.Restore")
+	defer span.End()
+	span.AddAttributes(trace.StringAttribute("request_id", req.RequestId))
+
+	request.Logger(ctx).Info("Received Restore request:", req.Redacted())
+
+	done := make(chan bool)
+	defer close(done)
+
+	updates := make(chan *proto.StatusUpdate)
+
+	go srv.updateHandler(ctx, stream, updates, done)
+
+	srv.restore(ctx, srv.currentPipeline(), req.VirtualMachine, updates)
+
+	close(updates)
+	<-done
+	return nil
+}
+
+func (srv *Server) restore(ctx context.Context, pipeline *Pipeline, vm *proto.VirtualMachine, updates chan<- *proto.StatusUpdate) {
+	srv.recycleMu.Lock()
+	defer srv.recycleMu.Unlock()
+
+	e, err := findInRecycleBin(pipeline, vm)
+	if err != nil {
+		updates <- &proto.StatusUpdate{ServiceName: serviceName, Failed: true, Message: err.Error()}
+		return
+	}
+
+	// the entry is removed as soon as all resources kept in the recycle bin are restored,
+	// so a restored VM is never purged even if a later step fails
+	last := lastRecycleStep(pipeline, e.VirtualMachine)
+	if last < 0 && !removeFromRecycleBin(pipeline, e, updates) {
+		return
+	}
+
+	for i, s := range pipeline.Steps {
+		if pipeline.skipStep(e.VirtualMachine, s) {
+			updates <- skippedUpdate(s)
+			continue
+		}
+
+		r, ok := s.Service.(RestoreService)
+		if !ok {
+			continue
+		}
+
+		if !r.Restore(ctx, e.VirtualMachine, updates) {
+			return
+		}
+
+		if i == last && !removeFromRecycleBin(pipeline, e, updates) {
+			return
+		}
+	}
+
+	updates <- &proto.StatusUpdate{ServiceName: serviceName, Message: fmt.Sprintf("VM %s restored", e.VirtualMachine.Name)}
+}
+
+// lastRecycleStep returns the index of the last step keeping resources of the VM in the recycle bin or -1 if there is none
+func lastRecycleStep(pipeline *Pipeline, vm *proto.VirtualMachine) int {
+	last := -1
+	for i, s := range pipeline.Steps {
+		if _, ok := s.Service.(RecycleService); ok && !pipeline.skipStep(vm, s) {
+			last = i
+		}
+	}
+
+	return last
+}
+
+func removeFromRecycleBin(pipeline *Pipeline, e *recyclebin.Entry, updates chan<- *proto.StatusUpdate) bool {
+	err := pipeline.RecycleBin.Remove(e.RequestID)
+	if err != nil {
+		updates <- &proto.StatusUpdate{ServiceName: serviceName, Failed: true, Message: err.Error()}
+		return false
+	}
+
+	return true
+}
+
+func findInRecycleBin(pipeline *Pipeline, vm *proto.VirtualMachine) (*recyclebin.Entry, error) {
+	if vm == nil {
+		return nil, fmt.Errorf("no virtual machine specified")
+	}
+
+	if pipeline.RecycleBin == nil {
+		return nil, fmt.Errorf("recycle bin is not enabled")
+	}
+
+	e := pipeline.RecycleBin.Find(vm.Name, vm.ClusterName)
+	if e == nil {
+		return nil, fmt.Errorf("VM %s not found in recycle bin", vm.Name)
+	}
+
+	return e, nil
+}
+
+// PurgeRecycleBin purges all VMs whose retention period in the recycle bin expired
+func (srv *Server) PurgeRecycleBin(ctx context.Context) {
+	srv.recycleMu.Lock()
+	defer srv.recycleMu.Unlock()
+
+	pipeline := srv.currentPipeline()
+	if pipeline.RecycleBin == nil {
+		return
+	}
+
+	for _, e := range pipeline.RecycleBin.Expired(time.Now()) {
+		srv.purge(request.WithID(ctx, e.RequestID), pipeline, e)
+	}
+}
+
+func (srv *Server) purge(ctx context.Context, pipeline *Pipeline, e *recyclebin.Entry) {
+	ctx, span := trace.StartSpan(ctx, "Server.purge")
+	defer span.End()
+
+	done := make(chan bool)
+	defer close(done)
+
+	updates := make(chan *proto.StatusUpdate)
+
+	go srv.updateHandler(ctx, &logClient{}, updates, done)
+
+	updates <- &proto.StatusUpdate{ServiceName: serviceName, Message: fmt.Sprintf("Purging VM %s from recycle bin", e.VirtualMachine.Name)}
+	if srv.purgeSteps(ctx, pipeline, e.VirtualMachine, updates) {
+		err := pipeline.RecycleBin.Remove(e.RequestID)
+		if err != nil {
+			updates <- &proto.StatusUpdate{ServiceName: serviceName, Failed: true, Message: err.Error()}
+		}
+	}
+
+	close(updates)
+	<-done
+}
+
+func (srv *Server) purgeSteps(ctx context.Context, pipeline *Pipeline, vm *proto.VirtualMachine, updates chan<- *proto.StatusUpdate) bool {
+	for _, s := range pipeline.Steps {
+		r, ok := s.Service.(RecycleService)
+		if !ok || pipeline.skipStep(vm, s) {
+			continue
+		}
+
+		if !r.Purge(ctx, vm, updates) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// logClient is used for operations not triggered by a client. Updates are logged only.
+type logClient struct{}
+
+func (c *logClient) Send(*proto.StatusUpdate) error {
+	return nil
+}
@@ -0,0 +1,247 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/MauveSoftware/provisionize/pkg/api/proto"
+	"github.com/MauveSoftware/provisionize/pkg/recyclebin"
+)
+
+type mockRecycleService struct {
+	calls []string
+}
+
+func (m *mockRecycleService) Provision(ctx context.Context, vm *proto.VirtualMachine, ch chan<- *proto.StatusUpdate) bool {
+	m.calls = append(m.calls, "provision")
+	return true
+}
+
+func (m *mockRecycleService) Deprovision(ctx context.Context, vm *proto.VirtualMachine, ch chan<- *proto.StatusUpdate) bool {
+	m.calls = append(m.calls, "deprovision")
+	return true
+}
+
+func (m *mockRecycleService) Recycle(ctx context.Context, vm *proto.VirtualMachine, ch chan<- *proto.StatusUpdate) bool {
+	m.calls = append(m.calls, "recycle")
+	return true
+}
+
+func (m *mockRecycleService) Purge(ctx context.Context, vm *proto.VirtualMachine, ch chan<- *proto.StatusUpdate) bool {
+	m.calls = append(m.calls, "purge")
+	return true
+}
+
+// mockOvirtRecycleService records the ID of the VM on recycling and requires it for purging like OvirtService
+type mockOvirtRecycleService struct {
+	mockRecycleService
+}
+
+func (m *mockOvirtRecycleService) Recycle(ctx context.Context, vm *proto.VirtualMachine, ch chan<- *proto.StatusUpdate) bool {
+	vm.Id = "vm-123"
+	return m.mockRecycleService.Recycle(ctx, vm, ch)
+}
+
+func (m *mockOvirtRecycleService) Purge(ctx context.Context, vm *proto.VirtualMachine, ch chan<- *proto.StatusUpdate) bool {
+	if len(vm.Id) == 0 {
+		return true
+	}
+
+	m.calls = append(m.calls, "purge "+vm.Id)
+	return true
+}
+
+// mockDNSRecycleService keeps the records on recycling and records the address of the VM like GoogleCloudDNSService
+type mockDNSRecycleService struct {
+	mockRecycleService
+}
+
+func (m *mockDNSRecycleService) Recycle(ctx context.Context, vm *proto.VirtualMachine, ch chan<- *proto.StatusUpdate) bool {
+	vm.PrimaryInterface().Ipv4.Address = "192.168.1.10"
+	return m.mockRecycleService.Recycle(ctx, vm, ch)
+}
+
+// mockTowerRestoreService records the address the VM is restored with like the ansible_ssh_host of the Tower service
+type mockTowerRestoreService struct {
+	mockService
+	hosts []string
+}
+
+func (m *mockTowerRestoreService) Restore(ctx context.Context, vm *proto.VirtualMachine, ch chan<- *proto.StatusUpdate) bool {
+	m.hosts = append(m.hosts, vm.PrimaryInterface().Ipv4.Address)
+	return true
+}
+
+func (m *mockRecycleService) Restore(ctx context.Context, vm *proto.VirtualMachine, ch chan<- *proto.StatusUpdate) bool {
+	m.calls = append(m.calls, "restore "+vm.Fqdn)
+	return true
+}
+
+type failingRestoreService struct {
+	mockService
+}
+
+func (m *failingRestoreService) Restore(ctx context.Context, vm *proto.VirtualMachine, ch chan<- *proto.StatusUpdate) bool {
+	ch <- &proto.StatusUpdate{ServiceName: m.name, Failed: true, Message: "restore failed"}
+	return false
+}
+
+func newRecycleBinServer(t *testing.T, retention time.Duration) (*Server, *mockRecycleService) {
+	bin, err := recyclebin.New(filepath.Join(t.TempDir(), "recycle_bin.json"), retention)
+	require.NoError(t, err)
+
+	vm := &mockRecycleService{}
+	p := &Pipeline{
+		Steps: []*Step{
+			{Name: "vm", Service: vm},
+			{Name: "dns", Service: &mockService{name: "dns"}},
+		},
+		RecycleBin: bin,
+	}
+
+	return NewServer(p), vm
+}
+
+func deprovisionize(t *testing.T, srv *Server) []*proto.StatusUpdate {
+	stream := &mockStream{}
+	req := &proto.ProvisionizeRequest{
+		RequestId:      "req1",
+		VirtualMachine: &proto.VirtualMachine{Name: "web1", Fqdn: "web1.example.com"},
+	}
+	require.NoError(t, srv.Deprovisionize(req, stream))
+
+	return stream.updates
+}
+
+func TestRestore(t *testing.T) {
+	srv, vm := newRecycleBinServer(t, time.Hour)
+
+	updates := deprovisionize(t, srv)
+	assert.Equal(t, []string{"recycle"}, vm.calls)
+	assert.Contains(t, updates[0].Message, "VM moved to recycle bin")
+
+	stream := &mockStream{}
+	err := srv.Restore(&proto.ProvisionizeRequest{VirtualMachine: &proto.VirtualMachine{Name: "web1"}}, stream)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"recycle", "restore web1.example.com"}, vm.calls)
+	assert.Equal(t, &proto.StatusUpdate{ServiceName: serviceName, Message: "VM web1 restored"}, stream.updates[len(stream.updates)-1])
+
+	stream = &mockStream{}
+	err = srv.Restore(&proto.ProvisionizeRequest{VirtualMachine: &proto.VirtualMachine{Name: "web1"}}, stream)
+	require.NoError(t, err)
+	assert.Equal(t, []*proto.StatusUpdate{{ServiceName: serviceName, Failed: true, Message: "VM web1 not found in recycle bin"}}, stream.updates)
+}
+
+func TestRestoreRemovesEntryOnceVMIsRestored(t *testing.T) {
+	srv, vm := newRecycleBinServer(t, 0)
+	p := srv.currentPipeline()
+	p.Steps = append(p.Steps, &Step{Name: "ipam", Service: &failingRestoreService{mockService{name: "ipam"}}})
+
+	deprovisionize(t, srv)
+
+	stream := &mockStream{}
+	err := srv.Restore(&proto.ProvisionizeRequest{VirtualMachine: &proto.VirtualMachine{Name: "web1"}}, stream)
+	require.NoError(t, err)
+	assert.Equal(t, &proto.StatusUpdate{ServiceName: "ipam", Failed: true, Message: "restore failed"}, stream.updates[len(stream.updates)-1])
+	assert.Nil(t, p.RecycleBin.Find("web1", ""))
+
+	srv.PurgeRecycleBin(context.Background())
+	assert.NotContains(t, vm.calls, "purge", "restored VM must not be purged")
+}
+
+func TestDeprovisionizeKeepsEntryWhenLaterStepFails(t *testing.T) {
+	srv, vm := newRecycleBinServer(t, time.Hour)
+	p := srv.currentPipeline()
+	p.Steps = append(p.Steps, &Step{Name: "netbox", Service: &mockService{name: "netbox", err: errors.New("failed")}})
+
+	updates := deprovisionize(t, srv)
+	assert.Equal(t, []string{"recycle"}, vm.calls)
+	assert.True(t, updates[len(updates)-1].Failed)
+	assert.NotNil(t, p.RecycleBin.Find("web1", ""), "recycled VM must be tracked in the recycle bin")
+}
+
+func TestPurgeRecycleBin(t *testing.T) {
+	srv, vm := newRecycleBinServer(t, 0)
+
+	deprovisionize(t, srv)
+	srv.PurgeRecycleBin(context.Background())
+	assert.Equal(t, []string{"recycle", "purge"}, vm.calls)
+
+	srv.PurgeRecycleBin(context.Background())
+	assert.Equal(t, []string{"recycle", "purge"}, vm.calls, "VM is purged only once")
+}
+
+func TestPurgeRecycleBinAfterReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "recycle_bin.json")
+	bin, err := recyclebin.New(path, 0)
+	require.NoError(t, err)
+
+	ipam := &mockRecycleService{}
+	vm := &mockOvirtRecycleService{}
+	steps := []*Step{
+		{Name: "ipam", Service: ipam},
+		{Name: "vm", Service: vm},
+	}
+	srv := NewServer(&Pipeline{Steps: steps, RecycleBin: bin})
+	deprovisionize(t, srv)
+
+	bin, err = recyclebin.New(path, 0)
+	require.NoError(t, err)
+	srv = NewServer(&Pipeline{Steps: steps, RecycleBin: bin})
+
+	srv.PurgeRecycleBin(context.Background())
+	assert.Equal(t, []string{"recycle", "purge"}, ipam.calls)
+	assert.Equal(t, []string{"recycle", "purge vm-123"}, vm.calls)
+}
+
+func TestRestoreWithAddressesFromRecycleStep(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "recycle_bin.json")
+	bin, err := recyclebin.New(path, time.Hour)
+	require.NoError(t, err)
+
+	tower := &mockTowerRestoreService{mockService: mockService{name: "tower"}}
+	steps := []*Step{
+		{Name: "vm", Service: &mockOvirtRecycleService{}},
+		{Name: "dns", Service: &mockDNSRecycleService{}},
+		{Name: "tower", Service: tower},
+	}
+	srv := NewServer(&Pipeline{Steps: steps, RecycleBin: bin})
+	deprovisionize(t, srv)
+
+	bin, err = recyclebin.New(path, time.Hour)
+	require.NoError(t, err)
+	srv = NewServer(&Pipeline{Steps: steps, RecycleBin: bin})
+
+	stream := &mockStream{}
+	require.NoError(t, srv.Restore(&proto.ProvisionizeRequest{VirtualMachine: &proto.VirtualMachine{Name: "web1"}}, stream))
+	assert.Equal(t, &proto.StatusUpdate{ServiceName: serviceName, Message: "VM web1 restored"}, stream.updates[len(stream.updates)-1])
+	assert.Equal(t, []string{"192.168.1.10"}, tower.hosts)
+}
+
+func TestProvisionizeRejectsRecycledName(t *testing.T) {
+	srv, vm := newRecycleBinServer(t, time.Hour)
+	deprovisionize(t, srv)
+
+	stream := &mockStream{}
+	req := &proto.ProvisionizeRequest{VirtualMachine: &proto.VirtualMachine{Name: "web1", ClusterName: "cluster2"}}
+	require.NoError(t, srv.Provisionize(req, stream))
+	assert.Equal(t, []*proto.StatusUpdate{
+		{ServiceName: serviceName, Failed: true, Message: "VM web1 is in the recycle bin. Restore it or wait until it is purged"},
+	}, stream.updates)
+	assert.Equal(t, []string{"recycle"}, vm.calls)
+}
+
+func TestRestoreWithoutRecycleBin(t *testing.T) {
+	srv := NewServer(pipelineForServices([]*mockService{{name: "service1"}}))
+
+	stream := &mockStream{}
+	err := srv.Restore(&proto.ProvisionizeRequest{VirtualMachine: &proto.VirtualMachine{Name: "web1"}}, stream)
+	require.NoError(t, err)
+	assert.Equal(t, []*proto.StatusUpdate{{ServiceName: serviceName, Failed: true, Message: "recycle bin is not enabled"}}, stream.updates)
+}
@@ -1,11 +1,18 @@
 package server
 
 import (
+	"context"
+	"fmt"
 	"net"
+	"sync"
+	"time"
 
 	"github.com/MauveSoftware/provisionize/pkg/api/proto"
+	"github.com/MauveSoftware/provisionize/pkg/recyclebin"
+	"github.com/MauveSoftware/provisionize/pkg/request"
 
 	"github.com/pkg/errors"
+	"go.opencensus.io/plugin/ocgrpc"
 	"go.opencensus.io/trace"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/reflection"
@@ -13,48 +20,180 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
+const (
+	serviceName = "Provisionize"
+
+	// closeTimeout limits the time waiting for background work of a replaced pipeline
+	closeTimeout = time.Minute
+)
+
 type client interface {
 	Send(*proto.StatusUpdate) error
 }
 
-type server struct {
-	services []ProvisionService
+// Pipeline is the ordered list of steps a request is processed with
+type Pipeline struct {
+	Steps      []*Step
+	Templates  TemplateService
+	RecycleBin *recyclebin.RecycleBin
 }
 
-// StartServer starts an gRPC API endpoint
-func StartServer(conn net.Listener, services []ProvisionService) error {
-	srv := &server{
-		services: services,
+// Step is a named service participating in a provisioning
+type Step struct {
+	Name    string
+	Service ProvisionService
+}
+
+func (p *Pipeline) normalize(vm *proto.VirtualMachine) error {
+	if vm == nil {
+		return errors.New("no virtual machine specified")
 	}
 
-	s := grpc.NewServer()
-	proto.RegisterProvisionizeServiceServer(s, srv)
-	reflection.Register(s)
+	return vm.NormalizeInterfaces()
+}
+
+func (p *Pipeline) applyTemplate(vm *proto.VirtualMachine) error {
+	if err := p.normalize(vm); err != nil {
+		return err
+	}
+
+	if p.Templates == nil {
+		return nil
+	}
+
+	return p.Templates.ApplyTemplate(vm)
+}
+
+func (p *Pipeline) skipStep(vm *proto.VirtualMachine, step *Step) bool {
+	return p.Templates != nil && p.Templates.SkipStep(vm, step.Name)
+}
+
+// close waits for the background work of the services until ctx is done
+func (p *Pipeline) close(ctx context.Context) error {
+	var result error
+	for _, s := range p.Steps {
+		c, ok := s.Service.(CloseService)
+		if !ok {
+			continue
+		}
+
+		err := c.Close(ctx)
+		if err != nil && result == nil {
+			result = errors.Wrapf(err, "step %s", s.Name)
+		}
+	}
+
+	return result
+}
+
+// Server implements the provisionize gRPC API
+type Server struct {
+	mu         sync.RWMutex
+	pipeline   *Pipeline
+	recycleMu  sync.Mutex
+	grpcServer *grpc.Server
+}
+
+// NewServer creates a new server processing requests with the given pipeline
+func NewServer(pipeline *Pipeline) *Server {
+	srv := &Server{
+		pipeline:   pipeline,
+		grpcServer: grpc.NewServer(grpc.StatsHandler(&ocgrpc.ServerHandler{})),
+	}
+	proto.RegisterProvisionizeServiceServer(srv.grpcServer, srv)
+	reflection.Register(srv.grpcServer)
 
+	return srv
+}
+
+// UpdatePipeline replaces the pipeline used for new requests. Requests already in progress keep the pipeline they started with,
+// the previous pipeline is closed in background.
+func (srv *Server) UpdatePipeline(pipeline *Pipeline) {
+	srv.mu.Lock()
+	previous := srv.pipeline
+	srv.pipeline = pipeline
+	srv.mu.Unlock()
+
+	if previous == nil || previous == pipeline {
+		return
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), closeTimeout)
+		defer cancel()
+
+		err := previous.close(ctx)
+		if err != nil {
+			log.Warnf("Could not close previous pipeline: %v", err)
+		}
+	}()
+}
+
+// Close stops the API endpoint once the requests in progress completed and waits for the background work of the current pipeline.
+// Requests still in progress when ctx is done are cancelled.
+func (srv *Server) Close(ctx context.Context) error {
+	stopped := make(chan struct{})
+	go func() {
+		srv.grpcServer.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		srv.grpcServer.Stop()
+	}
+
+	return srv.currentPipeline().close(ctx)
+}
+
+func (srv *Server) currentPipeline() *Pipeline {
+	srv.mu.RLock()
+	defer srv.mu.RUnlock()
+
+	return srv.pipeline
+}
+
+// Serve starts an gRPC API endpoint
+func (srv *Server) Serve(conn net.Listener) error {
 	log.Println("Starting API server on", conn.Addr())
-	if err := s.Serve(conn); err != nil {
+	// the server can be closed before it was started
+	if err := srv.grpcServer.Serve(conn); err != nil && err != grpc.ErrServerStopped {
 		return errors.Wrap(err, "failed to serve")
 	}
 
 	return nil
 }
 
-func (srv *server) Provisionize(req *proto.ProvisionizeRequest, stream proto.ProvisionizeService_ProvisionizeServer) error {
-	log.Info("Received Provisionize request:", req)
-	ctx, span := trace.StartSpan(stream.Context(), "API.Provisionize")
+func (srv *Server) Provisionize(req *proto.ProvisionizeRequest, stream proto.ProvisionizeService_ProvisionizeServer) error {
+	ctx, span := trace.StartSpan(request.WithID(stream.Context(), req.RequestId), "API.Provisionize")
 	defer span.End()
+	span.AddAttributes(trace.StringAttribute("request_id", req.RequestId))
 
-	// TODO: sanity checks
+	request.Logger(ctx).Info("Received Provisionize request:", req.Redacted())
 
 	done := make(chan bool)
 	defer close(done)
 
 	updates := make(chan *proto.StatusUpdate)
 
-	go srv.updateHandler(req.RequestId, stream, updates, done)
+	go srv.updateHandler(ctx, stream, updates, done)
+
+	pipeline := srv.currentPipeline()
+	if err := srv.checkProvisionable(pipeline, req.VirtualMachine); err != nil {
+		updates <- &proto.StatusUpdate{ServiceName: serviceName, Failed: true, Message: err.Error()}
+		close(updates)
+		<-done
+		return nil
+	}
+
+	for _, s := range pipeline.Steps {
+		if pipeline.skipStep(req.VirtualMachine, s) {
+			updates <- skippedUpdate(s)
+			continue
+		}
 
-	for _, s := range srv.services {
-		if !s.Provision(ctx, req.VirtualMachine, updates) {
+		if !s.Service.Provision(ctx, req.VirtualMachine, updates) {
 			break
 		}
 	}
@@ -64,10 +203,28 @@ func (srv *server) Provisionize(req *proto.ProvisionizeRequest, stream proto.Pro
 	return nil
 }
 
-func (srv *server) Deprovisionize(req *proto.ProvisionizeRequest, stream proto.ProvisionizeService_DeprovisionizeServer) error {
-	log.Info("Received Deprovisionize request:", req)
-	ctx, span := trace.StartSpan(stream.Context(), "API.Deprovisionize")
+// checkProvisionable applies the template and rejects names still in the recycle bin.
+// Steps identify the resources of a recycled VM by its name, so purging it would remove the resources of the new VM.
+func (srv *Server) checkProvisionable(pipeline *Pipeline, vm *proto.VirtualMachine) error {
+	err := pipeline.applyTemplate(vm)
+	if err != nil {
+		return err
+	}
+
+	if pipeline.RecycleBin != nil && pipeline.RecycleBin.Find(vm.Name, "") != nil {
+		return fmt.Errorf("VM %s is in the recycle bin. Restore it or wait until it is purged", vm.Name)
+	}
+
+	return nil
+}
+
+func (srv *Server) Deprovisionize(req *proto.ProvisionizeRequest, stream proto.ProvisionizeService_DeprovisionizeServer) error {
+	ctx := request.WithDeprovisionOptions(request.WithID(stream.Context(), req.RequestId), req.DeprovisionOptions)
+	ctx, span := trace.StartSpan(ctx, "API.Deprovisionize")
 	defer span.End()
+	span.AddAttributes(trace.StringAttribute("request_id", req.RequestId))
+
+	request.Logger(ctx).Info("Received Deprovisionize request:", req.Redacted())
 
 	// TODO: sanity checks
 
@@ -76,31 +233,138 @@ func (srv *server) Deprovisionize(req *proto.ProvisionizeRequest, stream proto.P
 
 	updates := make(chan *proto.StatusUpdate)
 
-	go srv.updateHandler(req.RequestId, stream, updates, done)
+	go srv.updateHandler(ctx, stream, updates, done)
 
-	for _, s := range srv.services {
-		if !s.Deprovision(ctx, req.VirtualMachine, updates) {
-			break
-		}
+	pipeline := srv.currentPipeline()
+	if err := pipeline.normalize(req.VirtualMachine); err != nil {
+		updates <- &proto.StatusUpdate{ServiceName: serviceName, Failed: true, Message: err.Error()}
+		close(updates)
+		<-done
+		return nil
 	}
 
+	srv.deprovision(ctx, pipeline, req, updates)
+
 	close(updates)
 	<-done
 	return nil
 }
 
-func (srv *server) updateHandler(id string, cl client, updates chan *proto.StatusUpdate,
+func (srv *Server) deprovision(ctx context.Context, pipeline *Pipeline, req *proto.ProvisionizeRequest, updates chan<- *proto.StatusUpdate) bool {
+	vm := req.VirtualMachine
+	if !srv.preDeprovision(ctx, pipeline, vm, updates) {
+		return false
+	}
+
+	recycled := false
+	for _, s := range pipeline.Steps {
+		if pipeline.skipStep(vm, s) {
+			updates <- skippedUpdate(s)
+			continue
+		}
+
+		if r, ok := s.Service.(RecycleService); ok && pipeline.RecycleBin != nil {
+			if !r.Recycle(ctx, vm, updates) {
+				return false
+			}
+
+			// the entry is added as soon as anything is kept in the recycle bin, so recycled resources are not orphaned
+			// if a later step fails. It is saved again after every step since steps record data needed to purge (e.g. the oVirt ID).
+			if recycled {
+				if !srv.updateRecycleBin(pipeline.RecycleBin, req, updates) {
+					return false
+				}
+
+				continue
+			}
+
+			if !srv.addToRecycleBin(pipeline.RecycleBin, req, updates) {
+				return false
+			}
+
+			recycled = true
+			continue
+		}
+
+		if !s.Service.Deprovision(ctx, vm, updates) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// preDeprovision runs the steps depending on the VM (e.g. decommissioning jobs) before any step deletes it
+func (srv *Server) preDeprovision(ctx context.Context, pipeline *Pipeline, vm *proto.VirtualMachine, updates chan<- *proto.StatusUpdate) bool {
+	for _, s := range pipeline.Steps {
+		p, ok := s.Service.(PreDeprovisionService)
+		if !ok || pipeline.skipStep(vm, s) {
+			continue
+		}
+
+		if !p.PreDeprovision(ctx, vm, updates) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (srv *Server) addToRecycleBin(bin *recyclebin.RecycleBin, req *proto.ProvisionizeRequest, updates chan<- *proto.StatusUpdate) bool {
+	e, err := bin.Add(req.RequestId, req.VirtualMachine, time.Now())
+	if err != nil {
+		updates <- &proto.StatusUpdate{ServiceName: serviceName, Failed: true, Message: err.Error()}
+		return false
+	}
+
+	updates <- &proto.StatusUpdate{
+		ServiceName: serviceName,
+		Message:     fmt.Sprintf("VM moved to recycle bin. It will be purged after %s", e.DeletedAt.Add(bin.Retention()).Format(time.RFC3339)),
+	}
+
+	return true
+}
+
+func (srv *Server) updateRecycleBin(bin *recyclebin.RecycleBin, req *proto.ProvisionizeRequest, updates chan<- *proto.StatusUpdate) bool {
+	err := bin.Update(req.RequestId, req.VirtualMachine)
+	if err != nil {
+		updates <- &proto.StatusUpdate{ServiceName: serviceName, Failed: true, Message: err.Error()}
+		return false
+	}
+
+	return true
+}
+
+func skippedUpdate(step *Step) *proto.StatusUpdate {
+	return &proto.StatusUpdate{
+		ServiceName: step.Name,
+		Message:     "Step is skipped for this template",
+	}
+}
+
+func (srv *Server) updateHandler(ctx context.Context, cl client, updates chan *proto.StatusUpdate,
 	done chan bool) {
+	logger := request.Logger(ctx)
+
 	for update := range updates {
-		log.Infof("Request: %s\nService: %s\nMessage: %s", id, update.ServiceName, update.Message)
+		l := logger.WithField("service", update.ServiceName)
+		if update.Failed {
+			l = l.WithField("failed", true)
+		}
+
+		if update.Warning {
+			l.Warn(update.Message)
+		} else {
+			l.Info(update.Message)
+		}
 
 		if len(update.DebugMessage) > 0 {
-			log.Debugf("Request: %s\nService: %s\nDebug-Message: %s", id, update.ServiceName, update.DebugMessage)
+			l.Debug(update.DebugMessage)
 		}
 
 		err := cl.Send(update)
 		if err != nil {
-			log.Errorf("Error while sending update to client: %v", err)
+			l.Errorf("Error while sending update to client: %v", err)
 		}
 	}
 
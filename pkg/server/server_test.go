@@ -3,7 +3,9 @@ package server
 import (
 	"context"
 	"fmt"
+	"net"
 	"testing"
+	"time"
 
 	"google.golang.org/grpc"
 
@@ -15,9 +17,11 @@ import (
 type mockService struct {
 	name string
 	err  error
+	vms  []*proto.VirtualMachine
 }
 
 func (m *mockService) Provision(ctx context.Context, vm *proto.VirtualMachine, ch chan<- *proto.StatusUpdate) bool {
+	m.vms = append(m.vms, vm)
 	status := &proto.StatusUpdate{
 		ServiceName: m.name,
 	}
@@ -49,6 +53,41 @@ func (m *mockService) Deprovision(ctx context.Context, vm *proto.VirtualMachine,
 	return result
 }
 
+type mockTemplateService struct {
+	skip      map[string]bool
+	templates []*proto.Template
+	applyErr  error
+}
+
+func (m *mockTemplateService) SkipStep(vm *proto.VirtualMachine, step string) bool {
+	return m.skip[step]
+}
+
+func (m *mockTemplateService) Templates() []*proto.Template {
+	return m.templates
+}
+
+func (m *mockTemplateService) ApplyTemplate(vm *proto.VirtualMachine) error {
+	if m.applyErr != nil {
+		return m.applyErr
+	}
+
+	if vm.CpuCores == 0 {
+		vm.CpuCores = 2
+	}
+
+	return nil
+}
+
+func pipelineForServices(services []*mockService) *Pipeline {
+	steps := make([]*Step, len(services))
+	for i, svc := range services {
+		steps[i] = &Step{Name: svc.name, Service: svc}
+	}
+
+	return &Pipeline{Steps: steps}
+}
+
 type mockStream struct {
 	grpc.ServerStream
 	updates []*proto.StatusUpdate
@@ -133,16 +172,9 @@ func TestProvisionize(t *testing.T) {
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			services := make([]ProvisionService, len(test.services))
-			for i, svc := range test.services {
-				services[i] = svc
-			}
-
-			srv := &server{
-				services: services,
-			}
+			srv := NewServer(pipelineForServices(test.services))
 
-			req := &proto.ProvisionizeRequest{}
+			req := &proto.ProvisionizeRequest{VirtualMachine: &proto.VirtualMachine{}}
 			stream := &mockStream{}
 			err := srv.Provisionize(req, stream)
 			if err != nil {
@@ -224,23 +256,240 @@ func TestDeprovisionize(t *testing.T) {
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			services := make([]ProvisionService, len(test.services))
-			for i, svc := range test.services {
-				services[i] = svc
+			srv := NewServer(pipelineForServices(test.services))
+
+			req := &proto.ProvisionizeRequest{VirtualMachine: &proto.VirtualMachine{}}
+			stream := &mockStream{}
+			err := srv.Deprovisionize(req, stream)
+			if err != nil {
+				t.Error(err)
 			}
 
-			srv := &server{
-				services: services,
+			assert.Equal(t, test.expectedResult, stream.updates)
+		})
+	}
+}
+
+type mockPreDeprovisionService struct {
+	mockService
+	preErr error
+}
+
+func (m *mockPreDeprovisionService) PreDeprovision(ctx context.Context, vm *proto.VirtualMachine, ch chan<- *proto.StatusUpdate) bool {
+	status := &proto.StatusUpdate{ServiceName: m.name, Message: "pre-deprovision"}
+	if m.preErr != nil {
+		status.Failed = true
+		status.Message = m.preErr.Error()
+	}
+
+	ch <- status
+	return m.preErr == nil
+}
+
+func TestDeprovisionizeRunsPreDeprovisionFirst(t *testing.T) {
+	tests := []struct {
+		name           string
+		preErr         error
+		expectedResult []*proto.StatusUpdate
+	}{
+		{
+			name: "success",
+			expectedResult: []*proto.StatusUpdate{
+				{ServiceName: "tower", Message: "pre-deprovision"},
+				{ServiceName: "ovirt"},
+				{ServiceName: "tower"},
+			},
+		},
+		{
+			name:   "pre-deprovision fails",
+			preErr: fmt.Errorf("job failed"),
+			expectedResult: []*proto.StatusUpdate{
+				{ServiceName: "tower", Failed: true, Message: "job failed"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			srv := NewServer(&Pipeline{
+				Steps: []*Step{
+					{Name: "ovirt", Service: &mockService{name: "ovirt"}},
+					{Name: "tower", Service: &mockPreDeprovisionService{mockService: mockService{name: "tower"}, preErr: test.preErr}},
+				},
+			})
+
+			stream := &mockStream{}
+			err := srv.Deprovisionize(&proto.ProvisionizeRequest{VirtualMachine: &proto.VirtualMachine{}}, stream)
+			if err != nil {
+				t.Fatal(err)
 			}
 
-			req := &proto.ProvisionizeRequest{}
+			assert.Equal(t, test.expectedResult, stream.updates)
+		})
+	}
+}
+
+func TestUpdatePipeline(t *testing.T) {
+	srv := NewServer(pipelineForServices([]*mockService{{name: "old"}}))
+	srv.UpdatePipeline(pipelineForServices([]*mockService{{name: "new"}}))
+
+	stream := &mockStream{}
+	err := srv.Provisionize(&proto.ProvisionizeRequest{VirtualMachine: &proto.VirtualMachine{}}, stream)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, []*proto.StatusUpdate{{ServiceName: "new"}}, stream.updates)
+}
+
+func TestSkipStep(t *testing.T) {
+	p := pipelineForServices([]*mockService{{name: "service1"}, {name: "service2"}})
+	p.Templates = &mockTemplateService{skip: map[string]bool{"service1": true}}
+	srv := NewServer(p)
+
+	stream := &mockStream{}
+	err := srv.Provisionize(&proto.ProvisionizeRequest{VirtualMachine: &proto.VirtualMachine{}}, stream)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []*proto.StatusUpdate{
+		{
+			ServiceName: "service1",
+			Message:     "Step is skipped for this template",
+		},
+		{
+			ServiceName: "service2",
+		},
+	}
+	assert.Equal(t, expected, stream.updates)
+}
+
+func TestApplyTemplate(t *testing.T) {
+	tests := []struct {
+		name           string
+		vm             *proto.VirtualMachine
+		applyErr       error
+		expectedResult []*proto.StatusUpdate
+		expectedCores  uint32
+	}{
+		{
+			name: "defaults applied",
+			vm:   &proto.VirtualMachine{},
+			expectedResult: []*proto.StatusUpdate{
+				{ServiceName: "service1"},
+			},
+			expectedCores: 2,
+		},
+		{
+			name: "value from request is kept",
+			vm:   &proto.VirtualMachine{CpuCores: 8},
+			expectedResult: []*proto.StatusUpdate{
+				{ServiceName: "service1"},
+			},
+			expectedCores: 8,
+		},
+		{
+			name:     "limit exceeded",
+			vm:       &proto.VirtualMachine{CpuCores: 64},
+			applyErr: fmt.Errorf("cpu_cores (64) exceeds the maximum of 16"),
+			expectedResult: []*proto.StatusUpdate{
+				{
+					ServiceName: "Provisionize",
+					Failed:      true,
+					Message:     "cpu_cores (64) exceeds the maximum of 16",
+				},
+			},
+		},
+		{
+			name: "no VM",
+			expectedResult: []*proto.StatusUpdate{
+				{
+					ServiceName: "Provisionize",
+					Failed:      true,
+					Message:     "no virtual machine specified",
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			svc := &mockService{name: "service1"}
+			p := pipelineForServices([]*mockService{svc})
+			p.Templates = &mockTemplateService{applyErr: test.applyErr}
+			srv := NewServer(p)
+
 			stream := &mockStream{}
-			err := srv.Deprovisionize(req, stream)
+			err := srv.Provisionize(&proto.ProvisionizeRequest{VirtualMachine: test.vm}, stream)
 			if err != nil {
-				t.Error(err)
+				t.Fatal(err)
 			}
 
 			assert.Equal(t, test.expectedResult, stream.updates)
+
+			if test.expectedCores > 0 {
+				assert.Equal(t, test.expectedCores, svc.vms[0].CpuCores)
+			}
 		})
 	}
 }
+
+type mockCloseService struct {
+	mockService
+	closed chan struct{}
+}
+
+func (m *mockCloseService) Close(ctx context.Context) error {
+	close(m.closed)
+	return nil
+}
+
+func TestUpdatePipelineClosesPrevious(t *testing.T) {
+	svc := &mockCloseService{mockService: mockService{name: "webhook"}, closed: make(chan struct{})}
+	srv := NewServer(&Pipeline{Steps: []*Step{{Name: "webhook", Service: svc}}})
+
+	srv.UpdatePipeline(pipelineForServices([]*mockService{{name: "new"}}))
+
+	select {
+	case <-svc.closed:
+	case <-time.After(time.Second):
+		t.Fatal("previous pipeline was not closed")
+	}
+}
+
+func TestClose(t *testing.T) {
+	svc := &mockCloseService{mockService: mockService{name: "webhook"}, closed: make(chan struct{})}
+	srv := NewServer(&Pipeline{Steps: []*Step{{Name: "webhook", Service: svc}, {Name: "dns", Service: &mockService{name: "dns"}}}})
+
+	assert.NoError(t, srv.Close(context.Background()))
+	_, open := <-svc.closed
+	assert.False(t, open)
+}
+
+func TestCloseStopsServing(t *testing.T) {
+	svc := &mockCloseService{mockService: mockService{name: "webhook"}, closed: make(chan struct{})}
+	srv := NewServer(&Pipeline{Steps: []*Step{{Name: "webhook", Service: svc}}})
+
+	list, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	served := make(chan error)
+	go func() {
+		served <- srv.Serve(list)
+	}()
+
+	assert.NoError(t, srv.Close(context.Background()))
+
+	select {
+	case err := <-served:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("server was not stopped")
+	}
+
+	_, open := <-svc.closed
+	assert.False(t, open)
+}
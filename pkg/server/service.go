@@ -14,3 +14,79 @@ type ProvisionService interface {
 	// Deprovision performs a step required to deprovision a virtual machine
 	Deprovision(ctx context.Context, vm *proto.VirtualMachine, ch chan<- *proto.StatusUpdate) bool
 }
+
+// TemplateService provides the settings of the template a VM is based on
+type TemplateService interface {
+	// SkipStep returns true if the step should not be performed for the VM
+	SkipStep(vm *proto.VirtualMachine, step string) bool
+
+	// Templates returns all templates VMs can be based on
+	Templates() []*proto.Template
+
+	// ApplyTemplate sets the defaults of the template for all values not set in the VM and checks the limits defined in the template
+	ApplyTemplate(vm *proto.VirtualMachine) error
+}
+
+// PreDeprovisionService is implemented by services which need the virtual machine to still exist when it is deprovisioned
+type PreDeprovisionService interface {
+	// PreDeprovision performs a step required before any step of the pipeline deprovisions the virtual machine
+	PreDeprovision(ctx context.Context, vm *proto.VirtualMachine, ch chan<- *proto.StatusUpdate) bool
+}
+
+// CloseService is implemented by services doing work in background which has to be finished before the service is dropped
+type CloseService interface {
+	// Close waits for the background work to complete until ctx is done
+	Close(ctx context.Context) error
+}
+
+// RestoreService is implemented by services able to restore a VM taken from the recycle bin
+type RestoreService interface {
+	// Restore reverts the changes made when the virtual machine was put into the recycle bin
+	Restore(ctx context.Context, vm *proto.VirtualMachine, ch chan<- *proto.StatusUpdate) bool
+}
+
+// RecycleService is implemented by services keeping resources of a deprovisioned VM while it is in the recycle bin
+type RecycleService interface {
+	RestoreService
+
+	// Recycle performs a step required to put a virtual machine into the recycle bin
+	Recycle(ctx context.Context, vm *proto.VirtualMachine, ch chan<- *proto.StatusUpdate) bool
+
+	// Purge removes the resources kept for a virtual machine after the retention period of the recycle bin expired
+	Purge(ctx context.Context, vm *proto.VirtualMachine, ch chan<- *proto.StatusUpdate) bool
+}
+
+// LifecycleService is implemented by services managing the power state and size of existing VMs
+type LifecycleService interface {
+	// Start starts the virtual machine
+	Start(ctx context.Context, vm *proto.VirtualMachine, ch chan<- *proto.StatusUpdate) bool
+
+	// Stop shuts down the virtual machine or stops it immediately if force is set
+	Stop(ctx context.Context, vm *proto.VirtualMachine, opts *proto.DeprovisionOptions, ch chan<- *proto.StatusUpdate) bool
+
+	// Reboot reboots the virtual machine
+	Reboot(ctx context.Context, vm *proto.VirtualMachine, ch chan<- *proto.StatusUpdate) bool
+
+	// Resize changes CPU cores and memory of the virtual machine to the values set in vm
+	Resize(ctx context.Context, vm *proto.VirtualMachine, ch chan<- *proto.StatusUpdate) bool
+}
+
+// InventoryService is implemented by services able to report the current state of a VM
+type InventoryService interface {
+	// Describe adds the state of the virtual machine known to the service to state
+	Describe(ctx context.Context, vm *proto.VirtualMachine, state *proto.VMState) error
+}
+
+// BatchInventoryService is implemented by inventory services able to describe many VMs with fewer requests than one by one
+type BatchInventoryService interface {
+	InventoryService
+
+	// DescribeAll adds the state known to the service to each of the states. The returned errors correspond to the states.
+	DescribeAll(ctx context.Context, states []*proto.VMState) []error
+}
+
+// VMLister is implemented by services able to list existing VMs
+type VMLister interface {
+	// ListVMs returns the state of all virtual machines matching the filters of the request
+	ListVMs(ctx context.Context, req *proto.ListVMsRequest) ([]*proto.VMState, error)
+}
@@ -0,0 +1,43 @@
+package server
+
+import (
+	"context"
+
+	"go.opencensus.io/trace"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/MauveSoftware/provisionize/pkg/api/proto"
+)
+
+// ListTemplates returns all templates configured on the server
+func (srv *Server) ListTemplates(ctx context.Context, req *proto.ListTemplatesRequest) (*proto.ListTemplatesResponse, error) {
+	_, span := trace.StartSpan(ctx, "API.ListTemplates")
+	defer span.End()
+
+	return &proto.ListTemplatesResponse{
+		Templates: srv.currentPipeline().templates(),
+	}, nil
+}
+
+// DescribeTemplate returns the template with the requested name
+func (srv *Server) DescribeTemplate(ctx context.Context, req *proto.DescribeTemplateRequest) (*proto.Template, error) {
+	_, span := trace.StartSpan(ctx, "API.DescribeTemplate")
+	defer span.End()
+
+	for _, t := range srv.currentPipeline().templates() {
+		if t.Name == req.Name {
+			return t, nil
+		}
+	}
+
+	return nil, status.Errorf(codes.NotFound, "template %s does not exist", req.Name)
+}
+
+func (p *Pipeline) templates() []*proto.Template {
+	if p.Templates == nil {
+		return []*proto.Template{}
+	}
+
+	return p.Templates.Templates()
+}
@@ -0,0 +1,83 @@
+package server
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/MauveSoftware/provisionize/pkg/api/proto"
+)
+
+func TestListTemplates(t *testing.T) {
+	templates := []*proto.Template{
+		{
+			Name:          "linux",
+			OvirtTemplate: "ubuntu-18.04",
+		},
+		{
+			Name:          "windows",
+			OvirtTemplate: "windows-2019",
+		},
+	}
+
+	srv := NewServer(&Pipeline{Templates: &mockTemplateService{templates: templates}})
+	res, err := srv.ListTemplates(context.Background(), &proto.ListTemplatesRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, templates, res.Templates)
+}
+
+func TestDescribeTemplate(t *testing.T) {
+	tests := []struct {
+		name         string
+		templateName string
+		expected     *proto.Template
+		expectedCode codes.Code
+	}{
+		{
+			name:         "existing template",
+			templateName: "linux",
+			expected: &proto.Template{
+				Name:          "linux",
+				OvirtTemplate: "ubuntu-18.04",
+			},
+		},
+		{
+			name:         "unknown template",
+			templateName: "bsd",
+			expectedCode: codes.NotFound,
+		},
+	}
+
+	srv := NewServer(&Pipeline{
+		Templates: &mockTemplateService{
+			templates: []*proto.Template{
+				{
+					Name:          "linux",
+					OvirtTemplate: "ubuntu-18.04",
+				},
+			},
+		},
+	})
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			res, err := srv.DescribeTemplate(context.Background(), &proto.DescribeTemplateRequest{Name: test.templateName})
+			if test.expectedCode != codes.OK {
+				assert.Equal(t, test.expectedCode, status.Code(err))
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assert.Equal(t, test.expected, res)
+		})
+	}
+}
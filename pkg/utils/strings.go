@@ -1,5 +1,10 @@
 package utils
 
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
 // ReverseString reverses a string
 func ReverseString(str string) string {
 	res := make([]byte, len(str))
@@ -16,3 +21,36 @@ func ReverseString(str string) string {
 
 	return string(res)
 }
+
+// Truncate shortens str to at most max bytes (cut at a rune boundary) and notes how many bytes were removed
+func Truncate(str string, max int) string {
+	if len(str) <= max {
+		return str
+	}
+
+	cut := max
+	for cut > 0 && !utf8.RuneStart(str[cut]) {
+		cut--
+	}
+
+	return str[:cut] + fmt.Sprintf("\n... (%d bytes truncated)", len(str)-cut)
+}
+
+// AppendMissing appends the values not already contained in list
+func AppendMissing(list []string, values ...string) []string {
+	for _, v := range values {
+		found := false
+		for _, x := range list {
+			if x == v {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			list = append(list, v)
+		}
+	}
+
+	return list
+}
@@ -25,3 +25,55 @@ func TestReverseString(t *testing.T) {
 		assert.Equal(t, test.expected, res)
 	}
 }
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		str      string
+		max      int
+		expected string
+	}{
+		{
+			str:      "short",
+			max:      10,
+			expected: "short",
+		},
+		{
+			str:      "abcdefghij",
+			max:      4,
+			expected: "abcd\n... (6 bytes truncated)",
+		},
+		{
+			str:      "aäöü",
+			max:      4,
+			expected: "aä\n... (4 bytes truncated)",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.str, func(t *testing.T) {
+			assert.Equal(t, test.expected, Truncate(test.str, test.max))
+		})
+	}
+}
+
+func TestAppendMissing(t *testing.T) {
+	tests := []struct {
+		list     []string
+		values   []string
+		expected []string
+	}{
+		{
+			values:   nil,
+			expected: nil,
+		},
+		{
+			list:     []string{"a", "b"},
+			values:   []string{"b", "c", "c"},
+			expected: []string{"a", "b", "c"},
+		},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, AppendMissing(test.list, test.values...))
+	}
+}
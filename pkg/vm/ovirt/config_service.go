@@ -12,3 +12,10 @@ type ConfigService interface {
 	// BootDiskName returns the name of the boot diks
 	BootDiskName(vm *proto.VirtualMachine) string
 }
+
+// LimitsService is implemented by config services defining limits for CPU cores and memory of VMs
+type LimitsService interface {
+	// CheckLimits checks CPU cores and memory of the VM against the limits of its template.
+	// If the VM does not name its template, the templates based on the oVirt template ovirtTemplate are used.
+	CheckLimits(vm *proto.VirtualMachine, ovirtTemplate string) error
+}
@@ -0,0 +1,177 @@
+package ovirt
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/MauveSoftware/provisionize/pkg/api/proto"
+)
+
+const (
+	defaultDiskInterface = "virtio_scsi"
+	defaultDiskFormat    = "cow"
+)
+
+var (
+	diskInterfaces = map[string]bool{"virtio": true, "virtio_scsi": true, "ide": true, "sata": true}
+	diskFormats    = map[string]bool{"cow": true, "raw": true}
+)
+
+// validateDisks checks the disks requested for the VM before anything is created
+func validateDisks(disks []*proto.Disk) error {
+	for i, d := range disks {
+		if d.SizeGb == 0 {
+			return fmt.Errorf("disks[%d]: size is required", i)
+		}
+
+		if len(d.StorageDomain) == 0 {
+			return fmt.Errorf("disks[%d]: storage domain is required", i)
+		}
+
+		if len(d.Interface) > 0 && !diskInterfaces[d.Interface] {
+			return fmt.Errorf("disks[%d]: unsupported interface %s", i, d.Interface)
+		}
+
+		if len(d.Format) > 0 && !diskFormats[d.Format] {
+			return fmt.Errorf("disks[%d]: unsupported format %s", i, d.Format)
+		}
+	}
+
+	return nil
+}
+
+// createDisks creates the additional disks of the VM and waits until all of them are ready to use
+func (s *OvirtService) createDisks(ctx context.Context, vm *proto.VirtualMachine, id string, ch chan<- *proto.StatusUpdate) bool {
+	for i, d := range vm.Disks {
+		diskID, err := s.createDisk(ctx, vm, id, i, d, ch)
+		if err != nil {
+			ch <- &proto.StatusUpdate{ServiceName: serviceName, Failed: true, Message: err.Error()}
+			return false
+		}
+
+		if !s.waitForDiskStatus(ctx, diskID, "ok", ch) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (s *OvirtService) createDisk(ctx context.Context, vm *proto.VirtualMachine, id string, idx int, d *proto.Disk, ch chan<- *proto.StatusUpdate) (string, error) {
+	name := d.Name
+	if len(name) == 0 {
+		name = fmt.Sprintf("%s_disk%d", vm.Name, idx+1)
+	}
+
+	a := &NewDiskAttachment{
+		Bootable:  d.Bootable,
+		Interface: valueOrDefault(d.Interface, defaultDiskInterface),
+		Active:    true,
+		Disk: Disk{
+			Name:            name,
+			Format:          valueOrDefault(d.Format, defaultDiskFormat),
+			ProvisionedSize: d.SizeGb << 30,
+			StorageDomains: &StorageDomains{
+				StorageDomains: []StorageDomain{{Name: d.StorageDomain}},
+			},
+		},
+	}
+	ch <- &proto.StatusUpdate{ServiceName: serviceName, Message: fmt.Sprintf("Creating disk %s (%d GB)", name, d.SizeGb), DebugMessage: string(a.serialize())}
+
+	b, err := s.sendRequest(ctx, fmt.Sprintf("vms/%s/diskattachments", id), "POST", bytes.NewReader(a.serialize()))
+	if err != nil {
+		return "", errors.Wrapf(err, "could not create disk %s", name)
+	}
+
+	var res DiskAttachment
+	err = xml.Unmarshal(b, &res)
+	if err != nil || len(res.Disk.ID) == 0 {
+		return "", fmt.Errorf("could not determine ID of disk %s", name)
+	}
+
+	return res.Disk.ID, nil
+}
+
+func (s *OvirtService) waitForDiskStatus(ctx context.Context, id string, desiredStatus string, ch chan<- *proto.StatusUpdate) bool {
+	currentStatus := ""
+	timeout := time.After(s.waitTimeout)
+
+	for {
+		select {
+		case <-timeout:
+			ch <- &proto.StatusUpdate{ServiceName: serviceName, Failed: true, Message: fmt.Sprintf("Timed out waiting for disk %s", id)}
+			return false
+
+		case <-ctx.Done():
+			ch <- &proto.StatusUpdate{ServiceName: serviceName, Failed: true, Message: ctx.Err().Error()}
+			return false
+
+		case <-time.After(s.pollingInterval):
+			var disk Disk
+			err := s.getAndParse(ctx, fmt.Sprintf("disks/%s", id), &disk)
+			if err != nil {
+				ch <- &proto.StatusUpdate{ServiceName: serviceName, Failed: true, Message: err.Error()}
+				return false
+			}
+
+			if disk.Status != currentStatus {
+				ch <- &proto.StatusUpdate{ServiceName: serviceName, Message: fmt.Sprintf("Disk %s: %s", disk.Name, disk.Status)}
+				currentStatus = disk.Status
+			}
+
+			if disk.Status == desiredStatus {
+				return true
+			}
+		}
+	}
+}
+
+// detachDataDisks detaches all disks except the boot disk of the template so they are kept when the VM is removed.
+// The boot disk is identified by its name since data disks can be bootable too.
+func (s *OvirtService) detachDataDisks(ctx context.Context, vm *proto.VirtualMachine, id string, ch chan<- *proto.StatusUpdate) bool {
+	bootDisk := s.configService.BootDiskName(vm)
+	if len(bootDisk) == 0 {
+		ch <- &proto.StatusUpdate{
+			ServiceName: serviceName,
+			Failed:      true,
+			Message:     fmt.Sprintf("Boot disk of VM %s is unknown (no template given): data disks can not be kept", vm.Name),
+		}
+		return false
+	}
+
+	var attachments DiskAttachments
+	err := s.getAndParse(ctx, fmt.Sprintf("vms/%s/diskattachments?follow=disk", id), &attachments)
+	if err != nil {
+		ch <- &proto.StatusUpdate{ServiceName: serviceName, Failed: true, Message: err.Error()}
+		return false
+	}
+
+	for _, a := range attachments.Attachments {
+		if a.Disk.Name == bootDisk {
+			continue
+		}
+
+		_, err := s.sendRequest(ctx, fmt.Sprintf("vms/%s/diskattachments/%s", id, a.ID), "DELETE", nil)
+		if err != nil {
+			ch <- &proto.StatusUpdate{ServiceName: serviceName, Failed: true, Message: errors.Wrapf(err, "could not detach disk %s", a.Disk.ID).Error()}
+			return false
+		}
+
+		ch <- &proto.StatusUpdate{ServiceName: serviceName, Message: fmt.Sprintf("Detached disk %s", a.Disk.ID)}
+	}
+
+	return true
+}
+
+func valueOrDefault(value, def string) string {
+	if len(value) == 0 {
+		return def
+	}
+
+	return value
+}
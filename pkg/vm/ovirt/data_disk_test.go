@@ -0,0 +1,137 @@
+package ovirt
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/MauveSoftware/provisionize/pkg/api/proto"
+)
+
+func TestValidateDisks(t *testing.T) {
+	tests := []struct {
+		name        string
+		disk        *proto.Disk
+		expectError string
+	}{
+		{
+			name: "valid",
+			disk: &proto.Disk{SizeGb: 100, StorageDomain: "ssd", Interface: "virtio", Format: "raw"},
+		},
+		{
+			name:        "missing size",
+			disk:        &proto.Disk{StorageDomain: "ssd"},
+			expectError: "disks[0]: size is required",
+		},
+		{
+			name:        "missing storage domain",
+			disk:        &proto.Disk{SizeGb: 100},
+			expectError: "disks[0]: storage domain is required",
+		},
+		{
+			name:        "unsupported interface",
+			disk:        &proto.Disk{SizeGb: 100, StorageDomain: "ssd", Interface: "scsi"},
+			expectError: "disks[0]: unsupported interface scsi",
+		},
+		{
+			name:        "unsupported format",
+			disk:        &proto.Disk{SizeGb: 100, StorageDomain: "ssd", Format: "qcow2"},
+			expectError: "disks[0]: unsupported format qcow2",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := validateDisks([]*proto.Disk{test.disk})
+			if len(test.expectError) > 0 {
+				assert.EqualError(t, err, test.expectError)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestCreateDisks(t *testing.T) {
+	body := ""
+	polls := 0
+	svc := newTestService(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "POST" && r.URL.Path == "/vms/123/diskattachments":
+			b, _ := ioutil.ReadAll(r.Body)
+			body = string(b)
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`<disk_attachment id="a1"><disk id="d1"/></disk_attachment>`))
+		case r.URL.Path == "/disks/d1":
+			polls++
+			if polls < 2 {
+				w.Write([]byte(`<disk id="d1"><name>db1_disk1</name><status>locked</status></disk>`))
+				return
+			}
+			w.Write([]byte(`<disk id="d1"><name>db1_disk1</name><status>ok</status></disk>`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	vm := &proto.VirtualMachine{
+		Name:  "db1",
+		Disks: []*proto.Disk{{SizeGb: 2, StorageDomain: "ssd"}},
+	}
+
+	ch := make(chan *proto.StatusUpdate, 10)
+	assert.True(t, svc.createDisks(context.Background(), vm, "123", ch))
+	assert.Equal(t, 2, polls)
+
+	expected := `<disk_attachment><bootable>false</bootable><pass_discard>false</pass_discard><interface>virtio_scsi</interface><active>true</active>` +
+		`<disk><name>db1_disk1</name><format>cow</format><provisioned_size>2147483648</provisioned_size>` +
+		`<storage_domains><storage_domain><name>ssd</name></storage_domain></storage_domains></disk></disk_attachment>`
+	assert.Equal(t, expected, body)
+}
+
+func TestDetachDataDisks(t *testing.T) {
+	tests := []struct {
+		name             string
+		template         string
+		expectFail       bool
+		expectedDetached []string
+	}{
+		{
+			name:             "bootable data disk is detached",
+			template:         "db",
+			expectedDetached: []string{"/vms/123/diskattachments/a2", "/vms/123/diskattachments/a3"},
+		},
+		{
+			name:             "boot disk unknown",
+			expectFail:       true,
+			expectedDetached: []string{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			detached := []string{}
+			svc := newTestService(t, func(w http.ResponseWriter, r *http.Request) {
+				if r.Method == "DELETE" {
+					detached = append(detached, r.URL.Path)
+					return
+				}
+
+				assert.Equal(t, "disk", r.URL.Query().Get("follow"))
+				w.Write([]byte(`<disk_attachments>
+					<disk_attachment id="a1"><bootable>true</bootable><disk id="d1"><name>new-disk</name></disk></disk_attachment>
+					<disk_attachment id="a2"><bootable>false</bootable><disk id="d2"><name>db1_disk1</name></disk></disk_attachment>
+					<disk_attachment id="a3"><bootable>true</bootable><disk id="d3"><name>db1_rescue</name></disk></disk_attachment>
+				</disk_attachments>`))
+			})
+
+			vm := &proto.VirtualMachine{Name: "db1", Template: test.template}
+			ch := make(chan *proto.StatusUpdate, 10)
+			assert.Equal(t, !test.expectFail, svc.detachDataDisks(context.Background(), vm, "123", ch))
+			assert.Equal(t, test.expectedDetached, detached)
+		})
+	}
+}
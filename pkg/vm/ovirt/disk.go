@@ -5,6 +5,19 @@ type Disks struct {
 }
 
 type Disk struct {
-	ID   string `xml:"id,attr"`
+	ID              string          `xml:"id,attr,omitempty"`
+	Name            string          `xml:"name,omitempty"`
+	Status          string          `xml:"status,omitempty"`
+	Format          string          `xml:"format,omitempty"`
+	ProvisionedSize uint64          `xml:"provisioned_size,omitempty"`
+	StorageDomains  *StorageDomains `xml:"storage_domains,omitempty"`
+}
+
+type StorageDomains struct {
+	StorageDomains []StorageDomain `xml:"storage_domain"`
+}
+
+type StorageDomain struct {
+	ID   string `xml:"id,attr,omitempty"`
 	Name string `xml:"name,omitempty"`
 }
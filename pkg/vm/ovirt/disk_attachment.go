@@ -3,12 +3,14 @@ package ovirt
 import "encoding/xml"
 
 type DiskAttachments struct {
-	Attachments []DiskAttachment
+	Attachments []DiskAttachment `xml:"disk_attachment"`
 }
 
 type DiskAttachment struct {
-	ID       string `xml:"id,attr"`
-	Bootable bool   `xml:"bootable"`
+	ID        string `xml:"id,attr"`
+	Bootable  bool   `xml:"bootable"`
+	Interface string `xml:"interface"`
+	Disk      Disk   `xml:"disk"`
 }
 
 type NewDiskAttachment struct {
@@ -0,0 +1,47 @@
+package ovirt
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	"go.opencensus.io/trace"
+
+	"github.com/MauveSoftware/provisionize/pkg/api/proto"
+)
+
+// GuestAgentReady checks if the guest agent of the VM reports the hostname set by cloud-init.
+// oVirt does not expose the status of cloud-init itself, so the FQDN reported by the guest agent is used as indicator.
+// This only approximates "cloud-init done": the hostname is set early, later cloud-init modules may still be running.
+func (s *OvirtService) GuestAgentReady(ctx context.Context, vm *proto.VirtualMachine) (bool, string, error) {
+	ctx, span := trace.StartSpan(ctx, "OvirtService.GuestAgentReady")
+	defer span.End()
+
+	if len(vm.Fqdn) == 0 {
+		return false, "", errors.New("FQDN of the VM is required to check the hostname reported by the guest agent")
+	}
+
+	v, err := s.findVM(ctx, vm)
+	if err != nil {
+		return false, "", err
+	}
+
+	if v == nil {
+		return false, "", fmt.Errorf("VM %s does not exist", vm.Name)
+	}
+
+	if v.Status != "up" {
+		return false, fmt.Sprintf("VM status: %s", v.Status), nil
+	}
+
+	if len(v.FQDN) == 0 {
+		return false, "Waiting for guest agent to report", nil
+	}
+
+	if !strings.EqualFold(v.FQDN, vm.Fqdn) {
+		return false, fmt.Sprintf("Guest agent reports hostname %s, waiting for %s", v.FQDN, vm.Fqdn), nil
+	}
+
+	return true, fmt.Sprintf("Guest agent reports hostname %s", v.FQDN), nil
+}
@@ -0,0 +1,69 @@
+package ovirt
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/MauveSoftware/provisionize/pkg/api/proto"
+)
+
+func TestGuestAgentReady(t *testing.T) {
+	tests := []struct {
+		name          string
+		status        string
+		fqdn          string
+		expectReady   bool
+		expectMessage string
+	}{
+		{
+			name:          "not running",
+			status:        "powering_up",
+			expectMessage: "VM status: powering_up",
+		},
+		{
+			name:          "guest agent not reporting",
+			status:        "up",
+			expectMessage: "Waiting for guest agent to report",
+		},
+		{
+			name:          "hostname not set yet",
+			status:        "up",
+			fqdn:          "localhost.localdomain",
+			expectMessage: "Guest agent reports hostname localhost.localdomain, waiting for web1.example.com",
+		},
+		{
+			name:          "ready",
+			status:        "up",
+			fqdn:          "web1.example.com",
+			expectReady:   true,
+			expectMessage: "Guest agent reports hostname web1.example.com",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			svc := newTestService(t, func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprintf(w, `<vms><vm id="1"><name>web1</name><status>%s</status><fqdn>%s</fqdn></vm></vms>`, test.status, test.fqdn)
+			})
+
+			ready, msg, err := svc.GuestAgentReady(context.Background(), &proto.VirtualMachine{Name: "web1", Fqdn: "web1.example.com"})
+			assert.NoError(t, err)
+			assert.Equal(t, test.expectReady, ready)
+			assert.Equal(t, test.expectMessage, msg)
+		})
+	}
+}
+
+func TestGuestAgentReadyRequiresFQDN(t *testing.T) {
+	svc := newTestService(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<vms><vm id="1"><name>web1</name><status>up</status><fqdn>localhost</fqdn></vm></vms>`))
+	})
+
+	ready, _, err := svc.GuestAgentReady(context.Background(), &proto.VirtualMachine{Name: "web1"})
+	assert.Error(t, err)
+	assert.False(t, ready)
+}
@@ -0,0 +1,190 @@
+package ovirt
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+	"go.opencensus.io/trace"
+
+	"github.com/MauveSoftware/provisionize/pkg/api/proto"
+)
+
+// inventory resolves names of objects referenced by VMs. Names are cached for the duration of a request.
+type inventory struct {
+	svc            *OvirtService
+	clusters       map[string]string
+	storageDomains map[string]string
+}
+
+func (s *OvirtService) newInventory() *inventory {
+	return &inventory{
+		svc:            s,
+		clusters:       make(map[string]string),
+		storageDomains: make(map[string]string),
+	}
+}
+
+// Describe adds status, ID, size and disks of the VM to state
+func (s *OvirtService) Describe(ctx context.Context, vm *proto.VirtualMachine, state *proto.VMState) error {
+	ctx, span := trace.StartSpan(ctx, "OvirtService.Describe")
+	defer span.End()
+
+	v, err := s.findVM(ctx, vm)
+	if err != nil {
+		return err
+	}
+
+	if v == nil {
+		return fmt.Errorf("VM %s does not exist", vm.Name)
+	}
+
+	return s.newInventory().describe(ctx, v, state, true)
+}
+
+// ListVMs returns the state of all VMs in the cluster and based on the template of the request (if set)
+func (s *OvirtService) ListVMs(ctx context.Context, req *proto.ListVMsRequest) ([]*proto.VMState, error) {
+	ctx, span := trace.StartSpan(ctx, "OvirtService.ListVMs")
+	defer span.End()
+
+	query := []string{}
+	if len(req.ClusterName) > 0 {
+		query = append(query, searchTerm("cluster", req.ClusterName))
+	}
+
+	if len(req.Template) > 0 {
+		name := s.configService.OvirtTemplateNameForVM(&proto.VirtualMachine{Template: req.Template})
+		if len(name) == 0 {
+			return nil, fmt.Errorf("template %s does not exist", req.Template)
+		}
+
+		query = append(query, searchTerm("template", name))
+	}
+
+	path := "vms"
+	if len(query) > 0 {
+		path += "?search=" + url.QueryEscape(strings.Join(query, " and "))
+	}
+
+	var vms VMs
+	err := s.getAndParse(ctx, path, &vms)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not retrieve VMs")
+	}
+
+	inv := s.newInventory()
+	states := make([]*proto.VMState, len(vms.VMs))
+	for i := range vms.VMs {
+		states[i] = &proto.VMState{VirtualMachine: &proto.VirtualMachine{Template: req.Template}}
+
+		err := inv.describe(ctx, &vms.VMs[i], states[i], req.Details)
+		if err != nil {
+			states[i].Errors = append(states[i].Errors, &proto.BackendError{ServiceName: serviceName, Message: err.Error()})
+		}
+	}
+
+	return states, nil
+}
+
+func (inv *inventory) describe(ctx context.Context, v *VM, state *proto.VMState, withDisks bool) error {
+	vm := state.VirtualMachine
+	vm.Id = v.ID
+	vm.Name = v.Name
+	vm.MemoryMb = uint32(v.Memory >> 20)
+	state.Status = v.Status
+
+	if len(vm.Fqdn) == 0 {
+		vm.Fqdn = v.FQDN
+	}
+
+	if v.CPU != nil {
+		vm.CpuCores = v.CPU.Topology.Cores * v.CPU.Topology.Sockets
+	}
+
+	if v.Cluster != nil {
+		name, err := inv.clusterName(ctx, v.Cluster.ID)
+		if err != nil {
+			return err
+		}
+
+		vm.ClusterName = name
+	}
+
+	if !withDisks {
+		return nil
+	}
+
+	disks, err := inv.disks(ctx, v.ID)
+	if err != nil {
+		return err
+	}
+
+	vm.Disks = disks
+	return nil
+}
+
+func (inv *inventory) clusterName(ctx context.Context, id string) (string, error) {
+	if name, found := inv.clusters[id]; found {
+		return name, nil
+	}
+
+	var c Cluster
+	err := inv.svc.getAndParse(ctx, fmt.Sprintf("clusters/%s", id), &c)
+	if err != nil {
+		return "", errors.Wrapf(err, "could not retrieve cluster %s", id)
+	}
+
+	inv.clusters[id] = c.Name
+	return c.Name, nil
+}
+
+func (inv *inventory) disks(ctx context.Context, vmID string) ([]*proto.Disk, error) {
+	var attachments DiskAttachments
+	err := inv.svc.getAndParse(ctx, fmt.Sprintf("vms/%s/diskattachments?follow=disk", vmID), &attachments)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not retrieve disks")
+	}
+
+	disks := make([]*proto.Disk, len(attachments.Attachments))
+	for i, a := range attachments.Attachments {
+		d := &proto.Disk{
+			Name:      a.Disk.Name,
+			SizeGb:    a.Disk.ProvisionedSize >> 30,
+			Interface: a.Interface,
+			Format:    a.Disk.Format,
+			Bootable:  a.Bootable,
+		}
+
+		if a.Disk.StorageDomains != nil && len(a.Disk.StorageDomains.StorageDomains) > 0 {
+			d.StorageDomain, err = inv.storageDomainName(ctx, a.Disk.StorageDomains.StorageDomains[0])
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		disks[i] = d
+	}
+
+	return disks, nil
+}
+
+func (inv *inventory) storageDomainName(ctx context.Context, sd StorageDomain) (string, error) {
+	if len(sd.Name) > 0 {
+		return sd.Name, nil
+	}
+
+	if name, found := inv.storageDomains[sd.ID]; found {
+		return name, nil
+	}
+
+	var res StorageDomain
+	err := inv.svc.getAndParse(ctx, fmt.Sprintf("storagedomains/%s", sd.ID), &res)
+	if err != nil {
+		return "", errors.Wrapf(err, "could not retrieve storage domain %s", sd.ID)
+	}
+
+	inv.storageDomains[sd.ID] = res.Name
+	return res.Name, nil
+}
@@ -0,0 +1,98 @@
+package ovirt
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/MauveSoftware/provisionize/pkg/api/proto"
+)
+
+const inventoryVM = `<vm id="1">
+	<name>db1</name>
+	<status>up</status>
+	<fqdn>db1.example.com</fqdn>
+	<memory>4294967296</memory>
+	<cpu><topology><cores>1</cores><sockets>4</sockets></topology></cpu>
+	<cluster id="c1"/>
+</vm>`
+
+func inventoryHandler(t *testing.T) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/vms":
+			switch r.URL.Query().Get("search") {
+			case `name="db1"`, `cluster="cluster1" and template="template1"`:
+				w.Write([]byte(`<vms>` + inventoryVM + `</vms>`))
+			default:
+				w.Write([]byte(`<vms/>`))
+			}
+		case "/clusters/c1":
+			w.Write([]byte(`<cluster id="c1"><name>cluster1</name></cluster>`))
+		case "/storagedomains/sd1":
+			w.Write([]byte(`<storage_domain id="sd1"><name>ssd</name></storage_domain>`))
+		case "/vms/1/diskattachments":
+			assert.Equal(t, "disk", r.URL.Query().Get("follow"))
+			w.Write([]byte(`<disk_attachments>
+				<disk_attachment id="a1">
+					<bootable>true</bootable>
+					<interface>virtio_scsi</interface>
+					<disk id="d1">
+						<name>db1_boot</name>
+						<format>cow</format>
+						<provisioned_size>21474836480</provisioned_size>
+						<storage_domains><storage_domain id="sd1"/></storage_domains>
+					</disk>
+				</disk_attachment>
+			</disk_attachments>`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	svc := newTestService(t, inventoryHandler(t))
+
+	state := &proto.VMState{VirtualMachine: &proto.VirtualMachine{Name: "db1"}}
+	require.NoError(t, svc.Describe(context.Background(), state.VirtualMachine, state))
+
+	expected := &proto.VMState{
+		Status: "up",
+		VirtualMachine: &proto.VirtualMachine{
+			Id:          "1",
+			Name:        "db1",
+			ClusterName: "cluster1",
+			Fqdn:        "db1.example.com",
+			CpuCores:    4,
+			MemoryMb:    4096,
+			Disks: []*proto.Disk{
+				{Name: "db1_boot", SizeGb: 20, StorageDomain: "ssd", Interface: "virtio_scsi", Format: "cow", Bootable: true},
+			},
+		},
+	}
+	assert.Equal(t, expected, state)
+
+	t.Run("unknown VM", func(t *testing.T) {
+		state := &proto.VMState{VirtualMachine: &proto.VirtualMachine{Name: "db2"}}
+		assert.EqualError(t, svc.Describe(context.Background(), state.VirtualMachine, state), "VM db2 does not exist")
+	})
+}
+
+func TestListVMs(t *testing.T) {
+	svc := newTestService(t, inventoryHandler(t))
+
+	states, err := svc.ListVMs(context.Background(), &proto.ListVMsRequest{ClusterName: "cluster1", Template: "linux"})
+	require.NoError(t, err)
+	require.Len(t, states, 1)
+
+	assert.Equal(t, "up", states[0].Status)
+	assert.Equal(t, &proto.VirtualMachine{Id: "1", Name: "db1", ClusterName: "cluster1", Fqdn: "db1.example.com", Template: "linux", CpuCores: 4, MemoryMb: 4096}, states[0].VirtualMachine)
+
+	states, err = svc.ListVMs(context.Background(), &proto.ListVMsRequest{ClusterName: "cluster2"})
+	require.NoError(t, err)
+	assert.Empty(t, states)
+}
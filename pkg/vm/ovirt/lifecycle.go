@@ -0,0 +1,198 @@
+package ovirt
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.opencensus.io/trace"
+
+	"github.com/MauveSoftware/provisionize/pkg/api/proto"
+)
+
+// Start starts an existing VM
+func (s *OvirtService) Start(ctx context.Context, vm *proto.VirtualMachine, ch chan<- *proto.StatusUpdate) bool {
+	ctx, span := trace.StartSpan(ctx, "OvirtService.Start")
+	defer span.End()
+
+	v := s.existingVM(ctx, vm, ch)
+	if v == nil {
+		return false
+	}
+
+	if v.Status == "up" {
+		ch <- &proto.StatusUpdate{ServiceName: serviceName, Message: fmt.Sprintf("VM %s is already up", v.Name)}
+		return true
+	}
+
+	return s.startVM(ctx, v.ID, ch) && s.waitForVMStatus(ctx, v.ID, "up", ch)
+}
+
+// Stop shuts down an existing VM (or stops it if force is set)
+func (s *OvirtService) Stop(ctx context.Context, vm *proto.VirtualMachine, opts *proto.DeprovisionOptions, ch chan<- *proto.StatusUpdate) bool {
+	ctx, span := trace.StartSpan(ctx, "OvirtService.Stop")
+	defer span.End()
+
+	v := s.existingVM(ctx, vm, ch)
+	if v == nil {
+		return false
+	}
+
+	if v.Status == "down" {
+		ch <- &proto.StatusUpdate{ServiceName: serviceName, Message: fmt.Sprintf("VM %s is already down", v.Name)}
+		return true
+	}
+
+	return s.powerOff(ctx, v, opts, ch)
+}
+
+// Reboot reboots an existing VM
+func (s *OvirtService) Reboot(ctx context.Context, vm *proto.VirtualMachine, ch chan<- *proto.StatusUpdate) bool {
+	ctx, span := trace.StartSpan(ctx, "OvirtService.Reboot")
+	defer span.End()
+
+	v := s.existingVM(ctx, vm, ch)
+	if v == nil {
+		return false
+	}
+
+	if v.Status != "up" {
+		ch <- &proto.StatusUpdate{ServiceName: serviceName, Failed: true, Message: fmt.Sprintf("VM is not up. Current status: %s", v.Status)}
+		return false
+	}
+
+	return s.sendAction(ctx, v.ID, "reboot", ch) && s.waitForReboot(ctx, v.ID, ch)
+}
+
+// waitForReboot waits until the VM left the status up (e.g. reboot_in_progress) and reached it again
+func (s *OvirtService) waitForReboot(ctx context.Context, id string, ch chan<- *proto.StatusUpdate) bool {
+	currentStatus := "up"
+	rebooting := false
+	timeoutCh := time.After(s.waitTimeout)
+
+	for {
+		select {
+		case <-timeoutCh:
+			ch <- &proto.StatusUpdate{ServiceName: serviceName, Failed: true, Message: errTimeout.Error()}
+			return false
+
+		case <-ctx.Done():
+			ch <- &proto.StatusUpdate{ServiceName: serviceName, Failed: true, Message: ctx.Err().Error()}
+			return false
+
+		case <-time.After(s.pollingInterval):
+			vm, err := s.getVM(ctx, id)
+			if err != nil {
+				ch <- &proto.StatusUpdate{ServiceName: serviceName, Failed: true, Message: err.Error()}
+				return false
+			}
+
+			if vm.Status != currentStatus {
+				ch <- &proto.StatusUpdate{ServiceName: serviceName, Message: fmt.Sprintf("New status: %s", vm.Status)}
+				currentStatus = vm.Status
+			}
+
+			if vm.Status != "up" {
+				rebooting = true
+			} else if rebooting {
+				return true
+			}
+		}
+	}
+}
+
+// Resize changes CPU cores and memory of an existing VM. Values not set in vm are not changed.
+// Changes oVirt can not apply to a running VM take effect on the next start.
+func (s *OvirtService) Resize(ctx context.Context, vm *proto.VirtualMachine, ch chan<- *proto.StatusUpdate) bool {
+	ctx, span := trace.StartSpan(ctx, "OvirtService.Resize")
+	defer span.End()
+
+	v := s.existingVM(ctx, vm, ch)
+	if v == nil {
+		return false
+	}
+
+	err := s.checkLimits(ctx, v, vm)
+	if err != nil {
+		ch <- &proto.StatusUpdate{ServiceName: serviceName, Failed: true, Message: err.Error()}
+		return false
+	}
+
+	u := &VMUpdate{Memory: uint64(vm.MemoryMb) << 20}
+	if vm.CpuCores > 0 {
+		u.CPU = &CPU{Topology: Topology{Cores: 1, Sockets: vm.CpuCores}}
+	}
+
+	b, err := s.sendRequest(ctx, fmt.Sprintf("vms/%s", v.ID), "PUT", bytes.NewReader(u.serialize()))
+	if err != nil {
+		ch <- &proto.StatusUpdate{ServiceName: serviceName, Failed: true, Message: err.Error()}
+		return false
+	}
+
+	msg := "VM resized"
+	if v.Status != "down" {
+		msg += " (changes which can not be applied to the running VM take effect on the next start)"
+	}
+
+	ch <- &proto.StatusUpdate{ServiceName: serviceName, Message: msg, DebugMessage: string(b)}
+	return true
+}
+
+// checkLimits validates the size of the VM after resizing against the limits of its template.
+// Values not set in vm are taken from the existing VM.
+func (s *OvirtService) checkLimits(ctx context.Context, v *VM, vm *proto.VirtualMachine) error {
+	l, ok := s.configService.(LimitsService)
+	if !ok {
+		return nil
+	}
+
+	target := &proto.VirtualMachine{
+		Name:     v.Name,
+		Template: vm.Template,
+		CpuCores: vm.CpuCores,
+		MemoryMb: vm.MemoryMb,
+	}
+
+	if target.CpuCores == 0 && v.CPU != nil {
+		target.CpuCores = v.CPU.Topology.Cores * v.CPU.Topology.Sockets
+	}
+
+	if target.MemoryMb == 0 {
+		target.MemoryMb = uint32(v.Memory >> 20)
+	}
+
+	ovirtTemplate := ""
+	if v.Template != nil {
+		var t Template
+		err := s.getAndParse(ctx, fmt.Sprintf("templates/%s", v.Template.ID), &t)
+		if err != nil {
+			return errors.Wrap(err, "could not retrieve template of VM")
+		}
+
+		ovirtTemplate = t.Name
+	}
+
+	return l.CheckLimits(target, ovirtTemplate)
+}
+
+// existingVM looks up the VM and reports a failure if it can not be found
+func (s *OvirtService) existingVM(ctx context.Context, vm *proto.VirtualMachine, ch chan<- *proto.StatusUpdate) *VM {
+	v, err := s.findVM(ctx, vm)
+	if err == nil && v == nil {
+		name := vm.Name
+		if len(vm.Id) > 0 {
+			name = vm.Id
+		}
+
+		err = fmt.Errorf("VM %s does not exist", name)
+	}
+
+	if err != nil {
+		ch <- &proto.StatusUpdate{ServiceName: serviceName, Failed: true, Message: err.Error()}
+		return nil
+	}
+
+	return v
+}
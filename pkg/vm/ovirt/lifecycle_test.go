@@ -0,0 +1,222 @@
+package ovirt
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/MauveSoftware/provisionize/pkg/api/proto"
+)
+
+type fakeLifecycleEngine struct {
+	status      string
+	rebootStuck bool
+	requests    []string
+}
+
+func (e *fakeLifecycleEngine) handle(w http.ResponseWriter, r *http.Request) {
+	b, _ := ioutil.ReadAll(r.Body)
+	if r.Method != "GET" {
+		e.requests = append(e.requests, r.Method+" "+r.URL.Path+" "+string(b))
+	}
+
+	switch r.URL.Path {
+	case "/vms":
+		if r.URL.Query().Get("search") == `name="web1"` {
+			fmt.Fprintf(w, `<vms><vm id="1"><name>web1</name><status>%s</status></vm></vms>`, e.status)
+			return
+		}
+		w.Write([]byte(`<vms/>`))
+	case "/vms/1":
+		fmt.Fprintf(w, `<vm id="1"><name>web1</name><status>%s</status></vm>`, e.status)
+		if e.status == "reboot_in_progress" {
+			e.status = "up"
+		}
+	case "/vms/1/start":
+		e.status = "up"
+	case "/vms/1/reboot":
+		if !e.rebootStuck {
+			e.status = "reboot_in_progress"
+		}
+	case "/vms/1/shutdown", "/vms/1/stop":
+		e.status = "down"
+	}
+}
+
+type mockLimitsService struct {
+	mockConfigService
+	vm            *proto.VirtualMachine
+	ovirtTemplate string
+}
+
+func (m *mockLimitsService) CheckLimits(vm *proto.VirtualMachine, ovirtTemplate string) error {
+	m.vm, m.ovirtTemplate = vm, ovirtTemplate
+	if vm.CpuCores > 8 {
+		return fmt.Errorf("cpu_cores (%d) exceeds the maximum of 8", vm.CpuCores)
+	}
+
+	return nil
+}
+
+func TestLifecycle(t *testing.T) {
+	tests := []struct {
+		name            string
+		status          string
+		action          func(svc *OvirtService, vm *proto.VirtualMachine, ch chan<- *proto.StatusUpdate) bool
+		vm              *proto.VirtualMachine
+		expectFail      bool
+		expectedRequest string
+		expectedStatus  string
+	}{
+		{
+			name:   "start",
+			status: "down",
+			action: func(svc *OvirtService, vm *proto.VirtualMachine, ch chan<- *proto.StatusUpdate) bool {
+				return svc.Start(context.Background(), vm, ch)
+			},
+			expectedRequest: "POST /vms/1/start <action/>",
+			expectedStatus:  "up",
+		},
+		{
+			name:   "start running VM",
+			status: "up",
+			action: func(svc *OvirtService, vm *proto.VirtualMachine, ch chan<- *proto.StatusUpdate) bool {
+				return svc.Start(context.Background(), vm, ch)
+			},
+			expectedStatus: "up",
+		},
+		{
+			name:   "shutdown",
+			status: "up",
+			action: func(svc *OvirtService, vm *proto.VirtualMachine, ch chan<- *proto.StatusUpdate) bool {
+				return svc.Stop(context.Background(), vm, &proto.DeprovisionOptions{Shutdown: true}, ch)
+			},
+			expectedRequest: "POST /vms/1/shutdown <action/>",
+			expectedStatus:  "down",
+		},
+		{
+			name:   "force stop",
+			status: "up",
+			action: func(svc *OvirtService, vm *proto.VirtualMachine, ch chan<- *proto.StatusUpdate) bool {
+				return svc.Stop(context.Background(), vm, &proto.DeprovisionOptions{Force: true}, ch)
+			},
+			expectedRequest: "POST /vms/1/stop <action/>",
+			expectedStatus:  "down",
+		},
+		{
+			name:   "reboot",
+			status: "up",
+			action: func(svc *OvirtService, vm *proto.VirtualMachine, ch chan<- *proto.StatusUpdate) bool {
+				return svc.Reboot(context.Background(), vm, ch)
+			},
+			expectedRequest: "POST /vms/1/reboot <action/>",
+			expectedStatus:  "up",
+		},
+		{
+			name:   "reboot stopped VM",
+			status: "down",
+			action: func(svc *OvirtService, vm *proto.VirtualMachine, ch chan<- *proto.StatusUpdate) bool {
+				return svc.Reboot(context.Background(), vm, ch)
+			},
+			expectFail:     true,
+			expectedStatus: "down",
+		},
+		{
+			name:   "resize",
+			status: "down",
+			vm:     &proto.VirtualMachine{Name: "web1", CpuCores: 4, MemoryMb: 2048},
+			action: func(svc *OvirtService, vm *proto.VirtualMachine, ch chan<- *proto.StatusUpdate) bool {
+				return svc.Resize(context.Background(), vm, ch)
+			},
+			expectedRequest: "PUT /vms/1 <vm><memory>2147483648</memory><cpu><topology><cores>1</cores><sockets>4</sockets></topology></cpu></vm>",
+			expectedStatus:  "down",
+		},
+		{
+			name:   "resize memory only",
+			status: "up",
+			vm:     &proto.VirtualMachine{Name: "web1", MemoryMb: 4096},
+			action: func(svc *OvirtService, vm *proto.VirtualMachine, ch chan<- *proto.StatusUpdate) bool {
+				return svc.Resize(context.Background(), vm, ch)
+			},
+			expectedRequest: "PUT /vms/1 <vm><memory>4294967296</memory></vm>",
+			expectedStatus:  "up",
+		},
+		{
+			name:   "unknown VM",
+			status: "down",
+			vm:     &proto.VirtualMachine{Name: "web2"},
+			action: func(svc *OvirtService, vm *proto.VirtualMachine, ch chan<- *proto.StatusUpdate) bool {
+				return svc.Start(context.Background(), vm, ch)
+			},
+			expectFail:     true,
+			expectedStatus: "down",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			e := &fakeLifecycleEngine{status: test.status}
+			svc := newTestService(t, e.handle)
+
+			vm := test.vm
+			if vm == nil {
+				vm = &proto.VirtualMachine{Name: "web1"}
+			}
+
+			ch := make(chan *proto.StatusUpdate, 100)
+			assert.Equal(t, !test.expectFail, test.action(svc, vm, ch))
+			assert.Equal(t, test.expectedStatus, e.status)
+
+			if len(test.expectedRequest) == 0 {
+				assert.Empty(t, e.requests)
+				return
+			}
+
+			assert.Equal(t, []string{test.expectedRequest}, e.requests)
+		})
+	}
+}
+
+func TestRebootWaitsForStatusChange(t *testing.T) {
+	e := &fakeLifecycleEngine{status: "up", rebootStuck: true}
+	svc := newTestService(t, e.handle)
+	svc.waitTimeout = 100 * time.Millisecond
+
+	ch := make(chan *proto.StatusUpdate, 100)
+	assert.False(t, svc.Reboot(context.Background(), &proto.VirtualMachine{Name: "web1"}, ch))
+	assert.Equal(t, []string{"POST /vms/1/reboot <action/>"}, e.requests)
+}
+
+func TestResizeChecksLimits(t *testing.T) {
+	var requests []string
+	svc := newTestService(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
+			requests = append(requests, r.Method+" "+r.URL.Path)
+		}
+
+		switch r.URL.Path {
+		case "/vms":
+			w.Write([]byte(`<vms><vm id="1"><name>web1</name><status>up</status><memory>2147483648</memory>` +
+				`<cpu><topology><cores>1</cores><sockets>2</sockets></topology></cpu><template id="10"/></vm></vms>`))
+		case "/templates/10":
+			w.Write([]byte(`<template id="10"><name>debian-12</name></template>`))
+		}
+	})
+	limits := &mockLimitsService{}
+	svc.configService = limits
+
+	ch := make(chan *proto.StatusUpdate, 100)
+	assert.False(t, svc.Resize(context.Background(), &proto.VirtualMachine{Name: "web1", CpuCores: 16}, ch))
+	assert.Equal(t, "debian-12", limits.ovirtTemplate)
+	assert.Equal(t, uint32(2048), limits.vm.MemoryMb)
+	assert.Empty(t, requests)
+
+	assert.True(t, svc.Resize(context.Background(), &proto.VirtualMachine{Name: "web1", MemoryMb: 4096}, ch))
+	assert.Equal(t, uint32(2), limits.vm.CpuCores)
+	assert.Equal(t, []string{"PUT /vms/1"}, requests)
+}
@@ -0,0 +1,55 @@
+package ovirt
+
+import "encoding/xml"
+
+// NICs represents a list of NICs of a VM
+type NICs struct {
+	NICs []NIC `xml:"nic"`
+}
+
+// NIC represents a network interface of an oVirt VM
+type NIC struct {
+	XMLName     struct{} `xml:"nic"`
+	ID          string   `xml:"id,attr,omitempty"`
+	Name        string   `xml:"name"`
+	VnicProfile *Ref     `xml:"vnic_profile,omitempty"`
+	MAC         *MAC     `xml:"mac,omitempty"`
+}
+
+// Ref is a reference to another oVirt object
+type Ref struct {
+	ID string `xml:"id,attr"`
+}
+
+// MAC represents the MAC address of a NIC
+type MAC struct {
+	Address string `xml:"address"`
+}
+
+// VnicProfiles represents a list of vNIC profiles
+type VnicProfiles struct {
+	Profiles []VnicProfile `xml:"vnic_profile"`
+}
+
+// VnicProfile represents a vNIC profile connecting a NIC to a logical network
+type VnicProfile struct {
+	ID      string `xml:"id,attr"`
+	Name    string `xml:"name"`
+	Network Ref    `xml:"network"`
+}
+
+// Networks represents a list of logical networks
+type Networks struct {
+	Networks []Network `xml:"network"`
+}
+
+// Network represents a logical network
+type Network struct {
+	ID   string `xml:"id,attr"`
+	Name string `xml:"name"`
+}
+
+func (n *NIC) serialize() []byte {
+	b, _ := xml.Marshal(n)
+	return b
+}
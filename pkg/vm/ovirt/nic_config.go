@@ -0,0 +1,129 @@
+package ovirt
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/pkg/errors"
+
+	"github.com/MauveSoftware/provisionize/pkg/api/proto"
+)
+
+// configureNICs connects the NICs of the VM to the networks requested for the interfaces.
+// The n-th interface of the VM is mapped to the NIC named nic<n>. NICs missing in the template are added.
+func (s *OvirtService) configureNICs(ctx context.Context, vm *proto.VirtualMachine, id string, ch chan<- *proto.StatusUpdate) bool {
+	var nics NICs
+	err := s.getAndParse(ctx, fmt.Sprintf("vms/%s/nics", id), &nics)
+	if err != nil {
+		ch <- &proto.StatusUpdate{ServiceName: serviceName, Failed: true, Message: errors.Wrap(err, "could not retrieve NICs").Error()}
+		return false
+	}
+
+	existing := make(map[string]NIC)
+	for _, n := range nics.NICs {
+		existing[n.Name] = n
+	}
+
+	for i, iface := range vm.Interfaces {
+		err := s.configureNIC(ctx, id, fmt.Sprintf("nic%d", i+1), iface, existing, ch)
+		if err != nil {
+			ch <- &proto.StatusUpdate{ServiceName: serviceName, Failed: true, Message: errors.Wrapf(err, "interface %s", iface.Name).Error()}
+			return false
+		}
+	}
+
+	return true
+}
+
+func (s *OvirtService) configureNIC(ctx context.Context, vmID, name string, iface *proto.NetworkInterface, existing map[string]NIC,
+	ch chan<- *proto.StatusUpdate) error {
+	hasNetwork := len(iface.VnicProfile) > 0 || len(iface.Network) > 0
+	current, found := existing[name]
+
+	if found && !hasNetwork && len(iface.Mac) == 0 {
+		return nil
+	}
+
+	if !found && !hasNetwork {
+		return fmt.Errorf("%s does not exist in the template, vnic_profile or network is required", name)
+	}
+
+	nic := &NIC{Name: name}
+	if hasNetwork {
+		profileID, err := s.vnicProfileID(ctx, iface)
+		if err != nil {
+			return err
+		}
+
+		nic.VnicProfile = &Ref{ID: profileID}
+	}
+
+	if len(iface.Mac) > 0 {
+		nic.MAC = &MAC{Address: iface.Mac}
+	}
+
+	path := fmt.Sprintf("vms/%s/nics", vmID)
+	method := "POST"
+	if found {
+		path += "/" + current.ID
+		method = "PUT"
+	}
+
+	b, err := s.sendRequest(ctx, path, method, bytes.NewReader(nic.serialize()))
+	if err != nil {
+		return errors.Wrapf(err, "could not configure %s", name)
+	}
+
+	ch <- &proto.StatusUpdate{ServiceName: serviceName, Message: fmt.Sprintf("Configured %s for interface %s", name, iface.Name), DebugMessage: string(b)}
+	return nil
+}
+
+// vnicProfileID returns the ID of the vNIC profile of the interface.
+// If no profile name is given, the profile named like the network is used.
+func (s *OvirtService) vnicProfileID(ctx context.Context, iface *proto.NetworkInterface) (string, error) {
+	name := iface.VnicProfile
+	if len(name) == 0 {
+		name = iface.Network
+	}
+
+	networkIDs := make(map[string]bool)
+	if len(iface.Network) > 0 {
+		var networks Networks
+		err := s.getAndParse(ctx, "networks?search="+url.QueryEscape(searchTerm("name", iface.Network)), &networks)
+		if err != nil {
+			return "", errors.Wrap(err, "could not retrieve networks")
+		}
+
+		for _, n := range networks.Networks {
+			networkIDs[n.ID] = true
+		}
+
+		if len(networkIDs) == 0 {
+			return "", fmt.Errorf("network %s not found", iface.Network)
+		}
+	}
+
+	var profiles VnicProfiles
+	err := s.getAndParse(ctx, "vnicprofiles", &profiles)
+	if err != nil {
+		return "", errors.Wrap(err, "could not retrieve vNIC profiles")
+	}
+
+	matches := []string{}
+	for _, p := range profiles.Profiles {
+		if p.Name == name && (len(networkIDs) == 0 || networkIDs[p.Network.ID]) {
+			matches = append(matches, p.ID)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("vNIC profile %s not found", name)
+	case 1:
+		return matches[0], nil
+	default:
+		return "", fmt.Errorf("vNIC profile %s is ambiguous, specify the network", name)
+	}
+}
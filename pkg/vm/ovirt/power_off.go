@@ -0,0 +1,67 @@
+package ovirt
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/MauveSoftware/provisionize/pkg/api/proto"
+)
+
+// powerOff brings a running VM down before it is removed.
+// With shutdown set the guest is shut down via ACPI first, with force set the VM is stopped if the shutdown
+// does not complete in time (or immediately if no shutdown was requested).
+func (s *OvirtService) powerOff(ctx context.Context, vm *VM, opts *proto.DeprovisionOptions, ch chan<- *proto.StatusUpdate) bool {
+	if !opts.Shutdown && !opts.Force {
+		ch <- &proto.StatusUpdate{
+			ServiceName: serviceName,
+			Failed:      true,
+			Message:     fmt.Sprintf("VM is not down. Current status: %s (use shutdown or force to power it off)", vm.Status),
+		}
+		return false
+	}
+
+	if opts.Shutdown {
+		timeout := s.shutdownTimeout(opts)
+		ch <- &proto.StatusUpdate{ServiceName: serviceName, Message: fmt.Sprintf("Shutting down VM (timeout: %v)", timeout)}
+
+		if !s.sendAction(ctx, vm.ID, "shutdown", ch) {
+			return false
+		}
+
+		err := s.pollVMStatus(ctx, vm.ID, "down", timeout, ch)
+		if err == nil {
+			return true
+		}
+
+		if err != errTimeout || !opts.Force {
+			ch <- &proto.StatusUpdate{ServiceName: serviceName, Failed: true, Message: fmt.Sprintf("VM did not shut down: %v", err)}
+			return false
+		}
+
+		ch <- &proto.StatusUpdate{ServiceName: serviceName, Message: fmt.Sprintf("VM did not shut down within %v", timeout)}
+	}
+
+	ch <- &proto.StatusUpdate{ServiceName: serviceName, Message: "Stopping VM"}
+	return s.sendAction(ctx, vm.ID, "stop", ch) && s.waitForVMStatus(ctx, vm.ID, "down", ch)
+}
+
+func (s *OvirtService) shutdownTimeout(opts *proto.DeprovisionOptions) time.Duration {
+	if opts.ShutdownTimeoutSeconds == 0 {
+		return s.waitTimeout
+	}
+
+	return time.Duration(opts.ShutdownTimeoutSeconds) * time.Second
+}
+
+func (s *OvirtService) sendAction(ctx context.Context, id, action string, ch chan<- *proto.StatusUpdate) bool {
+	b, err := s.sendRequest(ctx, fmt.Sprintf("vms/%s/%s", id, action), "POST", strings.NewReader("<action/>"))
+	if err != nil {
+		ch <- &proto.StatusUpdate{ServiceName: serviceName, Failed: true, Message: err.Error()}
+		return false
+	}
+
+	ch <- &proto.StatusUpdate{ServiceName: serviceName, Message: fmt.Sprintf("Action %s sent", action), DebugMessage: string(b)}
+	return true
+}
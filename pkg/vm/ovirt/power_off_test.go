@@ -0,0 +1,100 @@
+package ovirt
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/MauveSoftware/provisionize/pkg/api/proto"
+	"github.com/MauveSoftware/provisionize/pkg/request"
+)
+
+func TestDeprovisionRunningVM(t *testing.T) {
+	tests := []struct {
+		name            string
+		opts            *proto.DeprovisionOptions
+		ignoreShutdown  bool
+		expectedActions []string
+		expectDeleted   bool
+	}{
+		{
+			name: "no options",
+		},
+		{
+			name:            "shutdown",
+			opts:            &proto.DeprovisionOptions{Shutdown: true},
+			expectedActions: []string{"shutdown"},
+			expectDeleted:   true,
+		},
+		{
+			name:            "shutdown timed out",
+			opts:            &proto.DeprovisionOptions{Shutdown: true},
+			ignoreShutdown:  true,
+			expectedActions: []string{"shutdown"},
+		},
+		{
+			name:            "shutdown timed out with force",
+			opts:            &proto.DeprovisionOptions{Shutdown: true, Force: true},
+			ignoreShutdown:  true,
+			expectedActions: []string{"shutdown", "stop"},
+			expectDeleted:   true,
+		},
+		{
+			name:            "force",
+			opts:            &proto.DeprovisionOptions{Force: true},
+			expectedActions: []string{"stop"},
+			expectDeleted:   true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			status := "up"
+			deleted := false
+			actions := []string{}
+
+			svc := newTestService(t, func(w http.ResponseWriter, r *http.Request) {
+				switch {
+				case r.URL.Path == "/vms":
+					fmt.Fprintf(w, `<vms><vm id="1"><name>test</name><status>%s</status></vm></vms>`, status)
+				case r.URL.Path == "/vms/1" && r.Method == "GET":
+					if deleted {
+						w.WriteHeader(http.StatusNotFound)
+						return
+					}
+					fmt.Fprintf(w, `<vm id="1"><name>test</name><status>%s</status></vm>`, status)
+				case r.URL.Path == "/vms/1" && r.Method == "DELETE":
+					deleted = true
+				case r.URL.Path == "/vms/1/shutdown":
+					actions = append(actions, "shutdown")
+					if !test.ignoreShutdown {
+						status = "down"
+					}
+				case r.URL.Path == "/vms/1/stop":
+					actions = append(actions, "stop")
+					status = "down"
+				}
+			})
+			svc.waitTimeout = 100 * time.Millisecond
+
+			ch := make(chan *proto.StatusUpdate, 100)
+			ctx := context.Background()
+			if test.opts != nil {
+				ctx = request.WithDeprovisionOptions(ctx, test.opts)
+			}
+
+			result := svc.Deprovision(ctx, &proto.VirtualMachine{Name: "test"}, ch)
+			assert.Equal(t, test.expectDeleted, result)
+			assert.Equal(t, test.expectDeleted, deleted)
+			if len(test.expectedActions) == 0 {
+				assert.Empty(t, actions)
+			} else {
+				assert.Equal(t, test.expectedActions, actions)
+			}
+		})
+	}
+}
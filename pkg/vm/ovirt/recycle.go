@@ -0,0 +1,239 @@
+package ovirt
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.opencensus.io/trace"
+
+	"github.com/MauveSoftware/provisionize/pkg/api/proto"
+	"github.com/MauveSoftware/provisionize/pkg/request"
+)
+
+const (
+	recycleBinTag   = "provisionize_recycle_bin"
+	recycledInfix   = "-deleted-"
+	recycledTimeFmt = "20060102150405"
+)
+
+// Recycle powers off the VM, renames it and tags it as pending deletion.
+// The ID of the VM is stored in the request so it can be restored or purged later.
+func (s *OvirtService) Recycle(ctx context.Context, vm *proto.VirtualMachine, ch chan<- *proto.StatusUpdate) bool {
+	ctx, span := trace.StartSpan(ctx, "OvirtService.Recycle")
+	defer span.End()
+
+	v, err := s.findVM(ctx, vm)
+	if err != nil {
+		ch <- &proto.StatusUpdate{ServiceName: serviceName, Failed: true, Message: err.Error()}
+		return false
+	}
+
+	if v == nil {
+		ch <- &proto.StatusUpdate{ServiceName: serviceName, Message: fmt.Sprintf("VM %s does not exist: skipping", vm.Name)}
+		return true
+	}
+
+	vm.Id = v.ID
+
+	if v.Status != "down" && !s.powerOff(ctx, v, request.DeprovisionOptions(ctx), ch) {
+		return false
+	}
+
+	name := recycledName(vm.Name, time.Now())
+	err = s.renameVM(ctx, v.ID, name)
+	if err == nil {
+		err = s.tagVM(ctx, v.ID)
+	}
+
+	if err != nil {
+		ch <- &proto.StatusUpdate{ServiceName: serviceName, Failed: true, Message: err.Error()}
+		return false
+	}
+
+	ch <- &proto.StatusUpdate{ServiceName: serviceName, Message: fmt.Sprintf("VM renamed to %s and tagged as %s", name, recycleBinTag)}
+	return true
+}
+
+// Restore renames a recycled VM back to its original name, removes the tag and starts it
+func (s *OvirtService) Restore(ctx context.Context, vm *proto.VirtualMachine, ch chan<- *proto.StatusUpdate) bool {
+	ctx, span := trace.StartSpan(ctx, "OvirtService.Restore")
+	defer span.End()
+
+	if len(vm.Id) == 0 {
+		ch <- &proto.StatusUpdate{ServiceName: serviceName, Message: fmt.Sprintf("VM %s was not recycled: skipping", vm.Name)}
+		return true
+	}
+
+	err := s.restoreVM(ctx, vm)
+	if err != nil {
+		ch <- &proto.StatusUpdate{ServiceName: serviceName, Failed: true, Message: err.Error()}
+		return false
+	}
+
+	ch <- &proto.StatusUpdate{ServiceName: serviceName, Message: fmt.Sprintf("VM renamed to %s", vm.Name)}
+	return s.startVM(ctx, vm.Id, ch) && s.waitForVMStatus(ctx, vm.Id, "up", ch)
+}
+
+// Purge deletes a recycled VM. VMs not named and tagged as recycled (e.g. restored in the meantime) are never deleted.
+func (s *OvirtService) Purge(ctx context.Context, vm *proto.VirtualMachine, ch chan<- *proto.StatusUpdate) bool {
+	ctx, span := trace.StartSpan(ctx, "OvirtService.Purge")
+	defer span.End()
+
+	if len(vm.Id) == 0 {
+		ch <- &proto.StatusUpdate{ServiceName: serviceName, Message: fmt.Sprintf("VM %s was not recycled: skipping", vm.Name)}
+		return true
+	}
+
+	v, err := s.getVMByID(ctx, vm)
+	if err != nil {
+		ch <- &proto.StatusUpdate{ServiceName: serviceName, Failed: true, Message: err.Error()}
+		return false
+	}
+
+	if v == nil {
+		ch <- &proto.StatusUpdate{ServiceName: serviceName, Message: fmt.Sprintf("VM %s does not exist: skipping", vm.Id)}
+		return true
+	}
+
+	tagged, err := s.hasRecycleBinTag(ctx, v.ID)
+	if err != nil {
+		ch <- &proto.StatusUpdate{ServiceName: serviceName, Failed: true, Message: err.Error()}
+		return false
+	}
+
+	if !isRecycledName(v.Name, vm.Name) || !tagged {
+		ch <- &proto.StatusUpdate{
+			ServiceName: serviceName,
+			Warning:     true,
+			Message:     fmt.Sprintf("VM %s (%s) is not in the recycle bin anymore: skipping", v.ID, v.Name),
+		}
+		return true
+	}
+
+	return s.deleteVMWithDisks(ctx, vm, v.ID, ch)
+}
+
+func (s *OvirtService) restoreVM(ctx context.Context, vm *proto.VirtualMachine) error {
+	v, err := s.getVM(ctx, vm.Id)
+	if err != nil {
+		return errors.Wrapf(err, "could not retrieve VM %s", vm.Id)
+	}
+
+	if !isRecycledName(v.Name, vm.Name) {
+		return fmt.Errorf("VM %s (%s) is not a recycled instance of %s", vm.Id, v.Name, vm.Name)
+	}
+
+	err = s.renameVM(ctx, vm.Id, vm.Name)
+	if err != nil {
+		return err
+	}
+
+	return s.untagVM(ctx, vm.Id)
+}
+
+func recycledName(name string, t time.Time) string {
+	return name + recycledInfix + t.UTC().Format(recycledTimeFmt)
+}
+
+func isRecycledName(actual, name string) bool {
+	suffix := strings.TrimPrefix(actual, name+recycledInfix)
+	if suffix == actual {
+		return false
+	}
+
+	_, err := time.Parse(recycledTimeFmt, suffix)
+	return err == nil
+}
+
+func (s *OvirtService) renameVM(ctx context.Context, id, name string) error {
+	u := &VMUpdate{Name: name}
+	_, err := s.sendRequest(ctx, fmt.Sprintf("vms/%s", id), "PUT", bytes.NewReader(u.serialize()))
+	if err != nil {
+		return errors.Wrapf(err, "could not rename VM to %s", name)
+	}
+
+	return nil
+}
+
+func (s *OvirtService) tagVM(ctx context.Context, id string) error {
+	tagID, err := s.recycleBinTagID(ctx, true)
+	if err != nil {
+		return err
+	}
+
+	t := &Tag{ID: tagID}
+	_, err = s.sendRequest(ctx, fmt.Sprintf("vms/%s/tags", id), "POST", bytes.NewReader(t.serialize()))
+	if err != nil {
+		return errors.Wrap(err, "could not tag VM")
+	}
+
+	return nil
+}
+
+func (s *OvirtService) hasRecycleBinTag(ctx context.Context, id string) (bool, error) {
+	var tags Tags
+	err := s.getAndParse(ctx, fmt.Sprintf("vms/%s/tags", id), &tags)
+	if err != nil {
+		return false, errors.Wrap(err, "could not retrieve tags of VM")
+	}
+
+	for _, t := range tags.Tags {
+		if t.Name == recycleBinTag {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (s *OvirtService) untagVM(ctx context.Context, id string) error {
+	tagID, err := s.recycleBinTagID(ctx, false)
+	if err != nil || len(tagID) == 0 {
+		return err
+	}
+
+	_, err = s.sendRequest(ctx, fmt.Sprintf("vms/%s/tags/%s", id, tagID), "DELETE", nil)
+	if err != nil && err.Error() != "404 Not Found" {
+		return errors.Wrap(err, "could not remove tag from VM")
+	}
+
+	return nil
+}
+
+// recycleBinTagID returns the ID of the tag marking VMs pending deletion. The tag is created if create is set and it does not exist.
+func (s *OvirtService) recycleBinTagID(ctx context.Context, create bool) (string, error) {
+	var tags Tags
+	err := s.getAndParse(ctx, "tags", &tags)
+	if err != nil {
+		return "", errors.Wrap(err, "could not retrieve tags")
+	}
+
+	for _, t := range tags.Tags {
+		if t.Name == recycleBinTag {
+			return t.ID, nil
+		}
+	}
+
+	if !create {
+		return "", nil
+	}
+
+	t := &Tag{Name: recycleBinTag, Description: "VMs deprovisioned by provisionize pending deletion"}
+	b, err := s.sendRequest(ctx, "tags", "POST", bytes.NewReader(t.serialize()))
+	if err != nil {
+		return "", errors.Wrap(err, "could not create tag")
+	}
+
+	created := &Tag{}
+	err = xml.Unmarshal(b, created)
+	if err != nil {
+		return "", errors.Wrap(err, "could not parse created tag")
+	}
+
+	return created.ID, nil
+}
@@ -0,0 +1,132 @@
+package ovirt
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/MauveSoftware/provisionize/pkg/api/proto"
+)
+
+type fakeRecycleEngine struct {
+	name     string
+	status   string
+	tagID    string
+	tagged   bool
+	deleted  bool
+	requests []string
+}
+
+func (e *fakeRecycleEngine) handle(w http.ResponseWriter, r *http.Request) {
+	e.requests = append(e.requests, r.Method+" "+r.URL.Path)
+
+	switch {
+	case r.URL.Path == "/vms":
+		fmt.Fprintf(w, `<vms><vm id="1"><name>%s</name><status>%s</status></vm></vms>`, e.name, e.status)
+	case r.URL.Path == "/vms/1" && r.Method == "GET":
+		if e.deleted {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fmt.Fprintf(w, `<vm id="1"><name>%s</name><status>%s</status></vm>`, e.name, e.status)
+	case r.URL.Path == "/vms/1" && r.Method == "PUT":
+		b, _ := ioutil.ReadAll(r.Body)
+		u := &VMUpdate{}
+		xml.Unmarshal(b, u)
+		e.name = u.Name
+	case r.URL.Path == "/vms/1" && r.Method == "DELETE":
+		e.deleted = true
+	case r.URL.Path == "/vms/1/start":
+		e.status = "up"
+	case r.URL.Path == "/tags" && r.Method == "GET":
+		if len(e.tagID) == 0 {
+			w.Write([]byte(`<tags><tag id="t0"><name>other</name></tag></tags>`))
+			return
+		}
+		fmt.Fprintf(w, `<tags><tag id="%s"><name>%s</name></tag></tags>`, e.tagID, recycleBinTag)
+	case r.URL.Path == "/tags" && r.Method == "POST":
+		e.tagID = "t1"
+		w.Write([]byte(`<tag id="t1"><name>provisionize_recycle_bin</name></tag>`))
+	case r.URL.Path == "/vms/1/tags" && r.Method == "GET":
+		if !e.tagged {
+			w.Write([]byte(`<tags/>`))
+			return
+		}
+		fmt.Fprintf(w, `<tags><tag id="%s"><name>%s</name></tag></tags>`, e.tagID, recycleBinTag)
+	case r.URL.Path == "/vms/1/tags":
+		e.tagged = true
+	case r.URL.Path == "/vms/1/tags/t1":
+		e.tagged = false
+	}
+}
+
+func TestRecycleAndRestore(t *testing.T) {
+	e := &fakeRecycleEngine{name: "web1", status: "down"}
+	svc := newTestService(t, e.handle)
+
+	ch := make(chan *proto.StatusUpdate, 100)
+	vm := &proto.VirtualMachine{Name: "web1"}
+
+	require.True(t, svc.Recycle(context.Background(), vm, ch))
+	assert.Equal(t, "1", vm.Id)
+	assert.True(t, isRecycledName(e.name, "web1"), e.name)
+	assert.True(t, e.tagged)
+	assert.False(t, e.deleted)
+
+	require.True(t, svc.Restore(context.Background(), vm, ch))
+	assert.Equal(t, "web1", e.name)
+	assert.Equal(t, "up", e.status)
+	assert.False(t, e.tagged)
+}
+
+func TestPurge(t *testing.T) {
+	e := &fakeRecycleEngine{name: recycledName("web1", time.Now()), status: "down", tagID: "t1", tagged: true}
+	svc := newTestService(t, e.handle)
+
+	ch := make(chan *proto.StatusUpdate, 100)
+
+	t.Run("unrelated VM", func(t *testing.T) {
+		assert.False(t, svc.Purge(context.Background(), &proto.VirtualMachine{Id: "1", Name: "web2"}, ch))
+		assert.False(t, e.deleted)
+	})
+
+	t.Run("not recycled", func(t *testing.T) {
+		assert.True(t, svc.Purge(context.Background(), &proto.VirtualMachine{Name: "web1"}, ch))
+		assert.False(t, e.deleted)
+	})
+
+	t.Run("restored VM", func(t *testing.T) {
+		e.name = "web1"
+		defer func() { e.name = recycledName("web1", time.Now()) }()
+
+		assert.True(t, svc.Purge(context.Background(), &proto.VirtualMachine{Id: "1", Name: "web1"}, ch))
+		assert.False(t, e.deleted)
+	})
+
+	t.Run("untagged VM", func(t *testing.T) {
+		e.tagged = false
+		defer func() { e.tagged = true }()
+
+		assert.True(t, svc.Purge(context.Background(), &proto.VirtualMachine{Id: "1", Name: "web1"}, ch))
+		assert.False(t, e.deleted)
+	})
+
+	t.Run("recycled VM", func(t *testing.T) {
+		assert.True(t, svc.Purge(context.Background(), &proto.VirtualMachine{Id: "1", Name: "web1"}, ch))
+		assert.True(t, e.deleted)
+	})
+}
+
+func TestIsRecycledName(t *testing.T) {
+	assert.True(t, isRecycledName("web1-deleted-20200101120000", "web1"))
+	assert.False(t, isRecycledName("web1", "web1"))
+	assert.False(t, isRecycledName("web10-deleted-20200101120000", "web1"))
+	assert.False(t, isRecycledName("web1-deleted-foo", "web1"))
+}
@@ -6,18 +6,27 @@ import (
 	"encoding/xml"
 	"fmt"
 	"io"
+	"net/url"
+	"strconv"
 	"strings"
 	"text/template"
 	"time"
 
 	"github.com/MauveSoftware/provisionize/pkg/api/proto"
+	"github.com/MauveSoftware/provisionize/pkg/request"
 	ovirt "github.com/czerwonk/ovirt_api/api"
 	"github.com/pkg/errors"
+	"go.opencensus.io/plugin/ochttp"
 	"go.opencensus.io/trace"
 )
 
 const serviceName = "oVirt"
 
+var errTimeout = errors.New("Operation timed out")
+
+// searchEscaper escapes the characters with special meaning in quoted values of oVirt search queries
+var searchEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+
 // OvirtService is the service responsible for creating the virtual machine
 type OvirtService struct {
 	template        string
@@ -25,10 +34,21 @@ type OvirtService struct {
 	client          *ovirt.Client
 	waitTimeout     time.Duration
 	pollingInterval time.Duration
+	keepDataDisks   bool
+}
+
+// Option configures optional behavior of OvirtService
+type Option func(s *OvirtService)
+
+// WithKeepDataDisks keeps all disks of a VM except the boot disk of its template when it is deprovisioned
+func WithKeepDataDisks() Option {
+	return func(s *OvirtService) {
+		s.keepDataDisks = true
+	}
 }
 
 // NewService creates a new instance of OvirtService
-func NewService(url, user, pass string, template string, configService ConfigService) (*OvirtService, error) {
+func NewService(url, user, pass string, template string, configService ConfigService, opts ...Option) (*OvirtService, error) {
 	client, err := ovirt.NewClient(url, user, pass, ovirt.WithDebug(), ovirt.WithInsecure())
 	if err != nil {
 		return nil, errors.Wrap(err, "could not create new oVirt client")
@@ -42,6 +62,10 @@ func NewService(url, user, pass string, template string, configService ConfigSer
 		configService:   configService,
 	}
 
+	for _, opt := range opts {
+		opt(svc)
+	}
+
 	return svc, nil
 }
 
@@ -50,7 +74,13 @@ func (s *OvirtService) Provision(ctx context.Context, vm *proto.VirtualMachine,
 	ctx, span := trace.StartSpan(ctx, "OvirtService.Provision")
 	defer span.End()
 
-	b, err := s.createVM(vm, ch)
+	err := validateDisks(vm.Disks)
+	if err != nil {
+		ch <- &proto.StatusUpdate{ServiceName: serviceName, Failed: true, Message: err.Error()}
+		return false
+	}
+
+	b, err := s.createVM(ctx, vm, ch)
 	if err != nil {
 		ch <- &proto.StatusUpdate{ServiceName: serviceName, Failed: true, Message: err.Error()}
 		return false
@@ -64,15 +94,20 @@ func (s *OvirtService) Provision(ctx context.Context, vm *proto.VirtualMachine,
 	}
 
 	ch <- &proto.StatusUpdate{ServiceName: serviceName, Message: "Waiting for VM initialization to complete"}
-	return s.waitForVMStatus(v.ID, "down", ch) &&
-		s.ensureBootDiskIsAttached(vm, v.ID, ch) &&
-		s.startVM(v.ID, ch) &&
-		s.waitForVMStatus(v.ID, "up", ch)
+	return s.waitForVMStatus(ctx, v.ID, "down", ch) &&
+		s.configureNICs(ctx, vm, v.ID, ch) &&
+		s.ensureBootDiskIsAttached(ctx, vm, v.ID, ch) &&
+		s.createDisks(ctx, vm, v.ID, ch) &&
+		s.startVM(ctx, v.ID, ch) &&
+		s.waitForVMStatus(ctx, v.ID, "up", ch)
 }
 
 // Deprovision deletes the virtual machine
 func (s *OvirtService) Deprovision(ctx context.Context, vm *proto.VirtualMachine, ch chan<- *proto.StatusUpdate) bool {
-	v, err := s.getVMByName(vm.Name)
+	ctx, span := trace.StartSpan(ctx, "OvirtService.Deprovision")
+	defer span.End()
+
+	v, err := s.findVM(ctx, vm)
 	if err != nil {
 		ch <- &proto.StatusUpdate{ServiceName: serviceName, Failed: true, Message: err.Error()}
 		return false
@@ -83,15 +118,22 @@ func (s *OvirtService) Deprovision(ctx context.Context, vm *proto.VirtualMachine
 		return true
 	}
 
-	if v.Status != "down" {
-		ch <- &proto.StatusUpdate{ServiceName: serviceName, Failed: true, Message: fmt.Sprintf("VM is not down. Current status: %s", v.Status)}
+	if v.Status != "down" && !s.powerOff(ctx, v, request.DeprovisionOptions(ctx), ch) {
 		return false
 	}
 
-	return s.deleteVM(v.ID, ch) && s.waitForVanish(v.ID, ch)
+	return s.deleteVMWithDisks(ctx, vm, v.ID, ch)
 }
 
-func (s *OvirtService) createVM(vm *proto.VirtualMachine, ch chan<- *proto.StatusUpdate) ([]byte, error) {
+func (s *OvirtService) deleteVMWithDisks(ctx context.Context, vm *proto.VirtualMachine, id string, ch chan<- *proto.StatusUpdate) bool {
+	if s.keepDataDisks && !s.detachDataDisks(ctx, vm, id, ch) {
+		return false
+	}
+
+	return s.deleteVM(ctx, id, ch) && s.waitForVanish(ctx, id, ch)
+}
+
+func (s *OvirtService) createVM(ctx context.Context, vm *proto.VirtualMachine, ch chan<- *proto.StatusUpdate) ([]byte, error) {
 	body, err := s.getVMCreateRequest(vm)
 	if err != nil {
 		return nil, err
@@ -99,11 +141,11 @@ func (s *OvirtService) createVM(vm *proto.VirtualMachine, ch chan<- *proto.Statu
 
 	ch <- &proto.StatusUpdate{
 		ServiceName:  serviceName,
-		DebugMessage: body.String(),
+		DebugMessage: redactPassword(body.String(), vm),
 		Message:      "Start creating VM",
 	}
 
-	b, err := s.sendCreateRequestWithRetry(body)
+	b, err := s.sendCreateRequestWithRetry(ctx, body)
 	if err != nil {
 		return nil, err
 	}
@@ -117,10 +159,10 @@ func (s *OvirtService) createVM(vm *proto.VirtualMachine, ch chan<- *proto.Statu
 	return b, nil
 }
 
-func (s *OvirtService) sendCreateRequestWithRetry(body io.Reader) ([]byte, error) {
+func (s *OvirtService) sendCreateRequestWithRetry(ctx context.Context, body io.Reader) ([]byte, error) {
 	isRetry := false
 	for {
-		b, err := s.client.SendRequest("vms?clone=true", "POST", body)
+		b, err := s.sendRequest(ctx, "vms?clone=true", "POST", body)
 		if err == nil {
 			return b, nil
 		}
@@ -144,6 +186,8 @@ func (s *OvirtService) getVMCreateRequest(vm *proto.VirtualMachine) (*bytes.Buff
 		"ovirt_template_name": func() string {
 			return s.configService.OvirtTemplateNameForVM(vm)
 		},
+		"xml":  xmlEscape,
+		"join": strings.Join,
 	}
 	tmpl, err := template.New("create-vm").Funcs(funcs).Parse(s.template)
 	if err != nil {
@@ -155,20 +199,49 @@ func (s *OvirtService) getVMCreateRequest(vm *proto.VirtualMachine) (*bytes.Buff
 	return w, err
 }
 
-func (s *OvirtService) waitForVMStatus(id string, desiredStatus string, ch chan<- *proto.StatusUpdate) bool {
+func xmlEscape(s string) string {
+	b := &bytes.Buffer{}
+	xml.EscapeText(b, []byte(s))
+	return b.String()
+}
+
+// redactPassword removes the cloud-init password from the request body to prevent it from being sent as debug message
+func redactPassword(body string, vm *proto.VirtualMachine) string {
+	if len(vm.CloudInit.GetPassword()) == 0 {
+		return body
+	}
+
+	return strings.Replace(body, xmlEscape(vm.CloudInit.Password), "********", -1)
+}
+
+func (s *OvirtService) waitForVMStatus(ctx context.Context, id string, desiredStatus string, ch chan<- *proto.StatusUpdate) bool {
+	err := s.pollVMStatus(ctx, id, desiredStatus, s.waitTimeout, ch)
+	if err != nil {
+		ch <- &proto.StatusUpdate{ServiceName: serviceName, Failed: true, Message: err.Error()}
+		return false
+	}
+
+	return true
+}
+
+// pollVMStatus waits until the VM reaches the desired status and reports every status change.
+// errTimeout is returned if the status is not reached within timeout.
+func (s *OvirtService) pollVMStatus(ctx context.Context, id string, desiredStatus string, timeout time.Duration, ch chan<- *proto.StatusUpdate) error {
 	currentStatus := ""
+	timeoutCh := time.After(timeout)
 
 	for {
 		select {
-		case <-time.After(s.waitTimeout):
-			ch <- &proto.StatusUpdate{ServiceName: serviceName, Failed: true, Message: "Operation timed out"}
-			return false
+		case <-timeoutCh:
+			return errTimeout
+
+		case <-ctx.Done():
+			return ctx.Err()
 
 		case <-time.After(s.pollingInterval):
-			vm, err := s.getVM(id)
+			vm, err := s.getVM(ctx, id)
 			if err != nil {
-				ch <- &proto.StatusUpdate{ServiceName: serviceName, Failed: true, Message: err.Error()}
-				return false
+				return err
 			}
 
 			if vm.Status != currentStatus {
@@ -177,21 +250,27 @@ func (s *OvirtService) waitForVMStatus(id string, desiredStatus string, ch chan<
 			}
 
 			if vm.Status == desiredStatus {
-				return true
+				return nil
 			}
 		}
 	}
 }
 
-func (s *OvirtService) waitForVanish(id string, ch chan<- *proto.StatusUpdate) bool {
+func (s *OvirtService) waitForVanish(ctx context.Context, id string, ch chan<- *proto.StatusUpdate) bool {
+	timeout := time.After(s.waitTimeout)
+
 	for {
 		select {
-		case <-time.After(s.waitTimeout):
-			ch <- &proto.StatusUpdate{ServiceName: serviceName, Failed: true, Message: "Operation timed out"}
+		case <-timeout:
+			ch <- &proto.StatusUpdate{ServiceName: serviceName, Failed: true, Message: errTimeout.Error()}
+			return false
+
+		case <-ctx.Done():
+			ch <- &proto.StatusUpdate{ServiceName: serviceName, Failed: true, Message: ctx.Err().Error()}
 			return false
 
 		case <-time.After(s.pollingInterval):
-			vm, err := s.getVM(id)
+			vm, err := s.getVM(ctx, id)
 			if err != nil && err.Error() != "404 Not Found" {
 				ch <- &proto.StatusUpdate{ServiceName: serviceName, Failed: true, Message: err.Error()}
 				return false
@@ -205,9 +284,9 @@ func (s *OvirtService) waitForVanish(id string, ch chan<- *proto.StatusUpdate) b
 	}
 }
 
-func (s *OvirtService) getVM(id string) (*VM, error) {
+func (s *OvirtService) getVM(ctx context.Context, id string) (*VM, error) {
 	var vm VM
-	err := s.client.GetAndParse(fmt.Sprintf("vms/%s", id), &vm)
+	err := s.getAndParse(ctx, fmt.Sprintf("vms/%s", id), &vm)
 	if err != nil {
 		return nil, err
 	}
@@ -215,43 +294,105 @@ func (s *OvirtService) getVM(id string) (*VM, error) {
 	return &vm, nil
 }
 
-func (s *OvirtService) getVMByName(name string) (*VM, error) {
+// findVM looks up the VM by its ID if set or by name (scoped to the cluster if set) otherwise.
+// nil is returned if the VM does not exist.
+func (s *OvirtService) findVM(ctx context.Context, vm *proto.VirtualMachine) (*VM, error) {
+	if len(vm.Id) > 0 {
+		return s.getVMByID(ctx, vm)
+	}
+
+	query := searchTerm("name", vm.Name)
+	if len(vm.ClusterName) > 0 {
+		query += " and " + searchTerm("cluster", vm.ClusterName)
+	}
+
 	var vms VMs
-	err := s.client.GetAndParse("vms", &vms)
+	err := s.getAndParse(ctx, "vms?search="+url.QueryEscape(query), &vms)
 	if err != nil {
-		return nil, err
+		return nil, errors.Wrap(err, "could not search VM")
+	}
+
+	matches := []VM{}
+	for _, v := range vms.VMs {
+		// the search also matches names with wildcards
+		if v.Name == vm.Name {
+			matches = append(matches, v)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, nil
+	case 1:
+		return &matches[0], nil
+	default:
+		return nil, fmt.Errorf("%d VMs named %s found, please specify cluster or ID", len(matches), vm.Name)
+	}
+}
+
+// searchTerm returns a term of an oVirt search query matching the quoted and escaped value
+func searchTerm(key, value string) string {
+	return fmt.Sprintf(`%s="%s"`, key, searchEscaper.Replace(value))
+}
+
+func (s *OvirtService) getVMByID(ctx context.Context, vm *proto.VirtualMachine) (*VM, error) {
+	v, err := s.getVM(ctx, vm.Id)
+	if err != nil {
+		if err.Error() == "404 Not Found" {
+			return nil, nil
+		}
+
+		return nil, errors.Wrapf(err, "could not retrieve VM %s", vm.Id)
+	}
+
+	if len(vm.Name) > 0 && v.Name != vm.Name && !isRecycledName(v.Name, vm.Name) {
+		return nil, fmt.Errorf("VM %s is named %s, not %s", vm.Id, v.Name, vm.Name)
+	}
+
+	return v, nil
+}
+
+// TemplateExists checks if a template with the given name exists in oVirt
+func (s *OvirtService) TemplateExists(ctx context.Context, name string) (bool, error) {
+	ctx, span := trace.StartSpan(ctx, "OvirtService.TemplateExists")
+	defer span.End()
+
+	var templates Templates
+	err := s.getAndParse(ctx, "templates?search="+url.QueryEscape(searchTerm("name", name)), &templates)
+	if err != nil {
+		return false, errors.Wrap(err, "could not retrieve templates")
 	}
 
-	for _, vm := range vms.VMs {
-		if vm.Name == name {
-			return &vm, nil
+	for _, t := range templates.Templates {
+		if t.Name == name {
+			return true, nil
 		}
 	}
 
-	return nil, nil
+	return false, nil
 }
 
-func (s *OvirtService) ensureBootDiskIsAttached(vm *proto.VirtualMachine, id string, ch chan<- *proto.StatusUpdate) bool {
-	if s.isBootDiskAttached(id, ch) {
+func (s *OvirtService) ensureBootDiskIsAttached(ctx context.Context, vm *proto.VirtualMachine, id string, ch chan<- *proto.StatusUpdate) bool {
+	if s.isBootDiskAttached(ctx, id, ch) {
 		return true
 	}
 
-	diskID := s.findCreatedDisk(vm, ch)
+	diskID := s.findCreatedDisk(ctx, vm, ch)
 	if diskID == "" {
 		return false
 	}
 
-	return s.attachDisk(id, diskID, ch)
+	return s.attachDisk(ctx, id, diskID, ch)
 }
 
-func (s *OvirtService) isBootDiskAttached(id string, ch chan<- *proto.StatusUpdate) bool {
+func (s *OvirtService) isBootDiskAttached(ctx context.Context, id string, ch chan<- *proto.StatusUpdate) bool {
 	ch <- &proto.StatusUpdate{
 		ServiceName: serviceName,
 		Message:     "Check if boot disk is attached to VM",
 	}
 
 	var attachments DiskAttachments
-	err := s.client.GetAndParse(fmt.Sprintf("vms/%s/diskattachments", id), &attachments)
+	err := s.getAndParse(ctx, fmt.Sprintf("vms/%s/diskattachments", id), &attachments)
 	if err != nil {
 		ch <- &proto.StatusUpdate{ServiceName: serviceName, Failed: true, Message: err.Error()}
 		return false
@@ -266,9 +407,9 @@ func (s *OvirtService) isBootDiskAttached(id string, ch chan<- *proto.StatusUpda
 	return false
 }
 
-func (s *OvirtService) findCreatedDisk(vm *proto.VirtualMachine, ch chan<- *proto.StatusUpdate) string {
+func (s *OvirtService) findCreatedDisk(ctx context.Context, vm *proto.VirtualMachine, ch chan<- *proto.StatusUpdate) string {
 	var disks Disks
-	err := s.client.GetAndParse("/disks?search=number_of_vms=0", &disks)
+	err := s.getAndParse(ctx, "/disks?search=number_of_vms=0", &disks)
 	if err != nil {
 		ch <- &proto.StatusUpdate{ServiceName: serviceName, Failed: true, Message: err.Error()}
 		return ""
@@ -289,7 +430,7 @@ func (s *OvirtService) findCreatedDisk(vm *proto.VirtualMachine, ch chan<- *prot
 	return ""
 }
 
-func (s *OvirtService) attachDisk(id, diskID string, ch chan<- *proto.StatusUpdate) bool {
+func (s *OvirtService) attachDisk(ctx context.Context, id, diskID string, ch chan<- *proto.StatusUpdate) bool {
 	d := &NewDiskAttachment{
 		Bootable:    true,
 		PassDiscard: false,
@@ -301,19 +442,19 @@ func (s *OvirtService) attachDisk(id, diskID string, ch chan<- *proto.StatusUpda
 	}
 	ch <- &proto.StatusUpdate{ServiceName: serviceName, Message: "Attaching disk " + diskID, DebugMessage: string(d.serialize())}
 
-	b, err := s.client.SendRequest(fmt.Sprintf("/vms/%s/diskattachments", id), "POST", bytes.NewReader(d.serialize()))
+	b, err := s.sendRequest(ctx, fmt.Sprintf("/vms/%s/diskattachments", id), "POST", bytes.NewReader(d.serialize()))
 	if err != nil {
 		ch <- &proto.StatusUpdate{ServiceName: serviceName, Failed: true, Message: err.Error()}
 		return false
 	}
 
 	ch <- &proto.StatusUpdate{ServiceName: serviceName, Message: "Disk attached", DebugMessage: string(b)}
-	return s.waitForVMStatus(id, "down", ch)
+	return s.waitForVMStatus(ctx, id, "down", ch)
 }
 
-func (s *OvirtService) startVM(id string, ch chan<- *proto.StatusUpdate) bool {
+func (s *OvirtService) startVM(ctx context.Context, id string, ch chan<- *proto.StatusUpdate) bool {
 	body := strings.NewReader("<action/>")
-	b, err := s.client.SendRequest(fmt.Sprintf("vms/%s/start", id), "POST", body)
+	b, err := s.sendRequest(ctx, fmt.Sprintf("vms/%s/start", id), "POST", body)
 	if err != nil {
 		ch <- &proto.StatusUpdate{ServiceName: serviceName, Failed: true, Message: err.Error()}
 		return false
@@ -323,8 +464,8 @@ func (s *OvirtService) startVM(id string, ch chan<- *proto.StatusUpdate) bool {
 	return true
 }
 
-func (s *OvirtService) deleteVM(id string, ch chan<- *proto.StatusUpdate) bool {
-	b, err := s.client.SendRequest(fmt.Sprintf("vms/%s", id), "DELETE", nil)
+func (s *OvirtService) deleteVM(ctx context.Context, id string, ch chan<- *proto.StatusUpdate) bool {
+	b, err := s.sendRequest(ctx, fmt.Sprintf("vms/%s", id), "DELETE", nil)
 	if err != nil {
 		ch <- &proto.StatusUpdate{ServiceName: serviceName, Failed: true, Message: err.Error()}
 		return false
@@ -333,3 +474,36 @@ func (s *OvirtService) deleteVM(id string, ch chan<- *proto.StatusUpdate) bool {
 	ch <- &proto.StatusUpdate{ServiceName: serviceName, Message: "VM deletion initiated", DebugMessage: string(b)}
 	return true
 }
+
+func (s *OvirtService) getAndParse(ctx context.Context, path string, v interface{}) error {
+	b, err := s.sendRequest(ctx, path, "GET", nil)
+	if err != nil {
+		return err
+	}
+
+	return xml.Unmarshal(b, v)
+}
+
+func (s *OvirtService) sendRequest(ctx context.Context, path, method string, body io.Reader) ([]byte, error) {
+	ctx, span := trace.StartSpan(ctx, "OvirtService.sendRequest", trace.WithSpanKind(trace.SpanKindClient))
+	defer span.End()
+	span.AddAttributes(
+		trace.StringAttribute(ochttp.MethodAttribute, method),
+		trace.StringAttribute(ochttp.PathAttribute, path))
+
+	b, err := s.client.SendRequest(path, method, body)
+	if err != nil {
+		// the oVirt client reports non 2xx responses as error containing the HTTP status line
+		if code, convErr := strconv.Atoi(strings.SplitN(err.Error(), " ", 2)[0]); convErr == nil {
+			span.AddAttributes(trace.Int64Attribute(ochttp.StatusCodeAttribute, int64(code)))
+			span.SetStatus(ochttp.TraceStatus(code, err.Error()))
+		} else {
+			span.SetStatus(trace.Status{Code: trace.StatusCodeUnknown, Message: err.Error()})
+		}
+
+		request.Logger(ctx).WithError(err).Debugf("oVirt API call %s %s failed", method, path)
+		return nil, err
+	}
+
+	return b, nil
+}
@@ -1,9 +1,13 @@
 package ovirt
 
 import (
+	"context"
 	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
@@ -59,6 +63,10 @@ func (m *mockConfigService) OvirtTemplateNameForVM(vm *proto.VirtualMachine) str
 }
 
 func (m *mockConfigService) BootDiskName(vm *proto.VirtualMachine) string {
+	if len(vm.Template) == 0 {
+		return ""
+	}
+
 	return "new-disk"
 }
 
@@ -138,3 +146,309 @@ func unify(str string) string {
 	str = strings.Replace(str, "\t", "", -1)
 	return strings.Replace(str, " ", "", -1)
 }
+
+func newTestService(t *testing.T, handler http.HandlerFunc) *OvirtService {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "HEAD" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		handler(w, r)
+	}))
+	t.Cleanup(s.Close)
+
+	svc, err := NewService(s.URL, "test", "foo", testTemplate, &mockConfigService{templateName: "template1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc.pollingInterval = 10 * time.Millisecond
+
+	return svc
+}
+
+func TestTemplateExists(t *testing.T) {
+	svc := newTestService(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/templates" || r.URL.Query().Get("search") != `name="ubuntu-18.04"` {
+			w.Write([]byte(`<templates/>`))
+			return
+		}
+
+		w.Write([]byte(`<templates><template id="123"><name>ubuntu-18.04</name></template></templates>`))
+	})
+
+	found, err := svc.TemplateExists(context.Background(), "ubuntu-18.04")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, found, "existing template")
+
+	found, err = svc.TemplateExists(context.Background(), "ubuntu-20.04")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.False(t, found, "unknown template")
+}
+
+func TestGetVMCreateRequestMultipleInterfaces(t *testing.T) {
+	tmpl, err := ioutil.ReadFile("../../../examples/template.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vm := &proto.VirtualMachine{
+		Name: "db1",
+		Interfaces: []*proto.NetworkInterface{
+			{
+				Name: "ens3",
+				Ipv4: &proto.IPConfig{Address: "192.168.1.100", PrefixLength: 24, Gateway: "192.168.1.1"},
+				Ipv6: &proto.IPConfig{Address: "2001:678:1e0::f00", PrefixLength: 64, Gateway: "2001:678:1e0::1"},
+			},
+			{
+				Name:    "ens4",
+				Network: "storage",
+				Ipv4:    &proto.IPConfig{Address: "10.0.0.100", PrefixLength: 24},
+			},
+		},
+	}
+	if err := vm.NormalizeInterfaces(); err != nil {
+		t.Fatal(err)
+	}
+
+	svc := &OvirtService{
+		template:      string(tmpl),
+		configService: &mockConfigService{templateName: "template1"},
+	}
+	r, err := svc.getVMCreateRequest(vm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `<nics>
+		<nic>
+		  <name>ens3</name>
+		  <boot_protocol>static</boot_protocol>
+		  <network>
+			<ip address="192.168.1.100" netmask="24" gateway="192.168.1.1" />
+			<ip address="2001:678:1e0::f00" netmask="64" gateway="2001:678:1e0::1" />
+		  </network>
+		  <on_boot>true</on_boot>
+		</nic>
+		<nic>
+		  <name>ens4</name>
+		  <boot_protocol>static</boot_protocol>
+		  <network>
+			<ip address="10.0.0.100" netmask="24" gateway="" />
+		  </network>
+		  <on_boot>true</on_boot>
+		</nic>
+	  </nics>`
+	assert.Contains(t, unify(r.String()), unify(expected))
+}
+
+func TestConfigureNICs(t *testing.T) {
+	requests := []string{}
+	svc := newTestService(t, func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		requests = append(requests, r.Method+" "+r.URL.Path+" "+string(b))
+
+		switch r.URL.Path {
+		case "/vms/123/nics":
+			if r.Method == "GET" {
+				w.Write([]byte(`<nics><nic id="n1"><name>nic1</name></nic></nics>`))
+			}
+		case "/networks":
+			if r.URL.Query().Get("search") == `name="storage"` {
+				w.Write([]byte(`<networks><network id="net2"><name>storage</name></network></networks>`))
+				return
+			}
+			w.Write([]byte(`<networks/>`))
+		case "/vnicprofiles":
+			w.Write([]byte(`<vnic_profiles>
+				<vnic_profile id="p1"><name>frontend</name><network id="net1"/></vnic_profile>
+				<vnic_profile id="p2"><name>storage</name><network id="net2"/></vnic_profile>
+				<vnic_profile id="p3"><name>storage</name><network id="net3"/></vnic_profile>
+			</vnic_profiles>`))
+		}
+	})
+
+	vm := &proto.VirtualMachine{
+		Interfaces: []*proto.NetworkInterface{
+			{Name: "ens3"},
+			{Name: "ens4", Network: "storage", Mac: "00:1a:4a:16:01:51"},
+		},
+	}
+
+	ch := make(chan *proto.StatusUpdate, 10)
+	assert.True(t, svc.configureNICs(context.Background(), vm, "123", ch))
+	assert.Contains(t, requests, `POST /vms/123/nics <nic><name>nic2</name><vnic_profile id="p2"></vnic_profile><mac><address>00:1a:4a:16:01:51</address></mac></nic>`)
+
+	t.Run("update existing NIC", func(t *testing.T) {
+		requests = nil
+		vm := &proto.VirtualMachine{
+			Interfaces: []*proto.NetworkInterface{{Name: "ens3", VnicProfile: "frontend"}},
+		}
+
+		assert.True(t, svc.configureNICs(context.Background(), vm, "123", ch))
+		assert.Contains(t, requests, `PUT /vms/123/nics/n1 <nic><name>nic1</name><vnic_profile id="p1"></vnic_profile></nic>`)
+	})
+
+	t.Run("ambiguous profile", func(t *testing.T) {
+		vm := &proto.VirtualMachine{
+			Interfaces: []*proto.NetworkInterface{{Name: "ens3", VnicProfile: "storage"}},
+		}
+
+		assert.False(t, svc.configureNICs(context.Background(), vm, "123", ch))
+	})
+
+	t.Run("missing network for additional NIC", func(t *testing.T) {
+		vm := &proto.VirtualMachine{
+			Interfaces: []*proto.NetworkInterface{{Name: "ens3"}, {Name: "ens4"}},
+		}
+
+		assert.False(t, svc.configureNICs(context.Background(), vm, "123", ch))
+	})
+}
+
+func TestGetVMCreateRequestCloudInit(t *testing.T) {
+	tmpl, err := ioutil.ReadFile("../../../examples/template.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vm := &proto.VirtualMachine{
+		Name: "db1",
+		CloudInit: &proto.CloudInit{
+			UserName:          "ops",
+			Password:          "s3cr<t",
+			AuthorizedSshKeys: []string{"ssh-ed25519 AAAA ops", "ssh-ed25519 BBBB dba"},
+			DnsServers:        []string{"192.168.1.53", "192.168.2.53"},
+			UserData:          "#cloud-config\npackages:\n- htop\n",
+		},
+	}
+	if err := vm.NormalizeInterfaces(); err != nil {
+		t.Fatal(err)
+	}
+
+	svc := &OvirtService{
+		template:      string(tmpl),
+		configService: &mockConfigService{templateName: "template1"},
+	}
+	r, err := svc.getVMCreateRequest(vm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := r.String()
+
+	assert.Contains(t, body, "<user_name>ops</user_name>")
+	assert.Contains(t, body, "<root_password>s3cr&lt;t</root_password>")
+	assert.Contains(t, body, "<authorized_ssh_keys>ssh-ed25519 AAAA ops&#xA;ssh-ed25519 BBBB dba</authorized_ssh_keys>")
+	assert.Contains(t, body, "<dns_servers>192.168.1.53 192.168.2.53</dns_servers>")
+	assert.Contains(t, body, "<custom_script>#cloud-config&#xA;packages:&#xA;- htop&#xA;</custom_script>")
+	assert.NotContains(t, body, "<timezone>")
+
+	redacted := redactPassword(body, vm)
+	assert.NotContains(t, redacted, "s3cr&lt;t")
+	assert.Contains(t, redacted, "<root_password>********</root_password>")
+}
+
+func TestFindVM(t *testing.T) {
+	svc := newTestService(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/vms":
+			switch r.URL.Query().Get("search") {
+			case `name="web1" and cluster="cluster1"`:
+				w.Write([]byte(`<vms><vm id="1"><name>web1</name></vm><vm id="2"><name>web10</name></vm></vms>`))
+			case `name="web1"`:
+				w.Write([]byte(`<vms><vm id="1"><name>web1</name></vm><vm id="3"><name>web1</name></vm></vms>`))
+			default:
+				w.Write([]byte(`<vms/>`))
+			}
+		case "/vms/1":
+			w.Write([]byte(`<vm id="1"><name>web1</name></vm>`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	tests := []struct {
+		name        string
+		vm          *proto.VirtualMachine
+		expectedID  string
+		expectError string
+	}{
+		{
+			name:       "by name and cluster",
+			vm:         &proto.VirtualMachine{Name: "web1", ClusterName: "cluster1"},
+			expectedID: "1",
+		},
+		{
+			name:        "ambiguous name",
+			vm:          &proto.VirtualMachine{Name: "web1"},
+			expectError: "2 VMs named web1 found, please specify cluster or ID",
+		},
+		{
+			name: "unknown name",
+			vm:   &proto.VirtualMachine{Name: "web2"},
+		},
+		{
+			name:       "by ID",
+			vm:         &proto.VirtualMachine{Id: "1", Name: "web1"},
+			expectedID: "1",
+		},
+		{
+			name: "unknown ID",
+			vm:   &proto.VirtualMachine{Id: "2"},
+		},
+		{
+			name:        "ID of other VM",
+			vm:          &proto.VirtualMachine{Id: "1", Name: "web2"},
+			expectError: "VM 1 is named web1, not web2",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			v, err := svc.findVM(context.Background(), test.vm)
+			if len(test.expectError) > 0 {
+				assert.EqualError(t, err, test.expectError)
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(test.expectedID) == 0 {
+				assert.Nil(t, v)
+				return
+			}
+
+			assert.Equal(t, test.expectedID, v.ID)
+		})
+	}
+}
+
+func TestSearchTerm(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected string
+	}{
+		{
+			value:    "web1",
+			expected: `name="web1"`,
+		},
+		{
+			value:    "web1 or name=db1",
+			expected: `name="web1 or name=db1"`,
+		},
+		{
+			value:    `we"b\1`,
+			expected: `name="we\"b\\1"`,
+		},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, searchTerm("name", test.value))
+	}
+}
@@ -0,0 +1,21 @@
+package ovirt
+
+import "encoding/xml"
+
+// Tags represents a list of tags
+type Tags struct {
+	Tags []Tag `xml:"tag"`
+}
+
+// Tag represents a tag which can be assigned to oVirt objects
+type Tag struct {
+	XMLName     struct{} `xml:"tag"`
+	ID          string   `xml:"id,attr,omitempty"`
+	Name        string   `xml:"name,omitempty"`
+	Description string   `xml:"description,omitempty"`
+}
+
+func (t *Tag) serialize() []byte {
+	b, _ := xml.Marshal(t)
+	return b
+}
@@ -0,0 +1,12 @@
+package ovirt
+
+// Templates represents a list of templates
+type Templates struct {
+	Templates []Template `xml:"template"`
+}
+
+// Template represents an oVirt template
+type Template struct {
+	ID   string `xml:"id,attr"`
+	Name string `xml:"name"`
+}
@@ -1,5 +1,7 @@
 package ovirt
 
+import "encoding/xml"
+
 // VMs represents a list of VMs
 type VMs struct {
 	VMs []VM `xml:"vm"`
@@ -7,7 +9,42 @@ type VMs struct {
 
 // VM represents an oVirt VM
 type VM struct {
-	ID     string `xml:"id,attr"`
-	Name   string `xml:"name"`
-	Status string `xml:"status"`
+	ID       string `xml:"id,attr"`
+	Name     string `xml:"name"`
+	Status   string `xml:"status"`
+	FQDN     string `xml:"fqdn"`
+	Memory   uint64 `xml:"memory"`
+	CPU      *CPU   `xml:"cpu"`
+	Cluster  *Ref   `xml:"cluster"`
+	Template *Ref   `xml:"template"`
+}
+
+// Cluster represents an oVirt cluster
+type Cluster struct {
+	ID   string `xml:"id,attr"`
+	Name string `xml:"name"`
+}
+
+// VMUpdate represents the changeable properties of a VM
+type VMUpdate struct {
+	XMLName struct{} `xml:"vm"`
+	Name    string   `xml:"name,omitempty"`
+	Memory  uint64   `xml:"memory,omitempty"`
+	CPU     *CPU     `xml:"cpu,omitempty"`
+}
+
+// CPU represents the CPU configuration of a VM
+type CPU struct {
+	Topology Topology `xml:"topology"`
+}
+
+// Topology represents the CPU topology of a VM
+type Topology struct {
+	Cores   uint32 `xml:"cores"`
+	Sockets uint32 `xml:"sockets"`
+}
+
+func (v *VMUpdate) serialize() []byte {
+	b, _ := xml.Marshal(v)
+	return b
 }